@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -13,7 +14,11 @@ import (
 	"nofx-lite/decision"
 	"nofx-lite/hook"
 	"nofx-lite/manager"
+	"nofx-lite/market"
+	"nofx-lite/pool"
+	"nofx-lite/ratelimit"
 	"nofx-lite/trader"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -108,6 +113,9 @@ func (s *Server) setupRoutes() {
 		api.POST("/equity-history-batch", s.handleEquityHistoryBatch)
 		api.GET("/traders/:id/public-config", s.handleGetPublicTraderConfig)
 
+		// 外部信号Webhook（使用交易员独立密钥校验，无需登录）
+		api.POST("/webhook/signal/:trader_id", s.handleWebhookSignal)
+
 		// 认证相关路由（无需认证）
 		api.POST("/register", s.handleRegister)
 		api.POST("/login", s.handleLogin)
@@ -123,6 +131,9 @@ func (s *Server) setupRoutes() {
 			// 服务器IP查询（需要认证，用于白名单配置）
 			protected.GET("/server-ip", s.handleGetServerIP)
 
+			// 交易所API限流使用情况
+			protected.GET("/rate-limits", s.handleRateLimits)
+
 			// AI交易员管理
 			protected.GET("/my-traders", s.handleTraderList)
 			protected.GET("/traders/:id/config", s.handleGetTraderConfig)
@@ -133,6 +144,9 @@ func (s *Server) setupRoutes() {
 			protected.POST("/traders/:id/stop", s.handleStopTrader)
 			protected.PUT("/traders/:id/prompt", s.handleUpdateTraderPrompt)
 			protected.POST("/traders/:id/sync-balance", s.handleSyncBalance)
+			protected.POST("/traders/:id/manual-decision", s.handleManualDecision)
+			protected.GET("/traders/:id/webhook", s.handleGetTraderWebhook)
+			protected.PUT("/traders/:id/webhook", s.handleUpdateTraderWebhook)
 
 			// AI模型配置
 			protected.GET("/models", s.handleGetModelConfigs)
@@ -148,14 +162,22 @@ func (s *Server) setupRoutes() {
 			protected.GET("/user/signal-sources", s.handleGetUserSignalSource)
 			protected.POST("/user/signal-sources", s.handleSaveUserSignalSource)
 
+			// 用户币种黑白名单
+			protected.GET("/user/symbol-filters", s.handleGetUserSymbolFilter)
+			protected.POST("/user/symbol-filters", s.handleSaveUserSymbolFilter)
+
 			// 指定trader的数据（使用query参数 ?trader_id=xxx）
 			protected.GET("/status", s.handleStatus)
 			protected.GET("/account", s.handleAccount)
 			protected.GET("/positions", s.handlePositions)
+			protected.GET("/executions", s.handleExecutions)
 			protected.GET("/decisions", s.handleDecisions)
 			protected.GET("/decisions/latest", s.handleLatestDecisions)
 			protected.GET("/statistics", s.handleStatistics)
 			protected.GET("/performance", s.handlePerformance)
+
+			// 市场分析
+			protected.GET("/market/correlations", s.handleMarketCorrelations)
 		}
 	}
 }
@@ -207,6 +229,34 @@ func (s *Server) handleGetSystemConfig(c *gin.Context) {
 	})
 }
 
+// handleRateLimits 各交易所API权重使用、重试、限流与熔断统计
+// 只返回公共行情接口与当前用户交易员所用账户的限流器，不暴露其他用户的API使用情况
+func (s *Server) handleRateLimits(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traders, err := s.database.GetTraders(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取交易员列表失败: %v", err)})
+		return
+	}
+
+	owned := make(map[string]bool)
+	for _, t := range traders {
+		if at, err := s.traderManager.GetTrader(t.ID); err == nil {
+			if name := at.GetRateLimiterName(); name != "" {
+				owned[name] = true
+			}
+		}
+	}
+
+	limiters := make([]ratelimit.Stats, 0)
+	for _, stats := range ratelimit.Snapshot() {
+		if ratelimit.IsPublic(stats.Name) || owned[stats.Name] {
+			limiters = append(limiters, stats)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"limiters": limiters})
+}
+
 // handleGetServerIP 获取服务器IP地址（用于白名单配置）
 func (s *Server) handleGetServerIP(c *gin.Context) {
 
@@ -391,6 +441,16 @@ type CreateTraderRequest struct {
 	IsCrossMargin        *bool   `json:"is_cross_margin"`        // 指针类型，nil表示使用默认值true
 	UseCoinPool          bool    `json:"use_coin_pool"`
 	UseOITop             bool    `json:"use_oi_top"`
+	RegimeRiskLimits     decision.RegimeRiskLimits `json:"regime_risk_limits"` // 按市场状态的风控参数（可选）
+	MaxSlippagePct       float64 `json:"max_slippage_pct"`       // 开仓最大预估滑点百分比（0=默认值）
+	TimeExitRules        *trader.TimeExitRules `json:"time_exit_rules"` // 按持仓时间的退出规则（可选）
+	TradingSchedule      *trader.TradingSchedule `json:"trading_schedule"` // 交易时段与禁止开仓时段（可选）
+	ReconcilePolicy      *trader.ReconcilePolicy `json:"reconcile_policy"` // 启动对账时无止损持仓的处理策略（可选）
+	PositionSizing       *trader.PositionSizing  `json:"position_sizing"`  // 开仓仓位计算模型（可选，默认AI建议仓位）
+	UseDefaultCoins      *bool                   `json:"use_default_coins"` // 未启用外部信号时使用默认币种，nil表示使用默认值true
+	SignalSources        []pool.SignalSourceSpec `json:"signal_sources"`    // 币种池的额外信号源（可选）
+	CorrelationThreshold float64 `json:"correlation_threshold"`   // 相关系数阈值（0=默认值）
+	MaxCorrelatedExposure float64 `json:"max_correlated_exposure"` // 同向高相关敞口上限，账户净值倍数（0=默认值）
 }
 
 type ModelConfig struct {
@@ -532,6 +592,64 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		scanIntervalMinutes = 3 // 默认3分钟，且不允许小于3
 	}
 
+	// 校验按市场状态的风控参数
+	regimeRiskLimits, err := encodeRegimeRiskLimits(req.RegimeRiskLimits)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 校验最大滑点
+	if err := validateMaxSlippagePct(req.MaxSlippagePct); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 校验相关性风控参数
+	if err := validateCorrelationLimits(req.CorrelationThreshold, req.MaxCorrelatedExposure); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 校验按持仓时间的退出规则
+	timeExitRules, err := encodeTimeExitRules(req.TimeExitRules)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 校验交易时段
+	tradingSchedule, err := encodeTradingSchedule(req.TradingSchedule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 校验启动对账策略
+	reconcilePolicy, err := encodeReconcilePolicy(req.ReconcilePolicy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 校验仓位计算模型
+	positionSizing, err := encodePositionSizing(req.PositionSizing)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 币种池配置：默认币种开关与额外信号源
+	useDefaultCoins := true
+	if req.UseDefaultCoins != nil {
+		useDefaultCoins = *req.UseDefaultCoins
+	}
+	signalSources, err := encodeSignalSources(req.SignalSources)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// ✨ 查询交易所实际余额，覆盖用户输入
 	actualBalance := req.InitialBalance // 默认使用用户输入
 	exchanges, err := s.database.GetExchanges(userID)
@@ -585,12 +703,12 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 				log.Printf("⚠️ 查询交易所余额失败，使用用户输入的初始资金: %v", balanceErr)
 			} else {
 				// 提取可用余额
-				if availableBalance, ok := balanceInfo["available_balance"].(float64); ok && availableBalance > 0 {
-					actualBalance = availableBalance
+				if balanceInfo.AvailableBalance > 0 {
+					actualBalance = balanceInfo.AvailableBalance
 					log.Printf("✓ 查询到交易所实际余额: %.2f USDT (用户输入: %.2f USDT)", actualBalance, req.InitialBalance)
-				} else if totalBalance, ok := balanceInfo["balance"].(float64); ok && totalBalance > 0 {
-					// 有些交易所可能只返回 balance 字段
-					actualBalance = totalBalance
+				} else if balanceInfo.TotalWalletBalance > 0 {
+					// 可用余额为0时（如全部被保证金占用）使用钱包余额
+					actualBalance = balanceInfo.TotalWalletBalance
 					log.Printf("✓ 查询到交易所实际余额: %.2f USDT (用户输入: %.2f USDT)", actualBalance, req.InitialBalance)
 				} else {
 					log.Printf("⚠️ 无法从余额信息中提取可用余额，使用用户输入的初始资金")
@@ -616,6 +734,16 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		OverrideBasePrompt:   req.OverrideBasePrompt,
 		SystemPromptTemplate: systemPromptTemplate,
 		IsCrossMargin:        isCrossMargin,
+		RegimeRiskLimits:     regimeRiskLimits,
+		MaxSlippagePct:       req.MaxSlippagePct,
+		TimeExitRules:        timeExitRules,
+		TradingSchedule:      tradingSchedule,
+		ReconcilePolicy:      reconcilePolicy,
+		PositionSizing:       positionSizing,
+		UseDefaultCoins:      useDefaultCoins,
+		SignalSources:        signalSources,
+		CorrelationThreshold: req.CorrelationThreshold,
+		MaxCorrelatedExposure: req.MaxCorrelatedExposure,
 		ScanIntervalMinutes:  scanIntervalMinutes,
 		IsRunning:            false,
 	}
@@ -658,6 +786,130 @@ type UpdateTraderRequest struct {
 	OverrideBasePrompt   bool    `json:"override_base_prompt"`
 	SystemPromptTemplate string  `json:"system_prompt_template"`
 	IsCrossMargin        *bool   `json:"is_cross_margin"`
+	RegimeRiskLimits     decision.RegimeRiskLimits `json:"regime_risk_limits"` // nil表示保持原值，{}表示清空
+	MaxSlippagePct       *float64 `json:"max_slippage_pct"`      // nil表示保持原值，0表示使用默认值
+	TimeExitRules        *trader.TimeExitRules `json:"time_exit_rules"` // nil表示保持原值，{}表示清空
+	TradingSchedule      *trader.TradingSchedule `json:"trading_schedule"` // nil表示保持原值，{}表示清空
+	ReconcilePolicy      *trader.ReconcilePolicy `json:"reconcile_policy"` // nil表示保持原值，{}表示恢复默认
+	PositionSizing       *trader.PositionSizing  `json:"position_sizing"`  // nil表示保持原值，{}表示恢复AI建议仓位
+	UseDefaultCoins      *bool                    `json:"use_default_coins"` // nil表示保持原值
+	SignalSources        *[]pool.SignalSourceSpec `json:"signal_sources"`    // nil表示保持原值，[]表示清空
+	CorrelationThreshold *float64 `json:"correlation_threshold"`   // nil表示保持原值，0表示使用默认值
+	MaxCorrelatedExposure *float64 `json:"max_correlated_exposure"` // nil表示保持原值，0表示使用默认值
+}
+
+// encodeRegimeRiskLimits 校验并序列化按市场状态的风控参数（空配置存为空字符串）
+func encodeRegimeRiskLimits(limits decision.RegimeRiskLimits) (string, error) {
+	if len(limits) == 0 {
+		return "", nil
+	}
+	if err := limits.Validate(); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(limits)
+	if err != nil {
+		return "", fmt.Errorf("序列化市场状态风控配置失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// encodeTimeExitRules 校验并序列化按持仓时间的退出规则（未启用任何规则时存为空字符串）
+func encodeTimeExitRules(rules *trader.TimeExitRules) (string, error) {
+	if !rules.Enabled() {
+		return "", nil
+	}
+	if err := rules.Validate(); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return "", fmt.Errorf("序列化持仓时间退出规则失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// encodeTradingSchedule 校验并序列化交易时段（未配置任何限制时存为空字符串）
+func encodeTradingSchedule(schedule *trader.TradingSchedule) (string, error) {
+	if !schedule.Enabled() {
+		return "", nil
+	}
+	if err := schedule.Validate(); err != nil {
+		return "", err
+	}
+	if err := schedule.ValidateCalendarFile(); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return "", fmt.Errorf("序列化交易时段配置失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// encodeReconcilePolicy 校验并序列化启动对账策略（默认策略存为空字符串）
+func encodeReconcilePolicy(policy *trader.ReconcilePolicy) (string, error) {
+	if policy.IsDefault() {
+		return "", nil
+	}
+	if err := policy.Validate(); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return "", fmt.Errorf("序列化启动对账策略失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// encodePositionSizing 校验并序列化仓位计算模型（AI建议仓位存为空字符串）
+func encodePositionSizing(sizing *trader.PositionSizing) (string, error) {
+	if !sizing.Enabled() {
+		return "", nil
+	}
+	if err := sizing.Validate(); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(sizing)
+	if err != nil {
+		return "", fmt.Errorf("序列化仓位计算模型失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// encodeSignalSources 校验并序列化交易员的额外信号源（空列表存为空字符串）
+func encodeSignalSources(specs []pool.SignalSourceSpec) (string, error) {
+	if len(specs) == 0 {
+		return "", nil
+	}
+	for i, spec := range specs {
+		if strings.TrimSpace(spec.Type) == "" {
+			return "", fmt.Errorf("signal_sources 第%d项缺少 type", i+1)
+		}
+	}
+	data, err := json.Marshal(specs)
+	if err != nil {
+		return "", fmt.Errorf("序列化信号源配置失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// validateMaxSlippagePct 校验最大滑点百分比（0表示使用默认值）
+func validateMaxSlippagePct(pct float64) error {
+	if pct < 0 || pct > 10 {
+		return fmt.Errorf("max_slippage_pct 必须在 0-10 之间")
+	}
+	return nil
+}
+
+// validateCorrelationLimits 校验相关性风控参数（0表示使用默认值）
+func validateCorrelationLimits(threshold, maxExposure float64) error {
+	if threshold < 0 || threshold > 1 {
+		return fmt.Errorf("correlation_threshold 必须在 0-1 之间")
+	}
+	if maxExposure < 0 || maxExposure > 20 {
+		return fmt.Errorf("max_correlated_exposure 必须在 0-20 之间")
+	}
+	return nil
 }
 
 // handleUpdateTrader 更新交易员配置
@@ -721,6 +973,94 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		systemPromptTemplate = existingTrader.SystemPromptTemplate // 如果请求中没有提供，保持原值
 	}
 
+	// 设置按市场状态的风控参数，未提供时保持原值
+	regimeRiskLimits := existingTrader.RegimeRiskLimits
+	if req.RegimeRiskLimits != nil {
+		regimeRiskLimits, err = encodeRegimeRiskLimits(req.RegimeRiskLimits)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 设置最大滑点，未提供时保持原值
+	maxSlippagePct := existingTrader.MaxSlippagePct
+	if req.MaxSlippagePct != nil {
+		if err := validateMaxSlippagePct(*req.MaxSlippagePct); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		maxSlippagePct = *req.MaxSlippagePct
+	}
+
+	// 设置相关性风控参数，未提供时保持原值
+	correlationThreshold := existingTrader.CorrelationThreshold
+	if req.CorrelationThreshold != nil {
+		correlationThreshold = *req.CorrelationThreshold
+	}
+	maxCorrelatedExposure := existingTrader.MaxCorrelatedExposure
+	if req.MaxCorrelatedExposure != nil {
+		maxCorrelatedExposure = *req.MaxCorrelatedExposure
+	}
+	if err := validateCorrelationLimits(correlationThreshold, maxCorrelatedExposure); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 设置按持仓时间的退出规则，未提供时保持原值
+	timeExitRules := existingTrader.TimeExitRules
+	if req.TimeExitRules != nil {
+		timeExitRules, err = encodeTimeExitRules(req.TimeExitRules)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 设置交易时段，未提供时保持原值
+	tradingSchedule := existingTrader.TradingSchedule
+	if req.TradingSchedule != nil {
+		tradingSchedule, err = encodeTradingSchedule(req.TradingSchedule)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 设置启动对账策略，未提供时保持原值
+	reconcilePolicy := existingTrader.ReconcilePolicy
+	if req.ReconcilePolicy != nil {
+		reconcilePolicy, err = encodeReconcilePolicy(req.ReconcilePolicy)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 设置仓位计算模型，未提供时保持原值
+	positionSizing := existingTrader.PositionSizing
+	if req.PositionSizing != nil {
+		positionSizing, err = encodePositionSizing(req.PositionSizing)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 设置币种池配置，未提供时保持原值
+	useDefaultCoins := existingTrader.UseDefaultCoins
+	if req.UseDefaultCoins != nil {
+		useDefaultCoins = *req.UseDefaultCoins
+	}
+	signalSources := existingTrader.SignalSources
+	if req.SignalSources != nil {
+		signalSources, err = encodeSignalSources(*req.SignalSources)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// 更新交易员配置
 	trader := &config.TraderRecord{
 		ID:                   traderID,
@@ -736,6 +1076,16 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		OverrideBasePrompt:   req.OverrideBasePrompt,
 		SystemPromptTemplate: systemPromptTemplate,
 		IsCrossMargin:        isCrossMargin,
+		RegimeRiskLimits:     regimeRiskLimits,
+		MaxSlippagePct:       maxSlippagePct,
+		TimeExitRules:        timeExitRules,
+		TradingSchedule:      tradingSchedule,
+		ReconcilePolicy:      reconcilePolicy,
+		PositionSizing:       positionSizing,
+		UseDefaultCoins:      useDefaultCoins,
+		SignalSources:        signalSources,
+		CorrelationThreshold: correlationThreshold,
+		MaxCorrelatedExposure: maxCorrelatedExposure,
 		ScanIntervalMinutes:  scanIntervalMinutes,
 		IsRunning:            existingTrader.IsRunning, // 保持原值
 	}
@@ -902,6 +1252,52 @@ func (s *Server) handleUpdateTraderPrompt(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "自定义prompt已更新"})
 }
 
+// handleManualDecision 人工下达决策（平仓、调整止损、对冲开仓等），不停止交易员
+// 与AI决策走相同的校验与执行路径，记录标记为 manual，并在下个周期告知AI
+func (s *Server) handleManualDecision(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	// 校验交易员是否属于当前用户
+	_, _, _, err := s.database.GetTraderConfig(userID, traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return
+	}
+
+	var req struct {
+		decision.Decision
+		Note string `json:"note"` // 操作原因（告知AI）
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Symbol = market.Normalize(strings.ToUpper(strings.TrimSpace(req.Symbol)))
+	note := truncateRunes(strings.TrimSpace(req.Note), 500)
+
+	log.Printf("👤 用户 %s 对交易员 %s 下达人工决策: %s %s", userID, traderID, req.Symbol, req.Action)
+	action, err := at.ExecuteManualDecision(req.Decision, note)
+	switch {
+	case errors.Is(err, trader.ErrTraderNotRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, trader.ErrTraderBusy):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, trader.ErrInvalidManualDecision):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "action": action})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "action": action})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "人工决策已执行", "action": action})
+	}
+}
+
 // handleSyncBalance 同步交易所余额到initial_balance（选项B：手动同步 + 选项C：智能检测）
 func (s *Server) handleSyncBalance(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -961,12 +1357,10 @@ func (s *Server) handleSyncBalance(c *gin.Context) {
 
 	// 提取可用余额
 	var actualBalance float64
-	if availableBalance, ok := balanceInfo["available_balance"].(float64); ok && availableBalance > 0 {
-		actualBalance = availableBalance
-	} else if availableBalance, ok := balanceInfo["availableBalance"].(float64); ok && availableBalance > 0 {
-		actualBalance = availableBalance
-	} else if totalBalance, ok := balanceInfo["balance"].(float64); ok && totalBalance > 0 {
-		actualBalance = totalBalance
+	if balanceInfo.AvailableBalance > 0 {
+		actualBalance = balanceInfo.AvailableBalance
+	} else if balanceInfo.TotalWalletBalance > 0 {
+		actualBalance = balanceInfo.TotalWalletBalance
 	} else {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法获取可用余额"})
 		return
@@ -1353,6 +1747,280 @@ func (s *Server) handleSaveUserSignalSource(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "用户信号源配置已保存"})
 }
 
+// handleGetUserSymbolFilter 获取用户币种黑白名单
+func (s *Server) handleGetUserSymbolFilter(c *gin.Context) {
+	userID := c.GetString("user_id")
+	filter, err := s.database.GetUserSymbolFilter(userID)
+	if err != nil {
+		// 如果配置不存在，返回空配置
+		c.JSON(http.StatusOK, gin.H{
+			"blacklist": []string{},
+			"whitelist": []string{},
+		})
+		return
+	}
+
+	blacklist := trader.ParseSymbolList(filter.Blacklist)
+	whitelist := trader.ParseSymbolList(filter.Whitelist)
+	if blacklist == nil {
+		blacklist = []string{}
+	}
+	if whitelist == nil {
+		whitelist = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"blacklist": blacklist,
+		"whitelist": whitelist,
+	})
+}
+
+// handleSaveUserSymbolFilter 保存用户币种黑白名单（下个决策周期生效）
+func (s *Server) handleSaveUserSymbolFilter(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req struct {
+		Blacklist []string `json:"blacklist"`
+		Whitelist []string `json:"whitelist"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	blacklist := trader.ParseSymbolList(strings.Join(req.Blacklist, ","))
+	whitelist := trader.ParseSymbolList(strings.Join(req.Whitelist, ","))
+	for _, symbol := range whitelist {
+		if slices.Contains(blacklist, symbol) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s 同时出现在黑名单和白名单中", symbol)})
+			return
+		}
+	}
+
+	err := s.database.SaveUserSymbolFilter(userID, strings.Join(blacklist, ","), strings.Join(whitelist, ","))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("保存币种黑白名单失败: %v", err)})
+		return
+	}
+
+	log.Printf("✓ 用户币种黑白名单已保存: user=%s, blacklist=%v, whitelist=%v", userID, blacklist, whitelist)
+	c.JSON(http.StatusOK, gin.H{"message": "币种黑白名单已保存"})
+}
+
+// handleGetTraderWebhook 获取交易员Webhook配置
+func (s *Server) handleGetTraderWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return
+	}
+
+	webhook, err := s.database.GetTraderWebhook(traderID)
+	if err != nil {
+		// 未配置时返回空配置
+		c.JSON(http.StatusOK, gin.H{
+			"configured": false,
+			"path":       "/api/webhook/signal/" + traderID,
+		})
+		return
+	}
+
+	// 密钥只保存哈希，无法再次查看（需要时通过 regenerate_secret 轮换）
+	c.JSON(http.StatusOK, gin.H{
+		"configured":         true,
+		"path":               "/api/webhook/signal/" + traderID,
+		"has_secret":         webhook.SecretHash != "",
+		"enabled":            webhook.Enabled,
+		"signal_ttl_minutes": webhook.SignalTTLMinutes,
+		"trigger_cycle":      webhook.TriggerCycle,
+	})
+}
+
+// handleUpdateTraderWebhook 创建或更新交易员Webhook配置（首次保存或regenerate_secret时生成新密钥，明文只在本次响应中返回）
+func (s *Server) handleUpdateTraderWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	var req struct {
+		Enabled          *bool `json:"enabled"`
+		SignalTTLMinutes int   `json:"signal_ttl_minutes"`
+		TriggerCycle     *bool `json:"trigger_cycle"`
+		RegenerateSecret bool  `json:"regenerate_secret"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.SignalTTLMinutes < 0 || req.SignalTTLMinutes > 7*24*60 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "信号有效期需在0-10080分钟之间"})
+		return
+	}
+
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return
+	}
+
+	webhook, err := s.database.GetTraderWebhook(traderID)
+	if err != nil {
+		webhook = &config.TraderWebhook{
+			TraderID:         traderID,
+			UserID:           userID,
+			Enabled:          true,
+			SignalTTLMinutes: 60,
+		}
+	}
+	var newSecret string
+	if webhook.SecretHash == "" || req.RegenerateSecret {
+		newSecret, err = config.GenerateWebhookSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("生成Webhook密钥失败: %v", err)})
+			return
+		}
+		webhook.SecretHash = config.HashWebhookSecret(newSecret)
+	}
+	if req.Enabled != nil {
+		webhook.Enabled = *req.Enabled
+	}
+	if req.SignalTTLMinutes > 0 {
+		webhook.SignalTTLMinutes = req.SignalTTLMinutes
+	}
+	if req.TriggerCycle != nil {
+		webhook.TriggerCycle = *req.TriggerCycle
+	}
+
+	if err := s.database.SaveTraderWebhook(webhook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("保存Webhook配置失败: %v", err)})
+		return
+	}
+
+	log.Printf("✓ 交易员Webhook配置已保存: trader=%s, enabled=%v, ttl=%d分钟, trigger_cycle=%v",
+		traderID, webhook.Enabled, webhook.SignalTTLMinutes, webhook.TriggerCycle)
+	result := gin.H{
+		"message":            "Webhook配置已保存",
+		"path":               "/api/webhook/signal/" + traderID,
+		"has_secret":         true,
+		"enabled":            webhook.Enabled,
+		"signal_ttl_minutes": webhook.SignalTTLMinutes,
+		"trigger_cycle":      webhook.TriggerCycle,
+	}
+	if newSecret != "" {
+		result["secret"] = newSecret // 仅在生成/轮换时返回明文，请妥善保存
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// truncateRunes 按字符截断（避免截断多字节的中文字符）
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
+
+// handleWebhookSignal 接收外部告警推送的交易信号（TradingView等）
+// 密钥可放在请求头 X-Webhook-Secret 或请求体 secret 字段（TradingView无法自定义请求头）
+func (s *Server) handleWebhookSignal(c *gin.Context) {
+	traderID := c.Param("trader_id")
+
+	var req struct {
+		Secret       string  `json:"secret"`
+		Symbol       string  `json:"symbol" binding:"required"`
+		Direction    string  `json:"direction" binding:"required"`
+		Strength     float64 `json:"strength"`
+		Message      string  `json:"message"`
+		TTLMinutes   int     `json:"ttl_minutes"`
+		TriggerCycle *bool   `json:"trigger_cycle"` // 只能在配置允许时关闭本次触发，不能强制触发
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret := c.GetHeader("X-Webhook-Secret")
+	if secret == "" {
+		secret = req.Secret
+	}
+
+	webhook, err := s.database.GetTraderWebhook(traderID)
+	if err != nil || !webhook.Enabled || !webhook.VerifySecret(secret) {
+		log.Printf("⚠️  Webhook信号校验失败: trader=%s, ip=%s", traderID, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Webhook校验失败"})
+		return
+	}
+
+	// 方向兼容常见写法
+	direction := strings.ToLower(strings.TrimSpace(req.Direction))
+	switch direction {
+	case "long", "buy":
+		direction = "long"
+	case "short", "sell":
+		direction = "short"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的信号方向: %s（支持 long/short/buy/sell）", req.Direction)})
+		return
+	}
+
+	// 强度: 0-1，兼容0-100的写法；未填写视为1
+	strength := req.Strength
+	if strength > 1 && strength <= 100 {
+		strength = strength / 100
+	}
+	if strength == 0 {
+		strength = 1
+	}
+	if strength < 0 || strength > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的信号强度: %v（范围0-1）", req.Strength)})
+		return
+	}
+
+	// 兼容TradingView的 {{ticker}} 写法（如 BINANCE:BTCUSDT.P）
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+	if idx := strings.LastIndex(symbol, ":"); idx >= 0 {
+		symbol = symbol[idx+1:]
+	}
+	symbol = market.Normalize(strings.TrimSuffix(symbol, ".P"))
+	message := truncateRunes(strings.TrimSpace(req.Message), 500)
+
+	ttlMinutes := webhook.SignalTTLMinutes
+	if req.TTLMinutes > 0 && req.TTLMinutes < ttlMinutes {
+		ttlMinutes = req.TTLMinutes // 只允许缩短有效期
+	}
+	if ttlMinutes <= 0 {
+		ttlMinutes = 60
+	}
+
+	signal := &config.WebhookSignal{
+		TraderID:  traderID,
+		Symbol:    symbol,
+		Direction: direction,
+		Strength:  strength,
+		Message:   message,
+	}
+	if err := s.database.CreateWebhookSignal(signal, time.Duration(ttlMinutes)*time.Minute); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("保存信号失败: %v", err)})
+		return
+	}
+	log.Printf("📡 收到外部信号: trader=%s %s %s 强度%.2f 有效期%d分钟", traderID, symbol, direction, strength, ttlMinutes)
+
+	// 可选：立即触发一次决策周期（以交易员配置为准，信号只能选择不触发）
+	triggered := false
+	if webhook.TriggerCycle && (req.TriggerCycle == nil || *req.TriggerCycle) {
+		if at, err := s.traderManager.GetTrader(traderID); err == nil {
+			triggered = at.TriggerCycle(fmt.Sprintf("外部信号 %s %s", symbol, direction))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "信号已接收",
+		"id":         signal.ID,
+		"expires_at": signal.ExpiresAt,
+		"triggered":  triggered,
+	})
+}
+
 // handleTraderList trader列表
 func (s *Server) handleTraderList(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -1416,6 +2084,16 @@ func (s *Server) handleGetTraderConfig(c *gin.Context) {
 	// 返回完整的模型ID，不做转换，保持与前端模型列表一致
 	aiModelID := traderConfig.AIModelID
 
+	regimeRiskLimits, _ := decision.ParseRegimeRiskLimits(traderConfig.RegimeRiskLimits)
+	timeExitRules, _ := trader.ParseTimeExitRules(traderConfig.TimeExitRules)
+	tradingSchedule, _ := trader.ParseTradingSchedule(traderConfig.TradingSchedule)
+	reconcilePolicy, _ := trader.ParseReconcilePolicy(traderConfig.ReconcilePolicy)
+	positionSizing, _ := trader.ParsePositionSizing(traderConfig.PositionSizing)
+	var signalSources []pool.SignalSourceSpec
+	if traderConfig.SignalSources != "" {
+		json.Unmarshal([]byte(traderConfig.SignalSources), &signalSources)
+	}
+
 	result := map[string]interface{}{
 		"trader_id":              traderConfig.ID,
 		"trader_name":            traderConfig.Name,
@@ -1432,6 +2110,16 @@ func (s *Server) handleGetTraderConfig(c *gin.Context) {
 		"is_cross_margin":        traderConfig.IsCrossMargin,
 		"use_coin_pool":          traderConfig.UseCoinPool,
 		"use_oi_top":             traderConfig.UseOITop,
+		"regime_risk_limits":     regimeRiskLimits,
+		"max_slippage_pct":       traderConfig.MaxSlippagePct,
+		"time_exit_rules":        timeExitRules,
+		"trading_schedule":       tradingSchedule,
+		"reconcile_policy":       reconcilePolicy,
+		"position_sizing":        positionSizing,
+		"use_default_coins":      traderConfig.UseDefaultCoins,
+		"signal_sources":         signalSources,
+		"correlation_threshold":  traderConfig.CorrelationThreshold,
+		"max_correlated_exposure": traderConfig.MaxCorrelatedExposure,
 		"is_running":             isRunning,
 	}
 
@@ -1499,10 +2187,10 @@ func (s *Server) handleAccount(c *gin.Context) {
 
 	log.Printf("✓ 返回账户信息 [%s]: 净值=%.2f, 可用=%.2f, 盈亏=%.2f (%.2f%%)",
 		trader.GetName(),
-		account["total_equity"],
-		account["available_balance"],
-		account["total_pnl"],
-		account["total_pnl_pct"])
+		account.TotalEquity,
+		account.AvailableBalance,
+		account.TotalPnL,
+		account.TotalPnLPct)
 	c.JSON(http.StatusOK, account)
 }
 
@@ -1531,6 +2219,97 @@ func (s *Server) handlePositions(c *gin.Context) {
 	c.JSON(http.StatusOK, positions)
 }
 
+// handleExecutions 最近的开仓执行进度（冰山/TWAP拆单）
+func (s *Server) handleExecutions(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, trader.GetExecutions())
+}
+
+// handleMarketCorrelations 收益率相关性矩阵与BTC beta
+// 参数: symbols=BTCUSDT,ETHUSDT（可选，默认使用trader当前持仓）, interval=4h, threshold=0.7
+// K线来自trader交易所对应的行情数据源（无可用trader时使用币安）
+func (s *Server) handleMarketCorrelations(c *gin.Context) {
+	interval := c.DefaultQuery("interval", market.DefaultCorrelationInterval)
+	if !market.IsValidCorrelationInterval(interval) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("不支持的K线周期: %s", interval)})
+		return
+	}
+
+	_, traderID, traderErr := s.getTraderFromQuery(c)
+	var source market.MarketDataSource
+	if traderErr == nil {
+		if at, err := s.traderManager.GetTrader(traderID); err == nil {
+			source = at.GetMarketSource()
+		}
+	}
+	if source == nil {
+		source = market.NewBinanceSource(false)
+	}
+
+	var symbols []string
+	if raw := c.Query("symbols"); raw != "" {
+		seen := make(map[string]bool)
+		for _, sym := range strings.Split(raw, ",") {
+			if sym = strings.TrimSpace(sym); sym != "" && !seen[market.Normalize(sym)] {
+				seen[market.Normalize(sym)] = true
+				symbols = append(symbols, market.Normalize(sym))
+			}
+		}
+		if len(symbols) > market.MaxCorrelationSymbols {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("最多同时计算 %d 个币种", market.MaxCorrelationSymbols)})
+			return
+		}
+	} else {
+		if traderErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": traderErr.Error()})
+			return
+		}
+		trader, err := s.traderManager.GetTrader(traderID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		positions, err := trader.GetPositions()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取持仓列表失败: %v", err)})
+			return
+		}
+		for _, pos := range positions {
+			symbols = append(symbols, pos.Symbol)
+		}
+	}
+
+	threshold := 0.7
+	if raw := c.Query("threshold"); raw != "" {
+		if v, err := strconv.ParseFloat(raw, 64); err == nil && v > 0 && v <= 1 {
+			threshold = v
+		}
+	}
+
+	correlations, err := market.ComputeCorrelations(source, symbols, interval)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("计算相关性失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"correlations": correlations,
+		"high_pairs":   correlations.HighlyCorrelatedPairs(correlations.Symbols, threshold),
+		"threshold":    threshold,
+	})
+}
+
 // handleDecisions 决策日志列表
 func (s *Server) handleDecisions(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
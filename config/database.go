@@ -2,8 +2,11 @@ package config
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/base32"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -46,6 +49,14 @@ type DatabaseInterface interface {
 	CreateUserSignalSource(userID, coinPoolURL, oiTopURL string) error
 	GetUserSignalSource(userID string) (*UserSignalSource, error)
 	UpdateUserSignalSource(userID, coinPoolURL, oiTopURL string) error
+	SaveUserSymbolFilter(userID, blacklist, whitelist string) error
+	GetUserSymbolFilter(userID string) (*UserSymbolFilter, error)
+	SaveTraderWebhook(webhook *TraderWebhook) error
+	GetTraderWebhook(traderID string) (*TraderWebhook, error)
+	SaveTraderRuntimeState(traderID, userID, state string) error
+	GetTraderRuntimeState(traderID string) (*TraderRuntimeState, error)
+	CreateWebhookSignal(signal *WebhookSignal, ttl time.Duration) error
+	GetActiveWebhookSignals(traderID string) ([]*WebhookSignal, error)
 	GetCustomCoins() []string
 	LoadBetaCodesFromFile(filePath string) error
 	ValidateBetaCode(code string) (bool, error)
@@ -174,6 +185,15 @@ func (d *Database) createTables() error {
             UNIQUE(user_id)
         )`,
 
+        `CREATE TABLE IF NOT EXISTS user_symbol_filters (
+            user_id TEXT PRIMARY KEY,
+            blacklist TEXT DEFAULT '',
+            whitelist TEXT DEFAULT '',
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+        )`,
+
         `CREATE TABLE IF NOT EXISTS traders (
             id TEXT PRIMARY KEY,
             user_id TEXT NOT NULL DEFAULT 'default',
@@ -192,11 +212,57 @@ func (d *Database) createTables() error {
             override_base_prompt BOOLEAN DEFAULT FALSE,
             system_prompt_template TEXT DEFAULT 'default',
             is_cross_margin BOOLEAN DEFAULT TRUE,
+            regime_risk_limits TEXT DEFAULT '',
+            max_slippage_pct DOUBLE PRECISION DEFAULT 0,
+            time_exit_rules TEXT DEFAULT '',
+            trading_schedule TEXT DEFAULT '',
+            reconcile_policy TEXT DEFAULT '',
+            position_sizing TEXT DEFAULT '',
+            use_default_coins BOOLEAN DEFAULT TRUE,
+            signal_sources TEXT DEFAULT '',
+            correlation_threshold DOUBLE PRECISION DEFAULT 0,
+            max_correlated_exposure DOUBLE PRECISION DEFAULT 0,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+        )`,
+
+        `CREATE TABLE IF NOT EXISTS trader_webhooks (
+            trader_id TEXT PRIMARY KEY,
+            user_id TEXT NOT NULL,
+            secret TEXT NOT NULL, -- 校验密钥的哈希（sha256:<hex>），不保存明文
+            enabled BOOLEAN DEFAULT TRUE,
+            signal_ttl_minutes INTEGER DEFAULT 60,
+            trigger_cycle BOOLEAN DEFAULT FALSE,
             created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
             updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (trader_id) REFERENCES traders(id) ON DELETE CASCADE,
+            FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+        )`,
+
+        `CREATE TABLE IF NOT EXISTS trader_runtime_state (
+            trader_id TEXT PRIMARY KEY,
+            user_id TEXT NOT NULL,
+            state TEXT NOT NULL DEFAULT '{}',
+            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (trader_id) REFERENCES traders(id) ON DELETE CASCADE,
             FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
         )`,
 
+        `CREATE TABLE IF NOT EXISTS webhook_signals (
+            id SERIAL PRIMARY KEY,
+            trader_id TEXT NOT NULL,
+            symbol TEXT NOT NULL,
+            direction TEXT NOT NULL,
+            strength DOUBLE PRECISION DEFAULT 0,
+            message TEXT DEFAULT '',
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            expires_at TIMESTAMP NOT NULL,
+            FOREIGN KEY (trader_id) REFERENCES traders(id) ON DELETE CASCADE
+        )`,
+
+        `CREATE INDEX IF NOT EXISTS idx_webhook_signals_trader_expires ON webhook_signals (trader_id, expires_at)`,
+
         `CREATE TABLE IF NOT EXISTS system_config (
             key TEXT PRIMARY KEY,
             value TEXT NOT NULL,
@@ -244,6 +310,16 @@ func (d *Database) createTables() error {
            BEFORE UPDATE ON user_signal_sources
            FOR EACH ROW EXECUTE FUNCTION set_updated_at()`,
 
+        `DROP TRIGGER IF EXISTS update_user_symbol_filters_updated_at ON user_symbol_filters`,
+        `CREATE TRIGGER update_user_symbol_filters_updated_at
+           BEFORE UPDATE ON user_symbol_filters
+           FOR EACH ROW EXECUTE FUNCTION set_updated_at()`,
+
+        `DROP TRIGGER IF EXISTS update_trader_webhooks_updated_at ON trader_webhooks`,
+        `CREATE TRIGGER update_trader_webhooks_updated_at
+           BEFORE UPDATE ON trader_webhooks
+           FOR EACH ROW EXECUTE FUNCTION set_updated_at()`,
+
         `DROP TRIGGER IF EXISTS update_system_config_updated_at ON system_config`,
         `CREATE TRIGGER update_system_config_updated_at
            BEFORE UPDATE ON system_config
@@ -272,6 +348,18 @@ func (d *Database) createTables() error {
         `ALTER TABLE IF EXISTS traders ADD COLUMN IF NOT EXISTS use_coin_pool BOOLEAN DEFAULT FALSE`,
         `ALTER TABLE IF EXISTS traders ADD COLUMN IF NOT EXISTS use_oi_top BOOLEAN DEFAULT FALSE`,
         `ALTER TABLE IF EXISTS traders ADD COLUMN IF NOT EXISTS system_prompt_template TEXT DEFAULT 'default'`,
+        `ALTER TABLE IF EXISTS traders ADD COLUMN IF NOT EXISTS regime_risk_limits TEXT DEFAULT ''`,
+        `ALTER TABLE IF EXISTS traders ADD COLUMN IF NOT EXISTS max_slippage_pct DOUBLE PRECISION DEFAULT 0`,
+        `ALTER TABLE IF EXISTS traders ADD COLUMN IF NOT EXISTS time_exit_rules TEXT DEFAULT ''`,
+        `ALTER TABLE IF EXISTS traders ADD COLUMN IF NOT EXISTS trading_schedule TEXT DEFAULT ''`,
+        `ALTER TABLE IF EXISTS traders ADD COLUMN IF NOT EXISTS reconcile_policy TEXT DEFAULT ''`,
+        `ALTER TABLE IF EXISTS traders ADD COLUMN IF NOT EXISTS position_sizing TEXT DEFAULT ''`,
+        `ALTER TABLE IF EXISTS traders ADD COLUMN IF NOT EXISTS use_default_coins BOOLEAN DEFAULT TRUE`,
+        `ALTER TABLE IF EXISTS traders ADD COLUMN IF NOT EXISTS signal_sources TEXT DEFAULT ''`,
+        `ALTER TABLE IF EXISTS traders ADD COLUMN IF NOT EXISTS correlation_threshold DOUBLE PRECISION DEFAULT 0`,
+        `ALTER TABLE IF EXISTS traders ADD COLUMN IF NOT EXISTS max_correlated_exposure DOUBLE PRECISION DEFAULT 0`,
+        // 历史明文保存的Webhook密钥改为哈希
+        `UPDATE trader_webhooks SET secret = 'sha256:' || encode(sha256(convert_to(secret, 'UTF8')), 'hex') WHERE secret NOT LIKE 'sha256:%'`,
         `ALTER TABLE IF EXISTS ai_models ADD COLUMN IF NOT EXISTS custom_api_url TEXT DEFAULT ''`,
         `ALTER TABLE IF EXISTS ai_models ADD COLUMN IF NOT EXISTS custom_model_name TEXT DEFAULT ''`,
     }
@@ -508,6 +596,16 @@ type TraderRecord struct {
 	OverrideBasePrompt   bool      `json:"override_base_prompt"`   // 是否覆盖基础prompt
 	SystemPromptTemplate string    `json:"system_prompt_template"` // 系统提示词模板名称
 	IsCrossMargin        bool      `json:"is_cross_margin"`        // 是否为全仓模式（true=全仓，false=逐仓）
+	RegimeRiskLimits     string    `json:"regime_risk_limits"`     // 按市场状态的风控参数（JSON）
+	MaxSlippagePct       float64   `json:"max_slippage_pct"`       // 开仓允许的最大预估滑点百分比（0=使用默认值）
+	TimeExitRules        string    `json:"time_exit_rules"`        // 按持仓时间的退出规则（JSON）
+	TradingSchedule      string    `json:"trading_schedule"`       // 交易时段与禁止开仓时段（JSON）
+	ReconcilePolicy      string    `json:"reconcile_policy"`       // 启动对账时无止损持仓的处理策略（JSON）
+	PositionSizing       string    `json:"position_sizing"`        // 开仓仓位计算模型（JSON）
+	UseDefaultCoins      bool      `json:"use_default_coins"`      // 未启用外部信号时使用默认币种（否则使用本地筛选器）
+	SignalSources        string    `json:"signal_sources"`         // 交易员币种池的额外信号源（JSON数组）
+	CorrelationThreshold float64   `json:"correlation_threshold"`  // 相关系数 >= 该值视为同一风险敞口（0=使用默认值）
+	MaxCorrelatedExposure float64  `json:"max_correlated_exposure"` // 同向高相关敞口上限（账户净值倍数，0=使用默认值）
 	CreatedAt            time.Time `json:"created_at"`
 	UpdatedAt            time.Time `json:"updated_at"`
 }
@@ -522,6 +620,70 @@ type UserSignalSource struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// UserSymbolFilter 用户币种黑白名单（逗号分隔的交易对）
+type UserSymbolFilter struct {
+	UserID    string    `json:"user_id"`
+	Blacklist string    `json:"blacklist"` // 禁止交易的币种
+	Whitelist string    `json:"whitelist"` // 非空时只允许交易这些币种
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TraderWebhook 交易员Webhook信号配置（外部告警推送信号）
+type TraderWebhook struct {
+	TraderID         string    `json:"trader_id"`
+	UserID           string    `json:"user_id"`
+	SecretHash       string    `json:"-"`                  // 校验密钥的哈希（每个交易员独立，明文只在生成时返回一次）
+	Enabled          bool      `json:"enabled"`
+	SignalTTLMinutes int       `json:"signal_ttl_minutes"` // 信号默认有效期（分钟）
+	TriggerCycle     bool      `json:"trigger_cycle"`      // 收到信号后是否立即触发一次决策周期
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// TraderRuntimeState 交易员运行时状态快照（JSON，重启后恢复风控暂停、峰值盈亏等）
+type TraderRuntimeState struct {
+	TraderID  string    `json:"trader_id"`
+	UserID    string    `json:"user_id"`
+	State     string    `json:"state"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookSignal 外部推送的交易信号
+type WebhookSignal struct {
+	ID        int       `json:"id"`
+	TraderID  string    `json:"trader_id"`
+	Symbol    string    `json:"symbol"`
+	Direction string    `json:"direction"` // long / short
+	Strength  float64   `json:"strength"`  // 信号强度 (0-1)
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GenerateWebhookSecret 生成Webhook校验密钥
+func GenerateWebhookSecret() (string, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// HashWebhookSecret 计算Webhook密钥的存储哈希
+func HashWebhookSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// VerifySecret 校验请求携带的Webhook密钥
+func (w *TraderWebhook) VerifySecret(secret string) bool {
+	if secret == "" || w.SecretHash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(HashWebhookSecret(secret)), []byte(w.SecretHash)) == 1
+}
+
 // GenerateOTPSecret 生成OTP密钥
 func GenerateOTPSecret() (string, error) {
 	secret := make([]byte, 20)
@@ -955,9 +1117,9 @@ func (d *Database) CreateExchange(userID, id, name, typ string, enabled bool, ap
 // CreateTrader 创建交易员
 func (d *Database) CreateTrader(trader *TraderRecord) error {
     _, err := d.db.Exec(`
-        INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes, is_running, btc_eth_leverage, altcoin_leverage, trading_symbols, use_coin_pool, use_oi_top, custom_prompt, override_base_prompt, system_prompt_template, is_cross_margin)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
-    `, trader.ID, trader.UserID, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance, trader.ScanIntervalMinutes, trader.IsRunning, trader.BTCETHLeverage, trader.AltcoinLeverage, trader.TradingSymbols, trader.UseCoinPool, trader.UseOITop, trader.CustomPrompt, trader.OverrideBasePrompt, trader.SystemPromptTemplate, trader.IsCrossMargin)
+        INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes, is_running, btc_eth_leverage, altcoin_leverage, trading_symbols, use_coin_pool, use_oi_top, custom_prompt, override_base_prompt, system_prompt_template, is_cross_margin, regime_risk_limits, max_slippage_pct, time_exit_rules, trading_schedule, reconcile_policy, position_sizing, use_default_coins, signal_sources, correlation_threshold, max_correlated_exposure)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27)
+    `, trader.ID, trader.UserID, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance, trader.ScanIntervalMinutes, trader.IsRunning, trader.BTCETHLeverage, trader.AltcoinLeverage, trader.TradingSymbols, trader.UseCoinPool, trader.UseOITop, trader.CustomPrompt, trader.OverrideBasePrompt, trader.SystemPromptTemplate, trader.IsCrossMargin, trader.RegimeRiskLimits, trader.MaxSlippagePct, trader.TimeExitRules, trader.TradingSchedule, trader.ReconcilePolicy, trader.PositionSizing, trader.UseDefaultCoins, trader.SignalSources, trader.CorrelationThreshold, trader.MaxCorrelatedExposure)
 	return err
 }
 
@@ -970,7 +1132,17 @@ func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
                COALESCE(use_coin_pool, FALSE) as use_coin_pool, COALESCE(use_oi_top, FALSE) as use_oi_top,
                COALESCE(custom_prompt, '') as custom_prompt, COALESCE(override_base_prompt, FALSE) as override_base_prompt,
                COALESCE(system_prompt_template, 'default') as system_prompt_template,
-               COALESCE(is_cross_margin, TRUE) as is_cross_margin, created_at, updated_at
+               COALESCE(is_cross_margin, TRUE) as is_cross_margin,
+               COALESCE(regime_risk_limits, '') as regime_risk_limits,
+               COALESCE(max_slippage_pct, 0) as max_slippage_pct,
+               COALESCE(time_exit_rules, '') as time_exit_rules,
+               COALESCE(trading_schedule, '') as trading_schedule,
+               COALESCE(reconcile_policy, '') as reconcile_policy,
+               COALESCE(position_sizing, '') as position_sizing,
+               COALESCE(use_default_coins, TRUE) as use_default_coins,
+               COALESCE(signal_sources, '') as signal_sources,
+               COALESCE(correlation_threshold, 0) as correlation_threshold,
+               COALESCE(max_correlated_exposure, 0) as max_correlated_exposure, created_at, updated_at
         FROM traders WHERE user_id = $1 ORDER BY created_at DESC
     `, userID)
 	if err != nil {
@@ -987,7 +1159,8 @@ func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
 			&trader.BTCETHLeverage, &trader.AltcoinLeverage, &trader.TradingSymbols,
 			&trader.UseCoinPool, &trader.UseOITop,
 			&trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
-			&trader.IsCrossMargin,
+			&trader.IsCrossMargin, &trader.RegimeRiskLimits, &trader.MaxSlippagePct, &trader.TimeExitRules, &trader.TradingSchedule, &trader.ReconcilePolicy, &trader.PositionSizing,
+			&trader.UseDefaultCoins, &trader.SignalSources, &trader.CorrelationThreshold, &trader.MaxCorrelatedExposure,
 			&trader.CreatedAt, &trader.UpdatedAt,
 		)
 		if err != nil {
@@ -1012,12 +1185,14 @@ func (d *Database) UpdateTrader(trader *TraderRecord) error {
             name = $1, ai_model_id = $2, exchange_id = $3, initial_balance = $4,
             scan_interval_minutes = $5, btc_eth_leverage = $6, altcoin_leverage = $7,
             trading_symbols = $8, custom_prompt = $9, override_base_prompt = $10,
-            system_prompt_template = $11, is_cross_margin = $12, updated_at = CURRENT_TIMESTAMP
-        WHERE id = $13 AND user_id = $14
+            system_prompt_template = $11, is_cross_margin = $12, regime_risk_limits = $13, max_slippage_pct = $14, time_exit_rules = $15, trading_schedule = $16, reconcile_policy = $17, position_sizing = $18,
+            use_default_coins = $19, signal_sources = $20, correlation_threshold = $21, max_correlated_exposure = $22, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $23 AND user_id = $24
     `, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance,
         trader.ScanIntervalMinutes, trader.BTCETHLeverage, trader.AltcoinLeverage,
         trader.TradingSymbols, trader.CustomPrompt, trader.OverrideBasePrompt,
-        trader.SystemPromptTemplate, trader.IsCrossMargin, trader.ID, trader.UserID)
+        trader.SystemPromptTemplate, trader.IsCrossMargin, trader.RegimeRiskLimits, trader.MaxSlippagePct, trader.TimeExitRules, trader.TradingSchedule, trader.ReconcilePolicy, trader.PositionSizing,
+        trader.UseDefaultCoins, trader.SignalSources, trader.CorrelationThreshold, trader.MaxCorrelatedExposure, trader.ID, trader.UserID)
     return err
 }
 
@@ -1057,6 +1232,16 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
             COALESCE(t.override_base_prompt, FALSE) as override_base_prompt,
 			COALESCE(t.system_prompt_template, 'default') as system_prompt_template,
             COALESCE(t.is_cross_margin, TRUE) as is_cross_margin,
+            COALESCE(t.regime_risk_limits, '') as regime_risk_limits,
+            COALESCE(t.max_slippage_pct, 0) as max_slippage_pct,
+            COALESCE(t.time_exit_rules, '') as time_exit_rules,
+            COALESCE(t.trading_schedule, '') as trading_schedule,
+            COALESCE(t.reconcile_policy, '') as reconcile_policy,
+            COALESCE(t.position_sizing, '') as position_sizing,
+            COALESCE(t.use_default_coins, TRUE) as use_default_coins,
+            COALESCE(t.signal_sources, '') as signal_sources,
+            COALESCE(t.correlation_threshold, 0) as correlation_threshold,
+            COALESCE(t.max_correlated_exposure, 0) as max_correlated_exposure,
 			t.created_at, t.updated_at,
 			a.id, a.user_id, a.name, a.provider, a.enabled, a.api_key,
 			COALESCE(a.custom_api_url, '') as custom_api_url,
@@ -1078,7 +1263,8 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
 		&trader.BTCETHLeverage, &trader.AltcoinLeverage, &trader.TradingSymbols,
 		&trader.UseCoinPool, &trader.UseOITop,
 		&trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
-		&trader.IsCrossMargin,
+		&trader.IsCrossMargin, &trader.RegimeRiskLimits, &trader.MaxSlippagePct, &trader.TimeExitRules, &trader.TradingSchedule, &trader.ReconcilePolicy, &trader.PositionSizing,
+			&trader.UseDefaultCoins, &trader.SignalSources, &trader.CorrelationThreshold, &trader.MaxCorrelatedExposure,
 		&trader.CreatedAt, &trader.UpdatedAt,
 		&aiModel.ID, &aiModel.UserID, &aiModel.Name, &aiModel.Provider, &aiModel.Enabled, &aiModel.APIKey,
 		&aiModel.CustomAPIURL, &aiModel.CustomModelName,
@@ -1157,6 +1343,126 @@ func (d *Database) UpdateUserSignalSource(userID, coinPoolURL, oiTopURL string)
     return err
 }
 
+// SaveUserSymbolFilter 创建或更新用户币种黑白名单
+func (d *Database) SaveUserSymbolFilter(userID, blacklist, whitelist string) error {
+    _, err := d.db.Exec(`
+        INSERT INTO user_symbol_filters (user_id, blacklist, whitelist, updated_at)
+        VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+        ON CONFLICT (user_id) DO UPDATE SET
+          blacklist = EXCLUDED.blacklist,
+          whitelist = EXCLUDED.whitelist,
+          updated_at = CURRENT_TIMESTAMP
+    `, userID, blacklist, whitelist)
+    return err
+}
+
+// GetUserSymbolFilter 获取用户币种黑白名单
+func (d *Database) GetUserSymbolFilter(userID string) (*UserSymbolFilter, error) {
+	var filter UserSymbolFilter
+    err := d.db.QueryRow(`
+        SELECT user_id, blacklist, whitelist, created_at, updated_at
+        FROM user_symbol_filters WHERE user_id = $1
+    `, userID).Scan(&filter.UserID, &filter.Blacklist, &filter.Whitelist, &filter.CreatedAt, &filter.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
+
+// SaveTraderWebhook 创建或更新交易员Webhook配置
+func (d *Database) SaveTraderWebhook(webhook *TraderWebhook) error {
+    _, err := d.db.Exec(`
+        INSERT INTO trader_webhooks (trader_id, user_id, secret, enabled, signal_ttl_minutes, trigger_cycle, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP)
+        ON CONFLICT (trader_id) DO UPDATE SET
+          secret = EXCLUDED.secret,
+          enabled = EXCLUDED.enabled,
+          signal_ttl_minutes = EXCLUDED.signal_ttl_minutes,
+          trigger_cycle = EXCLUDED.trigger_cycle,
+          updated_at = CURRENT_TIMESTAMP
+    `, webhook.TraderID, webhook.UserID, webhook.SecretHash, webhook.Enabled, webhook.SignalTTLMinutes, webhook.TriggerCycle)
+    return err
+}
+
+// GetTraderWebhook 获取交易员Webhook配置
+func (d *Database) GetTraderWebhook(traderID string) (*TraderWebhook, error) {
+	var webhook TraderWebhook
+    err := d.db.QueryRow(`
+        SELECT trader_id, user_id, secret, enabled, signal_ttl_minutes, trigger_cycle, created_at, updated_at
+        FROM trader_webhooks WHERE trader_id = $1
+    `, traderID).Scan(
+        &webhook.TraderID, &webhook.UserID, &webhook.SecretHash, &webhook.Enabled,
+        &webhook.SignalTTLMinutes, &webhook.TriggerCycle, &webhook.CreatedAt, &webhook.UpdatedAt,
+    )
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// SaveTraderRuntimeState 保存交易员运行时状态（每个交易员一行，覆盖写入）
+func (d *Database) SaveTraderRuntimeState(traderID, userID, state string) error {
+    _, err := d.db.Exec(`
+        INSERT INTO trader_runtime_state (trader_id, user_id, state, updated_at)
+        VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+        ON CONFLICT (trader_id) DO UPDATE SET
+          state = EXCLUDED.state,
+          updated_at = CURRENT_TIMESTAMP
+    `, traderID, userID, state)
+    return err
+}
+
+// GetTraderRuntimeState 获取交易员运行时状态（不存在时返回 sql.ErrNoRows）
+func (d *Database) GetTraderRuntimeState(traderID string) (*TraderRuntimeState, error) {
+	var state TraderRuntimeState
+    err := d.db.QueryRow(`
+        SELECT trader_id, user_id, state, updated_at
+        FROM trader_runtime_state WHERE trader_id = $1
+    `, traderID).Scan(&state.TraderID, &state.UserID, &state.State, &state.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// CreateWebhookSignal 保存外部信号，有效期从写入时起算（同时清理该交易员已过期的信号）
+func (d *Database) CreateWebhookSignal(signal *WebhookSignal, ttl time.Duration) error {
+	if _, err := d.db.Exec(`DELETE FROM webhook_signals WHERE trader_id = $1 AND expires_at <= CURRENT_TIMESTAMP`, signal.TraderID); err != nil {
+		log.Printf("⚠️  清理过期信号失败: %v", err)
+	}
+
+    return d.db.QueryRow(`
+        INSERT INTO webhook_signals (trader_id, symbol, direction, strength, message, expires_at)
+        VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP + $6 * INTERVAL '1 second')
+        RETURNING id, created_at, expires_at
+    `, signal.TraderID, signal.Symbol, signal.Direction, signal.Strength, signal.Message, int(ttl.Seconds())).Scan(&signal.ID, &signal.CreatedAt, &signal.ExpiresAt)
+}
+
+// GetActiveWebhookSignals 获取交易员未过期的外部信号（按接收时间倒序）
+func (d *Database) GetActiveWebhookSignals(traderID string) ([]*WebhookSignal, error) {
+    rows, err := d.db.Query(`
+        SELECT id, trader_id, symbol, direction, strength, message, created_at, expires_at
+        FROM webhook_signals
+        WHERE trader_id = $1 AND expires_at > CURRENT_TIMESTAMP
+        ORDER BY created_at DESC
+    `, traderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var signals []*WebhookSignal
+	for rows.Next() {
+		var signal WebhookSignal
+		if err := rows.Scan(&signal.ID, &signal.TraderID, &signal.Symbol, &signal.Direction, &signal.Strength,
+			&signal.Message, &signal.CreatedAt, &signal.ExpiresAt); err != nil {
+			return nil, err
+		}
+		signals = append(signals, &signal)
+	}
+	return signals, rows.Err()
+}
+
 // GetCustomCoins 获取所有交易员自定义币种 / Get all trader-customized currencies
 func (d *Database) GetCustomCoins() []string {
 	var symbol string
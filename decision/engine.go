@@ -46,7 +46,9 @@ type PositionInfo struct {
 	PeakPnLPct       float64 `json:"peak_pnl_pct"` // 历史最高收益率（百分比）
 	LiquidationPrice float64 `json:"liquidation_price"`
 	MarginUsed       float64 `json:"margin_used"`
+	FundingFee       float64 `json:"funding_fee"` // 持仓期间累计资金费（正=收取，负=支付）
 	UpdateTime       int64   `json:"update_time"` // 持仓更新时间戳（毫秒）
+	AddCount         int     `json:"add_count"`   // 已加仓次数（不含首次开仓）
 }
 
 // AccountInfo 账户信息
@@ -62,8 +64,9 @@ type AccountInfo struct {
 
 // CandidateCoin 候选币种（来自币种池）
 type CandidateCoin struct {
-	Symbol  string   `json:"symbol"`
-	Sources []string `json:"sources"` // 来源: "ai500" 和/或 "oi_top"
+	Symbol  string             `json:"symbol"`
+	Sources []pool.SourceScore `json:"sources"` // 来源信号源及归一化评分（如 ai500、oi_top、自定义信号源）
+	Score   float64            `json:"score"`   // 各信号源加权综合评分
 }
 
 // OITopData 持仓量增长Top数据（用于AI决策参考）
@@ -90,19 +93,70 @@ type Context struct {
 	BTCETHLeverage  int                     `json:"-"` // BTC/ETH杠杆倍数（从配置读取）
 	AltcoinLeverage int                     `json:"-"` // 山寨币杠杆倍数（从配置读取）
 	UseTestnet      bool                    `json:"-"` // 是否使用测试网（从交易所配置读取）
+	DataSource      market.MarketDataSource `json:"-"` // 行情数据源（与交易所一致，nil时使用币安）
+	Correlations    *market.CorrelationMatrix `json:"-"` // 持仓与候选币种的收益率相关性
+	MarketRegime    *market.RegimeData        `json:"-"` // 整体市场状态（以BTC为准）
+	RegimeRiskLimits RegimeRiskLimits         `json:"-"` // 按市场状态调整的风控参数（从trader配置读取）
+	CorrelationThreshold  float64             `json:"-"` // 相关系数阈值（从trader配置读取，0=默认值）
+	MaxCorrelatedExposure float64             `json:"-"` // 同向高相关敞口上限，账户净值倍数（从trader配置读取，0=默认值）
+	CoinPool        *pool.CoinPool            `json:"-"` // 交易员的币种池（nil时不加载OI Top数据）
+	SymbolUniverse  SymbolChecker             `json:"-"` // 可交易币种范围（nil时不校验）
+	EntriesDisabledReason string              `json:"-"` // 非空时禁止开仓/加仓（交易时段外、禁止时段或日历事件）
+	ManualActions   []ManualAction            `json:"-"` // 上次AI决策后人工执行的操作
+	Liquidation     LiquidationEstimator      `json:"-"` // 强平价格估算（nil时不校验止损与强平价格的距离）
+}
+
+// ManualAction 人工通过API下达并执行的决策（下个周期告知AI）
+type ManualAction struct {
+	Time    time.Time
+	Symbol  string
+	Action  string
+	Note    string // 人工备注（操作原因）
+	Success bool
+	Error   string
+}
+
+// SymbolChecker 可交易币种校验（交易所上架状态、用户黑白名单）
+type SymbolChecker interface {
+	CheckSymbol(symbol string) error
+}
+
+// 相关性风控默认参数（trader未配置时使用）
+const (
+	defaultCorrelationThreshold          = 0.7 // 相关系数 >= 该值视为同一风险敞口
+	defaultMaxCorrelatedExposureMultiple = 3.0 // 同向高相关持仓名义价值合计上限（账户净值倍数）
+)
+
+// correlationLimits 返回相关系数阈值与同向高相关敞口上限（账户净值倍数）
+func correlationLimits(ctx *Context) (threshold, maxMultiple float64) {
+	threshold, maxMultiple = defaultCorrelationThreshold, defaultMaxCorrelatedExposureMultiple
+	if ctx == nil {
+		return
+	}
+	if ctx.CorrelationThreshold > 0 {
+		threshold = ctx.CorrelationThreshold
+	}
+	if ctx.MaxCorrelatedExposure > 0 {
+		maxMultiple = ctx.MaxCorrelatedExposure
+	}
+	return
 }
 
 // Decision AI的交易决策
 type Decision struct {
 	Symbol string `json:"symbol"`
-	Action string `json:"action"` // "open_long", "open_short", "close_long", "close_short", "update_stop_loss", "update_take_profit", "partial_close", "hold", "wait"
+	Action string `json:"action"` // "open_long", "open_short", "add_long", "add_short", "close_long", "close_short", "update_stop_loss", "update_take_profit", "partial_close", "hold", "wait"
 
-	// 开仓参数
+	// 开仓参数（加仓时 position_size_usd 为本次加仓金额，stop_loss/take_profit 作用于整个持仓）
 	Leverage        int     `json:"leverage,omitempty"`
 	PositionSizeUSD float64 `json:"position_size_usd,omitempty"`
 	StopLoss        float64 `json:"stop_loss,omitempty"`
 	TakeProfit      float64 `json:"take_profit,omitempty"`
 
+	// 多级止盈（可选，用于开仓/加仓/update_take_profit）：按比例分档止盈，最后一档平掉剩余
+	TakeProfitLadder    []TakeProfitLevel `json:"take_profit_ladder,omitempty"`
+	MoveStopToBreakeven bool              `json:"move_stop_to_breakeven,omitempty"` // TP1成交后止损移到开仓均价
+
 	// 调整参数（新增）
 	NewStopLoss     float64 `json:"new_stop_loss,omitempty"`    // 用于 update_stop_loss
 	NewTakeProfit   float64 `json:"new_take_profit,omitempty"`  // 用于 update_take_profit
@@ -188,7 +242,13 @@ func fetchMarketDataForContext(ctx *Context) error {
     // 先收集数据，再统一做 OI 过滤（避免逐个币种阈值不一致）
     preFilterData := make(map[string]*market.Data)
     for symbol := range symbolSet {
-        data, err := market.Get(symbol, ctx.UseTestnet)
+        var data *market.Data
+        var err error
+        if ctx.DataSource != nil {
+            data, err = market.GetFrom(ctx.DataSource, symbol)
+        } else {
+            data, err = market.Get(symbol, ctx.UseTestnet)
+        }
         if err != nil {
             // 单个币种失败不影响整体，只记录错误
             continue
@@ -252,7 +312,11 @@ func fetchMarketDataForContext(ctx *Context) error {
     }
 
 	// 加载OI Top数据（不影响主流程）
-	oiPositions, err := pool.GetOITopPositions()
+	var oiPositions []pool.OIPosition
+	var err error
+	if ctx.CoinPool != nil && ctx.CoinPool.HasOITop() {
+		oiPositions, err = ctx.CoinPool.GetOITopPositions()
+	}
 	if err == nil {
 		for _, pos := range oiPositions {
 			// 标准化符号匹配
@@ -268,6 +332,33 @@ func fetchMarketDataForContext(ctx *Context) error {
 		}
 	}
 
+	source := ctx.DataSource
+	if source == nil {
+		source = market.NewBinanceSource(ctx.UseTestnet)
+	}
+
+	// 整体市场状态以BTC为准（不影响主流程）
+	if btcData, ok := preFilterData["BTCUSDT"]; ok && btcData.Regime != nil {
+		ctx.MarketRegime = btcData.Regime
+	} else {
+		if regime, err := market.GetMarketRegime(source); err == nil {
+			ctx.MarketRegime = regime
+		} else {
+			log.Printf("⚠️  判断市场状态失败: %v", err)
+		}
+	}
+
+	// 计算持仓与候选币种的相关性（不影响主流程）
+	corrSymbols := make([]string, 0, len(ctx.MarketDataMap))
+	for symbol := range ctx.MarketDataMap {
+		corrSymbols = append(corrSymbols, symbol)
+	}
+	if correlations, err := market.ComputeCorrelations(source, corrSymbols, market.DefaultCorrelationInterval); err == nil {
+		ctx.Correlations = correlations
+	} else {
+		log.Printf("⚠️  计算相关性失败: %v", err)
+	}
+
 	return nil
 }
 
@@ -390,14 +481,19 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
     sb.WriteString("8) CRITICAL: Stop-loss and take-profit placement:\n")
     sb.WriteString("   - For LONG positions: stop_loss < entry_price < take_profit\n")
     sb.WriteString("   - For SHORT positions: take_profit < entry_price < stop_loss\n")
-    sb.WriteString("   - Violating this will cause validation failure!\n\n")
+    sb.WriteString("   - Violating this will cause validation failure!\n")
+    sb.WriteString("9) Correlated exposure: same-direction notional across highly correlated symbols must stay within the cap shown in the 相关性 section; opens/adds beyond it are rejected.\n")
+    sb.WriteString(fmt.Sprintf("10) Pyramiding: add_long/add_short only into a profitable position, max %d adds per position, each add ≤ current position notional; stop_loss/take_profit apply to the whole position.\n", MaxPositionAdds))
+    sb.WriteString(fmt.Sprintf("11) Take-profit ladder (optional, opens/adds/update_take_profit): 2–%d levels ordered from nearest to farthest, percentage = share of the position closed at that level, last level may be 0 to close the rest; move_stop_to_breakeven moves the stop to entry after TP1 fills.\n\n", MaxTakeProfitLevels))
 
     // 3. Output format (JSON-only, strict)
     sb.WriteString("# Output Format (strict)\n\n")
     sb.WriteString("Return ONLY a single JSON object with key 'decisions'. No extra text.\n")
     sb.WriteString("Example (schema only, not a suggestion):\n")
-    sb.WriteString("{\n  \"decisions\": [\n    {\n      \"symbol\": \"BTCUSDT\",\n      \"action\": \"open_long|open_short|close_long|close_short|update_stop_loss|update_take_profit|partial_close|hold|wait\",\n      \"leverage\": <int>,\n      \"position_size_usd\": <number>,\n      \"stop_loss\": <number>,\n      \"take_profit\": <number>,\n      \"new_stop_loss\": <number>,\n      \"new_take_profit\": <number>,\n      \"close_percentage\": <number>,\n      \"confidence\": <int>,\n      \"risk_usd\": <number>,\n      \"reasoning\": \"short rationale in English\"\n    }\n  ]\n}\n\n")
+    sb.WriteString("{\n  \"decisions\": [\n    {\n      \"symbol\": \"BTCUSDT\",\n      \"action\": \"open_long|open_short|add_long|add_short|close_long|close_short|update_stop_loss|update_take_profit|partial_close|hold|wait\",\n      \"leverage\": <int>,\n      \"position_size_usd\": <number>,\n      \"stop_loss\": <number>,\n      \"take_profit\": <number>,\n      \"take_profit_ladder\": [{\"price\": <number>, \"percentage\": <number>}],\n      \"move_stop_to_breakeven\": <bool>,\n      \"new_stop_loss\": <number>,\n      \"new_take_profit\": <number>,\n      \"close_percentage\": <number>,\n      \"confidence\": <int>,\n      \"risk_usd\": <number>,\n      \"reasoning\": \"short rationale in English\"\n    }\n  ]\n}\n\n")
     sb.WriteString("Required fields for opens: leverage, position_size_usd, stop_loss, take_profit, confidence, risk_usd, reasoning.\n")
+    sb.WriteString("Required fields for adds: position_size_usd (add amount), stop_loss, take_profit, confidence, reasoning.\n")
+    sb.WriteString("Optional for opens/adds/update_take_profit: take_profit_ladder, move_stop_to_breakeven (take_profit/new_take_profit then equals the last level price).\n")
 
     return sb.String()
 }
@@ -419,6 +515,29 @@ func buildUserPrompt(ctx *Context) string {
 	sb.WriteString(fmt.Sprintf("时间: %s | 周期: #%d | 运行: %d分钟\n\n",
 		ctx.CurrentTime, ctx.CallCount, ctx.RuntimeMinutes))
 
+	// 交易时段限制：只允许管理现有持仓
+	if ctx.EntriesDisabledReason != "" {
+		sb.WriteString(fmt.Sprintf("⛔ 当前禁止开仓: %s\n", ctx.EntriesDisabledReason))
+		sb.WriteString("本周期不要输出 open_long/open_short/add_long/add_short，只允许 close_long/close_short/partial_close/update_stop_loss/update_take_profit/hold/wait\n\n")
+	}
+
+	// 人工干预：上次决策后人工执行的操作
+	if len(ctx.ManualActions) > 0 {
+		sb.WriteString("## 👤 人工干预（上次决策后）\n")
+		for _, action := range ctx.ManualActions {
+			status := "✓"
+			if !action.Success {
+				status = "✗ " + action.Error
+			}
+			line := fmt.Sprintf("- %s %s %s %s", action.Time.Format("15:04"), action.Symbol, action.Action, status)
+			if action.Note != "" {
+				line += " | 备注: " + action.Note
+			}
+			sb.WriteString(line + "\n")
+		}
+		sb.WriteString("以上为人工操作，请尊重其意图：除非行情明显变化，不要立即反向操作或撤销人工设置的止损止盈\n\n")
+	}
+
 	// BTC 市场
 	if btcData, hasBTC := ctx.MarketDataMap["BTCUSDT"]; hasBTC {
 		sb.WriteString(fmt.Sprintf("BTC: %.2f (1h: %+.2f%%, 4h: %+.2f%%) | MACD: %.4f | RSI: %.2f\n\n",
@@ -426,6 +545,20 @@ func buildUserPrompt(ctx *Context) string {
 			btcData.CurrentMACD, btcData.CurrentRSI7))
 	}
 
+	// 整体市场状态（BTC主导）及对应风控
+	if ctx.MarketRegime != nil {
+		sb.WriteString(fmt.Sprintf("市场状态(BTC主导): %s\n", market.FormatRegime(ctx.MarketRegime)))
+		if limit, ok := ctx.RegimeRiskLimits[string(ctx.MarketRegime.Regime)]; ok {
+			if limit.MaxLeverage > 0 {
+				sb.WriteString(fmt.Sprintf("当前状态杠杆上限: %dx\n", limit.MaxLeverage))
+			}
+			if limit.PositionSizeFactor > 0 {
+				sb.WriteString(fmt.Sprintf("当前状态仓位上限系数: %.2f\n", limit.PositionSizeFactor))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
 	// 账户
 	sb.WriteString(fmt.Sprintf("账户: 净值%.2f | 余额%.2f (%.1f%%) | 盈亏%+.2f%% | 保证金%.1f%% | 持仓%d个\n\n",
 		ctx.Account.TotalEquity,
@@ -453,10 +586,15 @@ func buildUserPrompt(ctx *Context) string {
 				}
 			}
 
-			sb.WriteString(fmt.Sprintf("%d. %s %s | 入场价%.4f 当前价%.4f | 盈亏%+.2f%% | 盈亏金额%+.2f USDT | 最高收益率%.2f%% | 杠杆%dx | 保证金%.0f | 强平价%.4f%s\n\n",
+			addInfo := ""
+			if pos.AddCount > 0 {
+				addInfo = fmt.Sprintf(" | 已加仓%d/%d次", pos.AddCount, MaxPositionAdds)
+			}
+
+			sb.WriteString(fmt.Sprintf("%d. %s %s | 入场价%.4f 当前价%.4f | 盈亏%+.2f%% | 盈亏金额%+.2f USDT | 资金费%+.2f USDT | 最高收益率%.2f%% | 杠杆%dx | 保证金%.0f | 强平价%.4f%s%s\n\n",
 				i+1, pos.Symbol, strings.ToUpper(pos.Side),
-				pos.EntryPrice, pos.MarkPrice, pos.UnrealizedPnLPct, pos.UnrealizedPnL, pos.PeakPnLPct,
-				pos.Leverage, pos.MarginUsed, pos.LiquidationPrice, holdingDuration))
+				pos.EntryPrice, pos.MarkPrice, pos.UnrealizedPnLPct, pos.UnrealizedPnL, pos.FundingFee, pos.PeakPnLPct,
+				pos.Leverage, pos.MarginUsed, pos.LiquidationPrice, holdingDuration, addInfo))
 
 			// 使用FormatMarketData输出完整市场数据
 			if marketData, ok := ctx.MarketDataMap[pos.Symbol]; ok {
@@ -468,6 +606,31 @@ func buildUserPrompt(ctx *Context) string {
 		sb.WriteString("当前持仓: 无\n\n")
 	}
 
+	// 相关性（持仓 + 候选币种）
+	if ctx.Correlations != nil {
+		symbols := make([]string, 0, len(ctx.Positions)+len(ctx.CandidateCoins))
+		seen := make(map[string]bool)
+		for _, pos := range ctx.Positions {
+			if !seen[pos.Symbol] {
+				seen[pos.Symbol] = true
+				symbols = append(symbols, pos.Symbol)
+			}
+		}
+		for _, coin := range ctx.CandidateCoins {
+			if _, ok := ctx.MarketDataMap[coin.Symbol]; ok && !seen[coin.Symbol] {
+				seen[coin.Symbol] = true
+				symbols = append(symbols, coin.Symbol)
+			}
+		}
+		threshold, maxMultiple := correlationLimits(ctx)
+		if summary := market.FormatCorrelationSummary(ctx.Correlations, symbols, threshold, 10); summary != "" {
+			sb.WriteString(fmt.Sprintf("## 相关性 (%s收益率, |ρ|≥%.2f 的同向敞口上限 %.0f USDT = %.1fx净值)\n",
+				ctx.Correlations.Interval, threshold, ctx.Account.TotalEquity*maxMultiple, maxMultiple))
+			sb.WriteString(summary)
+			sb.WriteString("\n")
+		}
+	}
+
 	// 候选币种（完整市场数据）
 	sb.WriteString(fmt.Sprintf("## 候选币种 (%d个)\n\n", len(ctx.MarketDataMap)))
 	displayedCount := 0
@@ -478,15 +641,25 @@ func buildUserPrompt(ctx *Context) string {
 		}
 		displayedCount++
 
+		// 来源标签：只展示带评分的信号源（default/custom 等固定列表不展示）
 		sourceTags := ""
-		if len(coin.Sources) > 1 {
-			sourceTags = " (AI500+OI_Top双重信号)"
-		} else if len(coin.Sources) == 1 && coin.Sources[0] == "oi_top" {
-			sourceTags = " (OI_Top持仓增长)"
+		var tags []string
+		for _, src := range coin.Sources {
+			if src.Score > 0 {
+				tags = append(tags, fmt.Sprintf("%s %.2f", src.Name, src.Score))
+			}
+		}
+		if len(tags) > 0 {
+			sourceTags = fmt.Sprintf(" (信号: %s | 综合%.2f)", strings.Join(tags, " + "), coin.Score)
 		}
 
 		// 使用FormatMarketData输出完整市场数据
 		sb.WriteString(fmt.Sprintf("### %d. %s%s\n\n", displayedCount, coin.Symbol, sourceTags))
+		for _, src := range coin.Sources {
+			if src.Reason != "" {
+				sb.WriteString(fmt.Sprintf("筛选理由(%s): %s\n", src.Name, src.Reason))
+			}
+		}
 		sb.WriteString(market.Format(marketData))
 		sb.WriteString("\n")
 	}
@@ -911,14 +1084,142 @@ func compactArrayOpen(s string) string {
 
 // validateDecisions 验证所有决策（需要账户信息和杠杆配置）
 func validateDecisions(decisions []Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int, ctx *Context) error {
-    for i, decision := range decisions {
-        if err := validateDecision(&decision, accountEquity, btcEthLeverage, altcoinLeverage, ctx); err != nil {
+    for i := range decisions {
+        // 按指针校验：多级止盈的归一化结果（最后一档比例、take_profit）写回决策
+        if err := validateDecision(&decisions[i], accountEquity, btcEthLeverage, altcoinLeverage, ctx); err != nil {
             return fmt.Errorf("Decision #%d failed validation: %w", i+1, err)
         }
     }
+    // 同向高相关敞口超限的开仓/加仓单独拒绝（改为wait），不影响本批次其他决策
+    rejected := validateCorrelatedExposure(decisions, accountEquity, ctx)
+    for i := range decisions {
+        err, ok := rejected[i]
+        if !ok {
+            continue
+        }
+        log.Printf("⚠️  拒绝决策 #%d %s %s: %v", i+1, decisions[i].Symbol, decisions[i].Action, err)
+        decisions[i].Reasoning = fmt.Sprintf("[rejected %s: %v] %s", decisions[i].Action, err, decisions[i].Reasoning)
+        decisions[i].Action = "wait"
+    }
     return nil
 }
 
+// ValidateManualDecision 校验人工下达的决策（与AI决策相同的风控校验）
+// ctx 由调用方构建，缺少该币种行情时补充获取（ATR距离、止盈阶梯参考价）
+func ValidateManualDecision(d *Decision, ctx *Context) error {
+	if d.Action == "hold" || d.Action == "wait" {
+		return fmt.Errorf("manual decision must be an executable action, got %s", d.Action)
+	}
+	if d.Symbol == "" {
+		return fmt.Errorf("symbol is required")
+	}
+	if ctx.MarketDataMap == nil {
+		ctx.MarketDataMap = make(map[string]*market.Data)
+	}
+	if _, ok := ctx.MarketDataMap[d.Symbol]; !ok {
+		if data, err := market.GetFrom(ctx.DataSource, d.Symbol); err == nil {
+			ctx.MarketDataMap[d.Symbol] = data
+		}
+	}
+
+	if err := validateDecision(d, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, ctx); err != nil {
+		return err
+	}
+	// 人工决策超出相关敞口上限时直接返回错误（不改为wait）
+	if err, ok := validateCorrelatedExposure([]Decision{*d}, ctx.Account.TotalEquity, ctx)[0]; ok {
+		return err
+	}
+	return nil
+}
+
+// ValidateSizedDecisions 按最终仓位（仓位模型计算后）重新校验开仓/加仓：止盈阶梯每档最小名义价值与同向相关敞口
+// 返回不通过的决策（key为决策下标）
+func ValidateSizedDecisions(decisions []Decision, ctx *Context) map[int]error {
+	rejected := make(map[int]error)
+	checked := make([]Decision, len(decisions))
+	copy(checked, decisions)
+	for i := range checked {
+		if err := validateLadderMinNotional(&checked[i]); err != nil {
+			rejected[i] = err
+			checked[i].Action = "wait" // 不计入相关敞口
+		}
+	}
+	for i, err := range validateCorrelatedExposure(checked, ctx.Account.TotalEquity, ctx) {
+		rejected[i] = err
+	}
+	return rejected
+}
+
+// validateCorrelatedExposure 检查同向高相关敞口，返回超限的决策（key为决策下标）
+// 对每个开仓/加仓决策，累加与其同方向且相关系数 >= 阈值的现有持仓及本批次已接受开仓的名义价值
+func validateCorrelatedExposure(decisions []Decision, accountEquity float64, ctx *Context) map[int]error {
+	rejected := make(map[int]error)
+	if ctx == nil || ctx.Correlations == nil || accountEquity <= 0 {
+		return rejected
+	}
+	threshold, maxMultiple := correlationLimits(ctx)
+
+	type exposure struct {
+		symbol   string
+		side     string
+		notional float64
+	}
+	var book []exposure
+	closing := make(map[string]bool) // 本批次会平掉的持仓
+	for _, d := range decisions {
+		switch d.Action {
+		case "close_long":
+			closing[d.Symbol+"_long"] = true
+		case "close_short":
+			closing[d.Symbol+"_short"] = true
+		}
+	}
+	for _, pos := range ctx.Positions {
+		if closing[pos.Symbol+"_"+pos.Side] {
+			continue
+		}
+		book = append(book, exposure{symbol: pos.Symbol, side: pos.Side, notional: pos.Quantity * pos.MarkPrice})
+	}
+
+	limit := accountEquity * maxMultiple
+	for i, d := range decisions {
+		if d.Action != "open_long" && d.Action != "open_short" && !IsAddAction(d.Action) {
+			continue
+		}
+		side := "long"
+		if d.Action == "open_short" || d.Action == "add_short" {
+			side = "short"
+		}
+
+		total := d.PositionSizeUSD
+		var related []string
+		for _, e := range book {
+			if e.side != side {
+				continue
+			}
+			corr := 1.0
+			if e.symbol != d.Symbol {
+				c, ok := ctx.Correlations.Correlation(d.Symbol, e.symbol)
+				if !ok || c < threshold {
+					continue
+				}
+				corr = c
+			}
+			total += e.notional
+			related = append(related, fmt.Sprintf("%s(ρ=%.2f)", e.symbol, corr))
+		}
+
+		if total > limit*1.01 {
+			// 超限的决策不计入敞口，后续决策按未执行处理
+			rejected[i] = fmt.Errorf("correlated %s exposure %.0f USDT exceeds %.0f USDT (%.1fx equity), correlated with %s",
+				side, total, limit, maxMultiple, strings.Join(related, ", "))
+			continue
+		}
+		book = append(book, exposure{symbol: d.Symbol, side: side, notional: d.PositionSizeUSD})
+	}
+	return rejected
+}
+
 // findMatchingBracket 查找匹配的右括号
 func findMatchingBracket(s string, start int) int {
 	if start >= len(s) || s[start] != '[' {
@@ -941,12 +1242,26 @@ func findMatchingBracket(s string, start int) int {
 	return -1
 }
 
+// MaxPositionValue 单笔开仓的名义价值上限：山寨币1.5倍、BTC/ETH 10倍账户净值，并按市场状态收紧
+func MaxPositionValue(ctx *Context, symbol string, accountEquity float64) float64 {
+	maxPositionValue := accountEquity * 1.5
+	if symbol == "BTCUSDT" || symbol == "ETHUSDT" {
+		maxPositionValue = accountEquity * 10
+	}
+	if limit, ok := regimeRiskLimit(ctx, symbol); ok && limit.PositionSizeFactor > 0 {
+		maxPositionValue *= limit.PositionSizeFactor
+	}
+	return maxPositionValue
+}
+
 // validateDecision 验证单个决策的有效性
 func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int, ctx *Context) error {
 	// 验证action
 	validActions := map[string]bool{
 		"open_long":          true,
 		"open_short":         true,
+		"add_long":           true,
+		"add_short":          true,
 		"close_long":         true,
 		"close_short":        true,
 		"update_stop_loss":   true,
@@ -960,14 +1275,30 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
         return fmt.Errorf("invalid action: %s", d.Action)
     }
 
+	// 多级止盈：校验阶梯并以最后一档作为 take_profit / new_take_profit
+	if err := validateTakeProfitLadder(d, ctx); err != nil {
+		return err
+	}
+
 	// 开仓操作必须提供完整参数
 	if d.Action == "open_long" || d.Action == "open_short" {
+		// 只允许开仓可交易范围内的币种（已有持仓的平仓/调整不受限制）
+		if ctx != nil && ctx.SymbolUniverse != nil {
+			if err := ctx.SymbolUniverse.CheckSymbol(d.Symbol); err != nil {
+				return fmt.Errorf("symbol not tradable: %w", err)
+			}
+		}
+
 		// 根据币种使用配置的杠杆上限
-		maxLeverage := altcoinLeverage          // 山寨币使用配置的杠杆
-		maxPositionValue := accountEquity * 1.5 // 山寨币最多1.5倍账户净值
+		maxLeverage := altcoinLeverage // 山寨币使用配置的杠杆
 		if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
-			maxLeverage = btcEthLeverage          // BTC和ETH使用配置的杠杆
-			maxPositionValue = accountEquity * 10 // BTC/ETH最多10倍账户净值
+			maxLeverage = btcEthLeverage // BTC和ETH使用配置的杠杆
+		}
+		maxPositionValue := MaxPositionValue(ctx, d.Symbol, accountEquity)
+
+		// 按市场状态收紧杠杆
+		if limit, ok := regimeRiskLimit(ctx, d.Symbol); ok && limit.MaxLeverage > 0 && limit.MaxLeverage < maxLeverage {
+			maxLeverage = limit.MaxLeverage
 		}
 
         if d.Leverage <= 0 || d.Leverage > maxLeverage {
@@ -996,9 +1327,9 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 		tolerance := maxPositionValue * 0.01 // 1%容差
         if d.PositionSizeUSD > maxPositionValue+tolerance {
             if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
-                return fmt.Errorf("BTC/ETH position notional cannot exceed %.0f USDT (10x equity), got %.0f", maxPositionValue, d.PositionSizeUSD)
+                return fmt.Errorf("BTC/ETH position notional cannot exceed %.0f USDT (%.1fx equity), got %.0f", maxPositionValue, maxPositionValue/accountEquity, d.PositionSizeUSD)
             } else {
-                return fmt.Errorf("Altcoin position notional cannot exceed %.0f USDT (1.5x equity), got %.0f", maxPositionValue, d.PositionSizeUSD)
+                return fmt.Errorf("Altcoin position notional cannot exceed %.0f USDT (%.1fx equity), got %.0f", maxPositionValue, maxPositionValue/accountEquity, d.PositionSizeUSD)
             }
         }
         if d.StopLoss <= 0 || d.TakeProfit <= 0 {
@@ -1086,8 +1417,24 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
                 return fmt.Errorf("estimated risk (%.2f USDT) exceeds risk_usd budget (%.2f USDT)", estimatedRiskUSD, d.RiskUSD)
             }
         }
+
+		// 止损必须安全地位于强平价格之内（过近时降低杠杆）
+		if err := CheckLiquidationDistance(d, ctx); err != nil {
+			return err
+		}
     }
 
+	// 加仓验证
+	if IsAddAction(d.Action) {
+		if err := validateAddDecision(d, accountEquity, ctx); err != nil {
+			return err
+		}
+		// 加仓后整个持仓的止损必须安全地位于强平价格之内
+		if err := CheckLiquidationDistance(d, ctx); err != nil {
+			return err
+		}
+	}
+
 	// 动态调整止损验证
 	if d.Action == "update_stop_loss" {
         if d.NewStopLoss <= 0 {
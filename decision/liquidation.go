@@ -0,0 +1,116 @@
+package decision
+
+import (
+	"fmt"
+	"log"
+	"math"
+)
+
+// maxStopToLiquidationRatio 止损距离不得超过强平距离的该比例（为标记价格偏离与止损滑点留出余量）
+const maxStopToLiquidationRatio = 0.7
+
+// LiquidationEstimator 估算开仓/加仓后的强平价格（按交易所维持保证金阶梯与仓位模式）
+type LiquidationEstimator interface {
+	// EstimateLiquidationPrice 返回预估强平价格（多头返回0表示价格归零也不会强平）
+	EstimateLiquidationPrice(symbol string, isLong bool, entryPrice, notional float64, leverage int) (float64, error)
+}
+
+// CheckLiquidationDistance 校验开仓/加仓后的止损是否安全地位于强平价格之内
+// 开仓：止损过于接近或超过强平价格时逐级降低杠杆（直接修改 d.Leverage），1倍仍不满足时返回错误
+// 加仓：按加仓后的总名义价值与新均价估算（沿用持仓杠杆，无法单独降低），不满足时返回错误
+func CheckLiquidationDistance(d *Decision, ctx *Context) error {
+	isOpen := d.Action == "open_long" || d.Action == "open_short"
+	if !isOpen && !IsAddAction(d.Action) {
+		return nil
+	}
+	if ctx == nil || ctx.Liquidation == nil || d.StopLoss <= 0 || d.PositionSizeUSD <= 0 {
+		return nil
+	}
+	md, ok := ctx.MarketDataMap[d.Symbol]
+	if !ok || md == nil || md.CurrentPrice <= 0 {
+		return nil
+	}
+	price := md.CurrentPrice
+	isLong := d.Action == "open_long" || d.Action == "add_long"
+
+	stopDist := price - d.StopLoss
+	if !isLong {
+		stopDist = d.StopLoss - price
+	}
+	if stopDist <= 0 {
+		return nil // 止损在当前价格错误一侧，由其他校验处理
+	}
+
+	if !isOpen {
+		return checkAddLiquidationDistance(d, ctx, isLong, price, stopDist)
+	}
+	if d.Leverage <= 0 {
+		return nil
+	}
+
+	var liqPrice float64
+	for leverage := d.Leverage; leverage >= 1; leverage-- {
+		estimated, err := ctx.Liquidation.EstimateLiquidationPrice(d.Symbol, isLong, price, d.PositionSizeUSD, leverage)
+		if err != nil {
+			log.Printf("⚠️ %s 估算强平价格失败，跳过强平距离校验: %v", d.Symbol, err)
+			return nil
+		}
+		liqPrice = estimated
+		if stopInsideLiquidation(isLong, price, stopDist, liqPrice) {
+			return deleverTo(d, leverage, liqPrice)
+		}
+	}
+
+	return fmt.Errorf("stop_loss %.4f is beyond or too close to estimated liquidation price %.4f (entry %.4f); stop distance must be ≤ %.0f%% of liquidation distance even at 1x",
+		d.StopLoss, liqPrice, price, maxStopToLiquidationRatio*100)
+}
+
+// checkAddLiquidationDistance 加仓：按加仓后的总持仓估算强平价格
+func checkAddLiquidationDistance(d *Decision, ctx *Context, isLong bool, price, stopDist float64) error {
+	side := "long"
+	if !isLong {
+		side = "short"
+	}
+	pos := findPosition(ctx, d.Symbol, side)
+	if pos == nil || pos.Leverage <= 0 {
+		return nil // 没有持仓由加仓校验处理
+	}
+
+	currentQuantity := math.Abs(pos.Quantity)
+	addQuantity := d.PositionSizeUSD / price
+	avgEntry := AverageEntryPrice(currentQuantity, pos.EntryPrice, addQuantity, price)
+	notional := (currentQuantity + addQuantity) * avgEntry
+
+	liqPrice, err := ctx.Liquidation.EstimateLiquidationPrice(d.Symbol, isLong, avgEntry, notional, pos.Leverage)
+	if err != nil {
+		log.Printf("⚠️ %s 估算强平价格失败，跳过强平距离校验: %v", d.Symbol, err)
+		return nil
+	}
+	if stopInsideLiquidation(isLong, price, stopDist, liqPrice) {
+		return nil
+	}
+	return fmt.Errorf("%s: stop_loss %.4f is beyond or too close to estimated liquidation price %.4f after add (avg entry %.4f, notional %.2f, %dx); stop distance must be ≤ %.0f%% of liquidation distance",
+		d.Action, d.StopLoss, liqPrice, avgEntry, notional, pos.Leverage, maxStopToLiquidationRatio*100)
+}
+
+// stopInsideLiquidation 止损距离（相对当前价格）是否不超过强平距离的 maxStopToLiquidationRatio
+func stopInsideLiquidation(isLong bool, price, stopDist, liqPrice float64) bool {
+	if isLong && liqPrice <= 0 {
+		return true
+	}
+	liqDist := price - liqPrice
+	if !isLong {
+		liqDist = liqPrice - price
+	}
+	return liqDist > 0 && stopDist <= liqDist*maxStopToLiquidationRatio
+}
+
+// deleverTo 将杠杆降到满足强平距离的倍数
+func deleverTo(d *Decision, leverage int, liqPrice float64) error {
+	if leverage < d.Leverage {
+		log.Printf("⚠️ %s 止损距强平价格过近，杠杆 %dx → %dx（预估强平价 %.4f，止损 %.4f）",
+			d.Symbol, d.Leverage, leverage, liqPrice, d.StopLoss)
+		d.Leverage = leverage
+	}
+	return nil
+}
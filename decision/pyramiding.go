@@ -0,0 +1,106 @@
+package decision
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxPositionAdds 每个持仓最多加仓次数（不含首次开仓）
+const MaxPositionAdds = 2
+
+// IsAddAction 是否为加仓动作
+func IsAddAction(action string) bool {
+	return action == "add_long" || action == "add_short"
+}
+
+// AverageEntryPrice 加仓后的持仓均价
+func AverageEntryPrice(quantity, entryPrice, addQuantity, addPrice float64) float64 {
+	total := quantity + addQuantity
+	if total <= 0 {
+		return 0
+	}
+	return (quantity*entryPrice + addQuantity*addPrice) / total
+}
+
+// findPosition 查找同币种同方向的持仓
+func findPosition(ctx *Context, symbol, side string) *PositionInfo {
+	if ctx == nil {
+		return nil
+	}
+	for i := range ctx.Positions {
+		if ctx.Positions[i].Symbol == symbol && ctx.Positions[i].Side == side {
+			return &ctx.Positions[i]
+		}
+	}
+	return nil
+}
+
+// validateAddDecision 验证加仓决策：只向盈利持仓加仓、次数上限、加仓规模递减、按新均价重算风险
+func validateAddDecision(d *Decision, accountEquity float64, ctx *Context) error {
+	side := "long"
+	if d.Action == "add_short" {
+		side = "short"
+	}
+
+	pos := findPosition(ctx, d.Symbol, side)
+	if pos == nil {
+		return fmt.Errorf("%s: no existing %s position to add to, use open_%s instead", d.Action, side, side)
+	}
+	if pos.UnrealizedPnL <= 0 {
+		return fmt.Errorf("%s: can only add to a profitable position (%s unrealized PnL %.2f USDT)", d.Action, d.Symbol, pos.UnrealizedPnL)
+	}
+	if pos.AddCount >= MaxPositionAdds {
+		return fmt.Errorf("%s: %s %s already added %d times (max %d)", d.Action, d.Symbol, side, pos.AddCount, MaxPositionAdds)
+	}
+
+	const minAddSize = 12.0 // 与开仓一致：交易所最小名义价值 + 安全边际
+	if d.PositionSizeUSD < minAddSize {
+		return fmt.Errorf("position_size_usd too small (%.2f), must be ≥ %.2f USDT (exchange min notional)", d.PositionSizeUSD, minAddSize)
+	}
+
+	// 金字塔加仓：单次加仓不超过当前持仓规模
+	currentNotional := pos.Quantity * pos.MarkPrice
+	if d.PositionSizeUSD > currentNotional*1.01 {
+		return fmt.Errorf("%s: add size %.2f USDT exceeds current position notional %.2f USDT (pyramid adds must shrink)", d.Action, d.PositionSizeUSD, currentNotional)
+	}
+
+	// 加仓后的总仓位不超过开仓上限
+	maxPositionValue := accountEquity * 1.5
+	if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
+		maxPositionValue = accountEquity * 10
+	}
+	if limit, ok := regimeRiskLimit(ctx, d.Symbol); ok && limit.PositionSizeFactor > 0 {
+		maxPositionValue *= limit.PositionSizeFactor
+	}
+	if currentNotional+d.PositionSizeUSD > maxPositionValue*1.01 {
+		return fmt.Errorf("%s: total notional after add %.0f USDT exceeds cap %.0f USDT", d.Action, currentNotional+d.PositionSizeUSD, maxPositionValue)
+	}
+
+	// 止损止盈作用于加仓后的整个持仓
+	if d.StopLoss <= 0 || d.TakeProfit <= 0 {
+		return fmt.Errorf("stop_loss and take_profit must be > 0 (applied to the whole position after add)")
+	}
+	price := pos.MarkPrice
+	if side == "long" && !(d.StopLoss < price && price < d.TakeProfit) {
+		return fmt.Errorf("for add_long, stop_loss < current price (%.4f) < take_profit is required", price)
+	}
+	if side == "short" && !(d.TakeProfit < price && price < d.StopLoss) {
+		return fmt.Errorf("for add_short, take_profit < current price (%.4f) < stop_loss is required", price)
+	}
+
+	// 按新均价重算整个持仓的风险
+	if d.RiskUSD > 0 && price > 0 {
+		addQuantity := d.PositionSizeUSD / price
+		avgEntry := AverageEntryPrice(pos.Quantity, pos.EntryPrice, addQuantity, price)
+		riskUSD := (pos.Quantity + addQuantity) * math.Abs(avgEntry-d.StopLoss)
+		if (side == "long" && d.StopLoss >= avgEntry) || (side == "short" && d.StopLoss <= avgEntry) {
+			riskUSD = 0 // 止损已锁定利润
+		}
+		if riskUSD > d.RiskUSD {
+			return fmt.Errorf("%s: position risk after add %.2f USDT (avg entry %.4f, stop %.4f) exceeds risk_usd budget %.2f USDT",
+				d.Action, riskUSD, avgEntry, d.StopLoss, d.RiskUSD)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package decision
+
+import (
+	"encoding/json"
+	"fmt"
+	"nofx-lite/market"
+	"strings"
+)
+
+// RegimeRiskLimit 某一市场状态下的风控参数（0表示不额外限制）
+type RegimeRiskLimit struct {
+	MaxLeverage        int     `json:"max_leverage"`         // 杠杆上限（不超过trader配置的杠杆）
+	PositionSizeFactor float64 `json:"position_size_factor"` // 仓位上限系数（乘以默认的净值倍数上限）
+}
+
+// RegimeRiskLimits 市场状态 -> 风控参数（key: trending_up / trending_down / ranging / high_volatility）
+type RegimeRiskLimits map[string]RegimeRiskLimit
+
+// ParseRegimeRiskLimits 解析trader配置中的JSON（空字符串返回nil）
+func ParseRegimeRiskLimits(raw string) (RegimeRiskLimits, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var limits RegimeRiskLimits
+	if err := json.Unmarshal([]byte(raw), &limits); err != nil {
+		return nil, fmt.Errorf("解析市场状态风控配置失败: %w", err)
+	}
+	if err := limits.Validate(); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// Validate 校验市场状态名称与参数范围
+func (l RegimeRiskLimits) Validate() error {
+	for regime, limit := range l {
+		if !market.IsValidRegime(regime) {
+			return fmt.Errorf("未知的市场状态: %s", regime)
+		}
+		if limit.MaxLeverage < 0 {
+			return fmt.Errorf("%s 杠杆上限不能为负数: %d", regime, limit.MaxLeverage)
+		}
+		if limit.PositionSizeFactor < 0 {
+			return fmt.Errorf("%s 仓位系数不能为负数: %.2f", regime, limit.PositionSizeFactor)
+		}
+	}
+	return nil
+}
+
+// regimeRiskLimit 返回币种适用的风控参数：币种自身状态与整体市场状态中取更严格者
+func regimeRiskLimit(ctx *Context, symbol string) (RegimeRiskLimit, bool) {
+	var result RegimeRiskLimit
+	found := false
+	if ctx == nil || len(ctx.RegimeRiskLimits) == 0 {
+		return result, false
+	}
+
+	apply := func(r *market.RegimeData) {
+		if r == nil {
+			return
+		}
+		limit, ok := ctx.RegimeRiskLimits[string(r.Regime)]
+		if !ok {
+			return
+		}
+		found = true
+		if limit.MaxLeverage > 0 && (result.MaxLeverage == 0 || limit.MaxLeverage < result.MaxLeverage) {
+			result.MaxLeverage = limit.MaxLeverage
+		}
+		if limit.PositionSizeFactor > 0 && (result.PositionSizeFactor == 0 || limit.PositionSizeFactor < result.PositionSizeFactor) {
+			result.PositionSizeFactor = limit.PositionSizeFactor
+		}
+	}
+
+	if data, ok := ctx.MarketDataMap[symbol]; ok {
+		apply(data.Regime)
+	}
+	apply(ctx.MarketRegime)
+	return result, found
+}
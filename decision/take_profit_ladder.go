@@ -0,0 +1,130 @@
+package decision
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxTakeProfitLevels 多级止盈最多档数
+const MaxTakeProfitLevels = 4
+
+// minTakeProfitLevelUSD 开仓时每档止盈的最小名义价值（交易所最小下单额 + 安全边际）
+const minTakeProfitLevelUSD = 12.0
+
+// TakeProfitLevel 止盈阶梯的一档
+type TakeProfitLevel struct {
+	Price      float64 `json:"price"`
+	Percentage float64 `json:"percentage"` // 该档平仓比例（%，占当前持仓），最后一档为0表示平掉剩余
+}
+
+// validateTakeProfitLadder 校验多级止盈：档数、比例、价格顺序（由近到远）
+// 校验通过后最后一档比例补足为剩余部分，并以最后一档价格作为 take_profit / new_take_profit
+func validateTakeProfitLadder(d *Decision, ctx *Context) error {
+	if len(d.TakeProfitLadder) == 0 {
+		if d.MoveStopToBreakeven {
+			return fmt.Errorf("move_stop_to_breakeven requires take_profit_ladder")
+		}
+		return nil
+	}
+
+	var side string
+	switch d.Action {
+	case "open_long", "add_long":
+		side = "long"
+	case "open_short", "add_short":
+		side = "short"
+	case "update_take_profit":
+		pos := findPosition(ctx, d.Symbol, "long")
+		if pos == nil {
+			pos = findPosition(ctx, d.Symbol, "short")
+		}
+		if pos == nil {
+			return fmt.Errorf("update_take_profit: no open %s position for take_profit_ladder", d.Symbol)
+		}
+		side = pos.Side
+	default:
+		return fmt.Errorf("take_profit_ladder is only supported for opens, adds and update_take_profit (got %s)", d.Action)
+	}
+
+	levels := d.TakeProfitLadder
+	if len(levels) < 2 || len(levels) > MaxTakeProfitLevels {
+		return fmt.Errorf("take_profit_ladder must have 2-%d levels, got %d", MaxTakeProfitLevels, len(levels))
+	}
+
+	total := 0.0
+	for i, level := range levels {
+		last := i == len(levels)-1
+		if level.Price <= 0 {
+			return fmt.Errorf("take_profit_ladder level %d: price must be > 0", i+1)
+		}
+		if level.Percentage < 0 || level.Percentage > 100 || (!last && level.Percentage == 0) {
+			return fmt.Errorf("take_profit_ladder level %d: percentage must be in (0,100] (only the last level may be 0 = rest): %.1f", i+1, level.Percentage)
+		}
+		if i > 0 {
+			prev := levels[i-1].Price
+			if (side == "long" && level.Price <= prev) || (side == "short" && level.Price >= prev) {
+				return fmt.Errorf("take_profit_ladder prices must move away from entry (nearest first) for %s: level %d %.4f vs level %d %.4f",
+					side, i+1, level.Price, i, prev)
+			}
+		}
+		total += level.Percentage
+	}
+
+	lastLevel := &levels[len(levels)-1]
+	before := total - lastLevel.Percentage
+	if before >= 100 {
+		return fmt.Errorf("take_profit_ladder levels before the last already close %.1f%%, nothing left for the last level", before)
+	}
+	if lastLevel.Percentage > 0 && math.Abs(total-100) > 0.5 {
+		return fmt.Errorf("take_profit_ladder percentages must sum to 100 (or leave the last level at 0 for the rest): %.1f", total)
+	}
+	// 最后一档平掉剩余
+	lastLevel.Percentage = 100 - before
+
+	// 第一档必须在当前价格的盈利一侧
+	if price := ladderReferencePrice(ctx, d.Symbol, side); price > 0 {
+		first := levels[0].Price
+		if (side == "long" && first <= price) || (side == "short" && first >= price) {
+			return fmt.Errorf("take_profit_ladder level 1 (%.4f) must be on the profit side of current price %.4f for %s", first, price, side)
+		}
+	}
+
+	if err := validateLadderMinNotional(d); err != nil {
+		return err
+	}
+
+	if d.Action == "update_take_profit" {
+		d.NewTakeProfit = lastLevel.Price
+	} else {
+		d.TakeProfit = lastLevel.Price
+	}
+	return nil
+}
+
+// validateLadderMinNotional 开仓时每档止盈至少达到交易所最小下单额（仓位模型改变仓位后需重新检查）
+func validateLadderMinNotional(d *Decision) error {
+	if (d.Action != "open_long" && d.Action != "open_short") || d.PositionSizeUSD <= 0 {
+		return nil
+	}
+	for i, level := range d.TakeProfitLadder {
+		if notional := d.PositionSizeUSD * level.Percentage / 100; notional < minTakeProfitLevelUSD {
+			return fmt.Errorf("take_profit_ladder level %d closes only %.2f USDT, must be ≥ %.2f USDT (exchange min notional)",
+				i+1, notional, minTakeProfitLevelUSD)
+		}
+	}
+	return nil
+}
+
+// ladderReferencePrice 校验止盈阶梯用的当前价格（优先行情，其次持仓标记价）
+func ladderReferencePrice(ctx *Context, symbol, side string) float64 {
+	if ctx == nil {
+		return 0
+	}
+	if md, ok := ctx.MarketDataMap[symbol]; ok && md != nil && md.CurrentPrice > 0 {
+		return md.CurrentPrice
+	}
+	if pos := findPosition(ctx, symbol, side); pos != nil {
+		return pos.MarkPrice
+	}
+	return 0
+}
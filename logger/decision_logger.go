@@ -7,9 +7,16 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
+// InitiatorSystem 系统（后台监控）发起的动作，区别于AI决策周期
+const InitiatorSystem = "system"
+
+// InitiatorManual 人工通过API下达的决策
+const InitiatorManual = "manual"
+
 // DecisionRecord 决策记录
 type DecisionRecord struct {
 	Timestamp      time.Time          `json:"timestamp"`       // 决策时间
@@ -25,6 +32,8 @@ type DecisionRecord struct {
 	ExecutionLog   []string           `json:"execution_log"`   // 执行日志
 	Success        bool               `json:"success"`         // 是否成功
 	ErrorMessage   string             `json:"error_message"`   // 错误信息（如果有）
+	Initiator      string             `json:"initiator,omitempty"` // 发起方：空=AI决策周期，system=后台监控，manual=人工
+	Reconciliation *ReconciliationReport `json:"reconciliation,omitempty"` // 启动对账报告（仅启动时的系统记录）
 }
 
 // AccountSnapshot 账户状态快照
@@ -53,7 +62,7 @@ type PositionSnapshot struct {
 
 // DecisionAction 决策动作
 type DecisionAction struct {
-	Action    string    `json:"action"`    // open_long, open_short, close_long, close_short, update_stop_loss, update_take_profit, partial_close
+	Action    string    `json:"action"`    // open_long, open_short, add_long, add_short, close_long, close_short, update_stop_loss, update_take_profit, partial_close
 	Symbol    string    `json:"symbol"`    // 币种
 	Quantity  float64   `json:"quantity"`  // 数量（部分平仓时使用）
 	Leverage  int       `json:"leverage"`  // 杠杆（开仓时）
@@ -62,12 +71,104 @@ type DecisionAction struct {
 	Timestamp time.Time `json:"timestamp"` // 执行时间
 	Success   bool      `json:"success"`   // 是否成功
 	Error     string    `json:"error"`     // 错误信息
+	Reason    string    `json:"reason,omitempty"` // 系统发起动作的触发原因（如 max_holding_period）
+
+	// 滑点记录（开仓时）
+	EstimatedPrice       float64 `json:"estimated_price,omitempty"`        // 按订单簿深度预估的成交均价
+	EstimatedSlippagePct float64 `json:"estimated_slippage_pct,omitempty"` // 预估滑点百分比
+	FillPrice            float64 `json:"fill_price,omitempty"`             // 实际成交均价
+	ActualSlippagePct    float64 `json:"actual_slippage_pct,omitempty"`    // 实际滑点百分比（相对下单前中间价）
+
+	// 拆单执行（开仓时）
+	ExecAlgo    string  `json:"exec_algo,omitempty"`    // market / iceberg / twap
+	ChildOrders int     `json:"child_orders,omitempty"` // 实际成交的子单数
+	OrderIDs    []int64 `json:"order_ids,omitempty"`    // 所有子单订单ID
+
+	// 仓位计算（开仓/加仓时使用的模型与输入）
+	Sizing *SizingRecord `json:"sizing,omitempty"`
+
+	// 加仓（add_long/add_short）后的持仓
+	AvgEntryPrice float64       `json:"avg_entry_price,omitempty"` // 加仓后的持仓均价
+	TotalQuantity float64       `json:"total_quantity,omitempty"`  // 加仓后的持仓总数量
+	PositionRisk  float64       `json:"position_risk,omitempty"`   // 按新均价与止损计算的整仓风险(USDT)
+	BuildUp       []PositionLeg `json:"build_up,omitempty"`        // 持仓建仓历史（首次开仓 + 各次加仓）
+
+	// 多级止盈（开仓/加仓/调整止盈/部分平仓后实际挂出的止盈阶梯）
+	TakeProfitLadder    []TakeProfitTarget `json:"take_profit_ladder,omitempty"`
+	MoveStopToBreakeven bool               `json:"move_stop_to_breakeven,omitempty"` // TP1成交后止损移到开仓均价
+}
+
+// TakeProfitTarget 止盈阶梯中的一档
+type TakeProfitTarget struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+	Filled   bool    `json:"filled,omitempty"`
+}
+
+// SizingRecord 开仓/加仓仓位的计算模型与输入
+type SizingRecord struct {
+	Model        string  `json:"model"`                       // ai / fixed_risk / volatility / kelly
+	ProposedUSD  float64 `json:"proposed_usd"`                // AI建议仓位
+	ModelUSD     float64 `json:"model_usd"`                   // 模型计算的仓位（保护调整前）
+	SizeUSD      float64 `json:"size_usd"`                    // 最终仓位
+	Equity       float64 `json:"equity"`                      // 账户净值
+	Available    float64 `json:"available"`                   // 可用余额
+	Price        float64 `json:"price,omitempty"`             // 当前价格
+	StopLoss     float64 `json:"stop_loss,omitempty"`         // 止损价
+	StopDistPct  float64 `json:"stop_distance_pct,omitempty"` // 止损距离（价格%）
+	ATR          float64 `json:"atr,omitempty"`               // ATR14（4h）
+	RiskPct      float64 `json:"risk_pct,omitempty"`          // 每笔风险（净值%）
+	WinRate      float64 `json:"win_rate,omitempty"`          // 历史胜率
+	ProfitFactor float64 `json:"profit_factor,omitempty"`     // 历史盈亏因子
+	PayoffRatio  float64 `json:"payoff_ratio,omitempty"`      // 平均盈利/平均亏损
+	KellyPct     float64 `json:"kelly_pct,omitempty"`         // 完整凯利比例（%）
+	Confidence   int     `json:"confidence,omitempty"`        // AI信心度
+	Cooldown     bool    `json:"cooldown,omitempty"`          // 近期大额亏损冷却
+	SlippagePct  float64 `json:"slippage_pct,omitempty"`      // 预估滑点
+	Note         string  `json:"note,omitempty"`              // 退回AI仓位的原因、上限截断等
+
+	// 加仓时的现有持仓（模型按整仓计算）
+	ExistingUSD     float64 `json:"existing_usd,omitempty"`      // 现有持仓名义价值
+	ExistingRiskUSD float64 `json:"existing_risk_usd,omitempty"` // 现有持仓按新止损的风险
+}
+
+// PositionLeg 持仓建仓的一笔（开仓或加仓）
+type PositionLeg struct {
+	Action    string    `json:"action"` // open_long / open_short / add_long / add_short（existing 表示重启前已有的持仓）
+	Quantity  float64   `json:"quantity"`
+	Price     float64   `json:"price"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ReconciledPosition 启动对账时检查的一个持仓
+type ReconciledPosition struct {
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"`
+	Quantity   float64 `json:"quantity"`
+	EntryPrice float64 `json:"entry_price"`
+	MarkPrice  float64 `json:"mark_price"`
+	Known      bool    `json:"known"`                 // 重启前已在跟踪（否则为外部/手动开的仓）
+	StopLoss   float64 `json:"stop_loss,omitempty"`   // 交易所已挂的止损触发价
+	TakeProfit float64 `json:"take_profit,omitempty"` // 交易所已挂的止盈触发价
+	Protected  bool    `json:"protected"`             // 是否挂有止损
+	Action     string  `json:"action"`                // adopted / protected / closed / flagged
+	Error      string  `json:"error,omitempty"`
+}
+
+// ReconciliationReport 启动对账报告（持仓与挂单核对结果）
+type ReconciliationReport struct {
+	Policy       string               `json:"policy"` // 无止损持仓的处理策略：protect / close / ignore
+	Positions    []ReconciledPosition `json:"positions"`
+	OpenOrders   int                  `json:"open_orders"`
+	OrphanOrders []string             `json:"orphan_orders,omitempty"` // 没有对应持仓的止损/止盈单
+	Error        string               `json:"error,omitempty"`         // 挂单查询失败等
 }
 
 // DecisionLogger 决策日志记录器
 type DecisionLogger struct {
 	logDir      string
 	cycleNumber int
+	mu          sync.Mutex // 决策周期与后台监控可能同时写记录
 }
 
 // NewDecisionLogger 创建决策日志记录器
@@ -101,6 +202,9 @@ func NewDecisionLogger(logDir string) *DecisionLogger {
 
 // LogDecision 记录决策
 func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	l.cycleNumber++
 	record.CycleNumber = l.cycleNumber
 	record.Timestamp = time.Now()
@@ -331,6 +435,25 @@ type SymbolPerformance struct {
 	AvgPnL        float64 `json:"avg_pn_l"`       // 平均盈亏
 }
 
+// addToOpenPosition 加仓后更新跟踪中的持仓（均价与数量）
+func addToOpenPosition(openPos map[string]interface{}, action DecisionAction) {
+	if openPos == nil {
+		return
+	}
+	openPrice, _ := openPos["openPrice"].(float64)
+	quantity, _ := openPos["quantity"].(float64)
+
+	avgPrice := action.AvgEntryPrice
+	if avgPrice <= 0 && quantity+action.Quantity > 0 {
+		avgPrice = (openPrice*quantity + action.Price*action.Quantity) / (quantity + action.Quantity)
+	}
+	openPos["openPrice"] = avgPrice
+	openPos["quantity"] = quantity + action.Quantity
+	if remaining, ok := openPos["remainingQuantity"].(float64); ok && remaining > 0 {
+		openPos["remainingQuantity"] = remaining + action.Quantity
+	}
+}
+
 // AnalyzePerformance 分析最近N个周期的交易表现
 func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAnalysis, error) {
     records, err := l.GetLatestRecords(lookbackCycles)
@@ -366,9 +489,9 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 
 				symbol := action.Symbol
 				side := ""
-				if action.Action == "open_long" || action.Action == "close_long" || action.Action == "partial_close" || action.Action == "auto_close_long" {
+				if action.Action == "open_long" || action.Action == "add_long" || action.Action == "close_long" || action.Action == "partial_close" || action.Action == "auto_close_long" {
 					side = "long"
-				} else if action.Action == "open_short" || action.Action == "close_short" || action.Action == "auto_close_short" {
+				} else if action.Action == "open_short" || action.Action == "add_short" || action.Action == "close_short" || action.Action == "auto_close_short" {
 					side = "short"
 				}
 
@@ -394,6 +517,9 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 						"quantity":  action.Quantity,
 						"leverage":  action.Leverage,
 					}
+				case "add_long", "add_short":
+					// 加仓：更新均价与数量
+					addToOpenPosition(openPositions[posKey], action)
 				case "close_long", "close_short", "auto_close_long", "auto_close_short":
 					// 移除已平仓记录
 					delete(openPositions, posKey)
@@ -412,9 +538,9 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 
 			symbol := action.Symbol
 			side := ""
-			if action.Action == "open_long" || action.Action == "close_long" || action.Action == "partial_close" || action.Action == "auto_close_long" {
+			if action.Action == "open_long" || action.Action == "add_long" || action.Action == "close_long" || action.Action == "partial_close" || action.Action == "auto_close_long" {
 				side = "long"
-			} else if action.Action == "open_short" || action.Action == "close_short" || action.Action == "auto_close_short" {
+			} else if action.Action == "open_short" || action.Action == "add_short" || action.Action == "close_short" || action.Action == "auto_close_short" {
 				side = "short"
 			}
 
@@ -446,6 +572,10 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 					"partialCloseVolume": 0.0,             // 🔧 BUG FIX：部分平倉總量
 				}
 
+			case "add_long", "add_short":
+				// 加仓：更新均价与数量，平仓时按加仓后的均价计算盈亏
+				addToOpenPosition(openPositions[posKey], action)
+
 			case "close_long", "close_short", "partial_close", "auto_close_long", "auto_close_short":
 				// 查找对应的开仓记录（可能来自预填充或当前窗口）
 				if openPos, exists := openPositions[posKey]; exists {
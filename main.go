@@ -10,7 +10,6 @@ import (
 	"nofx-lite/crypto"
 	"nofx-lite/manager"
 	"nofx-lite/market"
-	"nofx-lite/pool"
 	"os"
 	"os/signal"
 	"strconv"
@@ -29,6 +28,7 @@ type ConfigFile struct {
 	DefaultCoins       []string              `json:"default_coins"`
 	CoinPoolAPIURL     string                `json:"coin_pool_api_url"`
 	OITopAPIURL        string                `json:"oi_top_api_url"`
+	SignalSources      json.RawMessage       `json:"signal_sources"` // 信号源配置（JSON数组，见pool.SignalSourceSpec）
 	MaxDailyLoss       float64               `json:"max_daily_loss"`
 	MaxDrawdown        float64               `json:"max_drawdown"`
 	StopTradingMinutes int                   `json:"stop_trading_minutes"`
@@ -89,6 +89,11 @@ func syncConfigToDatabase(database *config.Database, configFile *ConfigFile) err
 		}
 	}
 
+	// 同步信号源配置（原样保存JSON）
+	if len(configFile.SignalSources) > 0 {
+		configs["signal_sources"] = string(configFile.SignalSources)
+	}
+
 	// 同步杠杆配置
 	if configFile.Leverage.BTCETHLeverage > 0 {
 		configs["btc_eth_leverage"] = strconv.Itoa(configFile.Leverage.BTCETHLeverage)
@@ -228,44 +233,11 @@ func main() {
 	log.Printf("✓ 配置数据库初始化成功")
 	fmt.Println()
 
-	// 从数据库读取默认主流币种列表
-	defaultCoinsJSON, _ := database.GetSystemConfig("default_coins")
-	var defaultCoins []string
-
-	if defaultCoinsJSON != "" {
-		// 尝试从JSON解析
-		if err := json.Unmarshal([]byte(defaultCoinsJSON), &defaultCoins); err != nil {
-			log.Printf("⚠️  解析default_coins配置失败: %v，使用硬编码默认值", err)
-			defaultCoins = []string{"BTCUSDT", "ETHUSDT", "SOLUSDT", "BNBUSDT", "XRPUSDT", "DOGEUSDT", "ADAUSDT", "HYPEUSDT"}
-		} else {
-			log.Printf("✓ 从数据库加载默认币种列表（共%d个）: %v", len(defaultCoins), defaultCoins)
-		}
-	} else {
-		// 如果数据库中没有配置，使用硬编码默认值
-		defaultCoins = []string{"BTCUSDT", "ETHUSDT", "SOLUSDT", "BNBUSDT", "XRPUSDT", "DOGEUSDT", "ADAUSDT", "HYPEUSDT"}
-		log.Printf("⚠️  数据库中未配置default_coins，使用硬编码默认值")
-	}
-
-	pool.SetDefaultCoins(defaultCoins)
-	// 设置是否使用默认主流币种
-	pool.SetUseDefaultCoins(useDefaultCoins)
+	// 币种池按交易员独立创建（由各用户的信号源配置 + 交易员的UseCoinPool/UseOITop决定）
 	if useDefaultCoins {
 		log.Printf("✓ 已启用默认主流币种列表")
 	}
 
-	// 设置币种池API URL
-	coinPoolAPIURL, _ := database.GetSystemConfig("coin_pool_api_url")
-	if coinPoolAPIURL != "" {
-		pool.SetCoinPoolAPI(coinPoolAPIURL)
-		log.Printf("✓ 已配置AI500币种池API")
-	}
-
-	oiTopAPIURL, _ := database.GetSystemConfig("oi_top_api_url")
-	if oiTopAPIURL != "" {
-		pool.SetOITopAPI(oiTopAPIURL)
-		log.Printf("✓ 已配置OI Top API")
-	}
-
 	// 创建TraderManager
 	traderManager := manager.NewTraderManager()
 
@@ -181,6 +181,44 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 	return nil
 }
 
+// signalSourceSettings 交易员币种池使用的信号源配置
+type signalSourceSettings struct {
+	CoinPoolURL     string
+	OITopURL        string
+	UseDefaultCoins bool
+	SignalSources   string
+}
+
+// resolveSignalSources 根据用户信号源配置与交易员开关，确定交易员币种池使用的信号源
+// 用户未配置URL时回退到系统配置；交易员未启用对应开关时不使用该信号源
+func resolveSignalSources(traderCfg *config.TraderRecord, coinPoolURL, oiTopURL string, database *config.Database) signalSourceSettings {
+	var settings signalSourceSettings
+
+	if traderCfg.UseCoinPool {
+		if coinPoolURL == "" {
+			coinPoolURL, _ = database.GetSystemConfig("coin_pool_api_url")
+		}
+		if coinPoolURL != "" {
+			settings.CoinPoolURL = coinPoolURL
+			log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
+		}
+	}
+	if traderCfg.UseOITop {
+		if oiTopURL == "" {
+			oiTopURL, _ = database.GetSystemConfig("oi_top_api_url")
+		}
+		if oiTopURL != "" {
+			settings.OITopURL = oiTopURL
+			log.Printf("✓ 交易员 %s 启用 OI TOP 信号源: %s", traderCfg.Name, oiTopURL)
+		}
+	}
+
+	// 默认币种 / 额外信号源按交易员配置
+	settings.UseDefaultCoins = traderCfg.UseDefaultCoins
+	settings.SignalSources = traderCfg.SignalSources
+	return settings
+}
+
 // addTraderFromConfig 内部方法：从配置添加交易员（不加锁，因为调用方已加锁）
 func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModelCfg *config.AIModelConfig, exchangeCfg *config.ExchangeConfig, coinPoolURL, oiTopURL string, maxDailyLoss, maxDrawdown float64, stopTradingMinutes int, defaultCoins []string, database *config.Database, userID string) error {
 	if _, exists := tm.traders[traderCfg.ID]; exists {
@@ -206,11 +244,7 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 	}
 
 	// 根据交易员配置决定是否使用信号源
-	var effectiveCoinPoolURL string
-	if traderCfg.UseCoinPool && coinPoolURL != "" {
-		effectiveCoinPoolURL = coinPoolURL
-		log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
-	}
+	signals := resolveSignalSources(traderCfg, coinPoolURL, oiTopURL, database)
 
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
@@ -223,7 +257,10 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		BinanceTestnet:        exchangeCfg.Testnet,
 		HyperliquidPrivateKey: "",
 		HyperliquidTestnet:    exchangeCfg.Testnet,
-		CoinPoolAPIURL:        effectiveCoinPoolURL,
+		CoinPoolAPIURL:        signals.CoinPoolURL,
+		OITopAPIURL:           signals.OITopURL,
+		UseDefaultCoins:       signals.UseDefaultCoins,
+		SignalSources:         signals.SignalSources,
 		UseQwen:               aiModelCfg.Provider == "qwen",
 		DeepSeekKey:           "",
 		QwenKey:               "",
@@ -239,7 +276,15 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		IsCrossMargin:         traderCfg.IsCrossMargin,
 		DefaultCoins:          defaultCoins,
 		TradingCoins:          tradingCoins,
-		SystemPromptTemplate:  traderCfg.SystemPromptTemplate, // 系统提示词模板
+		RegimeRiskLimits:      traderCfg.RegimeRiskLimits,      // 按市场状态的风控参数
+		MaxSlippagePct:        traderCfg.MaxSlippagePct,        // 开仓最大预估滑点
+		CorrelationThreshold:  traderCfg.CorrelationThreshold,  // 相关系数阈值
+		MaxCorrelatedExposure: traderCfg.MaxCorrelatedExposure, // 同向高相关敞口上限
+		TimeExitRules:         traderCfg.TimeExitRules,         // 按持仓时间的退出规则
+		TradingSchedule:       traderCfg.TradingSchedule,       // 交易时段与禁止开仓时段
+		ReconcilePolicy:       traderCfg.ReconcilePolicy,       // 启动对账策略
+		PositionSizing:        traderCfg.PositionSizing,        // 仓位计算模型
+		SystemPromptTemplate:  traderCfg.SystemPromptTemplate,  // 系统提示词模板
 	}
 
 	// 根据交易所类型设置API密钥
@@ -315,11 +360,7 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 	}
 
 	// 根据交易员配置决定是否使用信号源
-	var effectiveCoinPoolURL string
-	if traderCfg.UseCoinPool && coinPoolURL != "" {
-		effectiveCoinPoolURL = coinPoolURL
-		log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
-	}
+	signals := resolveSignalSources(traderCfg, coinPoolURL, oiTopURL, database)
 
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
@@ -332,7 +373,10 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		BinanceTestnet:        exchangeCfg.Testnet,
 		HyperliquidPrivateKey: "",
 		HyperliquidTestnet:    exchangeCfg.Testnet,
-		CoinPoolAPIURL:        effectiveCoinPoolURL,
+		CoinPoolAPIURL:        signals.CoinPoolURL,
+		OITopAPIURL:           signals.OITopURL,
+		UseDefaultCoins:       signals.UseDefaultCoins,
+		SignalSources:         signals.SignalSources,
 		UseQwen:               aiModelCfg.Provider == "qwen",
 		DeepSeekKey:           "",
 		QwenKey:               "",
@@ -348,6 +392,14 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		IsCrossMargin:         traderCfg.IsCrossMargin,
 		DefaultCoins:          defaultCoins,
 		TradingCoins:          tradingCoins,
+		RegimeRiskLimits:      traderCfg.RegimeRiskLimits,      // 按市场状态的风控参数
+		MaxSlippagePct:        traderCfg.MaxSlippagePct,        // 开仓最大预估滑点
+		CorrelationThreshold:  traderCfg.CorrelationThreshold,  // 相关系数阈值
+		MaxCorrelatedExposure: traderCfg.MaxCorrelatedExposure, // 同向高相关敞口上限
+		TimeExitRules:         traderCfg.TimeExitRules,         // 按持仓时间的退出规则
+		TradingSchedule:       traderCfg.TradingSchedule,       // 交易时段与禁止开仓时段
+		ReconcilePolicy:       traderCfg.ReconcilePolicy,       // 启动对账策略
+		PositionSizing:        traderCfg.PositionSizing,        // 仓位计算模型
 	}
 
 	// 根据交易所类型设置API密钥
@@ -477,11 +529,11 @@ func (tm *TraderManager) GetComparisonData() (map[string]interface{}, error) {
 			"trader_name":     t.GetName(),
 			"ai_model":        t.GetAIModel(),
 			"exchange":        t.GetExchange(),
-			"total_equity":    account["total_equity"],
-			"total_pnl":       account["total_pnl"],
-			"total_pnl_pct":   account["total_pnl_pct"],
-			"position_count":  account["position_count"],
-			"margin_used_pct": account["margin_used_pct"],
+			"total_equity":    account.TotalEquity,
+			"total_pnl":       account.TotalPnL,
+			"total_pnl_pct":   account.TotalPnLPct,
+			"position_count":  account.PositionCount,
+			"margin_used_pct": account.MarginUsedPct,
 			"call_count":      status["call_count"],
 			"is_running":      status["is_running"],
 		})
@@ -563,6 +615,8 @@ func (tm *TraderManager) getConcurrentTraderData(traders []*trader.AutoTrader) [
 		index int
 		data  map[string]interface{}
 	}
+	// 下方goroutine参数名trader遮蔽了包名
+	type accountSummary = trader.AccountSummary
 
 	// 创建结果通道
 	resultChan := make(chan traderResult, len(traders))
@@ -575,7 +629,7 @@ func (tm *TraderManager) getConcurrentTraderData(traders []*trader.AutoTrader) [
 			defer cancel()
 
 			// 使用通道来实现超时控制
-			accountChan := make(chan map[string]interface{}, 1)
+			accountChan := make(chan *accountSummary, 1)
 			errorChan := make(chan error, 1)
 
 			go func() {
@@ -598,11 +652,11 @@ func (tm *TraderManager) getConcurrentTraderData(traders []*trader.AutoTrader) [
 					"trader_name":     trader.GetName(),
 					"ai_model":        trader.GetAIModel(),
 					"exchange":        trader.GetExchange(),
-					"total_equity":    account["total_equity"],
-					"total_pnl":       account["total_pnl"],
-					"total_pnl_pct":   account["total_pnl_pct"],
-					"position_count":  account["position_count"],
-					"margin_used_pct": account["margin_used_pct"],
+					"total_equity":    account.TotalEquity,
+					"total_pnl":       account.TotalPnL,
+					"total_pnl_pct":   account.TotalPnLPct,
+					"position_count":  account.PositionCount,
+					"margin_used_pct": account.MarginUsedPct,
 					"is_running":      status["is_running"],
 				}
 			case err := <-errorChan:
@@ -869,34 +923,41 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 	}
 
 	// 根据交易员配置决定是否使用信号源
-	var effectiveCoinPoolURL string
-	if traderCfg.UseCoinPool && coinPoolURL != "" {
-		effectiveCoinPoolURL = coinPoolURL
-		log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
-	}
+	signals := resolveSignalSources(traderCfg, coinPoolURL, oiTopURL, database)
 
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
-		ID:                   traderCfg.ID,
-		Name:                 traderCfg.Name,
-		AIModel:              aiModelCfg.Provider, // 使用provider作为模型标识
-		Exchange:             exchangeCfg.ID,      // 使用exchange ID
-		InitialBalance:       traderCfg.InitialBalance,
-		BTCETHLeverage:       traderCfg.BTCETHLeverage,
-		AltcoinLeverage:      traderCfg.AltcoinLeverage,
-		ScanInterval:         time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
-		CoinPoolAPIURL:       effectiveCoinPoolURL,
-		CustomAPIURL:         aiModelCfg.CustomAPIURL,    // 自定义API URL
-		CustomModelName:      aiModelCfg.CustomModelName, // 自定义模型名称
-		UseQwen:              aiModelCfg.Provider == "qwen",
-		MaxDailyLoss:         maxDailyLoss,
-		MaxDrawdown:          maxDrawdown,
-		StopTradingTime:      time.Duration(stopTradingMinutes) * time.Minute,
-		IsCrossMargin:        traderCfg.IsCrossMargin,
-		DefaultCoins:         defaultCoins,
-		TradingCoins:         tradingCoins,
-		SystemPromptTemplate: traderCfg.SystemPromptTemplate, // 系统提示词模板
-		HyperliquidTestnet:   exchangeCfg.Testnet,            // Hyperliquid测试网
+		ID:                    traderCfg.ID,
+		Name:                  traderCfg.Name,
+		AIModel:               aiModelCfg.Provider, // 使用provider作为模型标识
+		Exchange:              exchangeCfg.ID,      // 使用exchange ID
+		InitialBalance:        traderCfg.InitialBalance,
+		BTCETHLeverage:        traderCfg.BTCETHLeverage,
+		AltcoinLeverage:       traderCfg.AltcoinLeverage,
+		ScanInterval:          time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
+		CoinPoolAPIURL:        signals.CoinPoolURL,
+		OITopAPIURL:           signals.OITopURL,
+		UseDefaultCoins:       signals.UseDefaultCoins,
+		SignalSources:         signals.SignalSources,
+		CustomAPIURL:          aiModelCfg.CustomAPIURL,    // 自定义API URL
+		CustomModelName:       aiModelCfg.CustomModelName, // 自定义模型名称
+		UseQwen:               aiModelCfg.Provider == "qwen",
+		MaxDailyLoss:          maxDailyLoss,
+		MaxDrawdown:           maxDrawdown,
+		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
+		IsCrossMargin:         traderCfg.IsCrossMargin,
+		DefaultCoins:          defaultCoins,
+		TradingCoins:          tradingCoins,
+		RegimeRiskLimits:      traderCfg.RegimeRiskLimits,      // 按市场状态的风控参数
+		MaxSlippagePct:        traderCfg.MaxSlippagePct,        // 开仓最大预估滑点
+		CorrelationThreshold:  traderCfg.CorrelationThreshold,  // 相关系数阈值
+		MaxCorrelatedExposure: traderCfg.MaxCorrelatedExposure, // 同向高相关敞口上限
+		TimeExitRules:         traderCfg.TimeExitRules,         // 按持仓时间的退出规则
+		TradingSchedule:       traderCfg.TradingSchedule,       // 交易时段与禁止开仓时段
+		ReconcilePolicy:       traderCfg.ReconcilePolicy,       // 启动对账策略
+		PositionSizing:        traderCfg.PositionSizing,        // 仓位计算模型
+		SystemPromptTemplate:  traderCfg.SystemPromptTemplate,  // 系统提示词模板
+		HyperliquidTestnet:    exchangeCfg.Testnet,             // Hyperliquid测试网
 	}
 
 	// 根据交易所类型设置API密钥
@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"nofx-lite/hook"
+	"nofx-lite/ratelimit"
 	"strconv"
 	"time"
 )
@@ -16,10 +17,29 @@ const (
 )
 
 type APIClient struct {
-	client *http.Client
+	client  *http.Client
+	baseURL string
 }
 
 func NewAPIClient() *APIClient {
+	return NewAPIClientWithBaseURL(baseURL)
+}
+
+// NewAPIClientWithBaseURL 创建指向指定fapi兼容端点的客户端（如币安测试网、Aster）
+func NewAPIClientWithBaseURL(base string) *APIClient {
+	exchange, profile := "binance", ratelimit.BinanceProfile
+	if base == asterBaseURL {
+		exchange, profile = "aster", ratelimit.AsterProfile
+	}
+
+	return &APIClient{
+		client:  newHTTPClient(exchange, profile),
+		baseURL: base,
+	}
+}
+
+// newHTTPClient 创建公共行情HTTP客户端（同一交易所的公共接口共享限流）
+func newHTTPClient(exchange string, profile ratelimit.Profile) *http.Client {
 	client := &http.Client{
 		Timeout: 30 * time.Second,
 	}
@@ -30,13 +50,11 @@ func NewAPIClient() *APIClient {
 		client = hookRes.GetResult()
 	}
 
-	return &APIClient{
-		client: client,
-	}
+	return ratelimit.WrapClient(client, ratelimit.For(exchange, ""), profile)
 }
 
 func (c *APIClient) GetExchangeInfo() (*ExchangeInfo, error) {
-	url := fmt.Sprintf("%s/fapi/v1/exchangeInfo", baseURL)
+	url := fmt.Sprintf("%s/fapi/v1/exchangeInfo", c.baseURL)
 	resp, err := c.client.Get(url)
 	if err != nil {
 		return nil, err
@@ -57,7 +75,7 @@ func (c *APIClient) GetExchangeInfo() (*ExchangeInfo, error) {
 }
 
 func (c *APIClient) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
-	url := fmt.Sprintf("%s/fapi/v1/klines", baseURL)
+	url := fmt.Sprintf("%s/fapi/v1/klines", c.baseURL)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -124,7 +142,7 @@ func parseKline(kr KlineResponse) (Kline, error) {
 }
 
 func (c *APIClient) GetCurrentPrice(symbol string) (float64, error) {
-	url := fmt.Sprintf("%s/fapi/v1/ticker/price", baseURL)
+	url := fmt.Sprintf("%s/fapi/v1/ticker/price", c.baseURL)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return 0, err
@@ -163,7 +181,7 @@ func (c *APIClient) GetCurrentPrice(symbol string) (float64, error) {
 // limit参数控制返回的档位数量，建议值：5, 10, 20
 // 返回的数据包含买卖盘各limit个档位的数据
 func (c *APIClient) GetOrderBookData(symbol string, limit int) (*DepthData, error) {
-	url := fmt.Sprintf("%s/fapi/v1/depth", baseURL)
+	url := fmt.Sprintf("%s/fapi/v1/depth", c.baseURL)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -245,3 +263,94 @@ func (c *APIClient) GetOrderBookData(symbol string, limit int) (*DepthData, erro
 
 	return depthData, nil
 }
+
+// Get24hrTickers 获取所有交易对的24小时行情
+func (c *APIClient) Get24hrTickers() ([]Ticker24hr, error) {
+	url := fmt.Sprintf("%s/fapi/v1/ticker/24hr", c.baseURL)
+	resp, err := c.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var tickers []Ticker24hr
+	if err := json.Unmarshal(body, &tickers); err != nil {
+		return nil, err
+	}
+	return tickers, nil
+}
+
+// GetAllFundingRates 一次性获取所有交易对的当前资金费率（symbol -> rate）
+func (c *APIClient) GetAllFundingRates() (map[string]float64, error) {
+	url := fmt.Sprintf("%s/fapi/v1/premiumIndex", c.baseURL)
+	resp, err := c.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var result []struct {
+		Symbol          string `json:"symbol"`
+		LastFundingRate string `json:"lastFundingRate"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	rates := make(map[string]float64, len(result))
+	for _, r := range result {
+		if rate, err := strconv.ParseFloat(r.LastFundingRate, 64); err == nil {
+			rates[r.Symbol] = rate
+		}
+	}
+	return rates, nil
+}
+
+// GetOpenInterestHist 获取持仓价值历史（USDT，时间升序）
+func (c *APIClient) GetOpenInterestHist(symbol, period string, limit int) ([]float64, error) {
+	url := fmt.Sprintf("%s/futures/data/openInterestHist?symbol=%s&period=%s&limit=%d", c.baseURL, symbol, period, limit)
+	resp, err := c.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var result []struct {
+		SumOpenInterestValue string `json:"sumOpenInterestValue"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, 0, len(result))
+	for _, r := range result {
+		if v, err := strconv.ParseFloat(r.SumOpenInterestValue, 64); err == nil {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
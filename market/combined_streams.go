@@ -102,6 +102,41 @@ func (c *CombinedStreamsClient) BatchSubscribeDepth(symbols []string, levels int
 	return nil
 }
 
+// BatchSubscribeAggTrades 批量订阅归集成交
+func (c *CombinedStreamsClient) BatchSubscribeAggTrades(symbols []string) error {
+	return c.batchSubscribe(symbols, "aggTrade", "归集成交")
+}
+
+// BatchSubscribeForceOrders 批量订阅强平订单
+func (c *CombinedStreamsClient) BatchSubscribeForceOrders(symbols []string) error {
+	return c.batchSubscribe(symbols, "forceOrder", "强平订单")
+}
+
+// batchSubscribe 按批次订阅 <symbol>@<suffix> 形式的流
+func (c *CombinedStreamsClient) batchSubscribe(symbols []string, suffix, desc string) error {
+	batches := c.splitIntoBatches(symbols, c.batchSize)
+
+	for i, batch := range batches {
+		log.Printf("订阅第 %d 批%s, 数量: %d", i+1, desc, len(batch))
+
+		streams := make([]string, len(batch))
+		for j, symbol := range batch {
+			streams[j] = fmt.Sprintf("%s@%s", strings.ToLower(symbol), suffix)
+		}
+
+		if err := c.subscribeStreams(streams); err != nil {
+			return fmt.Errorf("第 %d 批%s订阅失败: %v", i+1, desc, err)
+		}
+
+		// 批次间延迟，避免被限制
+		if i < len(batches)-1 {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	return nil
+}
+
 // splitIntoBatches 将切片分成指定大小的批次
 func (c *CombinedStreamsClient) splitIntoBatches(symbols []string, batchSize int) [][]string {
 	var batches [][]string
@@ -0,0 +1,254 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCorrelationInterval 相关性计算默认使用的K线周期（4h×100根 ≈ 16天）
+const DefaultCorrelationInterval = "4h"
+
+// MaxCorrelationSymbols 单次相关性计算的最多币种数（每个币种都需要获取K线）
+const MaxCorrelationSymbols = 30
+
+// correlationIntervals 允许用于相关性计算的K线周期
+var correlationIntervals = map[string]bool{
+	"15m": true, "30m": true, "1h": true, "2h": true, "4h": true, "8h": true, "12h": true, "1d": true,
+}
+
+// IsValidCorrelationInterval 是否为允许的相关性K线周期
+func IsValidCorrelationInterval(interval string) bool {
+	return correlationIntervals[interval]
+}
+
+// CorrelationMatrix 收益率相关性矩阵与BTC beta
+type CorrelationMatrix struct {
+	Symbols   []string                      `json:"symbols"`
+	Interval  string                        `json:"interval"`
+	Samples   int                           `json:"samples"` // 参与计算的最少收益率样本数
+	Matrix    map[string]map[string]float64 `json:"matrix"`
+	BTCBeta   map[string]float64            `json:"btc_beta"`
+	BTCCorr   map[string]float64            `json:"btc_corr"`
+	UpdatedAt time.Time                     `json:"updated_at"`
+}
+
+// CorrelatedPair 高相关币对
+type CorrelatedPair struct {
+	A           string  `json:"a"`
+	B           string  `json:"b"`
+	Correlation float64 `json:"correlation"`
+}
+
+// ComputeCorrelations 基于数据源的K线计算对数收益率相关性矩阵和BTC beta
+// 各币种按K线开盘时间对齐，只使用所有币种共有的时间点
+func ComputeCorrelations(source MarketDataSource, symbols []string, interval string) (*CorrelationMatrix, error) {
+	if source == nil {
+		return nil, fmt.Errorf("行情数据源未设置")
+	}
+	if interval == "" {
+		interval = DefaultCorrelationInterval
+	}
+
+	// BTC 作为基准必须参与计算
+	symbolSet := map[string]bool{"BTCUSDT": true}
+	for _, s := range symbols {
+		symbolSet[Normalize(s)] = true
+	}
+
+	returns := make(map[string]map[int64]float64)
+	for symbol := range symbolSet {
+		klines, err := source.GetCurrentKlines(symbol, interval)
+		if err != nil || len(klines) < 3 {
+			continue
+		}
+		returns[symbol] = logReturns(klines)
+	}
+	btcReturns, ok := returns["BTCUSDT"]
+	if !ok {
+		return nil, fmt.Errorf("BTC K线数据不可用")
+	}
+
+	result := &CorrelationMatrix{
+		Interval:  interval,
+		Matrix:    make(map[string]map[string]float64),
+		BTCBeta:   make(map[string]float64),
+		BTCCorr:   make(map[string]float64),
+		UpdatedAt: time.Now(),
+	}
+	for symbol := range returns {
+		result.Symbols = append(result.Symbols, symbol)
+	}
+	sort.Strings(result.Symbols)
+
+	minSamples := 0
+	for i, a := range result.Symbols {
+		if result.Matrix[a] == nil {
+			result.Matrix[a] = make(map[string]float64)
+		}
+		result.Matrix[a][a] = 1
+		for _, b := range result.Symbols[i+1:] {
+			x, y := alignReturns(returns[a], returns[b])
+			if len(x) < 2 {
+				continue
+			}
+			if minSamples == 0 || len(x) < minSamples {
+				minSamples = len(x)
+			}
+			corr := pearson(x, y)
+			result.Matrix[a][b] = corr
+			if result.Matrix[b] == nil {
+				result.Matrix[b] = make(map[string]float64)
+			}
+			result.Matrix[b][a] = corr
+		}
+
+		// BTC beta = cov(r, r_btc) / var(r_btc)
+		x, y := alignReturns(returns[a], btcReturns)
+		if len(x) >= 2 {
+			result.BTCBeta[a] = beta(x, y)
+			result.BTCCorr[a] = result.Matrix[a]["BTCUSDT"]
+		}
+	}
+	result.Samples = minSamples
+
+	return result, nil
+}
+
+// Correlation 返回两个币种的相关系数（无数据时返回false）
+func (m *CorrelationMatrix) Correlation(a, b string) (float64, bool) {
+	if m == nil {
+		return 0, false
+	}
+	row, ok := m.Matrix[a]
+	if !ok {
+		return 0, false
+	}
+	corr, ok := row[b]
+	return corr, ok
+}
+
+// HighlyCorrelatedPairs 返回给定币种之间相关系数绝对值 >= threshold 的币对（按相关性降序）
+func (m *CorrelationMatrix) HighlyCorrelatedPairs(symbols []string, threshold float64) []CorrelatedPair {
+	var pairs []CorrelatedPair
+	if m == nil {
+		return pairs
+	}
+	for i, a := range symbols {
+		for _, b := range symbols[i+1:] {
+			if corr, ok := m.Correlation(a, b); ok && math.Abs(corr) >= threshold {
+				pairs = append(pairs, CorrelatedPair{A: a, B: b, Correlation: corr})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return math.Abs(pairs[i].Correlation) > math.Abs(pairs[j].Correlation)
+	})
+	return pairs
+}
+
+// FormatCorrelationSummary 生成紧凑的相关性摘要（BTC beta + 高相关币对）
+func FormatCorrelationSummary(m *CorrelationMatrix, symbols []string, threshold float64, maxPairs int) string {
+	if m == nil || len(symbols) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+
+	betas := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		if b, ok := m.BTCBeta[s]; ok && s != "BTCUSDT" {
+			betas = append(betas, fmt.Sprintf("%s β=%.2f ρ=%.2f", s, b, m.BTCCorr[s]))
+		}
+	}
+	if len(betas) > 0 {
+		sb.WriteString("btc_beta: " + strings.Join(betas, " | ") + "\n")
+	}
+
+	pairs := m.HighlyCorrelatedPairs(symbols, threshold)
+	if len(pairs) > maxPairs {
+		pairs = pairs[:maxPairs]
+	}
+	if len(pairs) > 0 {
+		items := make([]string, 0, len(pairs))
+		for _, p := range pairs {
+			items = append(items, fmt.Sprintf("%s~%s %.2f", p.A, p.B, p.Correlation))
+		}
+		sb.WriteString(fmt.Sprintf("high_corr(|ρ|≥%.2f): %s\n", threshold, strings.Join(items, " | ")))
+	}
+	return sb.String()
+}
+
+// logReturns 计算对数收益率（key为K线开盘时间）
+func logReturns(klines []Kline) map[int64]float64 {
+	returns := make(map[int64]float64, len(klines))
+	for i := 1; i < len(klines); i++ {
+		prev := klines[i-1].Close
+		cur := klines[i].Close
+		if prev > 0 && cur > 0 {
+			returns[klines[i].OpenTime] = math.Log(cur / prev)
+		}
+	}
+	return returns
+}
+
+// alignReturns 按时间对齐两个收益率序列
+func alignReturns(a, b map[int64]float64) ([]float64, []float64) {
+	keys := make([]int64, 0, len(a))
+	for t := range a {
+		if _, ok := b[t]; ok {
+			keys = append(keys, t)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	x := make([]float64, len(keys))
+	y := make([]float64, len(keys))
+	for i, t := range keys {
+		x[i] = a[t]
+		y[i] = b[t]
+	}
+	return x, y
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// pearson 皮尔逊相关系数
+func pearson(x, y []float64) float64 {
+	mx, my := mean(x), mean(y)
+	var cov, vx, vy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		cov += dx * dy
+		vx += dx * dx
+		vy += dy * dy
+	}
+	if vx == 0 || vy == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(vx*vy)
+}
+
+// beta 回归系数 cov(x, y) / var(y)
+func beta(x, y []float64) float64 {
+	mx, my := mean(x), mean(y)
+	var cov, vy float64
+	for i := range x {
+		cov += (x[i] - mx) * (y[i] - my)
+		vy += (y[i] - my) * (y[i] - my)
+	}
+	if vy == 0 {
+		return 0
+	}
+	return cov / vy
+}
@@ -31,27 +31,42 @@ type FundingRateCache struct {
 var (
 	fundingRateMap sync.Map // map[string]*FundingRateCache
 	frCacheTTL     = 1 * time.Hour
+
+	// 资金费率详情（预测费率随溢价指数变化，缓存时间更短）
+	fundingDataMap sync.Map // map[string]*fundingDataCache
+	fdCacheTTL     = 5 * time.Minute
 )
 
-// Get 获取指定代币的市场数据
+// fundingDataCache 资金费率详情缓存
+type fundingDataCache struct {
+	Data      *FundingData
+	UpdatedAt time.Time
+}
+
+// Get 获取指定代币的市场数据（币安数据源）
 func Get(symbol string, testnet ...bool) (*Data, error) {
 	// 检查是否使用测试网，默认为false
 	useTestnet := false
 	if len(testnet) > 0 {
 		useTestnet = testnet[0]
 	}
+	return GetFrom(NewBinanceSource(useTestnet), symbol)
+}
+
+// GetFrom 从指定数据源获取代币的市场数据
+func GetFrom(source MarketDataSource, symbol string) (*Data, error) {
 	var klines3m, klines4h []Kline
 	var err error
 	// 标准化symbol
 	symbol = Normalize(symbol)
 	// 获取3分钟K线数据 (最近10个)
-	klines3m, err = WSMonitorCli.GetCurrentKlines(symbol, "3m") // 多获取一些用于计算
+	klines3m, err = source.GetCurrentKlines(symbol, "3m") // 多获取一些用于计算
 	if err != nil {
 		return nil, fmt.Errorf("获取3分钟K线失败: %v", err)
 	}
 
 	// 获取4小时K线数据 (最近10个)
-	klines4h, err = WSMonitorCli.GetCurrentKlines(symbol, "4h") // 多获取用于计算指标
+	klines4h, err = source.GetCurrentKlines(symbol, "4h") // 多获取用于计算指标
 	if err != nil {
 		return nil, fmt.Errorf("获取4小时K线失败: %v", err)
 	}
@@ -90,29 +105,49 @@ func Get(symbol string, testnet ...bool) (*Data, error) {
 	}
 
 	// 获取OI数据
-	oiData, err := GetOpenInterestData(symbol, useTestnet)
+	oiData, err := source.GetOpenInterest(symbol)
 	if err != nil {
 		// OI失败不影响整体,使用默认值
 		oiData = &OIData{Latest: 0, Average: 0}
 	}
 
 	// 获取Funding Rate
-	fundingRate, _ := GetFundingRate(symbol, useTestnet)
+	fundingRate, _ := source.GetFundingRate(symbol)
+
+	// 获取资金费率历史与预测（失败不影响整体）
+	fundingData, err := source.GetFundingData(symbol)
+	if err != nil {
+		log.Printf("获取资金费率详情失败: %v", err)
+		fundingData = nil
+	}
 
 	// 获取深度数据 (获取10档深度数据)
-	depthData, err := GetDepthData(symbol, useTestnet)
+	depthData, err := source.GetDepth(symbol, 10)
 	if err != nil {
 		// 深度数据获取失败不影响整体，记录错误并继续
 		log.Printf("获取深度数据失败: %v", err)
 		depthData = nil
 	}
 
+	// 获取订单流数据（WS未就绪时为nil）
+	orderFlow := source.GetOrderFlow(symbol)
+
 	// 计算日内系列数据
 	intradayData := calculateIntradaySeries(klines3m)
 
 	// 计算长期数据
 	longerTermData := calculateLongerTermData(klines4h)
 
+	// 判断市场状态（默认复用4小时K线）
+	regimeKlines := klines4h
+	if config.Regime.Interval != "4h" {
+		if regimeKlines, err = source.GetCurrentKlines(symbol, config.Regime.Interval); err != nil {
+			log.Printf("获取市场状态K线失败: %v", err)
+			regimeKlines = nil
+		}
+	}
+	regime := ClassifyRegime(symbol, regimeKlines, config.Regime.Interval)
+
 	return &Data{
 		Symbol:            symbol,
 		CurrentPrice:      currentPrice,
@@ -123,7 +158,10 @@ func Get(symbol string, testnet ...bool) (*Data, error) {
 		CurrentRSI7:       currentRSI7,
 		OpenInterest:      oiData,
 		FundingRate:       fundingRate,
+		Funding:           fundingData,
 		DepthData:         depthData,
+		OrderFlow:         orderFlow,
+		Regime:            regime,
 		IntradaySeries:    intradayData,
 		LongerTermContext: longerTermData,
 	}, nil
@@ -336,17 +374,16 @@ func calculateLongerTermData(klines []Kline) *LongerTermData {
 
 // GetOpenInterestData 获取持仓数据（导出供测试使用）
 func GetOpenInterestData(symbol string, testnet bool) (*OIData, error) {
-	// 使用统一的APIClient
-	apiClient := NewAPIClient()
-	
-	// 获取基础URL（复用api_client.go中的常量）
-	baseURL := getBaseURL(testnet)
-	
+	return NewAPIClientWithBaseURL(getBaseURL(testnet)).GetOpenInterest(symbol)
+}
+
+// GetOpenInterest 获取持仓量（fapi兼容接口）
+func (c *APIClient) GetOpenInterest(symbol string) (*OIData, error) {
 	// 构建请求URL
-	url := fmt.Sprintf("%s/fapi/v1/openInterest?symbol=%s", baseURL, symbol)
+	url := fmt.Sprintf("%s/fapi/v1/openInterest?symbol=%s", c.baseURL, symbol)
 	
 	// 使用统一的HTTP客户端进行请求
-	resp, err := apiClient.client.Get(url)
+	resp, err := c.client.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get open interest data: %w", err)
 	}
@@ -386,9 +423,15 @@ func GetOpenInterestData(symbol string, testnet bool) (*OIData, error) {
 
 // GetFundingRate 获取资金费率（导出供测试使用）
 func GetFundingRate(symbol string, testnet bool) (float64, error) {
-	// 检查缓存（有效期 1 小时）
-	// Funding Rate 每 8 小时才更新，1 小时缓存非常合理
-	if cached, ok := fundingRateMap.Load(symbol); ok {
+	return getCachedFundingRate(symbol, func() (float64, error) {
+		return NewAPIClientWithBaseURL(getBaseURL(testnet)).GetPremiumFundingRate(symbol)
+	})
+}
+
+// getCachedFundingRate 带缓存的资金费率查询
+// Funding Rate 每 8 小时才更新，1 小时缓存非常合理
+func getCachedFundingRate(key string, fetch func() (float64, error)) (float64, error) {
+	if cached, ok := fundingRateMap.Load(key); ok {
 		cache := cached.(*FundingRateCache)
 		if time.Since(cache.UpdatedAt) < frCacheTTL {
 			// 缓存命中，直接返回
@@ -396,31 +439,57 @@ func GetFundingRate(symbol string, testnet bool) (float64, error) {
 		}
 	}
 
-	// 使用统一的APIClient
-	apiClient := NewAPIClient()
-	
-	// 获取基础URL（复用api_client.go中的常量）
-	baseURL := getBaseURL(testnet)
-	
+	rate, err := fetch()
+	if err != nil {
+		return 0, err
+	}
+
+	// 更新缓存
+	fundingRateMap.Store(key, &FundingRateCache{
+		Rate:      rate,
+		UpdatedAt: time.Now(),
+	})
+
+	return rate, nil
+}
+
+// PremiumIndex 标记价格与资金费率
+type PremiumIndex struct {
+	MarkPrice       float64
+	LastFundingRate float64 // 币安返回的是本期预测费率（下次结算使用）
+	NextFundingTime time.Time
+}
+
+// GetPremiumFundingRate 通过premiumIndex获取最新资金费率（fapi兼容接口，不走缓存）
+func (c *APIClient) GetPremiumFundingRate(symbol string) (float64, error) {
+	index, err := c.GetPremiumIndex(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return index.LastFundingRate, nil
+}
+
+// GetPremiumIndex 获取premiumIndex（fapi兼容接口）
+func (c *APIClient) GetPremiumIndex(symbol string) (*PremiumIndex, error) {
 	// 构建请求URL
-	url := fmt.Sprintf("%s/fapi/v1/premiumIndex?symbol=%s", baseURL, symbol)
+	url := fmt.Sprintf("%s/fapi/v1/premiumIndex?symbol=%s", c.baseURL, symbol)
 	
 	// 使用统一的HTTP客户端进行请求
-	resp, err := apiClient.client.Get(url)
+	resp, err := c.client.Get(url)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get funding rate: %w", err)
+		return nil, fmt.Errorf("failed to get funding rate: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 检查响应状态
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return 0, fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	var result struct {
@@ -434,21 +503,101 @@ func GetFundingRate(symbol string, testnet bool) (float64, error) {
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
-		return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
 	rate, err := strconv.ParseFloat(result.LastFundingRate, 64)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse funding rate: %w", err)
+		return nil, fmt.Errorf("failed to parse funding rate: %w", err)
 	}
+	markPrice, _ := strconv.ParseFloat(result.MarkPrice, 64)
 
-	// 更新缓存
-	fundingRateMap.Store(symbol, &FundingRateCache{
-		Rate:      rate,
+	return &PremiumIndex{
+		MarkPrice:       markPrice,
+		LastFundingRate: rate,
+		NextFundingTime: time.UnixMilli(result.NextFundingTime),
+	}, nil
+}
+
+// GetFundingRateHistory 获取已结算的资金费率历史（fapi兼容接口，时间升序）
+func (c *APIClient) GetFundingRateHistory(symbol string, limit int) ([]FundingRatePoint, error) {
+	url := fmt.Sprintf("%s/fapi/v1/fundingRate?symbol=%s&limit=%d", c.baseURL, symbol, limit)
+
+	resp, err := c.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get funding rate history: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var result []struct {
+		FundingRate string `json:"fundingRate"`
+		FundingTime int64  `json:"fundingTime"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	history := make([]FundingRatePoint, 0, len(result))
+	for _, r := range result {
+		rate, err := strconv.ParseFloat(r.FundingRate, 64)
+		if err != nil {
+			continue
+		}
+		history = append(history, FundingRatePoint{
+			Time: time.UnixMilli(r.FundingTime),
+			Rate: rate,
+		})
+	}
+	return history, nil
+}
+
+// getCachedFundingData 带缓存的资金费率详情查询
+func getCachedFundingData(key string, fetch func() (*FundingData, error)) (*FundingData, error) {
+	if cached, ok := fundingDataMap.Load(key); ok {
+		cache := cached.(*fundingDataCache)
+		if time.Since(cache.UpdatedAt) < fdCacheTTL {
+			return cache.Data, nil
+		}
+	}
+
+	data, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	fundingDataMap.Store(key, &fundingDataCache{
+		Data:      data,
 		UpdatedAt: time.Now(),
 	})
+	return data, nil
+}
 
-	return rate, nil
+// newFundingData 根据预测费率与历史计算资金费率详情
+func newFundingData(predicted float64, next time.Time, history []FundingRatePoint) *FundingData {
+	data := &FundingData{
+		PredictedRate:   predicted,
+		NextFundingTime: next,
+		History:         history,
+	}
+	if len(history) > 0 {
+		sum := 0.0
+		for _, h := range history {
+			sum += h.Rate
+		}
+		data.AverageRate = sum / float64(len(history))
+	}
+	if len(history) >= 2 {
+		data.IntervalHours = history[len(history)-1].Time.Sub(history[len(history)-2].Time).Hours()
+	}
+	return data
 }
 
 // Format 格式化输出市场数据
@@ -482,6 +631,43 @@ func Format(data *Data) string {
         sb.WriteString(fmt.Sprintf("funding=%.2e\n", data.FundingRate))
     }
 
+    // Funding detail: predicted next rate, time to settlement, recent settled rates
+    if fd := data.Funding; fd != nil {
+        sb.WriteString(fmt.Sprintf("funding_detail: predicted=%.2e", fd.PredictedRate))
+        if !fd.NextFundingTime.IsZero() {
+            sb.WriteString(fmt.Sprintf(" next_in=%dm", int(time.Until(fd.NextFundingTime).Minutes())))
+        }
+        if fd.IntervalHours > 0 {
+            sb.WriteString(fmt.Sprintf(" interval=%.0fh", fd.IntervalHours))
+        }
+        if len(fd.History) > 0 {
+            start := len(fd.History) - 3
+            if start < 0 {
+                start = 0
+            }
+            recent := make([]string, 0, 3)
+            for _, h := range fd.History[start:] {
+                recent = append(recent, fmt.Sprintf("%.2e", h.Rate))
+            }
+            sb.WriteString(fmt.Sprintf(" hist_avg=%.2e recent=[%s]", fd.AverageRate, strings.Join(recent, ", ")))
+        }
+        sb.WriteString("\n")
+    }
+
+    // Market regime: trend / range / high volatility
+    if data.Regime != nil {
+        sb.WriteString(FormatRegime(data.Regime) + "\n")
+    }
+
+    // Order flow (rolling window = config.OrderFlow.Window): CVD, taker ratio, large trades, liquidations by side
+    if of := data.OrderFlow; of != nil {
+        sb.WriteString(fmt.Sprintf(
+            "flow(%s): cvd15m=%.0f cvd_window=%.0f taker_buy_sell=%.2f large_buy=%d large_sell=%d liq_long=%.0f(%d) liq_short=%.0f(%d)\n",
+            formatWindow(config.OrderFlow.Window), of.CVD15m, of.CVDWindow, of.TakerBuySellRatio, of.LargeBuyCount, of.LargeSellCount,
+            of.LongLiqVolume, of.LongLiqCount, of.ShortLiqVolume, of.ShortLiqCount,
+        ))
+    }
+
     return sb.String()
 }
 
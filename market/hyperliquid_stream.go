@@ -0,0 +1,302 @@
+package market
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	hyperliquidWSURL        = "wss://api.hyperliquid.xyz/ws"
+	hyperliquidTestnetWSURL = "wss://api.hyperliquid-testnet.xyz/ws"
+
+	// hyperliquidPingInterval 心跳间隔（服务端60秒无消息会断开连接）
+	hyperliquidPingInterval = 30 * time.Second
+	// hyperliquidKlineLimit 每个周期缓存的K线数量（与币安组合流缓存一致）
+	hyperliquidKlineLimit = 100
+	// hyperliquidKlineRESTRefresh WebSocket断开期间，缓存K线通过REST刷新的最小间隔
+	hyperliquidKlineRESTRefresh = 1 * time.Minute
+)
+
+// hyperliquidStreams 按WebSocket地址共享的行情流（多个交易员共用一个连接）
+var hyperliquidStreams sync.Map // url -> *hyperliquidStream
+
+// hyperliquidStream Hyperliquid WebSocket行情流
+// candle 订阅维护K线缓存（REST快照初始化），trades 订阅驱动订单流统计
+type hyperliquidStream struct {
+	url       string
+	startOnce sync.Once
+
+	mu        sync.Mutex
+	conn      *websocket.Conn
+	subs      map[string]map[string]string // 订阅key -> subscription（重连后重新订阅）
+	klines    map[string]*hyperliquidKlineCache
+	writeMu   sync.Mutex
+	orderFlow sync.Map // coin -> *orderFlowTracker
+}
+
+// hyperliquidKlineCache 单个币种单个周期的K线缓存
+type hyperliquidKlineCache struct {
+	klines    []Kline
+	updatedAt time.Time
+}
+
+type hyperliquidWSMessage struct {
+	Channel string          `json:"channel"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type hyperliquidWSCandle struct {
+	OpenTime  int64  `json:"t"`
+	CloseTime int64  `json:"T"`
+	Coin      string `json:"s"`
+	Interval  string `json:"i"`
+	Open      string `json:"o"`
+	Close     string `json:"c"`
+	High      string `json:"h"`
+	Low       string `json:"l"`
+	Volume    string `json:"v"`
+	Trades    int    `json:"n"`
+}
+
+type hyperliquidWSTrade struct {
+	Coin string `json:"coin"`
+	Side string `json:"side"` // B=主动买入, A=主动卖出
+	Px   string `json:"px"`
+	Sz   string `json:"sz"`
+	Time int64  `json:"time"`
+}
+
+// getHyperliquidStream 获取（或创建）指定地址的行情流
+func getHyperliquidStream(testnet bool) *hyperliquidStream {
+	url := hyperliquidWSURL
+	if testnet {
+		url = hyperliquidTestnetWSURL
+	}
+	value, _ := hyperliquidStreams.LoadOrStore(url, &hyperliquidStream{
+		url:    url,
+		subs:   make(map[string]map[string]string),
+		klines: make(map[string]*hyperliquidKlineCache),
+	})
+	return value.(*hyperliquidStream)
+}
+
+func klineCacheKey(coin, interval string) string {
+	return coin + "|" + interval
+}
+
+// subscribe 注册订阅（首次订阅时启动连接，已连接时立即发送）
+func (s *hyperliquidStream) subscribe(subscription map[string]string) {
+	key := subscription["type"] + "|" + subscription["coin"] + "|" + subscription["interval"]
+
+	s.mu.Lock()
+	if _, ok := s.subs[key]; ok {
+		s.mu.Unlock()
+		return
+	}
+	s.subs[key] = subscription
+	conn := s.conn
+	s.mu.Unlock()
+
+	s.startOnce.Do(func() { go s.run() })
+	if conn != nil {
+		if err := s.send(conn, map[string]interface{}{"method": "subscribe", "subscription": subscription}); err != nil {
+			log.Printf("⚠️ Hyperliquid订阅 %s 失败: %v", key, err)
+		}
+	}
+}
+
+// send 串行写入（gorilla websocket 不支持并发写）
+func (s *hyperliquidStream) send(conn *websocket.Conn, msg interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return conn.WriteJSON(msg)
+}
+
+// run 连接并读取消息，断开后自动重连并恢复所有订阅
+func (s *hyperliquidStream) run() {
+	dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+	for {
+		conn, _, err := dialer.Dial(s.url, nil)
+		if err != nil {
+			log.Printf("❌ Hyperliquid WebSocket连接失败: %v，5秒后重试", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		s.mu.Lock()
+		s.conn = conn
+		// 断线期间可能漏掉K线推送，清空缓存，下次请求时重新通过REST初始化
+		s.klines = make(map[string]*hyperliquidKlineCache)
+		subs := make([]map[string]string, 0, len(s.subs))
+		for _, sub := range s.subs {
+			subs = append(subs, sub)
+		}
+		s.mu.Unlock()
+		log.Printf("✓ Hyperliquid WebSocket已连接，恢复 %d 个订阅", len(subs))
+
+		for _, sub := range subs {
+			if err := s.send(conn, map[string]interface{}{"method": "subscribe", "subscription": sub}); err != nil {
+				log.Printf("⚠️ Hyperliquid订阅失败: %v", err)
+			}
+		}
+
+		done := make(chan struct{})
+		go s.keepAlive(conn, done)
+		s.readLoop(conn)
+		close(done)
+
+		s.mu.Lock()
+		s.conn = nil
+		s.mu.Unlock()
+		conn.Close()
+
+		log.Println("⚠️ Hyperliquid WebSocket断开，3秒后重连...")
+		time.Sleep(3 * time.Second)
+	}
+}
+
+func (s *hyperliquidStream) keepAlive(conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(hyperliquidPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := s.send(conn, map[string]string{"method": "ping"}); err != nil {
+				log.Printf("⚠️ Hyperliquid心跳失败: %v", err)
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (s *hyperliquidStream) readLoop(conn *websocket.Conn) {
+	for {
+		conn.SetReadDeadline(time.Now().Add(2 * hyperliquidPingInterval))
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			log.Printf("读取Hyperliquid WebSocket消息失败: %v", err)
+			return
+		}
+
+		var msg hyperliquidWSMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			continue
+		}
+		switch msg.Channel {
+		case "candle":
+			var candle hyperliquidWSCandle
+			if err := json.Unmarshal(msg.Data, &candle); err != nil {
+				log.Printf("解析Hyperliquid K线数据失败: %v", err)
+				continue
+			}
+			s.updateKline(candle)
+		case "trades":
+			var trades []hyperliquidWSTrade
+			if err := json.Unmarshal(msg.Data, &trades); err != nil {
+				log.Printf("解析Hyperliquid成交数据失败: %v", err)
+				continue
+			}
+			s.addTrades(trades)
+		}
+	}
+}
+
+// updateKline 用推送的K线更新缓存：同一根K线覆盖，新K线追加
+func (s *hyperliquidStream) updateKline(c hyperliquidWSCandle) {
+	kline := Kline{
+		OpenTime:  c.OpenTime,
+		CloseTime: c.CloseTime,
+		Trades:    c.Trades,
+	}
+	kline.Open, _ = parseFloat(c.Open)
+	kline.High, _ = parseFloat(c.High)
+	kline.Low, _ = parseFloat(c.Low)
+	kline.Close, _ = parseFloat(c.Close)
+	kline.Volume, _ = parseFloat(c.Volume)
+	kline.QuoteVolume = kline.Volume * kline.Close
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cache, ok := s.klines[klineCacheKey(c.Coin, c.Interval)]
+	if !ok || len(cache.klines) == 0 {
+		return // 尚未完成REST初始化
+	}
+	n := len(cache.klines)
+	switch last := cache.klines[n-1]; {
+	case kline.OpenTime == last.OpenTime:
+		cache.klines[n-1] = kline
+	case kline.OpenTime > last.OpenTime:
+		cache.klines = append(cache.klines, kline)
+		if len(cache.klines) > hyperliquidKlineLimit {
+			cache.klines = cache.klines[len(cache.klines)-hyperliquidKlineLimit:]
+		}
+	default:
+		return // 旧K线，忽略
+	}
+	cache.updatedAt = time.Now()
+}
+
+// cachedKlines 返回缓存K线的副本（WebSocket断开且缓存过期时返回false）
+func (s *hyperliquidStream) cachedKlines(coin, interval string) ([]Kline, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cache, ok := s.klines[klineCacheKey(coin, interval)]
+	if !ok || len(cache.klines) == 0 {
+		return nil, false
+	}
+	if s.conn == nil && time.Since(cache.updatedAt) > hyperliquidKlineRESTRefresh {
+		return nil, false
+	}
+	result := make([]Kline, len(cache.klines))
+	copy(result, cache.klines)
+	return result, true
+}
+
+// storeKlines 保存REST快照并订阅该周期的K线推送
+func (s *hyperliquidStream) storeKlines(coin, interval string, klines []Kline) {
+	s.mu.Lock()
+	stored := make([]Kline, len(klines))
+	copy(stored, klines)
+	s.klines[klineCacheKey(coin, interval)] = &hyperliquidKlineCache{klines: stored, updatedAt: time.Now()}
+	s.mu.Unlock()
+
+	s.subscribe(map[string]string{"type": "candle", "coin": coin, "interval": interval})
+}
+
+func (s *hyperliquidStream) addTrades(trades []hyperliquidWSTrade) {
+	for _, t := range trades {
+		value, ok := s.orderFlow.Load(t.Coin)
+		if !ok {
+			continue
+		}
+		px, _ := parseFloat(t.Px)
+		sz, _ := parseFloat(t.Sz)
+		value.(*orderFlowTracker).addTrade(time.UnixMilli(t.Time), px*sz, t.Side == "B")
+	}
+}
+
+// getOrderFlow 获取订单流统计
+// 未订阅的币种会动态订阅成交流，首次调用返回nil（尚无数据）；Hyperliquid公开成交不区分强平单
+func (s *hyperliquidStream) getOrderFlow(coin, symbol string) *OrderFlowData {
+	value, loaded := s.orderFlow.LoadOrStore(coin, &orderFlowTracker{})
+	if !loaded {
+		s.subscribe(map[string]string{"type": "trades", "coin": coin})
+		return nil
+	}
+
+	data := value.(*orderFlowTracker).snapshot(symbol)
+	if data.UpdatedAt.IsZero() {
+		return nil
+	}
+	return data
+}
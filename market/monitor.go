@@ -19,6 +19,7 @@ type WSMonitor struct {
 	klineDataMap4h sync.Map // 存储每个交易对的K线历史数据
 	tickerDataMap  sync.Map // 存储每个交易对的ticker数据
 	depthDataMap   sync.Map // 存储每个交易对的深度数据
+	orderFlowMap   sync.Map // 存储每个交易对的订单流统计 (*orderFlowTracker)
 	depthDataCache map[string]*DepthData // 深度数据缓存，减少重复计算
 	cacheMutex     sync.RWMutex // 缓存读写锁
 	cacheExpiry    time.Duration // 缓存过期时间
@@ -163,6 +164,8 @@ func (m *WSMonitor) subscribeAll() error {
 		}
 		// 订阅深度数据
 		m.subscribeDepth(symbol)
+		// 订阅订单流（归集成交 + 强平）
+		m.subscribeOrderFlow(symbol)
 	}
 	for _, st := range subKlineTime {
 		err := m.combinedClient.BatchSubscribeKlines(m.symbols, st)
@@ -177,6 +180,15 @@ func (m *WSMonitor) subscribeAll() error {
 		log.Printf("❌ 订阅深度数据失败: %v", err)
 		return err
 	}
+	// 批量订阅订单流
+	if err := m.combinedClient.BatchSubscribeAggTrades(m.symbols); err != nil {
+		log.Printf("❌ 订阅归集成交失败: %v", err)
+		return err
+	}
+	if err := m.combinedClient.BatchSubscribeForceOrders(m.symbols); err != nil {
+		log.Printf("❌ 订阅强平订单失败: %v", err)
+		return err
+	}
 	log.Println("所有交易对订阅完成")
 	return nil
 }
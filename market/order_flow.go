@@ -0,0 +1,238 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// orderFlowBucket 按分钟聚合的订单流统计
+type orderFlowBucket struct {
+	Minute        int64 // 所属分钟（unix秒 / 60）
+	BuyVolume     float64
+	SellVolume    float64
+	TradeCount    int
+	LargeBuy      int
+	LargeSell     int
+	LongLiqVol    float64
+	ShortLiqVol   float64
+	LongLiqCount  int
+	ShortLiqCount int
+}
+
+// orderFlowTracker 单个交易对的滚动订单流统计
+// 逐笔成交数据量很大，这里按分钟聚合，只保留窗口内的分钟桶
+type orderFlowTracker struct {
+	mu        sync.Mutex
+	buckets   []*orderFlowBucket
+	updatedAt time.Time
+}
+
+// bucketAt 返回指定时间所属的分钟桶，并清理窗口外的旧桶（调用方持有锁）
+// 分钟桶按时间升序排列，乱序到达的旧数据插入到对应位置
+func (t *orderFlowTracker) bucketAt(ts time.Time) *orderFlowBucket {
+	minute := ts.Unix() / 60
+	// 从尾部向前查找（绝大多数成交属于最新的分钟）
+	i := len(t.buckets)
+	for i > 0 && t.buckets[i-1].Minute > minute {
+		i--
+	}
+	if i > 0 && t.buckets[i-1].Minute == minute {
+		return t.buckets[i-1]
+	}
+
+	b := &orderFlowBucket{Minute: minute}
+	t.buckets = append(t.buckets, nil)
+	copy(t.buckets[i+1:], t.buckets[i:])
+	t.buckets[i] = b
+	t.prune(time.Now())
+	return b
+}
+
+// prune 清理超出统计窗口的分钟桶（调用方持有锁）
+func (t *orderFlowTracker) prune(now time.Time) {
+	oldest := now.Add(-config.OrderFlow.Window).Unix() / 60
+	i := 0
+	for i < len(t.buckets) && t.buckets[i].Minute < oldest {
+		i++
+	}
+	if i > 0 {
+		t.buckets = t.buckets[i:]
+	}
+}
+
+// formatWindow 统计窗口的紧凑表示（如 1h、30m）
+func formatWindow(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	}
+	return fmt.Sprintf("%dm", int(d/time.Minute))
+}
+
+// avgTradeNotional 窗口内平均单笔成交额（调用方持有锁）
+func (t *orderFlowTracker) avgTradeNotional() float64 {
+	total := 0.0
+	count := 0
+	for _, b := range t.buckets {
+		total += b.BuyVolume + b.SellVolume
+		count += b.TradeCount
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
+
+func (t *orderFlowTracker) addTrade(ts time.Time, notional float64, takerBuy bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// 大单阈值：窗口平均单笔成交额的倍数，且不低于最低成交额
+	threshold := t.avgTradeNotional() * config.OrderFlow.LargeTradeMultiple
+	if threshold < config.OrderFlow.LargeTradeMin {
+		threshold = config.OrderFlow.LargeTradeMin
+	}
+
+	b := t.bucketAt(ts)
+	b.TradeCount++
+	if takerBuy {
+		b.BuyVolume += notional
+		if notional >= threshold {
+			b.LargeBuy++
+		}
+	} else {
+		b.SellVolume += notional
+		if notional >= threshold {
+			b.LargeSell++
+		}
+	}
+	t.updatedAt = time.Now()
+}
+
+func (t *orderFlowTracker) addLiquidation(ts time.Time, notional float64, longLiquidated bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.bucketAt(ts)
+	if longLiquidated {
+		b.LongLiqVol += notional
+		b.LongLiqCount++
+	} else {
+		b.ShortLiqVol += notional
+		b.ShortLiqCount++
+	}
+	t.updatedAt = time.Now()
+}
+
+// snapshot 汇总窗口内的订单流数据
+func (t *orderFlowTracker) snapshot(symbol string) *OrderFlowData {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.prune(now)
+
+	data := &OrderFlowData{
+		Symbol:    symbol,
+		UpdatedAt: t.updatedAt,
+	}
+	recent := now.Add(-15*time.Minute).Unix() / 60
+	for _, b := range t.buckets {
+		delta := b.BuyVolume - b.SellVolume
+		data.CVDWindow += delta
+		if b.Minute >= recent {
+			data.CVD15m += delta
+		}
+		data.TakerBuyVolume += b.BuyVolume
+		data.TakerSellVolume += b.SellVolume
+		data.LargeBuyCount += b.LargeBuy
+		data.LargeSellCount += b.LargeSell
+		data.LongLiqVolume += b.LongLiqVol
+		data.ShortLiqVolume += b.ShortLiqVol
+		data.LongLiqCount += b.LongLiqCount
+		data.ShortLiqCount += b.ShortLiqCount
+	}
+	if data.TakerSellVolume > 0 {
+		data.TakerBuySellRatio = data.TakerBuyVolume / data.TakerSellVolume
+	}
+	return data
+}
+
+// getOrderFlowTracker 获取（或创建）交易对的订单流统计器
+func (m *WSMonitor) getOrderFlowTracker(symbol string) *orderFlowTracker {
+	value, _ := m.orderFlowMap.LoadOrStore(symbol, &orderFlowTracker{})
+	return value.(*orderFlowTracker)
+}
+
+// subscribeOrderFlow 注册归集成交与强平订单监听
+func (m *WSMonitor) subscribeOrderFlow(symbol string) []string {
+	lower := strings.ToLower(symbol)
+	aggStream := fmt.Sprintf("%s@aggTrade", lower)
+	liqStream := fmt.Sprintf("%s@forceOrder", lower)
+
+	m.getOrderFlowTracker(symbol)
+	go m.handleAggTradeData(symbol, m.combinedClient.AddSubscriber(aggStream, 1000))
+	go m.handleForceOrderData(symbol, m.combinedClient.AddSubscriber(liqStream, 100))
+
+	return []string{aggStream, liqStream}
+}
+
+func (m *WSMonitor) handleAggTradeData(symbol string, ch <-chan []byte) {
+	tracker := m.getOrderFlowTracker(symbol)
+	for data := range ch {
+		var trade AggTradeWSData
+		if err := json.Unmarshal(data, &trade); err != nil {
+			log.Printf("解析归集成交数据失败: %v", err)
+			continue
+		}
+		price, _ := parseFloat(trade.Price)
+		qty, _ := parseFloat(trade.Quantity)
+		// 买方是maker => 主动卖出
+		tracker.addTrade(time.UnixMilli(trade.TradeTime), price*qty, !trade.IsBuyerMaker)
+	}
+}
+
+func (m *WSMonitor) handleForceOrderData(symbol string, ch <-chan []byte) {
+	tracker := m.getOrderFlowTracker(symbol)
+	for data := range ch {
+		var order ForceOrderWSData
+		if err := json.Unmarshal(data, &order); err != nil {
+			log.Printf("解析强平订单数据失败: %v", err)
+			continue
+		}
+		price, _ := parseFloat(order.Order.AveragePrice)
+		if price == 0 {
+			price, _ = parseFloat(order.Order.Price)
+		}
+		qty, _ := parseFloat(order.Order.FilledQty)
+		if qty == 0 {
+			qty, _ = parseFloat(order.Order.OrigQuantity)
+		}
+		// 强平卖单 => 多头被强平
+		tracker.addLiquidation(time.UnixMilli(order.Order.TradeTime), price*qty, order.Order.Side == "SELL")
+	}
+}
+
+// GetOrderFlow 获取交易对的订单流统计
+// 未订阅的交易对会动态订阅，首次调用返回nil（尚无数据）
+func (m *WSMonitor) GetOrderFlow(symbol string) *OrderFlowData {
+	value, loaded := m.orderFlowMap.LoadOrStore(symbol, &orderFlowTracker{})
+	if !loaded {
+		subStr := m.subscribeOrderFlow(symbol)
+		if err := m.combinedClient.subscribeStreams(subStr); err != nil {
+			log.Printf("警告: 动态订阅订单流失败: %v", err)
+		} else {
+			log.Printf("动态订阅流: %v", subStr)
+		}
+		return nil
+	}
+
+	data := value.(*orderFlowTracker).snapshot(symbol)
+	if data.UpdatedAt.IsZero() {
+		return nil
+	}
+	return data
+}
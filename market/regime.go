@@ -0,0 +1,227 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Regime 市场状态
+type Regime string
+
+const (
+	RegimeTrendingUp     Regime = "trending_up"     // 上升趋势
+	RegimeTrendingDown   Regime = "trending_down"   // 下降趋势
+	RegimeRanging        Regime = "ranging"         // 震荡
+	RegimeHighVolatility Regime = "high_volatility" // 高波动
+)
+
+// AllRegimes 所有市场状态（用于配置校验）
+var AllRegimes = []Regime{RegimeTrendingUp, RegimeTrendingDown, RegimeRanging, RegimeHighVolatility}
+
+// IsValidRegime 判断是否为合法的市场状态
+func IsValidRegime(r string) bool {
+	for _, regime := range AllRegimes {
+		if string(regime) == r {
+			return true
+		}
+	}
+	return false
+}
+
+// RegimeData 市场状态分类结果
+type RegimeData struct {
+	Symbol        string  `json:"symbol"`
+	Interval      string  `json:"interval"`
+	Regime        Regime  `json:"regime"`
+	ADX           float64 `json:"adx"`            // ADX(14) 趋势强度
+	ATRPercentile float64 `json:"atr_percentile"` // 当前ATR14在近期ATR序列中的分位 (0-100)
+	EMASlope      float64 `json:"ema_slope"`      // EMA20斜率（N根K线变化百分比）
+	RealizedVol   float64 `json:"realized_vol"`   // 年化实现波动率（百分比）
+}
+
+// RegimeConfig 市场状态分类配置
+type RegimeConfig struct {
+	Interval             string  `json:"interval"`                // 分类使用的K线周期
+	ADXPeriod            int     `json:"adx_period"`              // ADX周期
+	TrendADX             float64 `json:"trend_adx"`               // ADX >= 该值视为趋势
+	EMASlopeBars         int     `json:"ema_slope_bars"`          // EMA斜率回看K线数
+	MinEMASlope          float64 `json:"min_ema_slope"`           // 趋势所需最小EMA斜率（百分比）
+	HighVolATRPercentile float64 `json:"high_vol_atr_percentile"` // ATR分位 >= 该值视为高波动
+	HighVolRealized      float64 `json:"high_vol_realized"`       // 年化实现波动率 >= 该值视为高波动（百分比）
+	VolWindow            int     `json:"vol_window"`              // 实现波动率计算窗口（K线数）
+}
+
+// ClassifyRegime 基于K线判断市场状态
+// 优先级: 高波动 > 趋势（ADX达标且EMA斜率明显）> 震荡
+func ClassifyRegime(symbol string, klines []Kline, interval string) *RegimeData {
+	cfg := config.Regime
+	if len(klines) < cfg.ADXPeriod*2+1 {
+		return nil
+	}
+
+	result := &RegimeData{
+		Symbol:        Normalize(symbol),
+		Interval:      interval,
+		ADX:           calculateADX(klines, cfg.ADXPeriod),
+		ATRPercentile: calculateATRPercentile(klines, 14),
+		EMASlope:      calculateEMASlope(klines, 20, cfg.EMASlopeBars),
+		RealizedVol:   calculateRealizedVol(klines, cfg.VolWindow, interval),
+	}
+
+	switch {
+	case result.ATRPercentile >= cfg.HighVolATRPercentile || result.RealizedVol >= cfg.HighVolRealized:
+		result.Regime = RegimeHighVolatility
+	case result.ADX >= cfg.TrendADX && result.EMASlope >= cfg.MinEMASlope:
+		result.Regime = RegimeTrendingUp
+	case result.ADX >= cfg.TrendADX && result.EMASlope <= -cfg.MinEMASlope:
+		result.Regime = RegimeTrendingDown
+	default:
+		result.Regime = RegimeRanging
+	}
+	return result
+}
+
+// GetMarketRegime 获取整体市场状态（以BTC为准）
+func GetMarketRegime(source MarketDataSource) (*RegimeData, error) {
+	interval := config.Regime.Interval
+	klines, err := source.GetCurrentKlines("BTCUSDT", interval)
+	if err != nil {
+		return nil, fmt.Errorf("获取BTC K线失败: %w", err)
+	}
+	regime := ClassifyRegime("BTCUSDT", klines, interval)
+	if regime == nil {
+		return nil, fmt.Errorf("BTC K线数量不足，无法判断市场状态")
+	}
+	return regime, nil
+}
+
+// FormatRegime 紧凑格式输出市场状态
+func FormatRegime(r *RegimeData) string {
+	if r == nil {
+		return ""
+	}
+	return fmt.Sprintf("regime(%s)=%s adx=%.1f atr_pctl=%.0f ema20_slope=%+.2f%% rv=%.0f%%",
+		r.Interval, r.Regime, r.ADX, r.ATRPercentile, r.EMASlope, r.RealizedVol)
+}
+
+// calculateADX 计算ADX（Wilder平滑）
+func calculateADX(klines []Kline, period int) float64 {
+	if len(klines) < period*2+1 {
+		return 0
+	}
+
+	n := len(klines)
+	trs := make([]float64, n)
+	plusDM := make([]float64, n)
+	minusDM := make([]float64, n)
+	for i := 1; i < n; i++ {
+		upMove := klines[i].High - klines[i-1].High
+		downMove := klines[i-1].Low - klines[i].Low
+		if upMove > downMove && upMove > 0 {
+			plusDM[i] = upMove
+		}
+		if downMove > upMove && downMove > 0 {
+			minusDM[i] = downMove
+		}
+		trs[i] = math.Max(klines[i].High-klines[i].Low,
+			math.Max(math.Abs(klines[i].High-klines[i-1].Close), math.Abs(klines[i].Low-klines[i-1].Close)))
+	}
+
+	// 初始平滑值
+	var atr, plus, minus float64
+	for i := 1; i <= period; i++ {
+		atr += trs[i]
+		plus += plusDM[i]
+		minus += minusDM[i]
+	}
+
+	dx := func() float64 {
+		if atr == 0 {
+			return 0
+		}
+		plusDI := 100 * plus / atr
+		minusDI := 100 * minus / atr
+		if plusDI+minusDI == 0 {
+			return 0
+		}
+		return 100 * math.Abs(plusDI-minusDI) / (plusDI + minusDI)
+	}
+
+	dxs := []float64{dx()}
+	for i := period + 1; i < n; i++ {
+		atr = atr - atr/float64(period) + trs[i]
+		plus = plus - plus/float64(period) + plusDM[i]
+		minus = minus - minus/float64(period) + minusDM[i]
+		dxs = append(dxs, dx())
+	}
+
+	// ADX = DX 的Wilder平滑
+	adx := mean(dxs[:period])
+	for _, v := range dxs[period:] {
+		adx = (adx*float64(period-1) + v) / float64(period)
+	}
+	return adx
+}
+
+// calculateATRPercentile 当前ATR在历史ATR序列中的分位
+func calculateATRPercentile(klines []Kline, period int) float64 {
+	if len(klines) <= period+1 {
+		return 0
+	}
+
+	atrs := make([]float64, 0, len(klines)-period)
+	for i := period + 1; i <= len(klines); i++ {
+		atrs = append(atrs, calculateATR(klines[:i], period))
+	}
+	current := atrs[len(atrs)-1]
+
+	sorted := append([]float64(nil), atrs...)
+	sort.Float64s(sorted)
+	below := sort.SearchFloat64s(sorted, current)
+	return float64(below) / float64(len(sorted)) * 100
+}
+
+// calculateEMASlope EMA在最近bars根K线内的变化百分比
+func calculateEMASlope(klines []Kline, period, bars int) float64 {
+	if len(klines) < period+bars {
+		return 0
+	}
+	current := calculateEMA(klines, period)
+	previous := calculateEMA(klines[:len(klines)-bars], period)
+	if previous == 0 {
+		return 0
+	}
+	return (current - previous) / previous * 100
+}
+
+// calculateRealizedVol 最近window根K线的年化实现波动率（百分比）
+func calculateRealizedVol(klines []Kline, window int, interval string) float64 {
+	start := len(klines) - window - 1
+	if start < 0 {
+		start = 0
+	}
+	var returns []float64
+	for i := start + 1; i < len(klines); i++ {
+		if klines[i-1].Close > 0 && klines[i].Close > 0 {
+			returns = append(returns, math.Log(klines[i].Close/klines[i-1].Close))
+		}
+	}
+	if len(returns) < 2 {
+		return 0
+	}
+
+	m := mean(returns)
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - m) * (r - m)
+	}
+	variance /= float64(len(returns) - 1)
+
+	step, err := intervalDuration(interval)
+	if err != nil || step <= 0 {
+		return 0
+	}
+	barsPerYear := float64(365*24) / step.Hours()
+	return math.Sqrt(variance*barsPerYear) * 100
+}
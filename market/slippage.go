@@ -0,0 +1,144 @@
+package market
+
+import "fmt"
+
+// SlippageEstimate 按订单簿深度估算的市价单成交情况
+type SlippageEstimate struct {
+	Symbol          string  `json:"symbol"`
+	IsBuy           bool    `json:"is_buy"`
+	Notional        float64 `json:"notional"`         // 订单名义价值(USDT)
+	FilledNotional  float64 `json:"filled_notional"`  // 可见深度内可成交的名义价值
+	ReferencePrice  float64 `json:"reference_price"`  // 参考价（中间价）
+	AvgPrice        float64 `json:"avg_price"`        // 预估成交均价
+	WorstPrice      float64 `json:"worst_price"`      // 吃到的最差档位价格
+	SlippagePct     float64 `json:"slippage_pct"`     // 预估滑点百分比（相对中间价，含半个价差）
+	LevelsUsed      int     `json:"levels_used"`      // 吃掉的档位数
+	SufficientDepth bool    `json:"sufficient_depth"` // 可见深度是否足以完全成交
+}
+
+// referencePrice 滑点参考价：优先中间价，缺失时用对手方最优价
+func referencePrice(depth *DepthData, isBuy bool) float64 {
+	if depth.MidPrice > 0 {
+		return depth.MidPrice
+	}
+	if isBuy && len(depth.Asks) > 0 {
+		return depth.Asks[0].Price
+	}
+	if !isBuy && len(depth.Bids) > 0 {
+		return depth.Bids[0].Price
+	}
+	return 0
+}
+
+// EstimateSlippage 逐档吃单估算成交均价与滑点（买单吃卖盘，卖单吃买盘）
+// 可见深度不足以完全成交时，剩余部分按最差档位价格计算，并标记 SufficientDepth=false
+func EstimateSlippage(depth *DepthData, isBuy bool, notional float64) (*SlippageEstimate, error) {
+	if depth == nil {
+		return nil, fmt.Errorf("深度数据为空")
+	}
+	levels := depth.Bids
+	if isBuy {
+		levels = depth.Asks
+	}
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("%s 订单簿无对手盘", depth.Symbol)
+	}
+	refPrice := referencePrice(depth, isBuy)
+	if refPrice <= 0 {
+		return nil, fmt.Errorf("%s 订单簿价格无效", depth.Symbol)
+	}
+
+	estimate := &SlippageEstimate{
+		Symbol:         depth.Symbol,
+		IsBuy:          isBuy,
+		Notional:       notional,
+		ReferencePrice: refPrice,
+	}
+
+	remaining := notional
+	var filledQty float64
+	for _, level := range levels {
+		if remaining <= 0 {
+			break
+		}
+		if level.Price <= 0 || level.Quantity <= 0 {
+			continue
+		}
+		levelNotional := level.Price * level.Quantity
+		take := levelNotional
+		if take > remaining {
+			take = remaining
+		}
+		filledQty += take / level.Price
+		estimate.FilledNotional += take
+		estimate.WorstPrice = level.Price
+		estimate.LevelsUsed++
+		remaining -= take
+	}
+
+	if estimate.LevelsUsed == 0 {
+		return nil, fmt.Errorf("%s 订单簿无有效档位", depth.Symbol)
+	}
+
+	estimate.SufficientDepth = remaining <= 1e-9
+	if !estimate.SufficientDepth {
+		// 超出可见深度的部分至少以最差档位成交（实际只会更差）
+		filledQty += remaining / estimate.WorstPrice
+	}
+	estimate.AvgPrice = notional / filledQty
+	estimate.SlippagePct = SlippagePct(estimate.AvgPrice, refPrice, isBuy)
+	return estimate, nil
+}
+
+// MaxNotionalWithinSlippage 在预估滑点不超过 maxSlippagePct 的前提下，可见深度内最多可成交的名义价值
+func MaxNotionalWithinSlippage(depth *DepthData, isBuy bool, maxSlippagePct float64) float64 {
+	if depth == nil {
+		return 0
+	}
+	levels := depth.Bids
+	if isBuy {
+		levels = depth.Asks
+	}
+	refPrice := referencePrice(depth, isBuy)
+	if refPrice <= 0 {
+		return 0
+	}
+
+	// 均价上限（买单）/ 下限（卖单）
+	limitPrice := refPrice * (1 + maxSlippagePct/100)
+	if !isBuy {
+		limitPrice = refPrice * (1 - maxSlippagePct/100)
+	}
+
+	var cost, qty float64
+	for _, level := range levels {
+		if level.Price <= 0 || level.Quantity <= 0 {
+			continue
+		}
+		// 整档吃下后均价仍在限制内
+		newAvg := (cost + level.Price*level.Quantity) / (qty + level.Quantity)
+		if (isBuy && newAvg <= limitPrice) || (!isBuy && newAvg >= limitPrice) {
+			cost += level.Price * level.Quantity
+			qty += level.Quantity
+			continue
+		}
+		// 只吃该档的一部分: (cost + q*p) / (qty + q) = limitPrice
+		partial := (limitPrice*qty - cost) / (level.Price - limitPrice)
+		if partial > 0 {
+			cost += partial * level.Price
+		}
+		break
+	}
+	return cost
+}
+
+// SlippagePct 成交价相对参考价的不利偏离百分比（买入高于参考价、卖出低于参考价为正）
+func SlippagePct(fillPrice, refPrice float64, isBuy bool) float64 {
+	if fillPrice <= 0 || refPrice <= 0 {
+		return 0
+	}
+	if isBuy {
+		return (fillPrice - refPrice) / refPrice * 100
+	}
+	return (refPrice - fillPrice) / refPrice * 100
+}
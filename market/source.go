@@ -0,0 +1,140 @@
+package market
+
+import (
+	"fmt"
+	"strings"
+)
+
+const asterBaseURL = "https://fapi.asterdex.com"
+
+// MarketDataSource 行情数据源
+// 不同交易所的价格、资金费率、深度存在差异，AI决策应使用与执行交易所一致的数据
+type MarketDataSource interface {
+	// Name 数据源名称（binance / hyperliquid / aster）
+	Name() string
+	// GetKlines 通过REST获取K线
+	GetKlines(symbol, interval string, limit int) ([]Kline, error)
+	// GetCurrentPrice 获取最新成交价
+	GetCurrentPrice(symbol string) (float64, error)
+	// GetDepth 获取订单簿深度
+	GetDepth(symbol string, limit int) (*DepthData, error)
+	// GetOpenInterest 获取持仓量
+	GetOpenInterest(symbol string) (*OIData, error)
+	// GetFundingRate 获取当前资金费率
+	GetFundingRate(symbol string) (float64, error)
+	// GetFundingData 获取资金费率历史、下次结算时间与预测费率
+	GetFundingData(symbol string) (*FundingData, error)
+	// GetCurrentKlines 获取实时K线：有WebSocket流的数据源返回流缓存，否则回退REST
+	GetCurrentKlines(symbol, interval string) ([]Kline, error)
+	// GetOrderFlow 获取订单流统计：没有成交流的数据源返回nil
+	GetOrderFlow(symbol string) *OrderFlowData
+}
+
+// NewMarketDataSource 根据交易平台创建对应的行情数据源
+func NewMarketDataSource(exchange string, testnet bool) MarketDataSource {
+	switch strings.ToLower(exchange) {
+	case "hyperliquid":
+		return NewHyperliquidSource(testnet)
+	case "aster":
+		return NewAsterSource()
+	default:
+		return NewBinanceSource(testnet)
+	}
+}
+
+// fapiSource 币安fapi兼容接口的数据源（币安、Aster）
+type fapiSource struct {
+	name       string
+	client     *APIClient
+	useStreams bool // 是否使用WSMonitor的组合流缓存（仅币安）
+}
+
+// NewBinanceSource 创建币安行情数据源（K线与订单流使用WSMonitor组合流）
+func NewBinanceSource(testnet bool) MarketDataSource {
+	return &fapiSource{
+		name:       "binance",
+		client:     NewAPIClientWithBaseURL(getBaseURL(testnet)),
+		useStreams: true,
+	}
+}
+
+// NewAsterSource 创建Aster行情数据源（接口与币安fapi兼容）
+func NewAsterSource() MarketDataSource {
+	return &fapiSource{
+		name:   "aster",
+		client: NewAPIClientWithBaseURL(asterBaseURL),
+	}
+}
+
+func (s *fapiSource) Name() string {
+	return s.name
+}
+
+func (s *fapiSource) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	return s.client.GetKlines(symbol, interval, limit)
+}
+
+func (s *fapiSource) GetCurrentPrice(symbol string) (float64, error) {
+	return s.client.GetCurrentPrice(symbol)
+}
+
+func (s *fapiSource) GetDepth(symbol string, limit int) (*DepthData, error) {
+	depthData, err := s.client.GetOrderBookData(symbol, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get depth data: %w", err)
+	}
+	return depthData, nil
+}
+
+func (s *fapiSource) GetOpenInterest(symbol string) (*OIData, error) {
+	return s.client.GetOpenInterest(symbol)
+}
+
+func (s *fapiSource) GetFundingRate(symbol string) (float64, error) {
+	// 币安沿用原有缓存key，其他数据源加前缀避免串用
+	key := symbol
+	if s.name != "binance" {
+		key = s.name + ":" + symbol
+	}
+	return getCachedFundingRate(key, func() (float64, error) {
+		return s.client.GetPremiumFundingRate(symbol)
+	})
+}
+
+func (s *fapiSource) GetFundingData(symbol string) (*FundingData, error) {
+	return getCachedFundingData(s.name+":"+symbol, func() (*FundingData, error) {
+		index, err := s.client.GetPremiumIndex(symbol)
+		if err != nil {
+			return nil, err
+		}
+		history, err := s.client.GetFundingRateHistory(symbol, 8)
+		if err != nil {
+			return nil, err
+		}
+		return newFundingData(index.LastFundingRate, index.NextFundingTime, history), nil
+	})
+}
+
+func (s *fapiSource) GetCurrentKlines(symbol, interval string) ([]Kline, error) {
+	if s.useStreams && WSMonitorCli != nil {
+		return WSMonitorCli.GetCurrentKlines(symbol, interval)
+	}
+	return s.client.GetKlines(symbol, interval, 100)
+}
+
+func (s *fapiSource) GetOrderFlow(symbol string) *OrderFlowData {
+	if s.useStreams && WSMonitorCli != nil {
+		return WSMonitorCli.GetOrderFlow(symbol)
+	}
+	return nil
+}
+
+// fillSpread 根据最优买卖价计算价差和中间价
+func fillSpread(depthData *DepthData) {
+	if len(depthData.Bids) > 0 && len(depthData.Asks) > 0 {
+		bestBid := depthData.Bids[0].Price
+		bestAsk := depthData.Asks[0].Price
+		depthData.Spread = bestAsk - bestBid
+		depthData.MidPrice = (bestBid + bestAsk) / 2
+	}
+}
@@ -0,0 +1,330 @@
+package market
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"nofx-lite/ratelimit"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	hyperliquidInfoURL        = "https://api.hyperliquid.xyz/info"
+	hyperliquidTestnetInfoURL = "https://api.hyperliquid-testnet.xyz/info"
+)
+
+// hyperliquidSource Hyperliquid行情数据源
+// 快照数据通过 /info 接口获取，实时K线与订单流使用WebSocket推送；资金费率为每小时费率（币安为每8小时）
+type hyperliquidSource struct {
+	client  *http.Client
+	infoURL string
+	stream  *hyperliquidStream
+
+	ctxMu       sync.Mutex
+	assetCtxs   map[string]hyperliquidAssetCtx // coin -> 资产上下文
+	ctxUpdateAt time.Time
+}
+
+type hyperliquidAssetCtx struct {
+	Funding      string `json:"funding"`
+	OpenInterest string `json:"openInterest"`
+	MarkPx       string `json:"markPx"`
+	MidPx        string `json:"midPx"`
+}
+
+// NewHyperliquidSource 创建Hyperliquid行情数据源
+func NewHyperliquidSource(testnet bool) MarketDataSource {
+	infoURL := hyperliquidInfoURL
+	if testnet {
+		infoURL = hyperliquidTestnetInfoURL
+	}
+	return &hyperliquidSource{
+		client:  newHTTPClient("hyperliquid", ratelimit.HyperliquidProfile),
+		infoURL: infoURL,
+		stream:  getHyperliquidStream(testnet),
+	}
+}
+
+// hyperliquidCoin 将 BTCUSDT 转为 Hyperliquid 的 BTC
+func hyperliquidCoin(symbol string) string {
+	return strings.TrimSuffix(Normalize(symbol), "USDT")
+}
+
+// post 调用 /info 接口
+func (s *hyperliquidSource) post(payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.infoURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("hyperliquid API error: status %d, body: %s", resp.StatusCode, string(data))
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (s *hyperliquidSource) Name() string {
+	return "hyperliquid"
+}
+
+// intervalDuration K线周期转时长
+func intervalDuration(interval string) (time.Duration, error) {
+	if len(interval) < 2 {
+		return 0, fmt.Errorf("invalid interval: %s", interval)
+	}
+	n, err := strconv.Atoi(interval[:len(interval)-1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval: %s", interval)
+	}
+	switch interval[len(interval)-1] {
+	case 'm':
+		return time.Duration(n) * time.Minute, nil
+	case 'h':
+		return time.Duration(n) * time.Hour, nil
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, nil
+	case 'w':
+		return time.Duration(n) * 7 * 24 * time.Hour, nil
+	}
+	return 0, fmt.Errorf("invalid interval: %s", interval)
+}
+
+func (s *hyperliquidSource) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	step, err := intervalDuration(interval)
+	if err != nil {
+		return nil, err
+	}
+	end := time.Now()
+	start := end.Add(-step * time.Duration(limit))
+
+	var candles []struct {
+		OpenTime  int64  `json:"t"`
+		CloseTime int64  `json:"T"`
+		Open      string `json:"o"`
+		Close     string `json:"c"`
+		High      string `json:"h"`
+		Low       string `json:"l"`
+		Volume    string `json:"v"`
+		Trades    int    `json:"n"`
+	}
+	err = s.post(map[string]interface{}{
+		"type": "candleSnapshot",
+		"req": map[string]interface{}{
+			"coin":      hyperliquidCoin(symbol),
+			"interval":  interval,
+			"startTime": start.UnixMilli(),
+			"endTime":   end.UnixMilli(),
+		},
+	}, &candles)
+	if err != nil {
+		return nil, fmt.Errorf("获取Hyperliquid K线失败: %w", err)
+	}
+
+	klines := make([]Kline, 0, len(candles))
+	for _, c := range candles {
+		kline := Kline{
+			OpenTime:  c.OpenTime,
+			CloseTime: c.CloseTime,
+			Trades:    c.Trades,
+		}
+		kline.Open, _ = strconv.ParseFloat(c.Open, 64)
+		kline.High, _ = strconv.ParseFloat(c.High, 64)
+		kline.Low, _ = strconv.ParseFloat(c.Low, 64)
+		kline.Close, _ = strconv.ParseFloat(c.Close, 64)
+		kline.Volume, _ = strconv.ParseFloat(c.Volume, 64)
+		kline.QuoteVolume = kline.Volume * kline.Close
+		klines = append(klines, kline)
+	}
+	return klines, nil
+}
+
+func (s *hyperliquidSource) GetCurrentPrice(symbol string) (float64, error) {
+	var mids map[string]string
+	if err := s.post(map[string]string{"type": "allMids"}, &mids); err != nil {
+		return 0, fmt.Errorf("获取Hyperliquid价格失败: %w", err)
+	}
+	coin := hyperliquidCoin(symbol)
+	px, ok := mids[coin]
+	if !ok {
+		return 0, fmt.Errorf("Hyperliquid未找到 %s 的价格", coin)
+	}
+	return strconv.ParseFloat(px, 64)
+}
+
+func (s *hyperliquidSource) GetDepth(symbol string, limit int) (*DepthData, error) {
+	var book struct {
+		Coin   string `json:"coin"`
+		Time   int64  `json:"time"`
+		Levels [][]struct {
+			Px string `json:"px"`
+			Sz string `json:"sz"`
+		} `json:"levels"`
+	}
+	if err := s.post(map[string]string{"type": "l2Book", "coin": hyperliquidCoin(symbol)}, &book); err != nil {
+		return nil, fmt.Errorf("failed to get depth data: %w", err)
+	}
+	if len(book.Levels) < 2 {
+		return nil, fmt.Errorf("failed to get depth data: empty book")
+	}
+
+	depthData := &DepthData{
+		Symbol:     Normalize(symbol),
+		Timestamp:  time.UnixMilli(book.Time),
+		LastUpdate: time.Now(),
+	}
+	for i, lvl := range book.Levels[0] {
+		if i >= limit {
+			break
+		}
+		price, _ := strconv.ParseFloat(lvl.Px, 64)
+		quantity, _ := strconv.ParseFloat(lvl.Sz, 64)
+		depthData.Bids = append(depthData.Bids, DepthLevel{Price: price, Quantity: quantity})
+	}
+	for i, lvl := range book.Levels[1] {
+		if i >= limit {
+			break
+		}
+		price, _ := strconv.ParseFloat(lvl.Px, 64)
+		quantity, _ := strconv.ParseFloat(lvl.Sz, 64)
+		depthData.Asks = append(depthData.Asks, DepthLevel{Price: price, Quantity: quantity})
+	}
+	fillSpread(depthData)
+	return depthData, nil
+}
+
+// getAssetCtx 获取资产上下文（资金费率、持仓量），一次请求覆盖所有币种，缓存30秒
+func (s *hyperliquidSource) getAssetCtx(symbol string) (*hyperliquidAssetCtx, error) {
+	s.ctxMu.Lock()
+	defer s.ctxMu.Unlock()
+
+	if s.assetCtxs == nil || time.Since(s.ctxUpdateAt) > 30*time.Second {
+		var resp []json.RawMessage
+		if err := s.post(map[string]string{"type": "metaAndAssetCtxs"}, &resp); err != nil {
+			return nil, fmt.Errorf("获取Hyperliquid资产信息失败: %w", err)
+		}
+		if len(resp) < 2 {
+			return nil, fmt.Errorf("获取Hyperliquid资产信息失败: 响应格式错误")
+		}
+		var meta struct {
+			Universe []struct {
+				Name string `json:"name"`
+			} `json:"universe"`
+		}
+		var ctxs []hyperliquidAssetCtx
+		if err := json.Unmarshal(resp[0], &meta); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(resp[1], &ctxs); err != nil {
+			return nil, err
+		}
+
+		assetCtxs := make(map[string]hyperliquidAssetCtx, len(ctxs))
+		for i, asset := range meta.Universe {
+			if i < len(ctxs) {
+				assetCtxs[asset.Name] = ctxs[i]
+			}
+		}
+		s.assetCtxs = assetCtxs
+		s.ctxUpdateAt = time.Now()
+	}
+
+	coin := hyperliquidCoin(symbol)
+	assetCtx, ok := s.assetCtxs[coin]
+	if !ok {
+		return nil, fmt.Errorf("Hyperliquid未找到币种 %s", coin)
+	}
+	return &assetCtx, nil
+}
+
+func (s *hyperliquidSource) GetOpenInterest(symbol string) (*OIData, error) {
+	assetCtx, err := s.getAssetCtx(symbol)
+	if err != nil {
+		return nil, err
+	}
+	oi, err := strconv.ParseFloat(assetCtx.OpenInterest, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse open interest: %w", err)
+	}
+	return &OIData{
+		Latest:  oi,
+		Average: oi * 0.999, // 近似平均值
+	}, nil
+}
+
+func (s *hyperliquidSource) GetFundingRate(symbol string) (float64, error) {
+	assetCtx, err := s.getAssetCtx(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(assetCtx.Funding, 64)
+}
+
+// GetFundingData Hyperliquid每小时整点结算，预测费率取自资产上下文
+func (s *hyperliquidSource) GetFundingData(symbol string) (*FundingData, error) {
+	return getCachedFundingData(s.Name()+":"+symbol, func() (*FundingData, error) {
+		predicted, err := s.GetFundingRate(symbol)
+		if err != nil {
+			return nil, err
+		}
+
+		var result []struct {
+			FundingRate string `json:"fundingRate"`
+			Time        int64  `json:"time"`
+		}
+		err = s.post(map[string]interface{}{
+			"type":      "fundingHistory",
+			"coin":      hyperliquidCoin(symbol),
+			"startTime": time.Now().Add(-8 * time.Hour).UnixMilli(),
+		}, &result)
+		if err != nil {
+			return nil, fmt.Errorf("获取Hyperliquid资金费率历史失败: %w", err)
+		}
+
+		history := make([]FundingRatePoint, 0, len(result))
+		for _, r := range result {
+			rate, err := strconv.ParseFloat(r.FundingRate, 64)
+			if err != nil {
+				continue
+			}
+			history = append(history, FundingRatePoint{Time: time.UnixMilli(r.Time), Rate: rate})
+		}
+
+		next := time.Now().Truncate(time.Hour).Add(time.Hour)
+		data := newFundingData(predicted, next, history)
+		data.IntervalHours = 1
+		return data, nil
+	})
+}
+
+// GetCurrentKlines 返回WebSocket维护的K线缓存；首次请求（或断线后缓存过期）通过REST快照初始化并订阅推送
+func (s *hyperliquidSource) GetCurrentKlines(symbol, interval string) ([]Kline, error) {
+	coin := hyperliquidCoin(symbol)
+	if klines, ok := s.stream.cachedKlines(coin, interval); ok {
+		return klines, nil
+	}
+	klines, err := s.GetKlines(symbol, interval, hyperliquidKlineLimit)
+	if err != nil {
+		return nil, err
+	}
+	s.stream.storeKlines(coin, interval, klines)
+	return klines, nil
+}
+
+// GetOrderFlow 基于WebSocket成交流统计订单流（不含强平数据）
+func (s *hyperliquidSource) GetOrderFlow(symbol string) *OrderFlowData {
+	return s.stream.getOrderFlow(hyperliquidCoin(symbol), Normalize(symbol))
+}
@@ -13,7 +13,10 @@ type Data struct {
 	CurrentRSI7       float64
 	OpenInterest      *OIData
 	FundingRate       float64
+	Funding           *FundingData // 资金费率历史与预测
 	DepthData         *DepthData // 深度数据
+	OrderFlow         *OrderFlowData // 订单流数据（归集成交 + 强平）
+	Regime            *RegimeData    // 市场状态（趋势/震荡/高波动）
 	IntradaySeries    *IntradayData
 	LongerTermContext *LongerTermData
 }
@@ -24,6 +27,39 @@ type OIData struct {
 	Average float64
 }
 
+// FundingData 资金费率详情
+type FundingData struct {
+	PredictedRate   float64            `json:"predicted_rate"`    // 下一期预测资金费率
+	NextFundingTime time.Time          `json:"next_funding_time"` // 下一次结算时间
+	IntervalHours   float64            `json:"interval_hours"`    // 结算间隔（小时）
+	History         []FundingRatePoint `json:"history"`           // 最近已结算费率（时间升序）
+	AverageRate     float64            `json:"average_rate"`      // 历史平均费率
+}
+
+// FundingRatePoint 已结算的资金费率
+type FundingRatePoint struct {
+	Time time.Time `json:"time"`
+	Rate float64   `json:"rate"`
+}
+
+// OrderFlowData 订单流数据
+// 由成交流（币安 aggTrade/forceOrder，Hyperliquid trades）滚动统计得出，金额单位均为USDT
+type OrderFlowData struct {
+	Symbol            string    `json:"symbol"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	CVD15m            float64   `json:"cvd_15m"`              // 15分钟累计成交量差（主动买入额 - 主动卖出额）
+	CVDWindow         float64   `json:"cvd_window"`           // 统计窗口内累计成交量差（窗口见 config.OrderFlow.Window，默认1小时）
+	TakerBuyVolume    float64   `json:"taker_buy_volume"`     // 窗口内主动买入额
+	TakerSellVolume   float64   `json:"taker_sell_volume"`    // 窗口内主动卖出额
+	TakerBuySellRatio float64   `json:"taker_buy_sell_ratio"` // 主动买卖比 (buy / sell)
+	LargeBuyCount     int       `json:"large_buy_count"`      // 窗口内大额主动买单数量
+	LargeSellCount    int       `json:"large_sell_count"`     // 窗口内大额主动卖单数量
+	LongLiqVolume     float64   `json:"long_liq_volume"`      // 窗口内多头强平额
+	ShortLiqVolume    float64   `json:"short_liq_volume"`     // 窗口内空头强平额
+	LongLiqCount      int       `json:"long_liq_count"`
+	ShortLiqCount     int       `json:"short_liq_count"`
+}
+
 // DepthData 深度数据
 // 包含买卖盘信息，用于分析市场流动性和情绪
 // Bid/Ask 数据按照价格从高到低排序
@@ -123,6 +159,9 @@ type Ticker24hr struct {
 	Symbol             string `json:"symbol"`
 	PriceChange        string `json:"priceChange"`
 	PriceChangePercent string `json:"priceChangePercent"`
+	LastPrice          string `json:"lastPrice"`
+	HighPrice          string `json:"highPrice"`
+	LowPrice           string `json:"lowPrice"`
 	Volume             string `json:"volume"`
 	QuoteVolume        string `json:"quoteVolume"`
 }
@@ -162,6 +201,8 @@ type Config struct {
 	AlertThresholds AlertThresholds `json:"alert_thresholds"`
 	UpdateInterval  int             `json:"update_interval"` // seconds
 	CleanupConfig   CleanupConfig   `json:"cleanup_config"`
+	OrderFlow       OrderFlowConfig `json:"order_flow"`
+	Regime          RegimeConfig    `json:"regime"`
 }
 
 type AlertThresholds struct {
@@ -171,6 +212,12 @@ type AlertThresholds struct {
 	RSIOverbought    float64 `json:"rsi_overbought"`
 	RSIOversold      float64 `json:"rsi_oversold"`
 }
+// OrderFlowConfig 订单流统计配置
+type OrderFlowConfig struct {
+	Window             time.Duration `json:"window"`               // 滚动统计窗口
+	LargeTradeMin      float64       `json:"large_trade_min"`      // 大单最低成交额(USDT)
+	LargeTradeMultiple float64       `json:"large_trade_multiple"` // 大单阈值 = 窗口内平均单笔成交额 * 倍数
+}
 type CleanupConfig struct {
 	InactiveTimeout   time.Duration `json:"inactive_timeout"`    // 不活跃超时时间
 	MinScoreThreshold float64       `json:"min_score_threshold"` // 最低评分阈值
@@ -192,5 +239,20 @@ var config = Config{
 		NoAlertTimeout:    20 * time.Minute,
 		CheckInterval:     5 * time.Minute,
 	},
+	OrderFlow: OrderFlowConfig{
+		Window:             1 * time.Hour,
+		LargeTradeMin:      10000,
+		LargeTradeMultiple: 10,
+	},
+	Regime: RegimeConfig{
+		Interval:             "4h",
+		ADXPeriod:            14,
+		TrendADX:             25,
+		EMASlopeBars:         5,
+		MinEMASlope:          0.5,
+		HighVolATRPercentile: 90,
+		HighVolRealized:      120,
+		VolWindow:            30,
+	},
 	UpdateInterval: 60, // 1 minute
 }
@@ -79,6 +79,39 @@ type DepthWSData struct {
 	Asks          [][]string   `json:"a"` // [价格, 数量]
 }
 
+// AggTradeWSData WebSocket归集成交数据
+type AggTradeWSData struct {
+	EventType    string `json:"e"`
+	EventTime    int64  `json:"E"`
+	Symbol       string `json:"s"`
+	AggTradeID   int64  `json:"a"`
+	Price        string `json:"p"`
+	Quantity     string `json:"q"`
+	FirstTradeID int64  `json:"f"`
+	LastTradeID  int64  `json:"l"`
+	TradeTime    int64  `json:"T"`
+	IsBuyerMaker bool   `json:"m"` // true=主动卖出（买方是maker）
+}
+
+// ForceOrderWSData WebSocket强平订单数据
+type ForceOrderWSData struct {
+	EventType string `json:"e"`
+	EventTime int64  `json:"E"`
+	Order     struct {
+		Symbol        string `json:"s"`
+		Side          string `json:"S"` // SELL=多头被强平, BUY=空头被强平
+		OrderType     string `json:"o"`
+		TimeInForce   string `json:"f"`
+		OrigQuantity  string `json:"q"`
+		Price         string `json:"p"`
+		AveragePrice  string `json:"ap"`
+		Status        string `json:"X"`
+		LastFilledQty string `json:"l"`
+		FilledQty     string `json:"z"`
+		TradeTime     int64  `json:"T"`
+	} `json:"o"`
+}
+
 func NewWSClient() *WSClient {
 	return &WSClient{
 		subscribers: make(map[string]chan []byte),
@@ -8,7 +8,9 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -24,19 +26,73 @@ var defaultMainstreamCoins = []string{
 	"HYPEUSDT",
 }
 
-// CoinPoolConfig 币种池配置
+// CoinPoolConfig 币种池配置（每个交易员独立）
 type CoinPoolConfig struct {
-	APIURL          string
+	APIURL          string        // AI500币种池API（为空表示不启用）
+	OITopAPIURL     string        // OI Top API（为空表示不启用）
 	Timeout         time.Duration
-	CacheDir        string
-	UseDefaultCoins bool // 是否使用默认主流币种
+	CacheDir        string        // 缓存目录（不同交易员使用不同目录，互不影响）
+	UseDefaultCoins bool          // 未启用外部信号时，是否使用默认主流币种（否则使用本地筛选器）
+	DefaultCoins    []string      // 默认主流币种（为空时使用内置列表）
+	SignalSources   string        // 额外信号源配置（JSON数组，见SignalSourceSpec）
 }
 
-var coinPoolConfig = CoinPoolConfig{
-	APIURL:          "",
-	Timeout:         30 * time.Second, // 增加到30秒
-	CacheDir:        "coin_pool_cache",
-	UseDefaultCoins: false, // 默认不使用
+// CoinPool 币种池：管理一组信号源及其缓存
+type CoinPool struct {
+	cfg    CoinPoolConfig
+	client *http.Client
+
+	mu      sync.RWMutex
+	entries map[string]*signalSourceEntry
+	order   []string // 注册顺序
+}
+
+// NewCoinPool 创建币种池，并根据配置注册信号源
+func NewCoinPool(cfg CoinPoolConfig) *CoinPool {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.CacheDir == "" {
+		cfg.CacheDir = "coin_pool_cache"
+	}
+	if len(cfg.DefaultCoins) == 0 {
+		cfg.DefaultCoins = defaultMainstreamCoins
+	}
+
+	p := &CoinPool{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: cfg.Timeout},
+		entries: make(map[string]*signalSourceEntry),
+	}
+
+	// 内置信号源：AI500 与 OI Top（按配置启用）
+	if strings.TrimSpace(cfg.APIURL) != "" {
+		p.RegisterSignalSource(&ai500Source{pool: p}, SourceOptions{Weight: 1, TTL: 3 * time.Minute, Limit: 20})
+	}
+	if strings.TrimSpace(cfg.OITopAPIURL) != "" {
+		p.RegisterSignalSource(&oiTopSource{pool: p}, SourceOptions{Weight: 1, TTL: 3 * time.Minute})
+	}
+
+	// 未启用外部信号时：默认币种或本地筛选器
+	if len(p.order) == 0 {
+		if cfg.UseDefaultCoins {
+			p.RegisterSignalSource(NewStaticSource("default", cfg.DefaultCoins), SourceOptions{Weight: 1})
+		} else {
+			p.RegisterSignalSource(NewScreenerSource(DefaultScreenerConfig()), SourceOptions{Weight: 1, TTL: 15 * time.Minute})
+		}
+	}
+
+	// 额外配置的信号源（static / json_url / screener，或调整内置信号源的权重）
+	if err := p.RegisterSignalSourcesFromJSON(cfg.SignalSources); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	return p
+}
+
+// HasExternalSignals 是否启用了外部信号API（AI500 / OI Top）
+func (p *CoinPool) HasExternalSignals() bool {
+	return strings.TrimSpace(p.cfg.APIURL) != "" || strings.TrimSpace(p.cfg.OITopAPIURL) != ""
 }
 
 // CoinPoolCache 币种池缓存
@@ -68,41 +124,17 @@ type CoinPoolAPIResponse struct {
 	} `json:"data"`
 }
 
-// SetCoinPoolAPI 设置币种池API
-func SetCoinPoolAPI(apiURL string) {
-	coinPoolConfig.APIURL = apiURL
-}
-
-// SetOITopAPI 设置OI Top API
-func SetOITopAPI(apiURL string) {
-	oiTopConfig.APIURL = apiURL
-}
-
-// SetUseDefaultCoins 设置是否使用默认主流币种
-func SetUseDefaultCoins(useDefault bool) {
-	coinPoolConfig.UseDefaultCoins = useDefault
-}
-
-// SetDefaultCoins 设置默认主流币种列表
-func SetDefaultCoins(coins []string) {
-	if len(coins) > 0 {
-		defaultMainstreamCoins = coins
-		log.Printf("✓ 已设置默认币种池（共%d个币种）: %v", len(coins), coins)
-	}
+// HasOITop 是否启用了OI Top API
+func (p *CoinPool) HasOITop() bool {
+	return strings.TrimSpace(p.cfg.OITopAPIURL) != ""
 }
 
-// GetCoinPool 获取币种池列表（带重试和缓存机制）
-func GetCoinPool() ([]CoinInfo, error) {
-	// 优先检查是否启用默认币种列表
-	if coinPoolConfig.UseDefaultCoins {
-		log.Printf("✓ 已启用默认主流币种列表")
-		return convertSymbolsToCoins(defaultMainstreamCoins), nil
-	}
-
+// GetCoinPool 获取AI500币种池列表（带重试和缓存机制）
+func (p *CoinPool) GetCoinPool() ([]CoinInfo, error) {
 	// 检查API URL是否配置
-	if strings.TrimSpace(coinPoolConfig.APIURL) == "" {
+	if strings.TrimSpace(p.cfg.APIURL) == "" {
 		log.Printf("⚠️  未配置币种池API URL，使用默认主流币种列表")
-		return convertSymbolsToCoins(defaultMainstreamCoins), nil
+		return convertSymbolsToCoins(p.cfg.DefaultCoins), nil
 	}
 
 	maxRetries := 3
@@ -115,13 +147,13 @@ func GetCoinPool() ([]CoinInfo, error) {
 			time.Sleep(2 * time.Second) // 重试前等待2秒
 		}
 
-		coins, err := fetchCoinPool()
+		coins, err := p.fetchCoinPool()
 		if err == nil {
 			if attempt > 1 {
 				log.Printf("✓ 第%d次重试成功", attempt)
 			}
 			// 成功获取后保存到缓存
-			if err := saveCoinPoolCache(coins); err != nil {
+			if err := p.saveCoinPoolCache(coins); err != nil {
 				log.Printf("⚠️  保存币种池缓存失败: %v", err)
 			}
 			return coins, nil
@@ -133,7 +165,7 @@ func GetCoinPool() ([]CoinInfo, error) {
 
 	// API获取失败，尝试使用缓存
 	log.Printf("⚠️  API请求全部失败，尝试使用历史缓存数据...")
-	cachedCoins, err := loadCoinPoolCache()
+	cachedCoins, err := p.loadCoinPoolCache()
 	if err == nil {
 		log.Printf("✓ 使用历史缓存数据（共%d个币种）", len(cachedCoins))
 		return cachedCoins, nil
@@ -141,18 +173,14 @@ func GetCoinPool() ([]CoinInfo, error) {
 
 	// 缓存也失败，使用默认主流币种
 	log.Printf("⚠️  无法加载缓存数据（最后错误: %v），使用默认主流币种列表", lastErr)
-	return convertSymbolsToCoins(defaultMainstreamCoins), nil
+	return convertSymbolsToCoins(p.cfg.DefaultCoins), nil
 }
 
 // fetchCoinPool 实际执行币种池请求
-func fetchCoinPool() ([]CoinInfo, error) {
+func (p *CoinPool) fetchCoinPool() ([]CoinInfo, error) {
 	log.Printf("🔄 正在请求AI500币种池...")
 
-	client := &http.Client{
-		Timeout: coinPoolConfig.Timeout,
-	}
-
-	resp, err := client.Get(coinPoolConfig.APIURL)
+	resp, err := p.client.Get(p.cfg.APIURL)
 	if err != nil {
 		return nil, fmt.Errorf("请求币种池API失败: %w", err)
 	}
@@ -192,9 +220,9 @@ func fetchCoinPool() ([]CoinInfo, error) {
 }
 
 // saveCoinPoolCache 保存币种池到缓存文件
-func saveCoinPoolCache(coins []CoinInfo) error {
+func (p *CoinPool) saveCoinPoolCache(coins []CoinInfo) error {
 	// 确保缓存目录存在
-	if err := os.MkdirAll(coinPoolConfig.CacheDir, 0755); err != nil {
+	if err := os.MkdirAll(p.cfg.CacheDir, 0755); err != nil {
 		return fmt.Errorf("创建缓存目录失败: %w", err)
 	}
 
@@ -209,7 +237,7 @@ func saveCoinPoolCache(coins []CoinInfo) error {
 		return fmt.Errorf("序列化缓存数据失败: %w", err)
 	}
 
-	cachePath := filepath.Join(coinPoolConfig.CacheDir, "latest.json")
+	cachePath := filepath.Join(p.cfg.CacheDir, "latest.json")
 	if err := ioutil.WriteFile(cachePath, data, 0644); err != nil {
 		return fmt.Errorf("写入缓存文件失败: %w", err)
 	}
@@ -219,8 +247,8 @@ func saveCoinPoolCache(coins []CoinInfo) error {
 }
 
 // loadCoinPoolCache 从缓存文件加载币种池
-func loadCoinPoolCache() ([]CoinInfo, error) {
-	cachePath := filepath.Join(coinPoolConfig.CacheDir, "latest.json")
+func (p *CoinPool) loadCoinPoolCache() ([]CoinInfo, error) {
+	cachePath := filepath.Join(p.cfg.CacheDir, "latest.json")
 
 	// 检查文件是否存在
 	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
@@ -251,8 +279,8 @@ func loadCoinPoolCache() ([]CoinInfo, error) {
 }
 
 // GetAvailableCoins 获取可用的币种列表（过滤不可用的）
-func GetAvailableCoins() ([]string, error) {
-	coins, err := GetCoinPool()
+func (p *CoinPool) GetAvailableCoins() ([]string, error) {
+	coins, err := p.GetCoinPool()
 	if err != nil {
 		return nil, err
 	}
@@ -274,8 +302,8 @@ func GetAvailableCoins() ([]string, error) {
 }
 
 // GetTopRatedCoins 获取评分最高的N个币种（按评分从大到小排序）
-func GetTopRatedCoins(limit int) ([]string, error) {
-	coins, err := GetCoinPool()
+func (p *CoinPool) GetTopRatedCoins(limit int) ([]string, error) {
+	coins, err := p.GetCoinPool()
 	if err != nil {
 		return nil, err
 	}
@@ -408,20 +436,10 @@ type OITopCache struct {
 	SourceType string       `json:"source_type"`
 }
 
-var oiTopConfig = struct {
-	APIURL   string
-	Timeout  time.Duration
-	CacheDir string
-}{
-	APIURL:   "",
-	Timeout:  30 * time.Second,
-	CacheDir: "coin_pool_cache",
-}
-
 // GetOITopPositions 获取持仓量增长Top20数据（带重试和缓存）
-func GetOITopPositions() ([]OIPosition, error) {
+func (p *CoinPool) GetOITopPositions() ([]OIPosition, error) {
 	// 检查API URL是否配置
-	if strings.TrimSpace(oiTopConfig.APIURL) == "" {
+	if strings.TrimSpace(p.cfg.OITopAPIURL) == "" {
 		log.Printf("⚠️  未配置OI Top API URL，跳过OI Top数据获取")
 		return []OIPosition{}, nil // 返回空列表，不是错误
 	}
@@ -436,13 +454,13 @@ func GetOITopPositions() ([]OIPosition, error) {
 			time.Sleep(2 * time.Second)
 		}
 
-		positions, err := fetchOITop()
+		positions, err := p.fetchOITop()
 		if err == nil {
 			if attempt > 1 {
 				log.Printf("✓ 第%d次重试成功", attempt)
 			}
 			// 成功获取后保存到缓存
-			if err := saveOITopCache(positions); err != nil {
+			if err := p.saveOITopCache(positions); err != nil {
 				log.Printf("⚠️  保存OI Top缓存失败: %v", err)
 			}
 			return positions, nil
@@ -454,7 +472,7 @@ func GetOITopPositions() ([]OIPosition, error) {
 
 	// API获取失败，尝试使用缓存
 	log.Printf("⚠️  OI Top API请求全部失败，尝试使用历史缓存数据...")
-	cachedPositions, err := loadOITopCache()
+	cachedPositions, err := p.loadOITopCache()
 	if err == nil {
 		log.Printf("✓ 使用历史OI Top缓存数据（共%d个币种）", len(cachedPositions))
 		return cachedPositions, nil
@@ -466,14 +484,10 @@ func GetOITopPositions() ([]OIPosition, error) {
 }
 
 // fetchOITop 实际执行OI Top请求
-func fetchOITop() ([]OIPosition, error) {
+func (p *CoinPool) fetchOITop() ([]OIPosition, error) {
 	log.Printf("🔄 正在请求OI Top数据...")
 
-	client := &http.Client{
-		Timeout: oiTopConfig.Timeout,
-	}
-
-	resp, err := client.Get(oiTopConfig.APIURL)
+	resp, err := p.client.Get(p.cfg.OITopAPIURL)
 	if err != nil {
 		return nil, fmt.Errorf("请求OI Top API失败: %w", err)
 	}
@@ -508,8 +522,8 @@ func fetchOITop() ([]OIPosition, error) {
 }
 
 // saveOITopCache 保存OI Top数据到缓存
-func saveOITopCache(positions []OIPosition) error {
-	if err := os.MkdirAll(oiTopConfig.CacheDir, 0755); err != nil {
+func (p *CoinPool) saveOITopCache(positions []OIPosition) error {
+	if err := os.MkdirAll(p.cfg.CacheDir, 0755); err != nil {
 		return fmt.Errorf("创建缓存目录失败: %w", err)
 	}
 
@@ -524,7 +538,7 @@ func saveOITopCache(positions []OIPosition) error {
 		return fmt.Errorf("序列化OI Top缓存数据失败: %w", err)
 	}
 
-	cachePath := filepath.Join(oiTopConfig.CacheDir, "oi_top_latest.json")
+	cachePath := filepath.Join(p.cfg.CacheDir, "oi_top_latest.json")
 	if err := ioutil.WriteFile(cachePath, data, 0644); err != nil {
 		return fmt.Errorf("写入OI Top缓存文件失败: %w", err)
 	}
@@ -534,8 +548,8 @@ func saveOITopCache(positions []OIPosition) error {
 }
 
 // loadOITopCache 从缓存加载OI Top数据
-func loadOITopCache() ([]OIPosition, error) {
-	cachePath := filepath.Join(oiTopConfig.CacheDir, "oi_top_latest.json")
+func (p *CoinPool) loadOITopCache() ([]OIPosition, error) {
+	cachePath := filepath.Join(p.cfg.CacheDir, "oi_top_latest.json")
 
 	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("OI Top缓存文件不存在")
@@ -564,8 +578,8 @@ func loadOITopCache() ([]OIPosition, error) {
 }
 
 // GetOITopSymbols 获取OI Top的币种符号列表
-func GetOITopSymbols() ([]string, error) {
-	positions, err := GetOITopPositions()
+func (p *CoinPool) GetOITopSymbols() ([]string, error) {
+	positions, err := p.GetOITopPositions()
 	if err != nil {
 		return nil, err
 	}
@@ -579,67 +593,61 @@ func GetOITopSymbols() ([]string, error) {
 	return symbols, nil
 }
 
-// MergedCoinPool 合并的币种池（AI500 + OI Top）
+// MergedCoinPool 合并的币种池（所有已注册信号源）
 type MergedCoinPool struct {
-	AI500Coins    []CoinInfo          // AI500评分币种
-	OITopCoins    []OIPosition        // 持仓量增长Top20
-	AllSymbols    []string            // 所有不重复的币种符号
-	SymbolSources map[string][]string // 每个币种的来源（"ai500"/"oi_top"）
+	AllSymbols    []string                 // 所有不重复的币种符号（按加权评分降序）
+	SymbolSources map[string][]SourceScore // 每个币种的来源及归一化评分
+	Scores        map[string]float64       // 每个币种的加权综合评分
 }
 
-// GetMergedCoinPool 获取合并后的币种池（AI500 + OI Top，去重）
-func GetMergedCoinPool(ai500Limit int) (*MergedCoinPool, error) {
-	// 1. 获取AI500数据
-	ai500TopSymbols, err := GetTopRatedCoins(ai500Limit)
-	if err != nil {
-		log.Printf("⚠️  获取AI500数据失败: %v", err)
-		ai500TopSymbols = []string{} // 失败时用空列表
+// GetMergedCoinPool 获取合并后的币种池（所有已注册信号源，按权重加权评分并去重）
+// limit <= 0 表示不限制数量
+func (p *CoinPool) GetMergedCoinPool(limit int) (*MergedCoinPool, error) {
+	merged := &MergedCoinPool{
+		SymbolSources: make(map[string][]SourceScore),
+		Scores:        make(map[string]float64),
 	}
 
-	// 2. 获取OI Top数据
-	oiTopSymbols, err := GetOITopSymbols()
-	if err != nil {
-		log.Printf("⚠️  获取OI Top数据失败: %v", err)
-		oiTopSymbols = []string{} // 失败时用空列表
+	p.mu.RLock()
+	entries := make([]*signalSourceEntry, 0, len(p.order))
+	for _, name := range p.order {
+		entries = append(entries, p.entries[name])
 	}
+	p.mu.RUnlock()
 
-	// 3. 合并并去重
-	symbolSet := make(map[string]bool)
-	symbolSources := make(map[string][]string)
-
-	// 添加AI500币种
-	for _, symbol := range ai500TopSymbols {
-		symbolSet[symbol] = true
-		symbolSources[symbol] = append(symbolSources[symbol], "ai500")
-	}
+	counts := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.source.Name()
+		coins, err := entry.get(p.cfg.CacheDir)
+		if err != nil {
+			log.Printf("⚠️  获取信号源 %s 数据失败: %v", name, err)
+			continue // 单个信号源失败不影响整体
+		}
+		counts = append(counts, fmt.Sprintf("%s=%d", name, len(coins)))
 
-	// 添加OI Top币种
-	for _, symbol := range oiTopSymbols {
-		if !symbolSet[symbol] {
-			symbolSet[symbol] = true
+		scores := normalizeSignalScores(coins)
+		for _, coin := range coins {
+			score := scores[coin.Symbol]
+			merged.SymbolSources[coin.Symbol] = append(merged.SymbolSources[coin.Symbol], SourceScore{Name: name, Score: score, Reason: coin.Reason})
+			merged.Scores[coin.Symbol] += score * entry.opts.Weight
 		}
-		symbolSources[symbol] = append(symbolSources[symbol], "oi_top")
 	}
 
-	// 转换为数组
-	var allSymbols []string
-	for symbol := range symbolSet {
-		allSymbols = append(allSymbols, symbol)
+	for symbol := range merged.Scores {
+		merged.AllSymbols = append(merged.AllSymbols, symbol)
 	}
-
-	// 获取完整数据
-	ai500Coins, _ := GetCoinPool()
-	oiTopPositions, _ := GetOITopPositions()
-
-	merged := &MergedCoinPool{
-		AI500Coins:    ai500Coins,
-		OITopCoins:    oiTopPositions,
-		AllSymbols:    allSymbols,
-		SymbolSources: symbolSources,
+	sort.Slice(merged.AllSymbols, func(i, j int) bool {
+		a, b := merged.AllSymbols[i], merged.AllSymbols[j]
+		if merged.Scores[a] != merged.Scores[b] {
+			return merged.Scores[a] > merged.Scores[b]
+		}
+		return a < b
+	})
+	if limit > 0 && len(merged.AllSymbols) > limit {
+		merged.AllSymbols = merged.AllSymbols[:limit]
 	}
 
-	log.Printf("📊 币种池合并完成: AI500=%d, OI_Top=%d, 总计(去重)=%d",
-		len(ai500TopSymbols), len(oiTopSymbols), len(allSymbols))
+	log.Printf("📊 币种池合并完成: %s, 总计(去重)=%d", strings.Join(counts, ", "), len(merged.AllSymbols))
 
 	return merged, nil
 }
@@ -0,0 +1,230 @@
+package pool
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx-lite/market"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ========== 本地筛选器（无需外部信号API） ==========
+
+// ScreenerWeights 各筛选维度的权重（0表示不参与排序）
+type ScreenerWeights struct {
+	QuoteVolume    float64 `json:"quote_volume"`    // 24h成交额
+	Volatility     float64 `json:"volatility"`      // 24h振幅 (high-low)/last
+	OIChange       float64 `json:"oi_change"`       // 持仓价值变化幅度
+	FundingExtreme float64 `json:"funding_extreme"` // 资金费率绝对值（极端费率）
+	Momentum       float64 `json:"momentum"`        // 24h涨跌幅绝对值
+}
+
+// ScreenerConfig 本地筛选器配置
+type ScreenerConfig struct {
+	TopN           int             `json:"top_n"`            // 输出前N个币种
+	MinQuoteVolume float64         `json:"min_quote_volume"` // 最低24h成交额(USDT)
+	OICandidates   int             `json:"oi_candidates"`    // 仅对成交额前N个币种计算OI变化（逐个请求，控制请求量）
+	OIPeriod       string          `json:"oi_period"`        // OI历史周期（如 1h）
+	OILookback     int             `json:"oi_lookback"`      // OI变化回看周期数
+	Exclude        []string        `json:"exclude"`          // 排除的币种
+	Weights        ScreenerWeights `json:"weights"`
+}
+
+// DefaultScreenerConfig 默认筛选器配置
+func DefaultScreenerConfig() ScreenerConfig {
+	return ScreenerConfig{
+		TopN:           20,
+		MinQuoteVolume: 20_000_000,
+		OICandidates:   40,
+		OIPeriod:       "1h",
+		OILookback:     4,
+		Weights: ScreenerWeights{
+			QuoteVolume:    1,
+			Volatility:     1,
+			OIChange:       1,
+			FundingExtreme: 0.5,
+			Momentum:       1,
+		},
+	}
+}
+
+// screenerRow 单个币种的筛选指标
+type screenerRow struct {
+	symbol      string
+	quoteVolume float64
+	volatility  float64 // 百分比
+	oiChange    float64 // 百分比
+	funding     float64
+	momentum    float64 // 百分比
+	score       float64
+}
+
+// ScreenerSource 本地筛选器信号源：基于交易所全部USDT永续合约的24h行情排序
+type ScreenerSource struct {
+	cfg    ScreenerConfig
+	client *market.APIClient
+}
+
+// NewScreenerSource 创建本地筛选器信号源（未设置的参数使用默认值）
+func NewScreenerSource(cfg ScreenerConfig) *ScreenerSource {
+	def := DefaultScreenerConfig()
+	if cfg.TopN <= 0 {
+		cfg.TopN = def.TopN
+	}
+	if cfg.OIPeriod == "" {
+		cfg.OIPeriod = def.OIPeriod
+	}
+	if cfg.OILookback <= 0 {
+		cfg.OILookback = def.OILookback
+	}
+	if cfg.Weights == (ScreenerWeights{}) {
+		cfg.Weights = def.Weights
+	}
+	return &ScreenerSource{cfg: cfg, client: market.NewAPIClient()}
+}
+
+func (s *ScreenerSource) Name() string { return "screener" }
+
+func (s *ScreenerSource) Fetch() ([]SignalCoin, error) {
+	log.Printf("🔄 本地筛选器正在扫描USDT永续合约...")
+
+	exchangeInfo, err := s.client.GetExchangeInfo()
+	if err != nil {
+		return nil, fmt.Errorf("获取交易规则失败: %w", err)
+	}
+	tickers, err := s.client.Get24hrTickers()
+	if err != nil {
+		return nil, fmt.Errorf("获取24h行情失败: %w", err)
+	}
+	fundingRates, err := s.client.GetAllFundingRates()
+	if err != nil {
+		log.Printf("⚠️  获取资金费率失败，忽略资金费率维度: %v", err)
+		fundingRates = map[string]float64{}
+	}
+
+	// 可交易的USDT永续合约
+	excluded := make(map[string]bool, len(s.cfg.Exclude))
+	for _, symbol := range s.cfg.Exclude {
+		excluded[normalizeSymbol(symbol)] = true
+	}
+	universe := make(map[string]bool)
+	for _, info := range exchangeInfo.Symbols {
+		if info.Status == "TRADING" && info.ContractType == "PERPETUAL" && info.QuoteAsset == "USDT" && !excluded[info.Symbol] {
+			universe[info.Symbol] = true
+		}
+	}
+
+	rows := make([]*screenerRow, 0, len(universe))
+	for _, t := range tickers {
+		if !universe[t.Symbol] {
+			continue
+		}
+		quoteVolume, _ := strconv.ParseFloat(t.QuoteVolume, 64)
+		if quoteVolume < s.cfg.MinQuoteVolume {
+			continue
+		}
+		last, _ := strconv.ParseFloat(t.LastPrice, 64)
+		high, _ := strconv.ParseFloat(t.HighPrice, 64)
+		low, _ := strconv.ParseFloat(t.LowPrice, 64)
+		change, _ := strconv.ParseFloat(t.PriceChangePercent, 64)
+		if last <= 0 {
+			continue
+		}
+		rows = append(rows, &screenerRow{
+			symbol:      t.Symbol,
+			quoteVolume: quoteVolume,
+			volatility:  (high - low) / last * 100,
+			funding:     fundingRates[t.Symbol],
+			momentum:    change,
+		})
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("没有满足条件的币种（最低成交额 %.0f USDT）", s.cfg.MinQuoteVolume)
+	}
+
+	// OI变化需要逐个请求，只对成交额靠前的币种计算
+	if s.cfg.Weights.OIChange > 0 && s.cfg.OICandidates > 0 {
+		sort.Slice(rows, func(i, j int) bool { return rows[i].quoteVolume > rows[j].quoteVolume })
+		s.fillOIChange(rows[:minInt(len(rows), s.cfg.OICandidates)])
+	}
+
+	// 各维度按分位打分后加权求和
+	w := s.cfg.Weights
+	addPercentileScore(rows, w.QuoteVolume, func(r *screenerRow) float64 { return r.quoteVolume })
+	addPercentileScore(rows, w.Volatility, func(r *screenerRow) float64 { return r.volatility })
+	addPercentileScore(rows, w.OIChange, func(r *screenerRow) float64 { return math.Abs(r.oiChange) })
+	addPercentileScore(rows, w.FundingExtreme, func(r *screenerRow) float64 { return math.Abs(r.funding) })
+	addPercentileScore(rows, w.Momentum, func(r *screenerRow) float64 { return math.Abs(r.momentum) })
+
+	sort.Slice(rows, func(i, j int) bool { return rows[i].score > rows[j].score })
+	if len(rows) > s.cfg.TopN {
+		rows = rows[:s.cfg.TopN]
+	}
+
+	coins := make([]SignalCoin, 0, len(rows))
+	for _, r := range rows {
+		coins = append(coins, SignalCoin{Symbol: r.symbol, Score: r.score, Reason: r.reason()})
+	}
+	log.Printf("✓ 本地筛选器完成: 候选%d个，输出前%d个", len(universe), len(coins))
+	return coins, nil
+}
+
+// fillOIChange 并发获取持仓价值变化
+func (s *ScreenerSource) fillOIChange(rows []*screenerRow) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 5)
+	for _, r := range rows {
+		wg.Add(1)
+		go func(r *screenerRow) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			values, err := s.client.GetOpenInterestHist(r.symbol, s.cfg.OIPeriod, s.cfg.OILookback+1)
+			if err != nil || len(values) < 2 || values[0] <= 0 {
+				return
+			}
+			r.oiChange = (values[len(values)-1] - values[0]) / values[0] * 100
+		}(r)
+	}
+	wg.Wait()
+}
+
+// addPercentileScore 按指标值在全部币种中的分位(0-1)乘以权重累加到评分
+func addPercentileScore(rows []*screenerRow, weight float64, value func(*screenerRow) float64) {
+	if weight <= 0 || len(rows) < 2 {
+		return
+	}
+	sorted := make([]*screenerRow, len(rows))
+	copy(sorted, rows)
+	sort.SliceStable(sorted, func(i, j int) bool { return value(sorted[i]) < value(sorted[j]) })
+	for i, r := range sorted {
+		r.score += weight * float64(i) / float64(len(sorted)-1)
+	}
+}
+
+// reason 筛选理由（传入AI prompt）
+func (r *screenerRow) reason() string {
+	parts := []string{
+		fmt.Sprintf("成交额%.0fM", r.quoteVolume/1_000_000),
+		fmt.Sprintf("振幅%.1f%%", r.volatility),
+		fmt.Sprintf("24h%+.1f%%", r.momentum),
+	}
+	if r.oiChange != 0 {
+		parts = append(parts, fmt.Sprintf("OI%+.1f%%", r.oiChange))
+	}
+	if r.funding != 0 {
+		parts = append(parts, fmt.Sprintf("资金费%.4f%%", r.funding*100))
+	}
+	return strings.Join(parts, " ")
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,506 @@
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ========== 信号源（可插拔） ==========
+
+// SignalCoin 信号源给出的单个币种
+type SignalCoin struct {
+	Symbol string  `json:"symbol"`
+	Score  float64 `json:"score"`            // 原始评分（各信号源量纲不同，合并时会归一化）
+	Reason string  `json:"reason,omitempty"` // 入选理由（可选，会传入AI prompt）
+}
+
+// SignalSource 币种信号源
+type SignalSource interface {
+	// Name 信号源名称（唯一，用于注册、缓存文件名和CandidateCoin.Sources）
+	Name() string
+	// Fetch 获取最新信号（不做缓存和重试，由注册中心统一处理）
+	Fetch() ([]SignalCoin, error)
+}
+
+// SourceOptions 信号源注册参数
+type SourceOptions struct {
+	Weight float64       `json:"weight"` // 合并评分时的权重（<=0 视为1）
+	TTL    time.Duration `json:"ttl"`    // 内存缓存有效期（0表示每次都请求）
+	Limit  int           `json:"limit"`  // 只取评分最高的前N个（0表示不限制）
+}
+
+// SourceScore 币种在某个信号源中的评分
+type SourceScore struct {
+	Name   string  `json:"name"`
+	Score  float64 `json:"score"`            // 归一化评分 (0-1]
+	Reason string  `json:"reason,omitempty"` // 入选理由
+}
+
+// signalSourceEntry 已注册的信号源及其缓存
+type signalSourceEntry struct {
+	source    SignalSource
+	opts      SourceOptions
+	mu        sync.Mutex
+	coins     []SignalCoin
+	fetchedAt time.Time
+}
+
+// signalSourceCache 信号源缓存文件结构
+type signalSourceCache struct {
+	Source    string       `json:"source"`
+	Coins     []SignalCoin `json:"coins"`
+	FetchedAt time.Time    `json:"fetched_at"`
+}
+
+// RegisterSignalSource 注册信号源（同名信号源会被替换）
+func (p *CoinPool) RegisterSignalSource(source SignalSource, opts SourceOptions) {
+	if opts.Weight <= 0 {
+		opts.Weight = 1
+	}
+	name := source.Name()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.entries[name]; !exists {
+		p.order = append(p.order, name)
+	}
+	p.entries[name] = &signalSourceEntry{source: source, opts: opts}
+	log.Printf("✓ 已注册信号源: %s (权重%.2f, TTL %v, 上限%d)", name, opts.Weight, opts.TTL, opts.Limit)
+}
+
+// UnregisterSignalSource 注销信号源
+func (p *CoinPool) UnregisterSignalSource(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.entries[name]; !exists {
+		return
+	}
+	delete(p.entries, name)
+	for i, n := range p.order {
+		if n == name {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// SignalSourceNames 返回已注册的信号源名称（按注册顺序）
+func (p *CoinPool) SignalSourceNames() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]string(nil), p.order...)
+}
+
+// GetSignals 获取指定信号源的信号（TTL缓存 → 重试请求 → 缓存文件兜底）
+func (p *CoinPool) GetSignals(name string) ([]SignalCoin, error) {
+	p.mu.RLock()
+	entry, ok := p.entries[name]
+	p.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("信号源不存在: %s", name)
+	}
+	return entry.get(p.cfg.CacheDir)
+}
+
+func (e *signalSourceEntry) get(cacheDir string) ([]SignalCoin, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.coins != nil && e.opts.TTL > 0 && time.Since(e.fetchedAt) < e.opts.TTL {
+		return e.coins, nil
+	}
+
+	name := e.source.Name()
+	maxRetries := 3
+	var lastErr error
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		if attempt > 1 {
+			log.Printf("⚠️  第%d次重试获取信号源 %s（共%d次）...", attempt, name, maxRetries)
+			time.Sleep(2 * time.Second)
+		}
+
+		coins, err := e.source.Fetch()
+		if err == nil {
+			coins = limitSignalCoins(coins, e.opts.Limit)
+			e.coins = coins
+			e.fetchedAt = time.Now()
+			if err := saveSignalCache(cacheDir, name, coins); err != nil {
+				log.Printf("⚠️  保存信号源 %s 缓存失败: %v", name, err)
+			}
+			return coins, nil
+		}
+
+		lastErr = err
+		log.Printf("❌ 第%d次请求信号源 %s 失败: %v", attempt, name, err)
+	}
+
+	// 请求全部失败：优先使用内存中的旧数据，其次使用缓存文件
+	if e.coins != nil {
+		log.Printf("⚠️  信号源 %s 请求失败，继续使用上次数据（%.1f分钟前）", name, time.Since(e.fetchedAt).Minutes())
+		return e.coins, nil
+	}
+	coins, err := loadSignalCache(cacheDir, name)
+	if err != nil {
+		return nil, fmt.Errorf("信号源 %s 不可用（最后错误: %v）: %w", name, lastErr, err)
+	}
+	return limitSignalCoins(coins, e.opts.Limit), nil
+}
+
+// limitSignalCoins 标准化币种符号、去重，并按评分取前N个
+func limitSignalCoins(coins []SignalCoin, limit int) []SignalCoin {
+	seen := make(map[string]bool, len(coins))
+	result := make([]SignalCoin, 0, len(coins))
+	for _, c := range coins {
+		symbol := normalizeSymbol(c.Symbol)
+		if symbol == "USDT" || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		result = append(result, SignalCoin{Symbol: symbol, Score: c.Score, Reason: c.Reason})
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Score > result[j].Score })
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// normalizeSignalScores 将原始评分归一化到 (0, 1]
+// 评分全部为正时按最大值缩放，否则按排名计算（第1名=1）
+func normalizeSignalScores(coins []SignalCoin) map[string]float64 {
+	scores := make(map[string]float64, len(coins))
+	if len(coins) == 0 {
+		return scores
+	}
+
+	maxScore := 0.0
+	allPositive := true
+	for _, c := range coins {
+		if c.Score <= 0 {
+			allPositive = false
+		}
+		if c.Score > maxScore {
+			maxScore = c.Score
+		}
+	}
+
+	n := float64(len(coins))
+	for i, c := range coins {
+		if allPositive && maxScore > 0 {
+			scores[c.Symbol] = c.Score / maxScore
+		} else {
+			scores[c.Symbol] = (n - float64(i)) / n
+		}
+	}
+	return scores
+}
+
+// saveSignalCache 保存信号源数据到缓存文件
+func saveSignalCache(cacheDir, name string, coins []SignalCoin) error {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return fmt.Errorf("创建缓存目录失败: %w", err)
+	}
+
+	data, err := json.MarshalIndent(signalSourceCache{
+		Source:    name,
+		Coins:     coins,
+		FetchedAt: time.Now(),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化缓存数据失败: %w", err)
+	}
+
+	if err := ioutil.WriteFile(signalCachePath(cacheDir, name), data, 0644); err != nil {
+		return fmt.Errorf("写入缓存文件失败: %w", err)
+	}
+	return nil
+}
+
+// loadSignalCache 从缓存文件加载信号源数据
+func loadSignalCache(cacheDir, name string) ([]SignalCoin, error) {
+	data, err := ioutil.ReadFile(signalCachePath(cacheDir, name))
+	if err != nil {
+		return nil, fmt.Errorf("读取缓存文件失败: %w", err)
+	}
+
+	var cache signalSourceCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("解析缓存数据失败: %w", err)
+	}
+
+	cacheAge := time.Since(cache.FetchedAt)
+	if cacheAge > 24*time.Hour {
+		log.Printf("⚠️  信号源 %s 缓存数据较旧（%.1f小时前），但仍可使用", name, cacheAge.Hours())
+	} else {
+		log.Printf("📂 信号源 %s 缓存数据时间: %s（%.1f分钟前）",
+			name, cache.FetchedAt.Format("2006-01-02 15:04:05"), cacheAge.Minutes())
+	}
+	return cache.Coins, nil
+}
+
+func signalCachePath(cacheDir, name string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == '.' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+	return filepath.Join(cacheDir, "signal_"+safe+".json")
+}
+
+// ========== 内置信号源 ==========
+
+// ai500Source AI500评分币种池
+type ai500Source struct {
+	pool *CoinPool
+}
+
+func (s *ai500Source) Name() string { return "ai500" }
+
+func (s *ai500Source) Fetch() ([]SignalCoin, error) {
+	coins, err := s.pool.fetchCoinPool()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]SignalCoin, 0, len(coins))
+	for _, coin := range coins {
+		if coin.IsAvailable {
+			result = append(result, SignalCoin{Symbol: coin.Pair, Score: coin.Score})
+		}
+	}
+	return result, nil
+}
+
+// oiTopSource 持仓量增长Top
+type oiTopSource struct {
+	pool *CoinPool
+}
+
+func (s *oiTopSource) Name() string { return "oi_top" }
+
+func (s *oiTopSource) Fetch() ([]SignalCoin, error) {
+	positions, err := s.pool.fetchOITop()
+	if err != nil {
+		return nil, err
+	}
+	// 按排名打分：第1名得分最高
+	result := make([]SignalCoin, 0, len(positions))
+	for _, pos := range positions {
+		result = append(result, SignalCoin{Symbol: pos.Symbol, Score: float64(len(positions) - pos.Rank + 1)})
+	}
+	return result, nil
+}
+
+// StaticSource 固定币种列表
+type StaticSource struct {
+	name    string
+	symbols []string
+}
+
+// NewStaticSource 创建固定币种列表信号源（所有币种评分相同）
+func NewStaticSource(name string, symbols []string) *StaticSource {
+	return &StaticSource{name: name, symbols: symbols}
+}
+
+func (s *StaticSource) Name() string { return s.name }
+
+func (s *StaticSource) Fetch() ([]SignalCoin, error) {
+	return symbolsToSignalCoins(s.symbols, 1), nil
+}
+
+// JSONFieldMapping JSON信号源的字段映射
+type JSONFieldMapping struct {
+	ListPath    string `json:"list_path"`    // 币种数组所在路径，点号分隔（如 "data.coins"，空表示根节点）
+	SymbolField string `json:"symbol_field"` // 币种字段名（默认 "symbol"）
+	ScoreField  string `json:"score_field"`  // 评分字段名（可选，缺省时按排名打分）
+}
+
+// JSONURLSource 通用JSON接口信号源
+type JSONURLSource struct {
+	name    string
+	url     string
+	mapping JSONFieldMapping
+	client  *http.Client
+}
+
+// NewJSONURLSource 创建通用JSON接口信号源
+func NewJSONURLSource(name, url string, mapping JSONFieldMapping) *JSONURLSource {
+	if mapping.SymbolField == "" {
+		mapping.SymbolField = "symbol"
+	}
+	return &JSONURLSource{
+		name:    name,
+		url:     url,
+		mapping: mapping,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *JSONURLSource) Name() string { return s.name }
+
+func (s *JSONURLSource) Fetch() ([]SignalCoin, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, fmt.Errorf("请求信号源API失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var root interface{}
+	if err := json.Unmarshal(body, &root); err != nil {
+		return nil, fmt.Errorf("JSON解析失败: %w", err)
+	}
+
+	node := root
+	if s.mapping.ListPath != "" {
+		for _, key := range strings.Split(s.mapping.ListPath, ".") {
+			obj, ok := node.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("路径 %s 无效: %s 不是对象", s.mapping.ListPath, key)
+			}
+			node = obj[key]
+		}
+	}
+	items, ok := node.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("路径 %s 不是数组", s.mapping.ListPath)
+	}
+
+	result := make([]SignalCoin, 0, len(items))
+	for i, item := range items {
+		coin := SignalCoin{Score: float64(len(items) - i)} // 缺省按排名打分
+		switch v := item.(type) {
+		case string:
+			coin.Symbol = v
+		case map[string]interface{}:
+			symbol, _ := v[s.mapping.SymbolField].(string)
+			coin.Symbol = symbol
+			if s.mapping.ScoreField != "" {
+				if score, ok := jsonNumber(v[s.mapping.ScoreField]); ok {
+					coin.Score = score
+				}
+			}
+		}
+		if coin.Symbol != "" {
+			result = append(result, coin)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("币种列表为空")
+	}
+	return result, nil
+}
+
+// jsonNumber 兼容数字和数字字符串
+func jsonNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func symbolsToSignalCoins(symbols []string, score float64) []SignalCoin {
+	coins := make([]SignalCoin, 0, len(symbols))
+	for _, symbol := range symbols {
+		coins = append(coins, SignalCoin{Symbol: symbol, Score: score})
+	}
+	return coins
+}
+
+// ========== 配置化注册 ==========
+
+// SignalSourceSpec 信号源配置（来自系统配置 signal_sources，JSON数组）
+type SignalSourceSpec struct {
+	Type       string   `json:"type"` // ai500 / oi_top / static / json_url / screener
+	Name       string   `json:"name"`
+	Weight     float64  `json:"weight"`
+	TTLSeconds int      `json:"ttl_seconds"`
+	Limit      int      `json:"limit"`
+	Disabled   bool     `json:"disabled"` // 为true时注销同名信号源（可用于关闭内置信号源）
+	Symbols    []string `json:"symbols"`  // static
+	URL        string   `json:"url"`      // json_url
+	JSONFieldMapping
+	Screener *ScreenerConfig `json:"screener"` // screener（为空时使用默认筛选条件）
+}
+
+// RegisterSignalSourcesFromJSON 按配置注册信号源
+func (p *CoinPool) RegisterSignalSourcesFromJSON(raw string) error {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	var specs []SignalSourceSpec
+	if err := json.Unmarshal([]byte(raw), &specs); err != nil {
+		return fmt.Errorf("解析信号源配置失败: %w", err)
+	}
+
+	for _, spec := range specs {
+		source, err := p.newSignalSourceFromSpec(spec)
+		if err != nil {
+			return err
+		}
+		if spec.Disabled {
+			p.UnregisterSignalSource(source.Name())
+			log.Printf("✓ 已禁用信号源: %s", source.Name())
+			continue
+		}
+		p.RegisterSignalSource(source, SourceOptions{
+			Weight: spec.Weight,
+			TTL:    time.Duration(spec.TTLSeconds) * time.Second,
+			Limit:  spec.Limit,
+		})
+	}
+	return nil
+}
+
+func (p *CoinPool) newSignalSourceFromSpec(spec SignalSourceSpec) (SignalSource, error) {
+	switch spec.Type {
+	case "ai500":
+		if strings.TrimSpace(p.cfg.APIURL) == "" && !spec.Disabled {
+			return nil, fmt.Errorf("ai500信号源未配置API URL")
+		}
+		return &ai500Source{pool: p}, nil
+	case "oi_top":
+		if strings.TrimSpace(p.cfg.OITopAPIURL) == "" && !spec.Disabled {
+			return nil, fmt.Errorf("oi_top信号源未配置API URL")
+		}
+		return &oiTopSource{pool: p}, nil
+	case "static":
+		if spec.Name == "" {
+			return nil, fmt.Errorf("static信号源缺少name")
+		}
+		return NewStaticSource(spec.Name, spec.Symbols), nil
+	case "json_url":
+		if spec.Name == "" || spec.URL == "" {
+			return nil, fmt.Errorf("json_url信号源缺少name或url")
+		}
+		return NewJSONURLSource(spec.Name, spec.URL, spec.JSONFieldMapping), nil
+	case "screener":
+		cfg := DefaultScreenerConfig()
+		if spec.Screener != nil {
+			cfg = *spec.Screener
+		}
+		return NewScreenerSource(cfg), nil
+	}
+	return nil, fmt.Errorf("不支持的信号源类型: %s", spec.Type)
+}
@@ -0,0 +1,262 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 权重统计窗口（各交易所均按分钟计算）
+	weightWindow = time.Minute
+	// 只使用官方限额的90%，为其它进程/手动操作留余量
+	safetyRatio = 0.9
+
+	// 熔断：连续失败次数阈值与冷却时间
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+)
+
+// 各交易所每分钟请求权重上限（官方文档）
+var defaultWeightLimits = map[string]int{
+	"binance":     2400,
+	"aster":       2400,
+	"hyperliquid": 1200,
+}
+
+// ErrCircuitOpen 熔断中，请求被直接拒绝
+var ErrCircuitOpen = errors.New("交易所API熔断中，暂停请求")
+
+// Stats 限流器使用情况（用于API展示）
+type Stats struct {
+	Name         string    `json:"name"`
+	Exchange     string    `json:"exchange"`
+	WeightLimit  int       `json:"weight_limit"`
+	UsedWeight   int       `json:"used_weight"` // 当前窗口已用权重（本地计数与服务端返回取较大值）
+	Requests     int64     `json:"requests"`
+	Retries      int64     `json:"retries"`
+	Throttled    int64     `json:"throttled"`    // 因权重不足而等待的次数
+	ThrottledMs  int64     `json:"throttled_ms"` // 累计等待时间
+	RateLimited  int64     `json:"rate_limited"` // 收到429的次数
+	Banned       int64     `json:"banned"`       // 收到418（IP封禁）的次数
+	Failures     int64     `json:"failures"`     // 最终失败次数（网络错误/5xx/限流）
+	CircuitOpen  bool      `json:"circuit_open"`
+	BlockedUntil time.Time `json:"blocked_until,omitempty"`
+}
+
+// Limiter 按交易所+API Key的权重限流器（带熔断与统计）
+type Limiter struct {
+	name     string
+	exchange string
+	limit    int
+
+	mu           sync.Mutex
+	windowStart  time.Time
+	used         int // 本地累计权重
+	serverUsed   int // 服务端返回的已用权重（X-MBX-USED-WEIGHT-1M）
+	blockedUntil time.Time
+
+	consecutiveFailures int
+	circuitOpenUntil    time.Time
+
+	stats Stats
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]*Limiter)
+)
+
+// Name 交易所+API Key对应的限流器名称（key 为空表示公共行情接口，不在统计中暴露API Key）
+func Name(exchange, key string) string {
+	if key == "" {
+		return exchange + ":public"
+	}
+	sum := sha256.Sum256([]byte(key))
+	return exchange + ":" + hex.EncodeToString(sum[:4])
+}
+
+// IsPublic 是否为公共行情接口的限流器（不属于任何账户）
+func IsPublic(name string) bool {
+	return strings.HasSuffix(name, ":public")
+}
+
+// For 获取交易所+API Key对应的限流器（同一Key的所有客户端共享）
+// key 为空表示公共行情接口
+func For(exchange, key string) *Limiter {
+	name := Name(exchange, key)
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if l, ok := registry[name]; ok {
+		return l
+	}
+
+	limit, ok := defaultWeightLimits[exchange]
+	if !ok {
+		limit = 1200
+	}
+	limit = int(float64(limit) * safetyRatio)
+
+	l := &Limiter{
+		name:        name,
+		exchange:    exchange,
+		limit:       limit,
+		windowStart: time.Now(),
+	}
+	registry[name] = l
+	return l
+}
+
+// Snapshot 返回所有限流器的使用情况
+func Snapshot() []Stats {
+	registryMu.Lock()
+	limiters := make([]*Limiter, 0, len(registry))
+	for _, l := range registry {
+		limiters = append(limiters, l)
+	}
+	registryMu.Unlock()
+
+	result := make([]Stats, 0, len(limiters))
+	for _, l := range limiters {
+		result = append(result, l.Stats())
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Stats 当前使用情况
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rollWindow(time.Now())
+	stats := l.stats
+	stats.Name = l.name
+	stats.Exchange = l.exchange
+	stats.WeightLimit = l.limit
+	stats.UsedWeight = l.currentUsed()
+	stats.CircuitOpen = time.Now().Before(l.circuitOpenUntil)
+	if time.Now().Before(l.blockedUntil) {
+		stats.BlockedUntil = l.blockedUntil
+	}
+	return stats
+}
+
+// Acquire 申请权重：熔断中直接返回错误，权重不足时等待下一个窗口
+func (l *Limiter) Acquire(ctx context.Context, weight int) error {
+	if weight <= 0 {
+		weight = 1
+	}
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		if now.Before(l.circuitOpenUntil) {
+			remaining := l.circuitOpenUntil.Sub(now)
+			l.mu.Unlock()
+			return fmt.Errorf("%w（%s，%.0f秒后恢复）", ErrCircuitOpen, l.name, remaining.Seconds())
+		}
+
+		l.rollWindow(now)
+		var wait time.Duration
+		switch {
+		case now.Before(l.blockedUntil):
+			wait = l.blockedUntil.Sub(now)
+		case l.currentUsed()+weight > l.limit:
+			wait = l.windowStart.Add(weightWindow).Sub(now)
+		default:
+			l.used += weight
+			l.stats.Requests++
+			l.mu.Unlock()
+			return nil
+		}
+		l.stats.Throttled++
+		l.stats.ThrottledMs += wait.Milliseconds()
+		l.mu.Unlock()
+
+		log.Printf("⏳ [%s] API权重不足，等待 %.1f 秒", l.name, wait.Seconds())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// SyncUsedWeight 同步服务端返回的已用权重
+func (l *Limiter) SyncUsedWeight(used int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rollWindow(time.Now())
+	if used > l.serverUsed {
+		l.serverUsed = used
+	}
+}
+
+// Block 在指定时间前暂停所有请求（429 Retry-After / 418 封禁）
+func (l *Limiter) Block(until time.Time, banned bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until.After(l.blockedUntil) {
+		l.blockedUntil = until
+	}
+	if banned {
+		l.stats.Banned++
+		log.Printf("🚫 [%s] IP被交易所封禁，暂停请求至 %s", l.name, until.Format("15:04:05"))
+	} else {
+		l.stats.RateLimited++
+		log.Printf("⚠️ [%s] 触发交易所限流(429)，暂停请求至 %s", l.name, until.Format("15:04:05"))
+	}
+}
+
+// RecordRetry 记录一次重试
+func (l *Limiter) RecordRetry() {
+	l.mu.Lock()
+	l.stats.Retries++
+	l.mu.Unlock()
+}
+
+// RecordResult 记录请求最终结果（failed=网络错误/5xx/限流等可重试类错误），驱动熔断器
+func (l *Limiter) RecordResult(failed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !failed {
+		l.consecutiveFailures = 0
+		return
+	}
+
+	l.stats.Failures++
+	l.consecutiveFailures++
+	if l.consecutiveFailures >= breakerThreshold {
+		l.circuitOpenUntil = time.Now().Add(breakerCooldown)
+		// 冷却结束后放行一次请求试探（半开），再失败则重新熔断
+		l.consecutiveFailures = breakerThreshold - 1
+		log.Printf("🔌 [%s] 连续失败%d次，熔断 %v", l.name, breakerThreshold, breakerCooldown)
+	}
+}
+
+// rollWindow 进入新窗口时重置计数（调用方持有锁）
+func (l *Limiter) rollWindow(now time.Time) {
+	if now.Sub(l.windowStart) >= weightWindow {
+		l.windowStart = now
+		l.used = 0
+		l.serverUsed = 0
+	}
+}
+
+func (l *Limiter) currentUsed() int {
+	if l.serverUsed > l.used {
+		return l.serverUsed
+	}
+	return l.used
+}
@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// 重试策略：签名请求带时间戳（recvWindow默认5秒），总退避时间需控制在窗口内
+	maxRetries     = 3
+	retryBaseDelay = 200 * time.Millisecond
+	retryMaxDelay  = 2 * time.Second
+)
+
+// HTTPError 非200响应
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// Backoff 第attempt次重试前的等待时间（指数退避 + 随机抖动）
+func Backoff(attempt int) time.Duration {
+	delay := retryBaseDelay << (attempt - 1)
+	if delay > retryMaxDelay || delay <= 0 {
+		delay = retryMaxDelay
+	}
+	// 抖动范围 [delay/2, delay)
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// isRetryableStatus 可重试的HTTP状态码
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests ||
+		status == http.StatusBadGateway ||
+		status == http.StatusServiceUnavailable ||
+		status == http.StatusGatewayTimeout ||
+		status == http.StatusInternalServerError
+}
+
+// IsNetworkError 网络层错误（超时、连接重置等）
+func IsNetworkError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "timeout") ||
+		strings.Contains(msg, "connection reset") ||
+		strings.Contains(msg, "connection refused") ||
+		strings.Contains(msg, "EOF")
+}
+
+// IsRetryable 错误是否值得重试
+// idempotent=false（下单等写操作）时只重试明确未被处理的请求（429），网络错误/5xx可能已成交，不重试
+func IsRetryable(err error, idempotent bool) bool {
+	if err == nil || errors.Is(err, ErrCircuitOpen) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		if httpErr.StatusCode == http.StatusTooManyRequests {
+			return true
+		}
+		return idempotent && isRetryableStatus(httpErr.StatusCode)
+	}
+	if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "Too Many Requests") {
+		return true
+	}
+	return idempotent && IsNetworkError(err)
+}
+
+// isFailure 是否计入熔断（业务错误如参数错误、余额不足不计入）
+func isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return isRetryableStatus(httpErr.StatusCode) || httpErr.StatusCode == http.StatusTeapot
+	}
+	return IsNetworkError(err) || strings.Contains(err.Error(), "429")
+}
+
+// Call 在限流器保护下执行SDK调用（只读查询，可重试）
+func Call[T any](ctx context.Context, l *Limiter, weight int, fn func() (T, error)) (T, error) {
+	return call(ctx, l, weight, true, fn)
+}
+
+// CallOnce 在限流器保护下执行写操作（下单/撤单，仅429时重试）
+func CallOnce[T any](ctx context.Context, l *Limiter, weight int, fn func() (T, error)) (T, error) {
+	return call(ctx, l, weight, false, fn)
+}
+
+func call[T any](ctx context.Context, l *Limiter, weight int, idempotent bool, fn func() (T, error)) (T, error) {
+	var zero T
+	for attempt := 0; ; attempt++ {
+		if err := l.Acquire(ctx, weight); err != nil {
+			return zero, err
+		}
+
+		result, err := fn()
+		if err == nil || attempt >= maxRetries || !IsRetryable(err, idempotent) {
+			l.RecordResult(isFailure(err))
+			return result, err
+		}
+
+		l.RecordRetry()
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case <-time.After(Backoff(attempt + 1)):
+		}
+	}
+}
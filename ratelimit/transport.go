@@ -0,0 +1,225 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Profile 交易所接口特性：请求权重与是否可安全重试
+type Profile struct {
+	Weight     func(req *http.Request) int
+	Idempotent func(req *http.Request) bool
+	// Retries 传输层重试次数（签名带一次性nonce的交易所设为0，由调用方重新签名后重试）
+	Retries int
+}
+
+// BinanceProfile 币安U本位合约（fapi）
+var BinanceProfile = Profile{
+	Weight:     binanceWeight,
+	Idempotent: isReadMethod,
+	Retries:    maxRetries,
+}
+
+// AsterProfile Aster（fapi兼容接口，nonce签名不可重放）
+var AsterProfile = Profile{
+	Weight:     binanceWeight,
+	Idempotent: isReadMethod,
+	Retries:    0,
+}
+
+// HyperliquidProfile Hyperliquid（/info 只读，/exchange 为写操作）
+var HyperliquidProfile = Profile{
+	Weight: hyperliquidWeight,
+	Idempotent: func(req *http.Request) bool {
+		return strings.HasSuffix(req.URL.Path, "/info")
+	},
+	Retries: maxRetries,
+}
+
+// WrapClient 返回带限流/重试/熔断的HTTP客户端（保留原客户端的超时等设置）
+func WrapClient(client *http.Client, l *Limiter, profile Profile) *http.Client {
+	if client == nil {
+		client = &http.Client{}
+	}
+	wrapped := *client
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped.Transport = &transport{base: base, limiter: l, profile: profile}
+	return &wrapped
+}
+
+type transport struct {
+	base    http.RoundTripper
+	limiter *Limiter
+	profile Profile
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	weight := 1
+	if t.profile.Weight != nil {
+		weight = t.profile.Weight(req)
+	}
+	idempotent := t.profile.Idempotent != nil && t.profile.Idempotent(req)
+	// 请求体无法重放时不重试
+	retries := t.profile.Retries
+	if req.Body != nil && req.GetBody == nil {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter.Acquire(req.Context(), weight); err != nil {
+			return nil, err
+		}
+
+		attemptReq := req
+		if attempt > 0 {
+			attemptReq = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				attemptReq.Body = body
+			}
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if err == nil {
+			t.observe(resp)
+		}
+
+		resultErr := err
+		if err == nil && resp.StatusCode != http.StatusOK && (isRetryableStatus(resp.StatusCode) || resp.StatusCode == http.StatusTeapot) {
+			resultErr = &HTTPError{StatusCode: resp.StatusCode}
+		}
+
+		if resultErr == nil || attempt >= retries || !IsRetryable(resultErr, idempotent) {
+			t.limiter.RecordResult(isFailure(resultErr))
+			return resp, err
+		}
+
+		// 重试前释放本次响应
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		t.limiter.RecordRetry()
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(Backoff(attempt + 1)):
+		}
+	}
+}
+
+// observe 读取响应中的限流信息
+func (t *transport) observe(resp *http.Response) {
+	if used := resp.Header.Get("X-MBX-USED-WEIGHT-1M"); used != "" {
+		if n, err := strconv.Atoi(used); err == nil {
+			t.limiter.SyncUsedWeight(n)
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusTeapot:
+		wait := time.Minute
+		if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && retryAfter > 0 {
+			wait = time.Duration(retryAfter) * time.Second
+		}
+		t.limiter.Block(time.Now().Add(wait), resp.StatusCode == http.StatusTeapot)
+	}
+}
+
+func isReadMethod(req *http.Request) bool {
+	return req.Method == http.MethodGet || req.Method == http.MethodHead
+}
+
+// binanceWeight 币安fapi接口权重（未列出的按1计算）
+func binanceWeight(req *http.Request) int {
+	path := req.URL.Path
+	query := req.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	hasSymbol := query.Get("symbol") != ""
+
+	switch {
+	case strings.HasSuffix(path, "/klines"):
+		switch {
+		case limit >= 1000:
+			return 10
+		case limit >= 500:
+			return 5
+		case limit >= 100:
+			return 2
+		}
+		return 1
+	case strings.HasSuffix(path, "/depth"):
+		switch {
+		case limit > 500:
+			return 20
+		case limit > 100:
+			return 10
+		case limit > 50:
+			return 5
+		}
+		return 2
+	case strings.HasSuffix(path, "/ticker/24hr"), strings.HasSuffix(path, "/openOrders"):
+		if hasSymbol {
+			return 1
+		}
+		return 40
+	case strings.HasSuffix(path, "/account"), strings.HasSuffix(path, "/balance"), strings.HasSuffix(path, "/positionRisk"):
+		return 5
+	case strings.HasSuffix(path, "/income"):
+		return 30
+	case strings.HasSuffix(path, "/allOrders"), strings.HasSuffix(path, "/userTrades"):
+		return 5
+	case strings.HasSuffix(path, "/premiumIndex"), strings.HasSuffix(path, "/ticker/price"):
+		if hasSymbol {
+			return 1
+		}
+		return 10
+	}
+	return 1
+}
+
+// hyperliquidWeight Hyperliquid接口权重：/exchange 为1，/info 轻量查询为2，其余20
+func hyperliquidWeight(req *http.Request) int {
+	if !strings.HasSuffix(req.URL.Path, "/info") {
+		return 1
+	}
+	if req.GetBody == nil {
+		return 20
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return 20
+	}
+	defer body.Close()
+	data, _ := io.ReadAll(body)
+
+	var payload struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(data), &payload); err != nil {
+		return 20
+	}
+	return HyperliquidInfoWeight(payload.Type)
+}
+
+// HyperliquidInfoWeight /info 查询类型对应的权重
+func HyperliquidInfoWeight(infoType string) int {
+	switch infoType {
+	case "l2Book", "allMids", "clearinghouseState", "orderStatus", "spotClearinghouseState", "exchangeStatus":
+		return 2
+	case "userRole":
+		return 60
+	}
+	return 20
+}
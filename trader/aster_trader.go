@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"net/url"
 	"nofx-lite/hook"
+	"nofx-lite/ratelimit"
 	"sort"
 	"strconv"
 	"strings"
@@ -69,6 +70,8 @@ func NewAsterTrader(user, signer, privateKeyHex string) (*AsterTrader, error) {
 	if res != nil && res.Error() == nil {
 		client = res.GetResult()
 	}
+	// nonce签名不可重放，传输层只做限流与熔断，重试由 request 重新签名后进行
+	client = ratelimit.WrapClient(client, ratelimit.For("aster", user), ratelimit.AsterProfile)
 
 	return &AsterTrader{
 		ctx:             context.Background(),
@@ -352,13 +355,12 @@ func (t *AsterTrader) request(method, endpoint string, params map[string]interfa
 
 		lastErr = err
 
-		// 如果是网络超时或临时错误，重试
-		if strings.Contains(err.Error(), "timeout") ||
-			strings.Contains(err.Error(), "connection reset") ||
-			strings.Contains(err.Error(), "EOF") {
+		// 网络超时/临时错误/限流时重试（每次重试重新签名）
+		// 写操作只在明确未被处理时（429）重试，结果不确定的下单由 placeOrderIdempotent 查询后决定
+		idempotent := strings.ToUpper(method) == "GET"
+		if ratelimit.IsRetryable(err, idempotent) {
 			if attempt < maxRetries {
-				waitTime := time.Duration(attempt) * time.Second
-				time.Sleep(waitTime)
+				time.Sleep(ratelimit.Backoff(attempt))
 				continue
 			}
 		}
@@ -396,7 +398,7 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 
 		body, _ := io.ReadAll(resp.Body)
 		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+			return nil, &ratelimit.HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
 		}
 		return body, nil
 
@@ -422,7 +424,7 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 
 		body, _ := io.ReadAll(resp.Body)
 		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+			return nil, &ratelimit.HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
 		}
 		return body, nil
 
@@ -432,7 +434,7 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 }
 
 // GetBalance 获取账户余额
-func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
+func (t *AsterTrader) GetBalance() (*AccountBalance, error) {
 	params := make(map[string]interface{})
 	body, err := t.request("GET", "/fapi/v3/balance", params)
 	if err != nil {
@@ -477,10 +479,10 @@ func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
 	if err != nil {
 		log.Printf("⚠️  获取持仓信息失败: %v", err)
 		// fallback: 无法获取持仓时使用简单计算
-		return map[string]interface{}{
-			"totalWalletBalance":    crossWalletBalance,
-			"availableBalance":      availableBalance,
-			"totalUnrealizedProfit": crossUnPnl,
+		return &AccountBalance{
+			TotalWalletBalance:    crossWalletBalance,
+			AvailableBalance:      availableBalance,
+			TotalUnrealizedProfit: crossUnPnl,
 		}, nil
 	}
 
@@ -489,20 +491,8 @@ func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
 	totalMarginUsed := 0.0
 	realUnrealizedPnl := 0.0
 	for _, pos := range positions {
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity
-		}
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		realUnrealizedPnl += unrealizedPnl
-
-		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
-		marginUsed := (quantity * markPrice) / float64(leverage)
-		totalMarginUsed += marginUsed
+		realUnrealizedPnl += pos.UnrealizedPnL
+		totalMarginUsed += pos.MarginUsed()
 	}
 
 	// ✅ Aster 正确计算方式:
@@ -512,15 +502,15 @@ func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
 	totalEquity := availableBalance + totalMarginUsed
 	totalWalletBalance := totalEquity - realUnrealizedPnl
 
-	return map[string]interface{}{
-		"totalWalletBalance":    totalWalletBalance, // 钱包余额（不含未实现盈亏）
-		"availableBalance":      availableBalance,   // 可用余额
-		"totalUnrealizedProfit": realUnrealizedPnl,  // 未实现盈亏（从持仓累加）
+	return &AccountBalance{
+		TotalWalletBalance:    totalWalletBalance, // 钱包余额（不含未实现盈亏）
+		AvailableBalance:      availableBalance,   // 可用余额
+		TotalUnrealizedProfit: realUnrealizedPnl,  // 未实现盈亏（从持仓累加）
 	}, nil
 }
 
 // GetPositions 获取持仓信息
-func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
+func (t *AsterTrader) GetPositions() ([]Position, error) {
 	params := make(map[string]interface{})
 	body, err := t.request("GET", "/fapi/v3/positionRisk", params)
 	if err != nil {
@@ -532,7 +522,7 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 		return nil, err
 	}
 
-	result := []map[string]interface{}{}
+	result := []Position{}
 	for _, pos := range positions {
 		posAmtStr, ok := pos["positionAmt"].(string)
 		if !ok {
@@ -557,16 +547,16 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 			posAmt = -posAmt
 		}
 
-		// 返回与Binance相同的字段名
-		result = append(result, map[string]interface{}{
-			"symbol":           pos["symbol"],
-			"side":             side,
-			"positionAmt":      posAmt,
-			"entryPrice":       entryPrice,
-			"markPrice":        markPrice,
-			"unRealizedProfit": unRealizedProfit,
-			"leverage":         leverageVal,
-			"liquidationPrice": liquidationPrice,
+		symbol, _ := pos["symbol"].(string)
+		result = append(result, Position{
+			Symbol:           symbol,
+			Side:             side,
+			Quantity:         posAmt,
+			EntryPrice:       entryPrice,
+			MarkPrice:        markPrice,
+			UnrealizedPnL:    unRealizedProfit,
+			Leverage:         int(leverageVal),
+			LiquidationPrice: liquidationPrice,
 		})
 	}
 
@@ -574,7 +564,7 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 }
 
 // OpenLong 开多单
-func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int, clientOrderID string) (*OrderResult, error) {
 	// 开仓前先取消所有挂单,防止残留挂单导致仓位叠加
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
@@ -585,6 +575,11 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (m
 		return nil, fmt.Errorf("设置杠杆失败: %w", err)
 	}
 
+	return t.IncreaseLong(symbol, quantity, clientOrderID)
+}
+
+// IncreaseLong 追加多单（不撤销已有挂单、不重设杠杆）
+func (t *AsterTrader) IncreaseLong(symbol string, quantity float64, clientOrderID string) (*OrderResult, error) {
 	// 获取当前价格
 	price, err := t.GetMarketPrice(symbol)
 	if err != nil {
@@ -627,21 +622,16 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (m
 		"price":        priceStr,
 	}
 
-	body, err := t.request("POST", "/fapi/v3/order", params)
+	result, err := t.placeOrder(symbol, params, clientOrderID)
 	if err != nil {
 		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
-	}
-
 	return result, nil
 }
 
 // OpenShort 开空单
-func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int, clientOrderID string) (*OrderResult, error) {
 	// 开仓前先取消所有挂单,防止残留挂单导致仓位叠加
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
@@ -652,6 +642,11 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (
 		return nil, fmt.Errorf("设置杠杆失败: %w", err)
 	}
 
+	return t.IncreaseShort(symbol, quantity, clientOrderID)
+}
+
+// IncreaseShort 追加空单（不撤销已有挂单、不重设杠杆）
+func (t *AsterTrader) IncreaseShort(symbol string, quantity float64, clientOrderID string) (*OrderResult, error) {
 	// 获取当前价格
 	price, err := t.GetMarketPrice(symbol)
 	if err != nil {
@@ -694,21 +689,16 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (
 		"price":        priceStr,
 	}
 
-	body, err := t.request("POST", "/fapi/v3/order", params)
+	result, err := t.placeOrder(symbol, params, clientOrderID)
 	if err != nil {
 		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
-	}
-
 	return result, nil
 }
 
 // CloseLong 平多单
-func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *AsterTrader) CloseLong(symbol string, quantity float64, clientOrderID string) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -717,8 +707,8 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 		}
 
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
+			if pos.Symbol == symbol && pos.Side == "long" {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -769,16 +759,11 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 		"price":        priceStr,
 	}
 
-	body, err := t.request("POST", "/fapi/v3/order", params)
+	result, err := t.placeOrder(symbol, params, clientOrderID)
 	if err != nil {
 		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
-	}
-
 	log.Printf("✓ 平多仓成功: %s 数量: %s", symbol, qtyStr)
 
 	// 平仓后取消该币种的所有挂单(止损止盈单)
@@ -790,7 +775,7 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 }
 
 // CloseShort 平空单
-func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *AsterTrader) CloseShort(symbol string, quantity float64, clientOrderID string) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -799,9 +784,9 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 		}
 
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
+			if pos.Symbol == symbol && pos.Side == "short" {
 				// Aster的GetPositions已经将空仓数量转换为正数，直接使用
-				quantity = pos["positionAmt"].(float64)
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -852,16 +837,11 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 		"price":        priceStr,
 	}
 
-	body, err := t.request("POST", "/fapi/v3/order", params)
+	result, err := t.placeOrder(symbol, params, clientOrderID)
 	if err != nil {
 		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
-	}
-
 	log.Printf("✓ 平空仓成功: %s 数量: %s", symbol, qtyStr)
 
 	// 平仓后取消该币种的所有挂单(止损止盈单)
@@ -872,6 +852,57 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 	return result, nil
 }
 
+// placeOrder 幂等下单（带newClientOrderId，结果不确定时先查询再重试）
+func (t *AsterTrader) placeOrder(symbol string, params map[string]interface{}, clientOrderID string) (*OrderResult, error) {
+	clientOrderID = ensureClientOrderID(clientOrderID)
+	params["newClientOrderId"] = clientOrderID
+	params["newOrderRespType"] = "RESULT" // 返回成交数量与均价
+
+	return placeOrderIdempotent(clientOrderID, func() (*OrderResult, error) {
+		body, err := t.request("POST", "/fapi/v3/order", params)
+		if err != nil {
+			return nil, err
+		}
+		return parseAsterOrderResult(body)
+	}, func() (*OrderResult, error) {
+		return t.queryOrderByClientID(symbol, clientOrderID)
+	})
+}
+
+// queryOrderByClientID 按clientOrderId查询订单（订单不存在返回 nil, nil）
+func (t *AsterTrader) queryOrderByClientID(symbol, clientOrderID string) (*OrderResult, error) {
+	params := map[string]interface{}{
+		"symbol":            symbol,
+		"origClientOrderId": clientOrderID,
+	}
+	body, err := t.request("GET", "/fapi/v3/order", params)
+	if err != nil {
+		// -2013 Order does not exist
+		if strings.Contains(err.Error(), "-2013") || strings.Contains(err.Error(), "Order does not exist") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return parseAsterOrderResult(body)
+}
+
+// parseAsterOrderResult 解析下单响应
+func parseAsterOrderResult(body []byte) (*OrderResult, error) {
+	var resp struct {
+		OrderID  int64  `json:"orderId"`
+		Symbol   string `json:"symbol"`
+		Status   string `json:"status"`
+		AvgPrice    string `json:"avgPrice"`
+		ExecutedQty string `json:"executedQty"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("解析下单响应失败: %w", err)
+	}
+	avgPrice, _ := strconv.ParseFloat(resp.AvgPrice, 64)
+	executedQty, _ := strconv.ParseFloat(resp.ExecutedQty, 64)
+	return &OrderResult{OrderID: resp.OrderID, Symbol: resp.Symbol, Status: resp.Status, AvgPrice: avgPrice, ExecutedQty: executedQty}, nil
+}
+
 // SetMarginMode 设置仓位模式
 func (t *AsterTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
 	// Aster支持仓位模式设置
@@ -1039,6 +1070,44 @@ func (t *AsterTrader) SetTakeProfit(symbol string, positionSide string, quantity
 	return err
 }
 
+// SetPartialTakeProfit 设置部分止盈单（reduceOnly，只平指定数量）
+func (t *AsterTrader) SetPartialTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	side := "SELL"
+	if positionSide == "SHORT" {
+		side = "BUY"
+	}
+
+	formattedPrice, err := t.formatPrice(symbol, takeProfitPrice)
+	if err != nil {
+		return err
+	}
+	formattedQty, err := t.formatQuantity(symbol, quantity)
+	if err != nil {
+		return err
+	}
+	prec, err := t.getPrecision(symbol)
+	if err != nil {
+		return err
+	}
+
+	priceStr := t.formatFloatWithPrecision(formattedPrice, prec.PricePrecision)
+	qtyStr := t.formatFloatWithPrecision(formattedQty, prec.QuantityPrecision)
+
+	params := map[string]interface{}{
+		"symbol":       symbol,
+		"positionSide": "BOTH",
+		"type":         "TAKE_PROFIT_MARKET",
+		"side":         side,
+		"stopPrice":    priceStr,
+		"quantity":     qtyStr,
+		"reduceOnly":   "true",
+		"timeInForce":  "GTC",
+	}
+
+	_, err = t.request("POST", "/fapi/v3/order", params)
+	return err
+}
+
 // CancelStopLossOrders 仅取消止损单（不影响止盈单）
 func (t *AsterTrader) CancelStopLossOrders(symbol string) error {
 	// 获取该币种的所有未完成订单
@@ -1330,3 +1399,152 @@ func (t *AsterTrader) FormatQuantity(symbol string, quantity float64) (string, e
 	}
 	return fmt.Sprintf("%v", formatted), nil
 }
+
+// GetTradableSymbols 获取可交易的USDT永续合约（status=TRADING 且 contractType=PERPETUAL）
+func (t *AsterTrader) GetTradableSymbols() (map[string]bool, error) {
+	resp, err := t.client.Get(t.baseURL + "/fapi/v3/exchangeInfo")
+	if err != nil {
+		return nil, fmt.Errorf("获取交易规则失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var info struct {
+		Symbols []struct {
+			Symbol       string `json:"symbol"`
+			Status       string `json:"status"`
+			ContractType string `json:"contractType"`
+			QuoteAsset   string `json:"quoteAsset"`
+		} `json:"symbols"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("解析交易规则失败: %w", err)
+	}
+
+	symbols := make(map[string]bool)
+	for _, s := range info.Symbols {
+		if s.Status == "TRADING" && s.ContractType == "PERPETUAL" && s.QuoteAsset == "USDT" {
+			symbols[s.Symbol] = true
+		}
+	}
+	return symbols, nil
+}
+
+// GetFundingFees 获取资金费净额（income类型FUNDING_FEE，正数为收取）
+func (t *AsterTrader) GetFundingFees(symbol string, since time.Time) (float64, error) {
+	params := map[string]interface{}{
+		"symbol":     symbol,
+		"incomeType": "FUNDING_FEE",
+		"startTime":  since.UnixMilli(),
+		"limit":      1000,
+	}
+	body, err := t.request("GET", "/fapi/v3/income", params)
+	if err != nil {
+		return 0, fmt.Errorf("获取资金费记录失败: %w", err)
+	}
+
+	var incomes []struct {
+		Income string `json:"income"`
+	}
+	if err := json.Unmarshal(body, &incomes); err != nil {
+		return 0, err
+	}
+
+	total := 0.0
+	for _, income := range incomes {
+		amount, _ := strconv.ParseFloat(income.Income, 64)
+		total += amount
+	}
+	return total, nil
+}
+
+// GetOpenOrders 获取所有币种的未成交挂单
+func (t *AsterTrader) GetOpenOrders() ([]OpenOrder, error) {
+	body, err := t.request("GET", "/fapi/v3/openOrders", map[string]interface{}{})
+	if err != nil {
+		return nil, fmt.Errorf("获取未完成订单失败: %w", err)
+	}
+
+	var orders []struct {
+		OrderID       int64  `json:"orderId"`
+		Symbol        string `json:"symbol"`
+		Type          string `json:"type"`
+		Side          string `json:"side"`
+		PositionSide  string `json:"positionSide"`
+		Price         string `json:"price"`
+		StopPrice     string `json:"stopPrice"`
+		OrigQty       string `json:"origQty"`
+		ReduceOnly    bool   `json:"reduceOnly"`
+		ClosePosition bool   `json:"closePosition"`
+	}
+	if err := json.Unmarshal(body, &orders); err != nil {
+		return nil, fmt.Errorf("解析订单数据失败: %w", err)
+	}
+
+	result := make([]OpenOrder, 0, len(orders))
+	for _, order := range orders {
+		price, _ := strconv.ParseFloat(order.Price, 64)
+		stopPrice, _ := strconv.ParseFloat(order.StopPrice, 64)
+		quantity, _ := strconv.ParseFloat(order.OrigQty, 64)
+		result = append(result, OpenOrder{
+			OrderID:      order.OrderID,
+			Symbol:       order.Symbol,
+			Type:         classifyOpenOrderType(order.Type),
+			PositionSide: openOrderPositionSide(order.PositionSide, order.Side),
+			Price:        price,
+			StopPrice:    stopPrice,
+			Quantity:     quantity,
+			ReduceOnly:   order.ReduceOnly || order.ClosePosition,
+		})
+	}
+	return result, nil
+}
+
+// GetMarginTiers 获取维持保证金阶梯（leverageBracket，带symbol时返回单个对象）
+func (t *AsterTrader) GetMarginTiers(symbol string) ([]MarginTier, error) {
+	body, err := t.request("GET", "/fapi/v3/leverageBracket", map[string]interface{}{
+		"symbol": symbol,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取杠杆分层失败: %w", err)
+	}
+
+	type leverageBracket struct {
+		Symbol   string `json:"symbol"`
+		Brackets []struct {
+			InitialLeverage  int     `json:"initialLeverage"`
+			NotionalCap      float64 `json:"notionalCap"`
+			NotionalFloor    float64 `json:"notionalFloor"`
+			MaintMarginRatio float64 `json:"maintMarginRatio"`
+			Cum              float64 `json:"cum"`
+		} `json:"brackets"`
+	}
+	var brackets []leverageBracket
+	if err := json.Unmarshal(body, &brackets); err != nil {
+		var single leverageBracket
+		if err := json.Unmarshal(body, &single); err != nil {
+			return nil, fmt.Errorf("解析杠杆分层失败: %w", err)
+		}
+		brackets = []leverageBracket{single}
+	}
+
+	var tiers []MarginTier
+	for _, b := range brackets {
+		if b.Symbol != symbol {
+			continue
+		}
+		for _, bracket := range b.Brackets {
+			tiers = append(tiers, MarginTier{
+				NotionalFloor:   bracket.NotionalFloor,
+				NotionalCap:     bracket.NotionalCap,
+				MaintMarginRate: bracket.MaintMarginRatio,
+				MaintAmount:     bracket.Cum,
+				MaxLeverage:     bracket.InitialLeverage,
+			})
+		}
+	}
+	return tiers, nil
+}
@@ -8,11 +8,14 @@ import (
 	"nofx-lite/decision"
 	"nofx-lite/logger"
 	"nofx-lite/market"
+	"nofx-lite/ratelimit"
 	"nofx-lite/mcp"
 	"nofx-lite/pool"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -41,7 +44,11 @@ type AutoTraderConfig struct {
 	AsterSigner     string // Aster API钱包地址
 	AsterPrivateKey string // Aster API钱包私钥
 
-	CoinPoolAPIURL string
+	// 信号源配置（每个交易员独立的币种池）
+	CoinPoolAPIURL  string // AI500币种池API（为空表示不启用）
+	OITopAPIURL     string // OI Top API（为空表示不启用）
+	UseDefaultCoins bool   // 未启用外部信号时，币种池是否使用默认币种（否则使用本地筛选器）
+	SignalSources   string // 额外信号源配置（JSON数组，见pool.SignalSourceSpec）
 
 	// AI配置
 	UseQwen     bool
@@ -77,6 +84,28 @@ type AutoTraderConfig struct {
 
 	// 系统提示词模板
 	SystemPromptTemplate string // 系统提示词模板名称（如 "default", "aggressive"）
+
+	// 按市场状态的风控参数（JSON，如 {"high_volatility":{"max_leverage":3,"position_size_factor":0.5}}）
+	RegimeRiskLimits string
+
+	// 开仓允许的最大预估滑点百分比（按订单簿深度估算，超出则缩减仓位或拒绝，0=默认0.5%）
+	MaxSlippagePct float64
+
+	// 相关性风控：相关系数阈值与同向高相关敞口上限（账户净值倍数），0=使用默认值
+	CorrelationThreshold  float64
+	MaxCorrelatedExposure float64
+
+	// 按持仓时间的退出规则（JSON，如 {"max_holding_hours":48,"stale_hours":12,"funding_exit_minutes":30}）
+	TimeExitRules string
+
+	// 交易时段（JSON，允许开仓的时段 + 禁止开仓时段 + 本地事件日历）
+	TradingSchedule string
+
+	// 启动对账策略（JSON，无止损持仓的处理方式）
+	ReconcilePolicy string
+
+	// 仓位计算模型（JSON，ai / fixed_risk / volatility / kelly）
+	PositionSizing string
 }
 
 // AutoTrader 自动交易器
@@ -87,6 +116,7 @@ type AutoTrader struct {
 	exchange              string // 交易平台名称
 	config                AutoTraderConfig
 	trader                Trader // 使用Trader接口（支持多平台）
+	marketSource          market.MarketDataSource // 与交易平台一致的行情数据源
 	mcpClient             *mcp.Client
 	decisionLogger        *logger.DecisionLogger // 决策日志记录器
 	initialBalance        float64
@@ -96,16 +126,44 @@ type AutoTrader struct {
 	systemPromptTemplate  string   // 系统提示词模板名称
 	defaultCoins          []string // 默认币种列表（从数据库获取）
 	tradingCoins          []string // 实际交易币种列表
+	regimeRiskLimits      decision.RegimeRiskLimits // 按市场状态的风控参数
+	timeExitRules         *TimeExitRules            // 按持仓时间的退出规则（nil表示不启用）
+	tradingSchedule       *TradingSchedule          // 交易时段（nil表示不限制开仓时间）
+	reconcilePolicy       *ReconcilePolicy          // 启动对账策略（nil表示只标记无止损持仓）
+	positionSizing        *PositionSizing           // 仓位计算模型（nil表示使用AI建议仓位）
+	coinPool              *pool.CoinPool            // 交易员独立的币种池
+	symbolUniverse        *SymbolUniverse           // 可交易币种范围（上架状态 + 用户黑白名单）
 	lastResetTime         time.Time
 	stopUntil             time.Time
-	isRunning             bool
+	isRunning             atomic.Bool // 主循环运行状态（HTTP请求goroutine也会读取）
 	startTime             time.Time          // 系统启动时间
 	callCount             int                // AI调用次数
 	positionFirstSeenTime map[string]int64   // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
+	positionBuildUp       map[string][]logger.PositionLeg // 持仓建仓历史 (symbol_side -> 开仓+加仓记录)
+	takeProfitLadders     map[string]*takeProfitLadder    // 多级止盈状态 (symbol_side -> 止盈阶梯)
+	positionStopLoss      map[string]float64              // 持仓当前止损价 (symbol_side -> 价格)
+	positionStateMutex    sync.RWMutex                    // 保护 positionFirstSeenTime / positionStopLoss / timeExitTightened（后台监控读写）
+	timeExitTightened     map[string]bool                 // 已因无进展收紧（或已提交收紧）止损的持仓
+	liquidationAlertTime  map[string]time.Time            // 上次强平预警时间（仅后台监控使用）
+	marginTiers           map[string]marginTierCacheEntry // 维持保证金阶梯缓存 (symbol -> 阶梯)
+	marginTiersMutex      sync.Mutex                      // 保护 marginTiers
 	stopMonitorCh         chan struct{}      // 用于停止监控goroutine
+	cycleTriggerCh        chan struct{}      // 外部信号触发立即执行
+	tradeEventCh          chan TradeEvent    // 交易所推送的成交/止盈止损事件
+	staleStopCh           chan staleStopRequest // 后台监控提交的无进展收紧止损（主循环执行）
+	manualDecisionCh      chan *manualDecisionRequest // 人工决策（API下达，主循环串行执行）
+	pendingManualActions  []decision.ManualAction     // 上次AI决策后执行的人工操作（下个周期告知AI）
+	manualOrderSeq        int                         // 人工决策序号（生成独立于AI周期的订单ID）
+	activeManualSeq       int                         // 正在执行的人工决策序号（0表示AI决策周期）
+	lastCycleTime         time.Time          // 上次决策周期开始时间
 	monitorWg             sync.WaitGroup     // 用于等待监控goroutine结束
 	peakPnLCache          map[string]float64 // 最高收益缓存 (symbol -> 峰值盈亏百分比)
 	peakPnLCacheMutex     sync.RWMutex       // 缓存读写锁
+	positionFunding       map[string]float64   // 持仓期间累计资金费 (symbol_side -> USDT，正数=收取)
+	positionFundingTime   map[string]time.Time // 资金费上次刷新时间
+	positionFundingMutex  sync.RWMutex
+	executions            []*ExecutionProgress // 最近的开仓执行进度（拆单时汇总子单）
+	executionsMutex       sync.RWMutex
     lastBalanceSyncTime   time.Time          // 上次余额同步时间
     database              interface{}        // 数据库引用（用于自动更新余额）
     userID                string             // 用户ID
@@ -114,6 +172,9 @@ type AutoTrader struct {
     equityPeak            float64            // Peak equity since start/reset
     drawdownBreachCount   map[string]int     // Consecutive drawdown breach counts (symbol_side -> count)
     drawdownBreachWindow  int                // Required consecutive checks to trigger emergency close
+    runtimeState          runtimeState       // 持久化的运行时状态快照（主循环与后台监控各自更新自己负责的字段）
+    runtimeStateMutex     sync.Mutex
+    savedRuntimeState     string             // 上次写入数据库的状态JSON（未变化时跳过写入）
 }
 
 // NewAutoTrader 创建自动交易器
@@ -158,10 +219,15 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		}
 	}
 
-	// 初始化币种池API
-	if config.CoinPoolAPIURL != "" {
-		pool.SetCoinPoolAPI(config.CoinPoolAPIURL)
-	}
+	// 初始化币种池（每个交易员独立的信号源与缓存目录）
+	coinPool := pool.NewCoinPool(pool.CoinPoolConfig{
+		APIURL:          config.CoinPoolAPIURL,
+		OITopAPIURL:     config.OITopAPIURL,
+		CacheDir:        filepath.Join("coin_pool_cache", config.ID),
+		UseDefaultCoins: config.UseDefaultCoins,
+		DefaultCoins:    config.DefaultCoins,
+		SignalSources:   config.SignalSources,
+	})
 
 	// 设置默认交易平台
 	if config.Exchange == "" {
@@ -199,6 +265,14 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		return nil, fmt.Errorf("不支持的交易平台: %s", config.Exchange)
 	}
 
+	// 行情数据源与执行交易所保持一致
+	testnet := config.BinanceTestnet
+	if config.Exchange == "hyperliquid" {
+		testnet = config.HyperliquidTestnet
+	}
+	marketSource := market.NewMarketDataSource(config.Exchange, testnet)
+	log.Printf("📈 [%s] 行情数据源: %s", config.Name, marketSource.Name())
+
 	// 验证初始金额配置
 	if config.InitialBalance <= 0 {
 		return nil, fmt.Errorf("初始金额必须大于0，请在配置中设置InitialBalance")
@@ -221,6 +295,10 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		log.Printf("✓ [%s] 决策日志目录已就绪: %s", config.Name, logDir)
 	}
 
+	// 可交易币种范围（交易所支持查询时校验上架状态）
+	universeProvider, _ := trader.(SymbolUniverseProvider)
+	symbolUniverse := NewSymbolUniverse(universeProvider)
+
 	// 设置默认系统提示词模板
 	systemPromptTemplate := config.SystemPromptTemplate
 	if systemPromptTemplate == "" {
@@ -228,28 +306,94 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		systemPromptTemplate = "adaptive"
 	}
 
-	return &AutoTrader{
+	// 解析按市场状态的风控参数（解析失败时不启用）
+	regimeRiskLimits, err := decision.ParseRegimeRiskLimits(config.RegimeRiskLimits)
+	if err != nil {
+		log.Printf("⚠️ [%s] %v，忽略市场状态风控配置", config.Name, err)
+	} else if len(regimeRiskLimits) > 0 {
+		log.Printf("✓ [%s] 已启用市场状态风控: %d 个状态", config.Name, len(regimeRiskLimits))
+	}
+
+	// 解析按持仓时间的退出规则（解析失败时不启用）
+	timeExitRules, err := ParseTimeExitRules(config.TimeExitRules)
+	if err != nil {
+		log.Printf("⚠️ [%s] %v，忽略持仓时间退出规则", config.Name, err)
+		timeExitRules = nil
+	} else if timeExitRules.Enabled() {
+		log.Printf("✓ [%s] 已启用持仓时间退出规则: 最长持仓 %.1fh | 无进展收紧 %.1fh | 资金费结算前 %d 分钟",
+			config.Name, timeExitRules.MaxHoldingHours, timeExitRules.StaleHours, timeExitRules.FundingExitMinutes)
+	}
+
+	// 解析交易时段（解析失败时不限制开仓时间）
+	tradingSchedule, err := ParseTradingSchedule(config.TradingSchedule)
+	if err != nil {
+		log.Printf("⚠️ [%s] %v，忽略交易时段配置", config.Name, err)
+		tradingSchedule = nil
+	} else if tradingSchedule.Enabled() {
+		log.Printf("✓ [%s] 已启用交易时段: %d 个交易时段 | %d 个禁止时段 | 事件日历: %s",
+			config.Name, len(tradingSchedule.Windows), len(tradingSchedule.Blackouts), tradingSchedule.CalendarFile)
+		if err := tradingSchedule.ValidateCalendarFile(); err != nil {
+			log.Printf("⚠️ [%s] %v，交易时段与禁止时段仍然生效，事件日历稍后重试加载", config.Name, err)
+		}
+	}
+
+	// 解析启动对账策略（解析失败时只标记不处理）
+	reconcilePolicy, err := ParseReconcilePolicy(config.ReconcilePolicy)
+	if err != nil {
+		log.Printf("⚠️ [%s] %v，无止损持仓只标记不处理", config.Name, err)
+		reconcilePolicy = nil
+	}
+
+	// 解析仓位计算模型（解析失败时使用AI建议仓位）
+	positionSizing, err := ParsePositionSizing(config.PositionSizing)
+	if err != nil {
+		log.Printf("⚠️ [%s] %v，使用AI建议仓位", config.Name, err)
+		positionSizing = nil
+	} else if positionSizing.Enabled() {
+		log.Printf("✓ [%s] 仓位计算模型: %s", config.Name, positionSizing.describe())
+	}
+
+	at := &AutoTrader{
 		id:                    config.ID,
 		name:                  config.Name,
 		aiModel:               config.AIModel,
 		exchange:              config.Exchange,
 		config:                config,
 		trader:                trader,
+		marketSource:          marketSource,
 		mcpClient:             mcpClient,
 		decisionLogger:        decisionLogger,
 		initialBalance:        config.InitialBalance,
 		systemPromptTemplate:  systemPromptTemplate,
 		defaultCoins:          config.DefaultCoins,
 		tradingCoins:          config.TradingCoins,
+		regimeRiskLimits:      regimeRiskLimits,
+		timeExitRules:         timeExitRules,
+		tradingSchedule:       tradingSchedule,
+		reconcilePolicy:       reconcilePolicy,
+		positionSizing:        positionSizing,
+		coinPool:              coinPool,
+		symbolUniverse:        symbolUniverse,
+		cycleTriggerCh:        make(chan struct{}, 1),
+		tradeEventCh:          make(chan TradeEvent, 64),
+		staleStopCh:           make(chan staleStopRequest, 16),
+		manualDecisionCh:      make(chan *manualDecisionRequest),
 		lastResetTime:         time.Now(),
 		startTime:             time.Now(),
 		callCount:             0,
-		isRunning:             false,
 		positionFirstSeenTime: make(map[string]int64),
+		positionBuildUp:       make(map[string][]logger.PositionLeg),
+		takeProfitLadders:     make(map[string]*takeProfitLadder),
+		positionStopLoss:      make(map[string]float64),
+		timeExitTightened:     make(map[string]bool),
+		liquidationAlertTime:  make(map[string]time.Time),
+		marginTiers:           make(map[string]marginTierCacheEntry),
 		stopMonitorCh:         make(chan struct{}),
 		monitorWg:             sync.WaitGroup{},
 		peakPnLCache:          make(map[string]float64),
 		peakPnLCacheMutex:     sync.RWMutex{},
+		positionFunding:       make(map[string]float64),
+		positionFundingTime:   make(map[string]time.Time),
         lastBalanceSyncTime:   time.Now(), // 初始化为当前时间
         database:              database,
         userID:                userID,
@@ -257,12 +401,17 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
         equityPeak:            config.InitialBalance,
         drawdownBreachCount:   make(map[string]int),
         drawdownBreachWindow:  3,
-    }, nil
+    }
+
+	// 恢复上次运行保存的风控暂停、峰值盈亏、持仓时间等状态
+	at.restoreRuntimeState()
+
+	return at, nil
 }
 
 // Run 运行自动交易主循环
 func (at *AutoTrader) Run() error {
-	at.isRunning = true
+	at.isRunning.Store(true)
 	at.stopMonitorCh = make(chan struct{})
 	at.startTime = time.Now()
 
@@ -289,9 +438,17 @@ func (at *AutoTrader) Run() error {
 	at.monitorWg.Add(1)
 	defer at.monitorWg.Done()
 
+	// 启动对账：核对持仓与挂单，接管外部持仓
+	at.reconcilePositions()
+
 	// 启动回撤监控
 	at.startDrawdownMonitor()
 
+	// 启动实时账户推送（交易所支持时）
+	if stop := at.startAccountStream(); stop != nil {
+		defer stop()
+	}
+
 	ticker := time.NewTicker(at.config.ScanInterval)
 	defer ticker.Stop()
 
@@ -300,12 +457,26 @@ func (at *AutoTrader) Run() error {
 		log.Printf("❌ 执行失败: %v", err)
 	}
 
-	for at.isRunning {
+	for at.isRunning.Load() {
 		select {
 		case <-ticker.C:
 			if err := at.runCycle(); err != nil {
 				log.Printf("❌ 执行失败: %v", err)
 			}
+		case <-at.cycleTriggerCh:
+			if since := time.Since(at.lastCycleTime); since < minTriggerInterval {
+				log.Printf("⏳ [%s] 距上次决策仅 %.0f 秒，忽略立即执行请求", at.name, since.Seconds())
+				continue
+			}
+			if err := at.runCycle(); err != nil {
+				log.Printf("❌ 执行失败: %v", err)
+			}
+		case event := <-at.tradeEventCh:
+			at.handleTradeEvent(event)
+		case req := <-at.staleStopCh:
+			at.applyStaleStop(req)
+		case req := <-at.manualDecisionCh:
+			at.handleManualDecision(req)
 		case <-at.stopMonitorCh:
 			log.Printf("[%s] ⏹ 收到停止信号，退出自动交易主循环", at.name)
 			return nil
@@ -317,12 +488,13 @@ func (at *AutoTrader) Run() error {
 
 // Stop 停止自动交易
 func (at *AutoTrader) Stop() {
-	if !at.isRunning {
+	// CompareAndSwap 保证并发调用Stop时只关闭一次停止通道
+	if !at.isRunning.CompareAndSwap(true, false) {
 		return
 	}
-	at.isRunning = false
 	close(at.stopMonitorCh) // 通知监控goroutine停止
 	at.monitorWg.Wait()     // 等待监控goroutine结束
+	at.checkpointStopState()
 	log.Println("⏹ 自动交易系统停止")
 }
 
@@ -373,18 +545,12 @@ func (at *AutoTrader) autoSyncBalanceIfNeeded() {
 	at.updateLastSyncTime()
 }
 
-// extractBalance 从余额信息中提取有效余额值
-func (at *AutoTrader) extractBalance(balanceInfo map[string]interface{}) float64 {
-	// 定义可能的余额字段及其优先级
-	balanceFields := []string{"available_balance", "availableBalance", "balance"}
-	
-	for _, field := range balanceFields {
-		if value, ok := balanceInfo[field].(float64); ok && value > 0 {
-			return value
-		}
+// extractBalance 从余额信息中提取有效余额值（可用余额）
+func (at *AutoTrader) extractBalance(balanceInfo *AccountBalance) float64 {
+	if balanceInfo == nil || balanceInfo.AvailableBalance <= 0 {
+		return 0
 	}
-	
-	return 0
+	return balanceInfo.AvailableBalance
 }
 
 // handleInvalidInitialBalance 处理初始余额无效的情况
@@ -435,6 +601,8 @@ func (at *AutoTrader) updateLastSyncTime() {
 // runCycle 运行一个交易周期（使用AI全权决策）
 func (at *AutoTrader) runCycle() error {
 	at.callCount++
+	at.lastCycleTime = time.Now()
+	defer at.checkpointCycleState()
 
 	log.Print("\n" + strings.Repeat("=", 70) + "\n")
 	log.Printf("⏰ %s - AI决策周期 #%d", time.Now().Format("2006-01-02 15:04:05"), at.callCount)
@@ -525,6 +693,23 @@ func (at *AutoTrader) runCycle() error {
         return nil
     }
 
+	// 交易时段：禁止开仓时只管理现有持仓，没有持仓则跳过本周期
+	if reason := at.entryBlockReason(); reason != "" {
+		ctx.EntriesDisabledReason = reason
+		log.Printf("⛔ 当前禁止开仓: %s", reason)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("Entries disabled: %s", reason))
+		if len(ctx.Positions) == 0 {
+			log.Printf("⏸ 无持仓需要管理，跳过本周期AI决策")
+			if err := at.decisionLogger.LogDecision(record); err != nil {
+				log.Printf("⚠ 保存决策记录失败: %v", err)
+			}
+			return nil
+		}
+	}
+
+	// 上次决策后的人工操作告知AI
+	ctx.ManualActions = at.takeManualActions()
+
     // 5. 调用AI获取完整决策
     log.Printf("🤖 正在请求AI分析并决策... [模板: %s]", at.systemPromptTemplate)
     decision, err := decision.GetFullDecisionWithCustomPrompt(ctx, at.mcpClient, at.customPrompt, at.overrideBasePrompt, at.systemPromptTemplate)
@@ -543,6 +728,8 @@ func (at *AutoTrader) runCycle() error {
 	if err != nil {
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("获取AI决策失败: %v", err)
+		// AI未能给出决策，人工操作留到下个周期再告知
+		at.pendingManualActions = append(ctx.ManualActions, at.pendingManualActions...)
 
 		// 打印系统提示词和AI思维链（即使有错误，也要输出以便调试）
 		if decision != nil {
@@ -582,7 +769,7 @@ func (at *AutoTrader) runCycle() error {
 	sortedDecisions := sortDecisionsByPriority(decision.Decisions)
 
 	// 在执行前，根据最近表现与风险预算，对开仓决策进行仓位大小优化与保护
-	sortedDecisions = at.adjustDecisionsByPerformance(sortedDecisions, ctx, recentPerf, record)
+	sortedDecisions, sizingRecords := at.adjustDecisionsByPerformance(sortedDecisions, ctx, recentPerf, record)
 
     log.Println("🔄 Execution order (optimized): close first → open later")
     for i, d := range sortedDecisions {
@@ -591,7 +778,7 @@ func (at *AutoTrader) runCycle() error {
 	log.Println()
 
 	// 执行决策并记录结果
-	for _, d := range sortedDecisions {
+	for i, d := range sortedDecisions {
 		actionRecord := logger.DecisionAction{
 			Action:    d.Action,
 			Symbol:    d.Symbol,
@@ -600,6 +787,38 @@ func (at *AutoTrader) runCycle() error {
 			Price:     0,
 			Timestamp: time.Now(),
 			Success:   false,
+			Sizing:    sizingRecords[i],
+		}
+
+		// 禁止开仓期间跳过开仓/加仓（AI仍输出时兜底）
+		if ctx.EntriesDisabledReason != "" && isEntryAction(d.Action) {
+			log.Printf("⛔ 跳过 %s %s: 当前禁止开仓（%s）", d.Symbol, d.Action, ctx.EntriesDisabledReason)
+			actionRecord.Error = fmt.Sprintf("当前禁止开仓: %s", ctx.EntriesDisabledReason)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✗ %s %s skipped: entries disabled", d.Symbol, d.Action))
+			record.Decisions = append(record.Decisions, actionRecord)
+			continue
+		}
+
+		// 仓位模型给出0仓位（如凯利比例为负）时不开仓
+		if sizing := actionRecord.Sizing; sizing != nil && sizing.Model != SizingModelAI && sizing.SizeUSD <= 0 {
+			log.Printf("⛔ 跳过 %s %s: 仓位模型 %s 计算仓位为0（%s）", d.Symbol, d.Action, sizing.Model, sizing.Note)
+			actionRecord.Error = fmt.Sprintf("仓位模型 %s 计算仓位为0: %s", sizing.Model, sizing.Note)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✗ %s %s skipped: zero size from sizing model", d.Symbol, d.Action))
+			record.Decisions = append(record.Decisions, actionRecord)
+			continue
+		}
+
+		// 仓位调整后按最终仓位重新校验止损与强平价格的距离（必要时降低杠杆）
+		if err := at.checkEntryLiquidation(&d, ctx); err != nil {
+			log.Printf("⛔ 跳过 %s %s: %v", d.Symbol, d.Action, err)
+			actionRecord.Error = err.Error()
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✗ %s %s skipped: stop too close to liquidation", d.Symbol, d.Action))
+			record.Decisions = append(record.Decisions, actionRecord)
+			continue
+		}
+		if d.Leverage != actionRecord.Leverage {
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("Leverage %s reduced %dx -> %dx to keep stop inside liquidation", d.Symbol, actionRecord.Leverage, d.Leverage))
+			actionRecord.Leverage = d.Leverage
 		}
 
         if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
@@ -683,23 +902,10 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		return nil, fmt.Errorf("获取账户余额失败: %w", err)
 	}
 
-	// 获取账户字段
-	totalWalletBalance := 0.0
-	totalUnrealizedProfit := 0.0
-	availableBalance := 0.0
-
-	if wallet, ok := balance["totalWalletBalance"].(float64); ok {
-		totalWalletBalance = wallet
-	}
-	if unrealized, ok := balance["totalUnrealizedProfit"].(float64); ok {
-		totalUnrealizedProfit = unrealized
-	}
-	if avail, ok := balance["availableBalance"].(float64); ok {
-		availableBalance = avail
-	}
+	availableBalance := balance.AvailableBalance
 
 	// Total Equity = 钱包余额 + 未实现盈亏
-	totalEquity := totalWalletBalance + totalUnrealizedProfit
+	totalEquity := balance.TotalEquity()
 
 	// 2. 获取持仓信息
 	positions, err := at.trader.GetPositions()
@@ -713,30 +919,27 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	// 当前持仓的key集合（用于清理已平仓的记录）
 	currentPositionKeys := make(map[string]bool)
 
+	// 统计每个币种的持仓方向数（双向持仓同时有多空时资金费无法按方向拆分）
+	symbolSides := make(map[string]int)
 	for _, pos := range positions {
-		symbol := pos["symbol"].(string)
-		side := pos["side"].(string)
-		entryPrice := pos["entryPrice"].(float64)
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity // 空仓数量为负，转为正数
+		if pos.Quantity != 0 {
+			symbolSides[pos.Symbol]++
 		}
+	}
+
+	for _, pos := range positions {
+		symbol := pos.Symbol
+		side := pos.Side
 
 		// 跳过已平仓的持仓（quantity = 0），防止"幽灵持仓"传递给AI
-		if quantity == 0 {
+		if pos.Quantity == 0 {
 			continue
 		}
 
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		liquidationPrice := pos["liquidationPrice"].(float64)
+		unrealizedPnl := pos.UnrealizedPnL
 
 		// 计算占用保证金（估算）
-		leverage := 10 // 默认值，实际应该从持仓信息获取
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
-		marginUsed := (quantity * markPrice) / float64(leverage)
+		marginUsed := pos.MarginUsed()
 		totalMarginUsed += marginUsed
 
 		// 计算盈亏百分比（基于保证金，考虑杠杆）
@@ -745,39 +948,74 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		// 跟踪持仓首次出现时间
 		posKey := symbol + "_" + side
 		currentPositionKeys[posKey] = true
+		at.positionStateMutex.Lock()
 		if _, exists := at.positionFirstSeenTime[posKey]; !exists {
 			// 新持仓，记录当前时间
 			at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
 		}
 		updateTime := at.positionFirstSeenTime[posKey]
+		at.positionStateMutex.Unlock()
 
 		// 获取该持仓的历史最高收益率
 		at.peakPnLCacheMutex.RLock()
 		peakPnlPct := at.peakPnLCache[symbol]
 		at.peakPnLCacheMutex.RUnlock()
 
+		// 持仓期间累计资金费
+		fundingFee := at.refreshPositionFunding(symbol, side, updateTime, symbolSides[symbol] > 1)
+
 		positionInfos = append(positionInfos, decision.PositionInfo{
 			Symbol:           symbol,
 			Side:             side,
-			EntryPrice:       entryPrice,
-			MarkPrice:        markPrice,
-			Quantity:         quantity,
-			Leverage:         leverage,
+			EntryPrice:       pos.EntryPrice,
+			MarkPrice:        pos.MarkPrice,
+			Quantity:         pos.Quantity,
+			Leverage:         pos.EffectiveLeverage(),
 			UnrealizedPnL:    unrealizedPnl,
 			UnrealizedPnLPct: pnlPct,
 			PeakPnLPct:       peakPnlPct,
-			LiquidationPrice: liquidationPrice,
+			LiquidationPrice: pos.LiquidationPrice,
 			MarginUsed:       marginUsed,
+			FundingFee:       fundingFee,
 			UpdateTime:       updateTime,
+			AddCount:         at.positionAddCount(posKey),
 		})
+
+		// 按持仓数量推进止盈阶梯（无实时推送时靠这里识别已成交档位）
+		at.syncTakeProfitLadder(posKey, symbol, &pos)
 	}
 
 	// 清理已平仓的持仓记录
+	at.positionStateMutex.Lock()
 	for key := range at.positionFirstSeenTime {
 		if !currentPositionKeys[key] {
 			delete(at.positionFirstSeenTime, key)
 		}
 	}
+	for key := range at.positionStopLoss {
+		if !currentPositionKeys[key] {
+			delete(at.positionStopLoss, key)
+		}
+	}
+	at.positionStateMutex.Unlock()
+	for key := range at.positionBuildUp {
+		if !currentPositionKeys[key] {
+			delete(at.positionBuildUp, key)
+		}
+	}
+	for key := range at.takeProfitLadders {
+		if !currentPositionKeys[key] {
+			delete(at.takeProfitLadders, key)
+		}
+	}
+	at.positionFundingMutex.Lock()
+	for key := range at.positionFunding {
+		if !currentPositionKeys[key] {
+			delete(at.positionFunding, key)
+			delete(at.positionFundingTime, key)
+		}
+	}
+	at.positionFundingMutex.Unlock()
 
 	// 3. 获取交易员的候选币种池
 	candidateCoins, err := at.getCandidateCoins()
@@ -814,6 +1052,13 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		BTCETHLeverage:  at.config.BTCETHLeverage,  // 使用配置的杠杆倍数
 		AltcoinLeverage: at.config.AltcoinLeverage, // 使用配置的杠杆倍数
 		UseTestnet:      at.config.BinanceTestnet,  // 使用测试网配置
+		DataSource:      at.marketSource,           // 与交易所一致的行情数据源
+		RegimeRiskLimits: at.regimeRiskLimits,      // 按市场状态的风控参数
+		CorrelationThreshold:  at.config.CorrelationThreshold,  // 相关系数阈值
+		MaxCorrelatedExposure: at.config.MaxCorrelatedExposure, // 同向高相关敞口上限
+		CoinPool:         at.coinPool,              // 交易员独立的币种池（OI Top数据）
+		SymbolUniverse:   at.symbolUniverse,        // 可交易币种范围（开仓校验）
+		Liquidation:      &liquidationEstimator{at: at, available: availableBalance}, // 强平价格估算（止损距离校验）
 		Account: decision.AccountInfo{
 			TotalEquity:      totalEquity,
 			AvailableBalance: availableBalance,
@@ -838,6 +1083,8 @@ func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, act
 		return at.executeOpenLongWithRecord(decision, actionRecord)
 	case "open_short":
 		return at.executeOpenShortWithRecord(decision, actionRecord)
+	case "add_long", "add_short":
+		return at.executeAddWithRecord(decision, actionRecord)
 	case "close_long":
 		return at.executeCloseLongWithRecord(decision, actionRecord)
 	case "close_short":
@@ -864,17 +1111,20 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	positions, err := at.trader.GetPositions()
 	if err == nil {
 		for _, pos := range positions {
-			symbol, _ := pos["symbol"].(string)
-			side, _ := pos["side"].(string)
-			posAmt, _ := pos["positionAmt"].(float64)
-			if symbol == decision.Symbol && strings.ToUpper(side) == "LONG" && posAmt != 0 {
+			if pos.Symbol == decision.Symbol && strings.ToUpper(pos.Side) == "LONG" && pos.Quantity != 0 {
 				return fmt.Errorf("❌ %s 已有多仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_long 决策", decision.Symbol)
 			}
 		}
 	}
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol, at.config.BinanceTestnet)
+	marketData, err := market.GetFrom(at.marketSource, decision.Symbol)
+	if err != nil {
+		return err
+	}
+
+	// 按订单簿深度估算滑点（超限时缩减仓位或拒绝）
+	slippage, err := at.checkSlippage(decision, true, marketData.DepthData, actionRecord)
 	if err != nil {
 		return err
 	}
@@ -891,10 +1141,7 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	if err != nil {
 		return fmt.Errorf("获取账户余额失败: %w", err)
 	}
-	availableBalance := 0.0
-	if avail, ok := balance["availableBalance"].(float64); ok {
-		availableBalance = avail
-	}
+	availableBalance := balance.AvailableBalance
 
 	// 手续费估算（Taker费率 0.04%）
 	estimatedFee := decision.PositionSizeUSD * 0.0004
@@ -911,30 +1158,31 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 		// 继续执行，不影响交易
 	}
 
-	// 开仓
-	order, err := at.trader.OpenLong(decision.Symbol, quantity, decision.Leverage)
-	if err != nil {
+	// 开仓（大单按盘口深度拆成冰山/TWAP子单）
+	execution, err := at.executeOpenOrder(decision, "long", quantity, marketData.CurrentPrice, marketData.DepthData, 0)
+	if execution == nil || execution.FilledQuantity <= 0 {
 		return err
 	}
-
-	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
+	if err != nil {
+		// 部分子单已成交：按已成交数量继续设置止损止盈，避免裸仓
+		log.Printf("  ⚠️ 开仓未全部完成（已成交 %.4f / %.4f）: %v", execution.FilledQuantity, quantity, err)
 	}
+	quantity = execution.FilledQuantity
+
+	// 记录订单ID、汇总成交与实际成交价
+	at.recordExecution(execution, slippage, actionRecord)
 
-	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
+	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", actionRecord.OrderID, quantity)
 
-	// 记录开仓时间
+	// 记录开仓时间与建仓历史
 	posKey := decision.Symbol + "_long"
+	at.positionStateMutex.Lock()
 	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	at.positionStateMutex.Unlock()
+	at.recordPositionLeg(posKey, decision.Action, quantity, execution.AvgPrice, marketData.CurrentPrice, actionRecord)
 
-	// 设置止损止盈
-	if err := at.trader.SetStopLoss(decision.Symbol, "LONG", quantity, decision.StopLoss); err != nil {
-		log.Printf("  ⚠ 设置止损失败: %v", err)
-	}
-	if err := at.trader.SetTakeProfit(decision.Symbol, "LONG", quantity, decision.TakeProfit); err != nil {
-		log.Printf("  ⚠ 设置止盈失败: %v", err)
-	}
+	// 设置止损止盈（决策带止盈阶梯时分档挂单）
+	at.placeProtectiveOrders(decision, "LONG", quantity, actionRecord)
 
 	return nil
 }
@@ -947,17 +1195,20 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	positions, err := at.trader.GetPositions()
 	if err == nil {
 		for _, pos := range positions {
-			symbol, _ := pos["symbol"].(string)
-			side, _ := pos["side"].(string)
-			posAmt, _ := pos["positionAmt"].(float64)
-			if symbol == decision.Symbol && strings.ToUpper(side) == "SHORT" && posAmt != 0 {
+			if pos.Symbol == decision.Symbol && strings.ToUpper(pos.Side) == "SHORT" && pos.Quantity != 0 {
 				return fmt.Errorf("❌ %s 已有空仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_short 决策", decision.Symbol)
 			}
 		}
 	}
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol, at.config.BinanceTestnet)
+	marketData, err := market.GetFrom(at.marketSource, decision.Symbol)
+	if err != nil {
+		return err
+	}
+
+	// 按订单簿深度估算滑点（超限时缩减仓位或拒绝）
+	slippage, err := at.checkSlippage(decision, false, marketData.DepthData, actionRecord)
 	if err != nil {
 		return err
 	}
@@ -974,10 +1225,7 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	if err != nil {
 		return fmt.Errorf("获取账户余额失败: %w", err)
 	}
-	availableBalance := 0.0
-	if avail, ok := balance["availableBalance"].(float64); ok {
-		availableBalance = avail
-	}
+	availableBalance := balance.AvailableBalance
 
 	// 手续费估算（Taker费率 0.04%）
 	estimatedFee := decision.PositionSizeUSD * 0.0004
@@ -994,30 +1242,31 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 		// 继续执行，不影响交易
 	}
 
-	// 开仓
-	order, err := at.trader.OpenShort(decision.Symbol, quantity, decision.Leverage)
-	if err != nil {
+	// 开仓（大单按盘口深度拆成冰山/TWAP子单）
+	execution, err := at.executeOpenOrder(decision, "short", quantity, marketData.CurrentPrice, marketData.DepthData, 0)
+	if execution == nil || execution.FilledQuantity <= 0 {
 		return err
 	}
-
-	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
+	if err != nil {
+		// 部分子单已成交：按已成交数量继续设置止损止盈，避免裸仓
+		log.Printf("  ⚠️ 开仓未全部完成（已成交 %.4f / %.4f）: %v", execution.FilledQuantity, quantity, err)
 	}
+	quantity = execution.FilledQuantity
 
-	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
+	// 记录订单ID、汇总成交与实际成交价
+	at.recordExecution(execution, slippage, actionRecord)
 
-	// 记录开仓时间
+	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", actionRecord.OrderID, quantity)
+
+	// 记录开仓时间与建仓历史
 	posKey := decision.Symbol + "_short"
+	at.positionStateMutex.Lock()
 	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	at.positionStateMutex.Unlock()
+	at.recordPositionLeg(posKey, decision.Action, quantity, execution.AvgPrice, marketData.CurrentPrice, actionRecord)
 
-	// 设置止损止盈
-	if err := at.trader.SetStopLoss(decision.Symbol, "SHORT", quantity, decision.StopLoss); err != nil {
-		log.Printf("  ⚠ 设置止损失败: %v", err)
-	}
-	if err := at.trader.SetTakeProfit(decision.Symbol, "SHORT", quantity, decision.TakeProfit); err != nil {
-		log.Printf("  ⚠ 设置止盈失败: %v", err)
-	}
+	// 设置止损止盈（决策带止盈阶梯时分档挂单）
+	at.placeProtectiveOrders(decision, "SHORT", quantity, actionRecord)
 
 	return nil
 }
@@ -1027,22 +1276,20 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 	log.Printf("  🔄 平多仓: %s", decision.Symbol)
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol, at.config.BinanceTestnet)
+	marketData, err := market.GetFrom(at.marketSource, decision.Symbol)
 	if err != nil {
 		return err
 	}
 	actionRecord.Price = marketData.CurrentPrice
 
 	// 平仓
-	order, err := at.trader.CloseLong(decision.Symbol, 0) // 0 = 全部平仓
+	order, err := at.trader.CloseLong(decision.Symbol, 0, at.decisionOrderID(decision.Symbol, decision.Action)) // 0 = 全部平仓
 	if err != nil {
 		return err
 	}
 
 	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	actionRecord.OrderID = order.OrderID
 
 	log.Printf("  ✓ 平仓成功")
 	return nil
@@ -1053,22 +1300,20 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 	log.Printf("  🔄 平空仓: %s", decision.Symbol)
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol, at.config.BinanceTestnet)
+	marketData, err := market.GetFrom(at.marketSource, decision.Symbol)
 	if err != nil {
 		return err
 	}
 	actionRecord.Price = marketData.CurrentPrice
 
 	// 平仓
-	order, err := at.trader.CloseShort(decision.Symbol, 0) // 0 = 全部平仓
+	order, err := at.trader.CloseShort(decision.Symbol, 0, at.decisionOrderID(decision.Symbol, decision.Action)) // 0 = 全部平仓
 	if err != nil {
 		return err
 	}
 
 	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	actionRecord.OrderID = order.OrderID
 
 	log.Printf("  ✓ 平仓成功")
 	return nil
@@ -1079,7 +1324,7 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 	log.Printf("  🎯 调整止损: %s → %.2f", decision.Symbol, decision.NewStopLoss)
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol, at.config.BinanceTestnet)
+	marketData, err := market.GetFrom(at.marketSource, decision.Symbol)
 	if err != nil {
 		return err
 	}
@@ -1092,12 +1337,10 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 	}
 
 	// 查找目标持仓
-	var targetPosition map[string]interface{}
-	for _, pos := range positions {
-		symbol, _ := pos["symbol"].(string)
-		posAmt, _ := pos["positionAmt"].(float64)
-		if symbol == decision.Symbol && posAmt != 0 {
-			targetPosition = pos
+	var targetPosition *Position
+	for i := range positions {
+		if positions[i].Symbol == decision.Symbol && positions[i].Quantity != 0 {
+			targetPosition = &positions[i]
 			break
 		}
 	}
@@ -1107,9 +1350,8 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 	}
 
 	// 获取持仓方向和数量
-	side, _ := targetPosition["side"].(string)
-	positionSide := strings.ToUpper(side)
-	positionAmt, _ := targetPosition["positionAmt"].(float64)
+	positionSide := strings.ToUpper(targetPosition.Side)
+	positionAmt := targetPosition.Quantity
 
 	// 验证新止损价格合理性
 	if positionSide == "LONG" && decision.NewStopLoss >= marketData.CurrentPrice {
@@ -1123,12 +1365,9 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 	var hasOppositePosition bool
 	oppositeSide := ""
 	for _, pos := range positions {
-		symbol, _ := pos["symbol"].(string)
-		posSide, _ := pos["side"].(string)
-		posAmt, _ := pos["positionAmt"].(float64)
-		if symbol == decision.Symbol && posAmt != 0 && strings.ToUpper(posSide) != positionSide {
+		if pos.Symbol == decision.Symbol && pos.Quantity != 0 && strings.ToUpper(pos.Side) != positionSide {
 			hasOppositePosition = true
-			oppositeSide = strings.ToUpper(posSide)
+			oppositeSide = strings.ToUpper(pos.Side)
 			break
 		}
 	}
@@ -1153,6 +1392,12 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 		return fmt.Errorf("修改止损失败: %w", err)
 	}
 
+	posKey := decision.Symbol + "_" + targetPosition.Side
+	at.setPositionStopLoss(posKey, decision.NewStopLoss)
+	if ladder := at.takeProfitLadders[posKey]; ladder != nil {
+		ladder.StopLoss = decision.NewStopLoss
+	}
+
 	log.Printf("  ✓ 止损已调整: %.2f (当前价格: %.2f)", decision.NewStopLoss, marketData.CurrentPrice)
 	return nil
 }
@@ -1162,7 +1407,7 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 	log.Printf("  🎯 调整止盈: %s → %.2f", decision.Symbol, decision.NewTakeProfit)
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol, at.config.BinanceTestnet)
+	marketData, err := market.GetFrom(at.marketSource, decision.Symbol)
 	if err != nil {
 		return err
 	}
@@ -1175,12 +1420,10 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 	}
 
 	// 查找目标持仓
-	var targetPosition map[string]interface{}
-	for _, pos := range positions {
-		symbol, _ := pos["symbol"].(string)
-		posAmt, _ := pos["positionAmt"].(float64)
-		if symbol == decision.Symbol && posAmt != 0 {
-			targetPosition = pos
+	var targetPosition *Position
+	for i := range positions {
+		if positions[i].Symbol == decision.Symbol && positions[i].Quantity != 0 {
+			targetPosition = &positions[i]
 			break
 		}
 	}
@@ -1190,9 +1433,8 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 	}
 
 	// 获取持仓方向和数量
-	side, _ := targetPosition["side"].(string)
-	positionSide := strings.ToUpper(side)
-	positionAmt, _ := targetPosition["positionAmt"].(float64)
+	positionSide := strings.ToUpper(targetPosition.Side)
+	positionAmt := targetPosition.Quantity
 
 	// 验证新止盈价格合理性
 	if positionSide == "LONG" && decision.NewTakeProfit <= marketData.CurrentPrice {
@@ -1206,12 +1448,9 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 	var hasOppositePosition bool
 	oppositeSide := ""
 	for _, pos := range positions {
-		symbol, _ := pos["symbol"].(string)
-		posSide, _ := pos["side"].(string)
-		posAmt, _ := pos["positionAmt"].(float64)
-		if symbol == decision.Symbol && posAmt != 0 && strings.ToUpper(posSide) != positionSide {
+		if pos.Symbol == decision.Symbol && pos.Quantity != 0 && strings.ToUpper(pos.Side) != positionSide {
 			hasOppositePosition = true
-			oppositeSide = strings.ToUpper(posSide)
+			oppositeSide = strings.ToUpper(pos.Side)
 			break
 		}
 	}
@@ -1229,6 +1468,12 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 		// 不中断执行，继续设置新止盈
 	}
 
+	// 持仓有止盈阶梯（或决策给出新阶梯）时按阶梯重挂
+	if at.updateTakeProfitLadder(decision, targetPosition, actionRecord) {
+		log.Printf("  ✓ 止盈阶梯已调整，最远一档: %.2f (当前价格: %.2f)", decision.NewTakeProfit, marketData.CurrentPrice)
+		return nil
+	}
+
 	// 调用交易所 API 修改止盈
 	quantity := math.Abs(positionAmt)
 	err = at.trader.SetTakeProfit(decision.Symbol, positionSide, quantity, decision.NewTakeProfit)
@@ -1250,7 +1495,7 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 	}
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol, at.config.BinanceTestnet)
+	marketData, err := market.GetFrom(at.marketSource, decision.Symbol)
 	if err != nil {
 		return err
 	}
@@ -1263,12 +1508,10 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 	}
 
 	// 查找目标持仓
-	var targetPosition map[string]interface{}
-	for _, pos := range positions {
-		symbol, _ := pos["symbol"].(string)
-		posAmt, _ := pos["positionAmt"].(float64)
-		if symbol == decision.Symbol && posAmt != 0 {
-			targetPosition = pos
+	var targetPosition *Position
+	for i := range positions {
+		if positions[i].Symbol == decision.Symbol && positions[i].Quantity != 0 {
+			targetPosition = &positions[i]
 			break
 		}
 	}
@@ -1278,9 +1521,8 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 	}
 
 	// 获取持仓方向和数量
-	side, _ := targetPosition["side"].(string)
-	positionSide := strings.ToUpper(side)
-	positionAmt, _ := targetPosition["positionAmt"].(float64)
+	positionSide := strings.ToUpper(targetPosition.Side)
+	positionAmt := targetPosition.Quantity
 
 	// 计算平仓数量
 	totalQuantity := math.Abs(positionAmt)
@@ -1288,11 +1530,11 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 	actionRecord.Quantity = closeQuantity
 
 	// 执行平仓
-	var order map[string]interface{}
+	var order *OrderResult
 	if positionSide == "LONG" {
-		order, err = at.trader.CloseLong(decision.Symbol, closeQuantity)
+		order, err = at.trader.CloseLong(decision.Symbol, closeQuantity, at.decisionOrderID(decision.Symbol, decision.Action))
 	} else {
-		order, err = at.trader.CloseShort(decision.Symbol, closeQuantity)
+		order, err = at.trader.CloseShort(decision.Symbol, closeQuantity, at.decisionOrderID(decision.Symbol, decision.Action))
 	}
 
 	if err != nil {
@@ -1300,14 +1542,15 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 	}
 
 	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	actionRecord.OrderID = order.OrderID
 
 	remainingQuantity := totalQuantity - closeQuantity
 	log.Printf("  ✓ 部分平仓成功: 平仓 %.4f (%.1f%%), 剩余 %.4f",
 		closeQuantity, decision.ClosePercentage, remainingQuantity)
 
+	// 止盈阶梯按剩余数量等比缩小并重挂
+	at.rescaleTakeProfitLadder(decision.Symbol, positionSide, remainingQuantity, actionRecord)
+
 	return nil
 }
 
@@ -1331,6 +1574,24 @@ func (at *AutoTrader) GetExchange() string {
 	return at.exchange
 }
 
+// GetMarketSource 获取与交易所一致的行情数据源
+func (at *AutoTrader) GetMarketSource() market.MarketDataSource {
+	return at.marketSource
+}
+
+// GetRateLimiterName 获取交易所账户对应的限流器名称（与交易客户端使用的限流器一致）
+func (at *AutoTrader) GetRateLimiterName() string {
+	switch at.exchange {
+	case "binance":
+		return ratelimit.Name("binance", at.config.BinanceAPIKey)
+	case "hyperliquid":
+		return ratelimit.Name("hyperliquid", at.config.HyperliquidWalletAddr)
+	case "aster":
+		return ratelimit.Name("aster", at.config.AsterUser)
+	}
+	return ""
+}
+
 // SetCustomPrompt 设置自定义交易策略prompt
 func (at *AutoTrader) SetCustomPrompt(prompt string) {
 	at.customPrompt = prompt
@@ -1375,7 +1636,7 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 		"trader_name":            at.name,
 		"ai_model":               at.aiModel,
 		"exchange":               at.exchange,
-		"is_running":             at.isRunning,
+		"is_running":             at.isRunning.Load(),
 		"start_time":             at.startTime.Format(time.RFC3339),
 		"runtime_minutes":        int(time.Since(at.startTime).Minutes()),
 		"call_count":             at.callCount,
@@ -1385,6 +1646,7 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 		"last_reset_time":        at.lastResetTime.Format(time.RFC3339),
 		"ai_provider":            aiProvider,
 		"system_prompt_template": at.systemPromptTemplate,
+		"entries_disabled":       at.entryBlockReason(),
 	}
 }
 
@@ -1398,30 +1660,53 @@ func (at *AutoTrader) GetDailyPnL() float64 {
     return at.dailyPnL
 }
 
+// AccountSummary 账户概览（用于API）
+type AccountSummary struct {
+	// 核心字段
+	TotalEquity      float64 `json:"total_equity"`      // 账户净值 = wallet + unrealized
+	WalletBalance    float64 `json:"wallet_balance"`    // 钱包余额（不含未实现盈亏）
+	UnrealizedProfit float64 `json:"unrealized_profit"` // 未实现盈亏（从API）
+	AvailableBalance float64 `json:"available_balance"` // 可用余额
+
+	// 盈亏统计
+	TotalPnL           float64 `json:"total_pnl"`            // 总盈亏 = equity - initial
+	TotalPnLPct        float64 `json:"total_pnl_pct"`        // 总盈亏百分比
+	TotalUnrealizedPnL float64 `json:"total_unrealized_pnl"` // 未实现盈亏（从持仓计算）
+	InitialBalance     float64 `json:"initial_balance"`      // 初始余额
+	DailyPnL           float64 `json:"daily_pnl"`            // 日盈亏
+	FundingFee         float64 `json:"funding_fee"`          // 当前持仓累计资金费（正=收取，负=支付）
+
+	// 持仓信息
+	PositionCount int     `json:"position_count"`  // 持仓数量
+	MarginUsed    float64 `json:"margin_used"`     // 保证金占用
+	MarginUsedPct float64 `json:"margin_used_pct"` // 保证金使用率
+}
+
+// PositionDetail 持仓详情（用于API）
+type PositionDetail struct {
+	Symbol           string  `json:"symbol"`
+	Side             string  `json:"side"`
+	EntryPrice       float64 `json:"entry_price"`
+	MarkPrice        float64 `json:"mark_price"`
+	Quantity         float64 `json:"quantity"`
+	Leverage         int     `json:"leverage"`
+	UnrealizedPnL    float64 `json:"unrealized_pnl"`
+	UnrealizedPnLPct float64 `json:"unrealized_pnl_pct"`
+	LiquidationPrice float64 `json:"liquidation_price"`
+	MarginUsed       float64 `json:"margin_used"`
+	FundingFee       float64 `json:"funding_fee"`
+	NetPnL           float64 `json:"net_pnl"` // 未实现盈亏 + 资金费
+}
+
 // GetAccountInfo 获取账户信息（用于API）
-func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
+func (at *AutoTrader) GetAccountInfo() (*AccountSummary, error) {
 	balance, err := at.trader.GetBalance()
 	if err != nil {
 		return nil, fmt.Errorf("获取余额失败: %w", err)
 	}
 
-	// 获取账户字段
-	totalWalletBalance := 0.0
-	totalUnrealizedProfit := 0.0
-	availableBalance := 0.0
-
-	if wallet, ok := balance["totalWalletBalance"].(float64); ok {
-		totalWalletBalance = wallet
-	}
-	if unrealized, ok := balance["totalUnrealizedProfit"].(float64); ok {
-		totalUnrealizedProfit = unrealized
-	}
-	if avail, ok := balance["availableBalance"].(float64); ok {
-		availableBalance = avail
-	}
-
 	// Total Equity = 钱包余额 + 未实现盈亏
-	totalEquity := totalWalletBalance + totalUnrealizedProfit
+	totalEquity := balance.TotalEquity()
 
 	// 获取持仓计算总保证金
 	positions, err := at.trader.GetPositions()
@@ -1431,21 +1716,11 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 
 	totalMarginUsed := 0.0
 	totalUnrealizedPnL := 0.0
+	totalFundingFee := 0.0
 	for _, pos := range positions {
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity
-		}
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		totalUnrealizedPnL += unrealizedPnl
-
-		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
-		marginUsed := (quantity * markPrice) / float64(leverage)
-		totalMarginUsed += marginUsed
+		totalFundingFee += at.getPositionFunding(pos.Symbol, pos.Side)
+		totalUnrealizedPnL += pos.UnrealizedPnL
+		totalMarginUsed += pos.MarginUsed()
 	}
 
 	totalPnL := totalEquity - at.initialBalance
@@ -1459,75 +1734,102 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 		marginUsedPct = (totalMarginUsed / totalEquity) * 100
 	}
 
-	return map[string]interface{}{
-		// 核心字段
-		"total_equity":      totalEquity,           // 账户净值 = wallet + unrealized
-		"wallet_balance":    totalWalletBalance,    // 钱包余额（不含未实现盈亏）
-		"unrealized_profit": totalUnrealizedProfit, // 未实现盈亏（从API）
-		"available_balance": availableBalance,      // 可用余额
-
-		// 盈亏统计
-		"total_pnl":            totalPnL,           // 总盈亏 = equity - initial
-		"total_pnl_pct":        totalPnLPct,        // 总盈亏百分比
-		"total_unrealized_pnl": totalUnrealizedPnL, // 未实现盈亏（从持仓计算）
-		"initial_balance":      at.initialBalance,  // 初始余额
-		"daily_pnl":            at.dailyPnL,        // 日盈亏
-
-		// 持仓信息
-		"position_count":  len(positions),  // 持仓数量
-		"margin_used":     totalMarginUsed, // 保证金占用
-		"margin_used_pct": marginUsedPct,   // 保证金使用率
+	return &AccountSummary{
+		TotalEquity:        totalEquity,
+		WalletBalance:      balance.TotalWalletBalance,
+		UnrealizedProfit:   balance.TotalUnrealizedProfit,
+		AvailableBalance:   balance.AvailableBalance,
+		TotalPnL:           totalPnL,
+		TotalPnLPct:        totalPnLPct,
+		TotalUnrealizedPnL: totalUnrealizedPnL,
+		InitialBalance:     at.initialBalance,
+		DailyPnL:           at.dailyPnL,
+		FundingFee:         totalFundingFee,
+		PositionCount:      len(positions),
+		MarginUsed:         totalMarginUsed,
+		MarginUsedPct:      marginUsedPct,
 	}, nil
 }
 
 // GetPositions 获取持仓列表（用于API）
-func (at *AutoTrader) GetPositions() ([]map[string]interface{}, error) {
+func (at *AutoTrader) GetPositions() ([]PositionDetail, error) {
 	positions, err := at.trader.GetPositions()
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
-	var result []map[string]interface{}
+	result := make([]PositionDetail, 0, len(positions))
 	for _, pos := range positions {
-		symbol := pos["symbol"].(string)
-		side := pos["side"].(string)
-		entryPrice := pos["entryPrice"].(float64)
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity
-		}
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		liquidationPrice := pos["liquidationPrice"].(float64)
-
-		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
-
 		// 计算占用保证金
-		marginUsed := (quantity * markPrice) / float64(leverage)
+		marginUsed := pos.MarginUsed()
 
-		// 计算盈亏百分比（基于保证金）
-		pnlPct := calculatePnLPercentage(unrealizedPnl, marginUsed)
+		// 持仓期间资金费（由决策周期刷新）
+		fundingFee := at.getPositionFunding(pos.Symbol, pos.Side)
 
-		result = append(result, map[string]interface{}{
-			"symbol":             symbol,
-			"side":               side,
-			"entry_price":        entryPrice,
-			"mark_price":         markPrice,
-			"quantity":           quantity,
-			"leverage":           leverage,
-			"unrealized_pnl":     unrealizedPnl,
-			"unrealized_pnl_pct": pnlPct,
-			"liquidation_price":  liquidationPrice,
-			"margin_used":        marginUsed,
+		result = append(result, PositionDetail{
+			Symbol:           pos.Symbol,
+			Side:             pos.Side,
+			EntryPrice:       pos.EntryPrice,
+			MarkPrice:        pos.MarkPrice,
+			Quantity:         pos.Quantity,
+			Leverage:         pos.EffectiveLeverage(),
+			UnrealizedPnL:    pos.UnrealizedPnL,
+			UnrealizedPnLPct: calculatePnLPercentage(pos.UnrealizedPnL, marginUsed), // 基于保证金
+			LiquidationPrice: pos.LiquidationPrice,
+			MarginUsed:       marginUsed,
+			FundingFee:       fundingFee,
+			NetPnL:           pos.UnrealizedPnL + fundingFee,
 		})
 	}
 
 	return result, nil
 }
 
+// refreshPositionFunding 刷新持仓期间累计资金费（每5分钟最多查询一次）
+// openedAt 为持仓首次出现时间（毫秒），交易所不支持查询时返回0
+// 资金费流水只按币种返回、不区分持仓方向，因此按单向持仓归属：同一币种同时持有多空（hedged）时无法拆分，返回0
+func (at *AutoTrader) refreshPositionFunding(symbol, side string, openedAt int64, hedged bool) float64 {
+	provider, ok := at.trader.(FundingFeeProvider)
+	if !ok || openedAt <= 0 {
+		return 0
+	}
+
+	posKey := symbol + "_" + side
+	if hedged {
+		at.positionFundingMutex.Lock()
+		delete(at.positionFunding, posKey)
+		delete(at.positionFundingTime, posKey)
+		at.positionFundingMutex.Unlock()
+		return 0
+	}
+	at.positionFundingMutex.RLock()
+	cached, exists := at.positionFunding[posKey]
+	lastRefresh := at.positionFundingTime[posKey]
+	at.positionFundingMutex.RUnlock()
+	if exists && time.Since(lastRefresh) < 5*time.Minute {
+		return cached
+	}
+
+	fee, err := provider.GetFundingFees(symbol, time.UnixMilli(openedAt))
+	if err != nil {
+		log.Printf("⚠️  获取 %s 资金费失败: %v", symbol, err)
+		return cached
+	}
+
+	at.positionFundingMutex.Lock()
+	at.positionFunding[posKey] = fee
+	at.positionFundingTime[posKey] = time.Now()
+	at.positionFundingMutex.Unlock()
+	return fee
+}
+
+// getPositionFunding 读取持仓累计资金费缓存
+func (at *AutoTrader) getPositionFunding(symbol, side string) float64 {
+	at.positionFundingMutex.RLock()
+	defer at.positionFundingMutex.RUnlock()
+	return at.positionFunding[symbol+"_"+side]
+}
+
 // calculatePnLPercentage 计算盈亏百分比（基于保证金，自动考虑杠杆）
 // 收益率 = 未实现盈亏 / 保证金 × 100%
 func calculatePnLPercentage(unrealizedPnl, marginUsed float64) float64 {
@@ -1551,8 +1853,8 @@ func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision
 			return 1 // 最高优先级：先平仓（包括部分平仓）
 		case "update_stop_loss", "update_take_profit":
 			return 2 // 调整持仓止盈止损
-		case "open_long", "open_short":
-			return 3 // 次优先级：后开仓
+		case "open_long", "open_short", "add_long", "add_short":
+			return 3 // 次优先级：后开仓/加仓
 		case "hold", "wait":
 			return 4 // 最低优先级：观望
 		default:
@@ -1576,103 +1878,225 @@ func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision
 	return sorted
 }
 
-// adjustDecisionsByPerformance 基于最近胜率、盈亏因子与账户资金对开仓仓位进行调整与保护
-func (at *AutoTrader) adjustDecisionsByPerformance(decisions []decision.Decision, ctx *decision.Context, recent *logger.PerformanceAnalysis, record *logger.DecisionRecord) []decision.Decision {
+// adjustDecisionsByPerformance 基于最近胜率、盈亏因子与账户资金对开仓/加仓仓位进行调整与保护
+// 配置了仓位计算模型时按模型计算仓位，返回每个开仓/加仓决策（按下标）的仓位计算记录
+// 仓位确定后重新校验相关敞口与止盈阶梯最小名义价值，不通过的决策改为wait
+func (at *AutoTrader) adjustDecisionsByPerformance(decisions []decision.Decision, ctx *decision.Context, recent *logger.PerformanceAnalysis, record *logger.DecisionRecord) ([]decision.Decision, map[int]*logger.SizingRecord) {
+    sizingRecords := make(map[int]*logger.SizingRecord)
     if len(decisions) == 0 {
-        return decisions
+        return decisions, sizingRecords
     }
 
-    available := ctx.Account.AvailableBalance
-    feeRate := 0.0004 // 与执行路径保持一致
+    inputs := at.loadSizingInputs(ctx, recent)
+    for i := range decisions {
+        d := &decisions[i]
+        if d.Action != "open_long" && d.Action != "open_short" && !decision.IsAddAction(d.Action) {
+            continue
+        }
+        sizingRecords[i] = at.sizeDecision(d, ctx, inputs, record)
+    }
+
+    // AI给出的仓位已在决策校验时检查过，模型仓位需要按最终数值重新检查
+    for i, err := range decision.ValidateSizedDecisions(decisions, ctx) {
+        d := &decisions[i]
+        log.Printf("⚠️  拒绝决策 %s %s: 按最终仓位 %.2f 校验失败: %v", d.Symbol, d.Action, d.PositionSizeUSD, err)
+        record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("Rejected %s %s after sizing (%.2f USD): %v", d.Symbol, d.Action, d.PositionSizeUSD, err))
+        if sizing := sizingRecords[i]; sizing != nil {
+            sizing.Note = fmt.Sprintf("rejected after sizing: %v", err)
+        }
+        d.Reasoning = fmt.Sprintf("[rejected %s: %v] %s", d.Action, err, d.Reasoning)
+        d.Action = "wait"
+    }
+
+    return decisions, sizingRecords
+}
+
+// sizingInputs 同一批决策共用的仓位计算输入
+type sizingInputs struct {
+    available            float64
+    feeRate              float64
+    hadRecentMarginError bool
+    winRate              float64
+    profitFactor         float64
+    recentTrades         []logger.TradeOutcome
+    recent               *logger.PerformanceAnalysis
+}
+
+// loadSizingInputs 读取账户资金、近期表现与近期保证金错误
+func (at *AutoTrader) loadSizingInputs(ctx *decision.Context, recent *logger.PerformanceAnalysis) *sizingInputs {
+    inputs := &sizingInputs{
+        available: ctx.Account.AvailableBalance,
+        feeRate:   0.0004, // 与执行路径保持一致
+        recent:    recent,
+    }
 
     // 获取最近记录用于错误保护（避免重复保证金不足等错误）
     recentRecords, _ := at.decisionLogger.GetLatestRecords(20)
-    hadRecentMarginError := false
     for _, r := range recentRecords {
         for _, line := range r.ExecutionLog {
             if strings.Contains(line, "保证金不足") || strings.Contains(strings.ToLower(line), "margin") {
-                hadRecentMarginError = true
+                inputs.hadRecentMarginError = true
                 break
             }
         }
-        if hadRecentMarginError {
+        if inputs.hadRecentMarginError {
             break
         }
     }
 
     // Recent win rate and profit factor
-    winRate := 0.0
-    profitFactor := 0.0
-    var recentTrades []logger.TradeOutcome
     if recent != nil {
-        winRate = recent.WinRate
-        profitFactor = recent.ProfitFactor
-        recentTrades = recent.RecentTrades
+        inputs.winRate = recent.WinRate
+        inputs.profitFactor = recent.ProfitFactor
+        inputs.recentTrades = recent.RecentTrades
     }
+    return inputs
+}
 
-    // Iterate and adjust open decisions
-    for i := range decisions {
-        d := &decisions[i]
-        if d.Action != "open_long" && d.Action != "open_short" {
-            continue
+// sizeDecision 计算开仓/加仓决策的最终仓位（写回 d.PositionSizeUSD），返回仓位计算记录
+// 加仓按整仓计算：风险模型扣除现有持仓按新止损的风险，波动率模型扣除现有持仓名义价值
+func (at *AutoTrader) sizeDecision(d *decision.Decision, ctx *decision.Context, inputs *sizingInputs, record *logger.DecisionRecord) *logger.SizingRecord {
+    // Apply cooldown if recent large loss on same symbol/side (reduce size)
+    side := "long"
+    if d.Action == "open_short" || d.Action == "add_short" {
+        side = "short"
+    }
+    now := time.Now()
+    recentLossCooldown := false
+    for ti := len(inputs.recentTrades) - 1; ti >= 0; ti-- {
+        t := inputs.recentTrades[ti]
+        if t.Symbol == d.Symbol && t.Side == side {
+            // If within 90 minutes and loss magnitude large, apply cooldown
+            if t.PnLPct <= -15 && now.Sub(t.CloseTime) < 90*time.Minute {
+                recentLossCooldown = true
+                record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("Cooldown applied for %s %s after recent large loss; size reduced", d.Symbol, strings.ToUpper(side)))
+            }
+            break
         }
+    }
+
+    base := d.PositionSizeUSD
+    if base <= 0 {
+        // If AI didn’t provide size, keep zero and apply only protections
+        base = 0
+    }
 
-        // Apply cooldown if recent large loss on same symbol/side (reduce size)
-        side := "long"
-        if d.Action == "open_short" {
-            side = "short"
+    // 按订单簿深度预估滑点（执行前还会用更深的订单簿再检查一次）
+    slippagePct := estimateSlippagePct(ctx.MarketDataMap[d.Symbol], side == "long", base)
+    if slippagePct > at.maxSlippagePct() {
+        record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("Estimated slippage %.3f%% for %s exceeds limit %.2f%%; size will be reduced or rejected before execution", slippagePct, d.Symbol, at.maxSlippagePct()))
+    }
+
+    sizing := &logger.SizingRecord{
+        Model:        at.positionSizing.model(),
+        ProposedUSD:  base,
+        Equity:       ctx.Account.TotalEquity,
+        Available:    inputs.available,
+        StopLoss:     d.StopLoss,
+        WinRate:      inputs.winRate,
+        ProfitFactor: inputs.profitFactor,
+        Confidence:   d.Confidence,
+        Cooldown:     recentLossCooldown,
+        SlippagePct:  slippagePct,
+    }
+    if data, ok := ctx.MarketDataMap[d.Symbol]; ok && data != nil {
+        sizing.Price = data.CurrentPrice
+        if data.LongerTermContext != nil {
+            sizing.ATR = data.LongerTermContext.ATR14
         }
-        now := time.Now()
-        recentLossCooldown := false
-        for ti := len(recentTrades) - 1; ti >= 0; ti-- {
-            t := recentTrades[ti]
-            if t.Symbol == d.Symbol && t.Side == side {
-                // If within 90 minutes and loss magnitude large, apply cooldown
-                if t.PnLPct <= -15 && now.Sub(t.CloseTime) < 90*time.Minute {
-                    recentLossCooldown = true
-                    record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("Cooldown applied for %s %s after recent large loss; size reduced", d.Symbol, strings.ToUpper(side)))
-                }
-                break
+    }
+    if decision.IsAddAction(d.Action) {
+        for _, pos := range ctx.Positions {
+            if pos.Symbol != d.Symbol || pos.Side != side {
+                continue
+            }
+            sizing.ExistingUSD = pos.Quantity * pos.MarkPrice
+            // 止损已越过均价（锁定利润）时现有持仓不再占用风险
+            if (side == "long" && d.StopLoss < pos.EntryPrice) || (side == "short" && d.StopLoss > pos.EntryPrice) {
+                sizing.ExistingRiskUSD = pos.Quantity * math.Abs(pos.EntryPrice-d.StopLoss)
             }
+            break
         }
+    }
 
-        base := d.PositionSizeUSD
-        if base <= 0 {
-            // If AI didn’t provide size, keep zero and apply only protections
-            base = 0
+    // 计算调整后的仓位大小
+    var newSize float64
+    modelSize, ok := 0.0, false
+    if at.positionSizing.Enabled() {
+        modelSize, ok = ComputeModelSize(at.positionSizing, sizing, inputs.recent)
+        if !ok {
+            record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("Sizing model %s unavailable for %s (%s); falling back to AI size", sizing.Model, d.Symbol, sizing.Note))
+            sizing.Model = SizingModelAI
         }
-
-        // 计算调整后的仓位大小
-        newSize := ComputeAdjustedSize(base, d.Leverage, available, feeRate, winRate, profitFactor, float64(d.Confidence), recentLossCooldown)
-
-        // If recent margin errors were observed, preemptively reduce size
-        if hadRecentMarginError && newSize > 0 {
-            newSize = newSize * 0.85
-            record.ExecutionLog = append(record.ExecutionLog, "Recent margin errors detected; preemptively reduced position size")
+    }
+    if ok {
+        sizing.ModelUSD = modelSize
+        newSize = ApplySizeProtections(modelSize, d.Leverage, inputs.available, inputs.feeRate, recentLossCooldown, slippagePct)
+        if maxValue := decision.MaxPositionValue(ctx, d.Symbol, ctx.Account.TotalEquity); maxValue > 0 && sizing.ExistingUSD+newSize > maxValue {
+            sizing.Note = fmt.Sprintf("capped at max position value %.2f", maxValue)
+            newSize = math.Max(maxValue-sizing.ExistingUSD, 0)
         }
+    } else {
+        newSize = ComputeAdjustedSize(base, d.Leverage, inputs.available, inputs.feeRate, inputs.winRate, inputs.profitFactor, float64(d.Confidence), recentLossCooldown, slippagePct)
+    }
+    // 金字塔加仓：单次加仓不超过当前持仓规模
+    if decision.IsAddAction(d.Action) && sizing.ExistingUSD > 0 && newSize > sizing.ExistingUSD {
+        sizing.Note = fmt.Sprintf("capped at current position notional %.2f", sizing.ExistingUSD)
+        newSize = sizing.ExistingUSD
+    }
 
-        // If margin clamp was applied by ComputeAdjustedSize, record a hint
-        if d.Leverage > 0 {
-            denom := (1.0/float64(d.Leverage) + feeRate)
-            maxSize := available / denom
-            if base > 0 && newSize > maxSize-1e-6 { // 接近上限，视为触发了限制
-                record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("Clamped size to avoid margin error; adjusted to %.2f USD", newSize))
-            }
-        }
+    // If recent margin errors were observed, preemptively reduce size
+    if inputs.hadRecentMarginError && newSize > 0 {
+        newSize = newSize * 0.85
+        record.ExecutionLog = append(record.ExecutionLog, "Recent margin errors detected; preemptively reduced position size")
+    }
 
-        if base > 0 && newSize != base {
-            record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("Adjusted position size %s %s: %.2f -> %.2f", d.Symbol, strings.ToUpper(side), base, newSize))
+    // If margin clamp was applied by ComputeAdjustedSize, record a hint
+    if d.Leverage > 0 {
+        denom := (1.0/float64(d.Leverage) + inputs.feeRate + slippagePct/100)
+        maxSize := inputs.available / denom
+        if base > 0 && newSize > maxSize-1e-6 { // 接近上限，视为触发了限制
+            record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("Clamped size to avoid margin error; adjusted to %.2f USD", newSize))
         }
+    }
 
-        d.PositionSizeUSD = newSize
+    if base > 0 && newSize != base {
+        record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("Adjusted position size %s %s: %.2f -> %.2f", d.Symbol, strings.ToUpper(side), base, newSize))
     }
 
-    return decisions
+    d.PositionSizeUSD = newSize
+    sizing.SizeUSD = newSize
+
+    log.Printf("📐 仓位计算 %s %s: 模型 %s | AI建议 %.2f | 模型 %.2f | 最终 %.2f | 净值 %.2f | 止损距离 %.2f%% | ATR %.4f | 风险 %.2f%% | 凯利 %.1f%%",
+        d.Symbol, strings.ToUpper(side), sizing.Model, sizing.ProposedUSD, sizing.ModelUSD, sizing.SizeUSD,
+        sizing.Equity, sizing.StopDistPct, sizing.ATR, sizing.RiskPct, sizing.KellyPct)
+    record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("Sizing %s %s: model=%s proposed=%.2f model_size=%.2f final=%.2f equity=%.2f stop_dist=%.2f%% atr=%.4f risk=%.2f%% win_rate=%.1f%% pf=%.2f kelly=%.1f%%",
+        d.Symbol, strings.ToUpper(side), sizing.Model, sizing.ProposedUSD, sizing.ModelUSD, sizing.SizeUSD,
+        sizing.Equity, sizing.StopDistPct, sizing.ATR, sizing.RiskPct, sizing.WinRate, sizing.ProfitFactor, sizing.KellyPct))
+    return sizing
 }
 
-// getCandidateCoins 获取交易员的候选币种列表
+// getCandidateCoins 获取交易员的候选币种列表（注入外部信号，并按可交易范围过滤）
 func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
+	candidateCoins, err := at.collectCandidateCoins()
+	if err != nil {
+		return nil, err
+	}
+	candidateCoins = at.mergeWebhookSignals(candidateCoins)
+
+	at.refreshSymbolUniverse()
+	return at.symbolUniverse.FilterCandidates(candidateCoins), nil
+}
+
+// collectCandidateCoins 按交易员配置收集候选币种
+// 优先级：自定义币种 > 币种池外部信号（AI500 / OI Top）> 默认币种 > 币种池（本地筛选器等）
+func (at *AutoTrader) collectCandidateCoins() ([]decision.CandidateCoin, error) {
 	if len(at.tradingCoins) == 0 {
+		// 交易员启用了外部信号源时，使用币种池
+		if at.coinPool.HasExternalSignals() {
+			return at.getCoinPoolCandidates()
+		}
+
 		// 使用数据库配置的默认币种列表
 		var candidateCoins []decision.CandidateCoin
 
@@ -1682,33 +2106,15 @@ func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 				symbol := normalizeSymbol(coin)
 				candidateCoins = append(candidateCoins, decision.CandidateCoin{
 					Symbol:  symbol,
-					Sources: []string{"default"}, // 标记为数据库默认币种
+					Sources: []pool.SourceScore{{Name: "default"}}, // 标记为数据库默认币种
 				})
 			}
 			log.Printf("📋 [%s] 使用数据库默认币种: %d个币种 %v",
 				at.name, len(candidateCoins), at.defaultCoins)
 			return candidateCoins, nil
 		} else {
-			// 如果数据库中没有配置默认币种，则使用AI500+OI Top作为fallback
-			const ai500Limit = 20 // AI500取前20个评分最高的币种
-
-			mergedPool, err := pool.GetMergedCoinPool(ai500Limit)
-			if err != nil {
-				return nil, fmt.Errorf("获取合并币种池失败: %w", err)
-			}
-
-			// 构建候选币种列表（包含来源信息）
-			for _, symbol := range mergedPool.AllSymbols {
-				sources := mergedPool.SymbolSources[symbol]
-				candidateCoins = append(candidateCoins, decision.CandidateCoin{
-					Symbol:  symbol,
-					Sources: sources, // "ai500" 和/或 "oi_top"
-				})
-			}
-
-			log.Printf("📋 [%s] 数据库无默认币种配置，使用AI500+OI Top: AI500前%d + OI_Top20 = 总计%d个候选币种",
-				at.name, ai500Limit, len(candidateCoins))
-			return candidateCoins, nil
+			// 如果数据库中没有配置默认币种，则使用交易员的币种池（本地筛选器等）作为fallback
+			return at.getCoinPoolCandidates()
 		}
 	} else {
 		// 使用自定义币种列表
@@ -1718,7 +2124,7 @@ func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 			symbol := normalizeSymbol(coin)
 			candidateCoins = append(candidateCoins, decision.CandidateCoin{
 				Symbol:  symbol,
-				Sources: []string{"custom"}, // 标记为自定义来源
+				Sources: []pool.SourceScore{{Name: "custom"}}, // 标记为自定义来源
 			})
 		}
 
@@ -1728,6 +2134,28 @@ func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 	}
 }
 
+// getCoinPoolCandidates 从交易员的币种池获取候选币种（合并各信号源，按加权评分排序）
+func (at *AutoTrader) getCoinPoolCandidates() ([]decision.CandidateCoin, error) {
+	mergedPool, err := at.coinPool.GetMergedCoinPool(0)
+	if err != nil {
+		return nil, fmt.Errorf("获取合并币种池失败: %w", err)
+	}
+
+	// 构建候选币种列表（包含来源信息）
+	var candidateCoins []decision.CandidateCoin
+	for _, symbol := range mergedPool.AllSymbols {
+		candidateCoins = append(candidateCoins, decision.CandidateCoin{
+			Symbol:  symbol,
+			Sources: mergedPool.SymbolSources[symbol],
+			Score:   mergedPool.Scores[symbol],
+		})
+	}
+
+	log.Printf("📋 [%s] 使用信号源 %v: 总计%d个候选币种",
+		at.name, at.coinPool.SignalSourceNames(), len(candidateCoins))
+	return candidateCoins, nil
+}
+
 // normalizeSymbol 标准化币种符号（确保以USDT结尾）
 func normalizeSymbol(symbol string) string {
 	// 转为大写
@@ -1756,6 +2184,9 @@ func (at *AutoTrader) startDrawdownMonitor() {
 			select {
 			case <-ticker.C:
 				at.checkPositionDrawdown()
+				at.checkTimeExits()
+				at.checkLiquidationDistance()
+				at.checkpointMonitorState()
             case <-at.stopMonitorCh:
                 log.Println("⏹ Stop drawdown monitor")
                 return
@@ -1774,20 +2205,13 @@ func (at *AutoTrader) checkPositionDrawdown() {
     }
 
 	for _, pos := range positions {
-		symbol := pos["symbol"].(string)
-		side := pos["side"].(string)
-		entryPrice := pos["entryPrice"].(float64)
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity // 空仓数量为负，转为正数
-		}
+		symbol := pos.Symbol
+		side := pos.Side
+		entryPrice := pos.EntryPrice
+		markPrice := pos.MarkPrice
 
 		// 计算当前盈亏百分比
-		leverage := 10 // 默认值
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
+		leverage := pos.EffectiveLeverage()
 
 		var currentPnLPct float64
 		if side == "long" {
@@ -1854,17 +2278,17 @@ func (at *AutoTrader) checkPositionDrawdown() {
 func (at *AutoTrader) emergencyClosePosition(symbol, side string) error {
     switch side {
     case "long":
-        order, err := at.trader.CloseLong(symbol, 0) // 0 = 全部平仓
+        order, err := at.trader.CloseLong(symbol, 0, "") // 0 = 全部平仓，紧急平仓使用随机ID
         if err != nil {
             return err
         }
-        log.Printf("✅ Emergency long close succeeded, order ID: %v", order["orderId"])
+        log.Printf("✅ Emergency long close succeeded, order ID: %v", order.OrderID)
     case "short":
-        order, err := at.trader.CloseShort(symbol, 0) // 0 = 全部平仓
+        order, err := at.trader.CloseShort(symbol, 0, "") // 0 = 全部平仓，紧急平仓使用随机ID
         if err != nil {
             return err
         }
-        log.Printf("✅ Emergency short close succeeded, order ID: %v", order["orderId"])
+        log.Printf("✅ Emergency short close succeeded, order ID: %v", order.OrderID)
     default:
         return fmt.Errorf("invalid side: %s", side)
     }
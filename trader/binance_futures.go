@@ -2,45 +2,34 @@ package trader
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"nofx-lite/hook"
+	"nofx-lite/ratelimit"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/adshao/go-binance/v2/common"
 	"github.com/adshao/go-binance/v2/futures"
 )
 
-// getBrOrderID 生成唯一订单ID（合约专用）
-// 格式: x-{BR_ID}{TIMESTAMP}{RANDOM}
+// getBrOrderID 生成带br ID前缀的订单ID（合约专用）
+// 格式: x-{BR_ID}{clientOrderID前21位}
 // 合约限制32字符，统一使用此限制以保持一致性
-// 使用纳秒时间戳+随机数确保全局唯一性（冲突概率 < 10^-20）
-func getBrOrderID() string {
+// clientOrderID 为确定性ID时结果也是确定的，重试时可按此ID查询订单
+func getBrOrderID(clientOrderID string) string {
 	brID := "KzrpZaP9" // 合约br ID
 
-	// 计算可用空间: 32 - len("x-KzrpZaP9") = 32 - 11 = 21字符
-	// 分配: 13位时间戳 + 8位随机数 = 21字符（完美利用）
-	timestamp := time.Now().UnixNano() % 10000000000000 // 13位纳秒时间戳
-
-	// 生成4字节随机数（8位十六进制）
-	randomBytes := make([]byte, 4)
-	rand.Read(randomBytes)
-	randomHex := hex.EncodeToString(randomBytes)
-
-	// 格式: x-KzrpZaP9{13位时间戳}{8位随机}
-	// 示例: x-KzrpZaP91234567890123abcdef12 (正好31字符)
-	orderID := fmt.Sprintf("x-%s%d%s", brID, timestamp, randomHex)
-
-	// 确保不超过32字符限制（理论上正好31字符）
-	if len(orderID) > 32 {
-		orderID = orderID[:32]
+	// 计算可用空间: 32 - len("x-KzrpZaP9") = 32 - 10 = 22字符，取21位十六进制
+	// 示例: x-KzrpZaP91a2b3c4d5e6f7a8b9c0d1e2 (31字符)
+	id := ensureClientOrderID(clientOrderID)
+	if len(id) > 21 {
+		id = id[:21]
 	}
-
-	return orderID
+	return fmt.Sprintf("x-%s%s", brID, id)
 }
 
 // FuturesTrader 币安合约交易器
@@ -48,17 +37,21 @@ type FuturesTrader struct {
 	client *futures.Client
 
 	// 余额缓存
-	cachedBalance     map[string]interface{}
+	cachedBalance     *AccountBalance
 	balanceCacheTime  time.Time
 	balanceCacheMutex sync.RWMutex
 
 	// 持仓缓存
-	cachedPositions     []map[string]interface{}
+	cachedPositions     []Position
 	positionsCacheTime  time.Time
 	positionsCacheMutex sync.RWMutex
 
-	// 缓存有效期（15秒）
+	// 缓存有效期（15秒，用户数据流连接时延长）
 	cacheDuration time.Duration
+
+	// 用户数据流（实时推送）
+	stream      *binanceUserStream
+	streamMutex sync.Mutex
 }
 
 // NewFuturesTrader 创建合约交易器
@@ -71,6 +64,9 @@ func NewFuturesTrader(apiKey, secretKey string, testnet bool, userId string) *Fu
 		client = hookRes.GetResult()
 	}
 
+	// 同一API Key的所有请求共享权重限流（含重试与熔断）
+	client.HTTPClient = ratelimit.WrapClient(client.HTTPClient, ratelimit.For("binance", apiKey), ratelimit.BinanceProfile)
+
 	// 同步时间，避免 Timestamp ahead 错误
 	syncBinanceServerTime(client)
 	trader := &FuturesTrader{
@@ -124,10 +120,10 @@ func syncBinanceServerTime(client *futures.Client) {
 }
 
 // GetBalance 获取账户余额（带缓存）
-func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
+func (t *FuturesTrader) GetBalance() (*AccountBalance, error) {
 	// 先检查缓存是否有效
 	t.balanceCacheMutex.RLock()
-	if t.cachedBalance != nil && time.Since(t.balanceCacheTime) < t.cacheDuration {
+	if t.cachedBalance != nil && time.Since(t.balanceCacheTime) < t.currentCacheDuration() {
 		cacheAge := time.Since(t.balanceCacheTime)
 		t.balanceCacheMutex.RUnlock()
 		log.Printf("✓ 使用缓存的账户余额（缓存时间: %.1f秒前）", cacheAge.Seconds())
@@ -152,10 +148,10 @@ func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
 		return nil, fmt.Errorf("获取账户信息失败: %w", err)
 	}
 
-	result := make(map[string]interface{})
-	result["totalWalletBalance"], _ = strconv.ParseFloat(account.TotalWalletBalance, 64)
-	result["availableBalance"], _ = strconv.ParseFloat(account.AvailableBalance, 64)
-	result["totalUnrealizedProfit"], _ = strconv.ParseFloat(account.TotalUnrealizedProfit, 64)
+	result := &AccountBalance{}
+	result.TotalWalletBalance, _ = strconv.ParseFloat(account.TotalWalletBalance, 64)
+	result.AvailableBalance, _ = strconv.ParseFloat(account.AvailableBalance, 64)
+	result.TotalUnrealizedProfit, _ = strconv.ParseFloat(account.TotalUnrealizedProfit, 64)
 
 	log.Printf("✓ 币安API返回: 总余额=%s, 可用=%s, 未实现盈亏=%s",
 		account.TotalWalletBalance,
@@ -172,10 +168,10 @@ func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
 }
 
 // GetPositions 获取所有持仓（带缓存）
-func (t *FuturesTrader) GetPositions() ([]map[string]interface{}, error) {
+func (t *FuturesTrader) GetPositions() ([]Position, error) {
 	// 先检查缓存是否有效
 	t.positionsCacheMutex.RLock()
-	if t.cachedPositions != nil && time.Since(t.positionsCacheTime) < t.cacheDuration {
+	if t.cachedPositions != nil && time.Since(t.positionsCacheTime) < t.currentCacheDuration() {
 		cacheAge := time.Since(t.positionsCacheTime)
 		t.positionsCacheMutex.RUnlock()
 		log.Printf("✓ 使用缓存的持仓信息（缓存时间: %.1f秒前）", cacheAge.Seconds())
@@ -190,30 +186,30 @@ func (t *FuturesTrader) GetPositions() ([]map[string]interface{}, error) {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
-	var result []map[string]interface{}
+	var result []Position
 	for _, pos := range positions {
 		posAmt, _ := strconv.ParseFloat(pos.PositionAmt, 64)
 		if posAmt == 0 {
 			continue // 跳过无持仓的
 		}
 
-		posMap := make(map[string]interface{})
-		posMap["symbol"] = pos.Symbol
-		posMap["positionAmt"], _ = strconv.ParseFloat(pos.PositionAmt, 64)
-		posMap["entryPrice"], _ = strconv.ParseFloat(pos.EntryPrice, 64)
-		posMap["markPrice"], _ = strconv.ParseFloat(pos.MarkPrice, 64)
-		posMap["unRealizedProfit"], _ = strconv.ParseFloat(pos.UnRealizedProfit, 64)
-		posMap["leverage"], _ = strconv.ParseFloat(pos.Leverage, 64)
-		posMap["liquidationPrice"], _ = strconv.ParseFloat(pos.LiquidationPrice, 64)
+		position := Position{Symbol: pos.Symbol}
+		position.EntryPrice, _ = strconv.ParseFloat(pos.EntryPrice, 64)
+		position.MarkPrice, _ = strconv.ParseFloat(pos.MarkPrice, 64)
+		position.UnrealizedPnL, _ = strconv.ParseFloat(pos.UnRealizedProfit, 64)
+		position.LiquidationPrice, _ = strconv.ParseFloat(pos.LiquidationPrice, 64)
+		position.Leverage, _ = strconv.Atoi(pos.Leverage)
 
-		// 判断方向
+		// 判断方向（币安空仓数量为负数，统一转为正数）
 		if posAmt > 0 {
-			posMap["side"] = "long"
+			position.Side = "long"
+			position.Quantity = posAmt
 		} else {
-			posMap["side"] = "short"
+			position.Side = "short"
+			position.Quantity = -posAmt
 		}
 
-		result = append(result, posMap)
+		result = append(result, position)
 	}
 
 	// 更新缓存
@@ -283,11 +279,9 @@ func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
 	positions, err := t.GetPositions()
 	if err == nil {
 		for _, pos := range positions {
-			if pos["symbol"] == symbol {
-				if lev, ok := pos["leverage"].(float64); ok {
-					currentLeverage = int(lev)
-					break
-				}
+			if pos.Symbol == symbol {
+				currentLeverage = pos.Leverage
+				break
 			}
 		}
 	}
@@ -323,7 +317,7 @@ func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
 }
 
 // OpenLong 开多仓
-func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int, clientOrderID string) (*OrderResult, error) {
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
@@ -336,6 +330,11 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 
 	// 注意：仓位模式应该由调用方（AutoTrader）在开仓前通过 SetMarginMode 设置
 
+	return t.IncreaseLong(symbol, quantity, clientOrderID)
+}
+
+// IncreaseLong 追加多仓（不撤销已有委托单、不重设杠杆）
+func (t *FuturesTrader) IncreaseLong(symbol string, quantity float64, clientOrderID string) (*OrderResult, error) {
 	// 格式化数量到正确精度
 	quantityStr, err := t.FormatQuantity(symbol, quantity)
 	if err != nil {
@@ -354,15 +353,7 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 	}
 
 	// 创建市价买入订单（使用br ID）
-	order, err := t.client.NewCreateOrderService().
-		Symbol(symbol).
-		Side(futures.SideTypeBuy).
-		PositionSide(futures.PositionSideTypeLong).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantityStr).
-		NewClientOrderID(getBrOrderID()).
-		Do(context.Background())
-
+	order, err := t.placeMarketOrder(symbol, futures.SideTypeBuy, futures.PositionSideTypeLong, quantityStr, clientOrderID)
 	if err != nil {
 		return nil, fmt.Errorf("开多仓失败: %w", err)
 	}
@@ -370,15 +361,11 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 	log.Printf("✓ 开多仓成功: %s 数量: %s", symbol, quantityStr)
 	log.Printf("  订单ID: %d", order.OrderID)
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return order, nil
 }
 
 // OpenShort 开空仓
-func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int, clientOrderID string) (*OrderResult, error) {
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
@@ -391,6 +378,11 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 
 	// 注意：仓位模式应该由调用方（AutoTrader）在开仓前通过 SetMarginMode 设置
 
+	return t.IncreaseShort(symbol, quantity, clientOrderID)
+}
+
+// IncreaseShort 追加空仓（不撤销已有委托单、不重设杠杆）
+func (t *FuturesTrader) IncreaseShort(symbol string, quantity float64, clientOrderID string) (*OrderResult, error) {
 	// 格式化数量到正确精度
 	quantityStr, err := t.FormatQuantity(symbol, quantity)
 	if err != nil {
@@ -409,15 +401,7 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 	}
 
 	// 创建市价卖出订单（使用br ID）
-	order, err := t.client.NewCreateOrderService().
-		Symbol(symbol).
-		Side(futures.SideTypeSell).
-		PositionSide(futures.PositionSideTypeShort).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantityStr).
-		NewClientOrderID(getBrOrderID()).
-		Do(context.Background())
-
+	order, err := t.placeMarketOrder(symbol, futures.SideTypeSell, futures.PositionSideTypeShort, quantityStr, clientOrderID)
 	if err != nil {
 		return nil, fmt.Errorf("开空仓失败: %w", err)
 	}
@@ -425,15 +409,11 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 	log.Printf("✓ 开空仓成功: %s 数量: %s", symbol, quantityStr)
 	log.Printf("  订单ID: %d", order.OrderID)
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return order, nil
 }
 
 // CloseLong 平多仓
-func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *FuturesTrader) CloseLong(symbol string, quantity float64, clientOrderID string) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -442,8 +422,8 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 		}
 
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
+			if pos.Symbol == symbol && pos.Side == "long" {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -460,15 +440,7 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 	}
 
 	// 创建市价卖出订单（平多，使用br ID）
-	order, err := t.client.NewCreateOrderService().
-		Symbol(symbol).
-		Side(futures.SideTypeSell).
-		PositionSide(futures.PositionSideTypeLong).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantityStr).
-		NewClientOrderID(getBrOrderID()).
-		Do(context.Background())
-
+	order, err := t.placeMarketOrder(symbol, futures.SideTypeSell, futures.PositionSideTypeLong, quantityStr, clientOrderID)
 	if err != nil {
 		return nil, fmt.Errorf("平多仓失败: %w", err)
 	}
@@ -480,15 +452,11 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return order, nil
 }
 
 // CloseShort 平空仓
-func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *FuturesTrader) CloseShort(symbol string, quantity float64, clientOrderID string) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -497,8 +465,8 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 		}
 
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				quantity = -pos["positionAmt"].(float64) // 空仓数量是负的，取绝对值
+			if pos.Symbol == symbol && pos.Side == "short" {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -515,15 +483,7 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 	}
 
 	// 创建市价买入订单（平空，使用br ID）
-	order, err := t.client.NewCreateOrderService().
-		Symbol(symbol).
-		Side(futures.SideTypeBuy).
-		PositionSide(futures.PositionSideTypeShort).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantityStr).
-		NewClientOrderID(getBrOrderID()).
-		Do(context.Background())
-
+	order, err := t.placeMarketOrder(symbol, futures.SideTypeBuy, futures.PositionSideTypeShort, quantityStr, clientOrderID)
 	if err != nil {
 		return nil, fmt.Errorf("平空仓失败: %w", err)
 	}
@@ -535,11 +495,58 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return order, nil
+}
+
+// placeMarketOrder 幂等市价下单（结果不确定时按clientOrderId查询后再重试）
+func (t *FuturesTrader) placeMarketOrder(symbol string, side futures.SideType, positionSide futures.PositionSideType, quantityStr, clientOrderID string) (*OrderResult, error) {
+	brOrderID := getBrOrderID(clientOrderID)
+
+	return placeOrderIdempotent(brOrderID, func() (*OrderResult, error) {
+		order, err := t.client.NewCreateOrderService().
+			Symbol(symbol).
+			Side(side).
+			PositionSide(positionSide).
+			Type(futures.OrderTypeMarket).
+			Quantity(quantityStr).
+			NewClientOrderID(brOrderID).
+			NewOrderResponseType(futures.NewOrderRespTypeRESULT). // 返回成交均价
+			Do(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		avgPrice, _ := strconv.ParseFloat(order.AvgPrice, 64)
+		executedQty, _ := strconv.ParseFloat(order.ExecutedQuantity, 64)
+		return &OrderResult{
+			OrderID:     order.OrderID,
+			Symbol:      order.Symbol,
+			Status:      string(order.Status),
+			AvgPrice:    avgPrice,
+			ExecutedQty: executedQty,
+		}, nil
+	}, func() (*OrderResult, error) {
+		order, err := t.client.NewGetOrderService().
+			Symbol(symbol).
+			OrigClientOrderID(brOrderID).
+			Do(context.Background())
+		if err != nil {
+			// -2013 订单不存在
+			var apiErr *common.APIError
+			if errors.As(err, &apiErr) && apiErr.Code == -2013 {
+				return nil, nil
+			}
+			return nil, err
+		}
+		avgPrice, _ := strconv.ParseFloat(order.AvgPrice, 64)
+		executedQty, _ := strconv.ParseFloat(order.ExecutedQuantity, 64)
+		return &OrderResult{
+			OrderID:     order.OrderID,
+			Symbol:      order.Symbol,
+			Status:      string(order.Status),
+			AvgPrice:    avgPrice,
+			ExecutedQty: executedQty,
+		}, nil
+	})
 }
 
 // CancelStopLossOrdersBySide 仅取消指定方向的止损单（防止双向持仓误删）
@@ -786,6 +793,38 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 	return nil
 }
 
+// SetPartialTakeProfit 设置部分止盈单（只平指定数量，不使用 closePosition）
+// 双向持仓模式下不能传 reduceOnly，由 positionSide 保证只减仓
+func (t *FuturesTrader) SetPartialTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	side := futures.SideTypeBuy
+	posSide := futures.PositionSideTypeShort
+	if positionSide == "LONG" {
+		side = futures.SideTypeSell
+		posSide = futures.PositionSideTypeLong
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(side).
+		PositionSide(posSide).
+		Type(futures.OrderTypeTakeProfitMarket).
+		StopPrice(fmt.Sprintf("%.8f", takeProfitPrice)).
+		Quantity(quantityStr).
+		WorkingType(futures.WorkingTypeContractPrice).
+		Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("设置部分止盈失败: %w", err)
+	}
+
+	log.Printf("  部分止盈设置: %s @ %.4f", quantityStr, takeProfitPrice)
+	return nil
+}
+
 // GetMinNotional 获取最小名义价值（Binance要求）
 func (t *FuturesTrader) GetMinNotional(symbol string) float64 {
 	// 使用保守的默认值 10 USDT，确保订单能够通过交易所验证
@@ -837,6 +876,22 @@ func (t *FuturesTrader) GetSymbolPrecision(symbol string) (int, error) {
 	return 3, nil // 默认精度为3
 }
 
+// GetTradableSymbols 获取可交易的USDT永续合约（status=TRADING 且 contractType=PERPETUAL）
+func (t *FuturesTrader) GetTradableSymbols() (map[string]bool, error) {
+	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取交易规则失败: %w", err)
+	}
+
+	symbols := make(map[string]bool)
+	for _, s := range exchangeInfo.Symbols {
+		if s.Status == string(futures.SymbolStatusTypeTrading) && s.ContractType == futures.ContractTypePerpetual && s.QuoteAsset == "USDT" {
+			symbols[s.Symbol] = true
+		}
+	}
+	return symbols, nil
+}
+
 // calculatePrecision 从stepSize计算精度
 func calculatePrecision(stepSize string) int {
 	// 去除尾部的0
@@ -915,3 +970,101 @@ func stringContains(s, substr string) bool {
 	}
 	return false
 }
+
+// GetFundingFees 获取资金费净额（income类型FUNDING_FEE，正数为收取）
+func (t *FuturesTrader) GetFundingFees(symbol string, since time.Time) (float64, error) {
+	incomes, err := t.client.NewGetIncomeHistoryService().
+		Symbol(symbol).
+		IncomeType("FUNDING_FEE").
+		StartTime(since.UnixMilli()).
+		Limit(1000).
+		Do(context.Background())
+	if err != nil {
+		return 0, fmt.Errorf("获取资金费记录失败: %w", err)
+	}
+
+	total := 0.0
+	for _, income := range incomes {
+		amount, _ := strconv.ParseFloat(income.Income, 64)
+		total += amount
+	}
+	return total, nil
+}
+
+// GetOpenOrders 获取所有币种的未成交挂单
+func (t *FuturesTrader) GetOpenOrders() ([]OpenOrder, error) {
+	orders, err := t.client.NewListOpenOrdersService().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取未完成订单失败: %w", err)
+	}
+
+	result := make([]OpenOrder, 0, len(orders))
+	for _, order := range orders {
+		price, _ := strconv.ParseFloat(order.Price, 64)
+		stopPrice, _ := strconv.ParseFloat(order.StopPrice, 64)
+		quantity, _ := strconv.ParseFloat(order.OrigQuantity, 64)
+		result = append(result, OpenOrder{
+			OrderID:      order.OrderID,
+			Symbol:       order.Symbol,
+			Type:         classifyOpenOrderType(string(order.Type)),
+			PositionSide: openOrderPositionSide(string(order.PositionSide), string(order.Side)),
+			Price:        price,
+			StopPrice:    stopPrice,
+			Quantity:     quantity,
+			ReduceOnly:   order.ReduceOnly || order.ClosePosition,
+		})
+	}
+	return result, nil
+}
+
+// classifyOpenOrderType 币安/Aster订单类型归类
+func classifyOpenOrderType(orderType string) string {
+	switch orderType {
+	case "STOP_MARKET", "STOP":
+		return OpenOrderStopLoss
+	case "TAKE_PROFIT_MARKET", "TAKE_PROFIT":
+		return OpenOrderTakeProfit
+	case "LIMIT":
+		return OpenOrderLimit
+	}
+	return OpenOrderOther
+}
+
+// openOrderPositionSide 挂单作用的持仓方向（单向持仓模式下按买卖方向推断：卖出减多，买入减空）
+func openOrderPositionSide(positionSide, side string) string {
+	switch strings.ToUpper(positionSide) {
+	case "LONG":
+		return "long"
+	case "SHORT":
+		return "short"
+	}
+	if strings.ToUpper(side) == "SELL" {
+		return "long"
+	}
+	return "short"
+}
+
+// GetMarginTiers 获取维持保证金阶梯（leverageBracket）
+func (t *FuturesTrader) GetMarginTiers(symbol string) ([]MarginTier, error) {
+	brackets, err := t.client.NewGetLeverageBracketService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取杠杆分层失败: %w", err)
+	}
+
+	var tiers []MarginTier
+	for _, b := range brackets {
+		if b.Symbol != symbol {
+			continue
+		}
+		for _, bracket := range b.Brackets {
+			tiers = append(tiers, MarginTier{
+				NotionalFloor:   bracket.NotionalFloor,
+				NotionalCap:     bracket.NotionalCap,
+				MaintMarginRate: bracket.MaintMarginRatio,
+				MaintAmount:     bracket.Cum,
+				MaxLeverage:     bracket.InitialLeverage,
+			})
+		}
+	}
+	return tiers, nil
+}
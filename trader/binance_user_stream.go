@@ -0,0 +1,408 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+const (
+	// listenKey 有效期60分钟，每30分钟续期一次
+	listenKeyKeepaliveInterval = 30 * time.Minute
+	// 推送连接正常时的缓存有效期（推送会实时更新持仓，REST仅用于校正标记价格/强平价）
+	streamCacheDuration = 60 * time.Second
+	// 断线重连最大等待时间
+	maxStreamReconnectWait = time.Minute
+)
+
+// binanceUserStream 币安用户数据流状态
+type binanceUserStream struct {
+	handler func(TradeEvent)
+	stopCh  chan struct{}
+
+	mu        sync.RWMutex
+	connected bool
+	orders    map[int64]*streamOrder // 未完结订单（按订单ID）
+	leverage  map[string]int         // 推送的杠杆变更 (symbol -> 倍数)
+}
+
+// streamOrder 推送中的订单状态（累计多笔成交的盈亏与手续费）
+type streamOrder struct {
+	Symbol       string
+	PositionSide string
+	OrigType     futures.OrderType
+	Status       futures.OrderStatusType
+	RealizedPnL  float64
+	Commission   float64
+}
+
+// StartAccountStream 启动用户数据流（listenKey WebSocket）
+func (t *FuturesTrader) StartAccountStream(handler func(TradeEvent)) error {
+	t.streamMutex.Lock()
+	defer t.streamMutex.Unlock()
+
+	if t.stream != nil {
+		return nil
+	}
+
+	// 先确认listenKey可用，避免API权限问题时静默失败
+	listenKey, err := t.client.NewStartUserStreamService().Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("获取listenKey失败: %w", err)
+	}
+
+	t.stream = &binanceUserStream{
+		handler:  handler,
+		stopCh:   make(chan struct{}),
+		orders:   make(map[int64]*streamOrder),
+		leverage: make(map[string]int),
+	}
+	go t.runUserStream(t.stream, listenKey)
+	return nil
+}
+
+// StopAccountStream 停止用户数据流
+func (t *FuturesTrader) StopAccountStream() {
+	t.streamMutex.Lock()
+	stream := t.stream
+	t.stream = nil
+	t.streamMutex.Unlock()
+
+	if stream != nil {
+		close(stream.stopCh)
+	}
+}
+
+// streamConnected 推送连接是否正常
+func (t *FuturesTrader) streamConnected() bool {
+	t.streamMutex.Lock()
+	stream := t.stream
+	t.streamMutex.Unlock()
+	if stream == nil {
+		return false
+	}
+	stream.mu.RLock()
+	defer stream.mu.RUnlock()
+	return stream.connected
+}
+
+// currentCacheDuration 推送正常时延长缓存有效期
+func (t *FuturesTrader) currentCacheDuration() time.Duration {
+	if t.streamConnected() {
+		return streamCacheDuration
+	}
+	return t.cacheDuration
+}
+
+// runUserStream 维持推送连接（断线指数退避重连）
+func (t *FuturesTrader) runUserStream(stream *binanceUserStream, listenKey string) {
+	wait := time.Second
+	for {
+		startedAt := time.Now()
+		err := t.serveUserStream(stream, listenKey)
+
+		stream.mu.Lock()
+		stream.connected = false
+		stream.mu.Unlock()
+
+		select {
+		case <-stream.stopCh:
+			t.client.NewCloseUserStreamService().ListenKey(listenKey).Do(context.Background())
+			log.Printf("⏹ 币安用户数据流已停止")
+			return
+		default:
+		}
+
+		// 连接稳定运行过一段时间则重置退避
+		if time.Since(startedAt) > maxStreamReconnectWait {
+			wait = time.Second
+		}
+		log.Printf("⚠️ 币安用户数据流断开: %v，%v后重连", err, wait)
+
+		select {
+		case <-stream.stopCh:
+			return
+		case <-time.After(wait):
+		}
+		wait *= 2
+		if wait > maxStreamReconnectWait {
+			wait = maxStreamReconnectWait
+		}
+
+		// listenKey 可能已过期，重新获取（未过期时币安返回同一个key并续期）
+		newKey, err := t.client.NewStartUserStreamService().Do(context.Background())
+		if err != nil {
+			log.Printf("⚠️ 重新获取listenKey失败: %v", err)
+			continue
+		}
+		listenKey = newKey
+	}
+}
+
+// serveUserStream 建立一次推送连接，阻塞直到断开或停止
+func (t *FuturesTrader) serveUserStream(stream *binanceUserStream, listenKey string) error {
+	expiredCh := make(chan struct{}, 1)
+	errCh := make(chan error, 1)
+
+	handler := func(event *futures.WsUserDataEvent) {
+		switch event.Event {
+		case futures.UserDataEventTypeAccountUpdate:
+			t.applyAccountUpdate(stream, event.AccountUpdate)
+		case futures.UserDataEventTypeOrderTradeUpdate:
+			t.applyOrderUpdate(stream, event.OrderTradeUpdate)
+		case futures.UserDataEventTypeAccountConfigUpdate:
+			t.applyConfigUpdate(stream, event.AccountConfigUpdate)
+		case futures.UserDataEventTypeConditionalOrderTriggerReject:
+			reject := event.ConditionalOrderTriggerReject
+			log.Printf("🚨 条件单触发被拒绝: %s 订单%d %s", reject.Symbol, reject.OrderId, reject.RejectReason)
+			stream.handler(TradeEvent{
+				Type:    TradeEventTriggerRejected,
+				Symbol:  reject.Symbol,
+				OrderID: reject.OrderId,
+				Reason:  reject.RejectReason,
+				Time:    time.UnixMilli(event.Time),
+			})
+		case futures.UserDataEventTypeListenKeyExpired:
+			select {
+			case expiredCh <- struct{}{}:
+			default:
+			}
+		}
+	}
+	errHandler := func(err error) {
+		select {
+		case errCh <- err:
+		default:
+		}
+	}
+
+	doneC, stopC, err := futures.WsUserDataServe(listenKey, handler, errHandler)
+	if err != nil {
+		return fmt.Errorf("连接用户数据流失败: %w", err)
+	}
+
+	// 连接建立后重新拉取一次REST快照，补齐断线期间错过的变化
+	t.invalidateCache()
+	if _, err := t.GetPositions(); err != nil {
+		log.Printf("⚠️ 同步持仓快照失败: %v", err)
+	}
+
+	stream.mu.Lock()
+	stream.connected = true
+	stream.mu.Unlock()
+	log.Printf("✓ 币安用户数据流已连接")
+
+	keepalive := time.NewTicker(listenKeyKeepaliveInterval)
+	defer keepalive.Stop()
+
+	var lastErr error
+	for {
+		select {
+		case <-doneC:
+			if lastErr == nil {
+				lastErr = fmt.Errorf("连接已关闭")
+			}
+			return lastErr
+		case err := <-errCh:
+			lastErr = err
+		case <-expiredCh:
+			close(stopC)
+			<-doneC
+			return fmt.Errorf("listenKey已过期")
+		case <-keepalive.C:
+			if err := t.client.NewKeepaliveUserStreamService().ListenKey(listenKey).Do(context.Background()); err != nil {
+				close(stopC)
+				<-doneC
+				return fmt.Errorf("listenKey续期失败: %w", err)
+			}
+		case <-stream.stopCh:
+			close(stopC)
+			<-doneC
+			return nil
+		}
+	}
+}
+
+// invalidateCache 使余额和持仓缓存失效（下次读取走REST）
+func (t *FuturesTrader) invalidateCache() {
+	t.balanceCacheMutex.Lock()
+	t.balanceCacheTime = time.Time{}
+	t.balanceCacheMutex.Unlock()
+
+	t.positionsCacheMutex.Lock()
+	t.positionsCacheTime = time.Time{}
+	t.positionsCacheMutex.Unlock()
+}
+
+// applyAccountUpdate 根据ACCOUNT_UPDATE更新持仓缓存
+// 推送不包含可用余额和强平价：余额缓存直接失效，新开持仓触发一次REST刷新
+func (t *FuturesTrader) applyAccountUpdate(stream *binanceUserStream, update futures.WsAccountUpdate) {
+	if len(update.Balances) > 0 {
+		t.balanceCacheMutex.Lock()
+		t.balanceCacheTime = time.Time{}
+		t.balanceCacheMutex.Unlock()
+	}
+	if len(update.Positions) == 0 {
+		return
+	}
+
+	t.positionsCacheMutex.Lock()
+	defer t.positionsCacheMutex.Unlock()
+
+	if t.cachedPositions == nil {
+		return
+	}
+
+	// 写时复制：已返回给调用方的切片不受影响
+	positions := make([]Position, 0, len(t.cachedPositions))
+	positions = append(positions, t.cachedPositions...)
+	needRefresh := false
+
+	for _, wp := range update.Positions {
+		posAmt, _ := strconv.ParseFloat(wp.Amount, 64)
+		side := strings.ToLower(string(wp.Side))
+		if side == "both" {
+			side = "long"
+			if posAmt < 0 {
+				side = "short"
+			}
+		}
+		if posAmt < 0 {
+			posAmt = -posAmt
+		}
+
+		index := -1
+		for i, pos := range positions {
+			if pos.Symbol == wp.Symbol && pos.Side == side {
+				index = i
+				break
+			}
+		}
+
+		if posAmt == 0 {
+			if index >= 0 {
+				positions = append(positions[:index], positions[index+1:]...)
+			}
+			continue
+		}
+		if index < 0 {
+			// 新持仓缺少杠杆和强平价，交给REST补全
+			needRefresh = true
+			continue
+		}
+
+		pos := positions[index]
+		pos.Quantity = posAmt
+		pos.EntryPrice, _ = strconv.ParseFloat(wp.EntryPrice, 64)
+		pos.UnrealizedPnL, _ = strconv.ParseFloat(wp.UnrealizedPnL, 64)
+		if markPrice, _ := strconv.ParseFloat(wp.MarkPrice, 64); markPrice > 0 {
+			pos.MarkPrice = markPrice
+		} else if side == "long" {
+			pos.MarkPrice = pos.EntryPrice + pos.UnrealizedPnL/posAmt
+		} else {
+			pos.MarkPrice = pos.EntryPrice - pos.UnrealizedPnL/posAmt
+		}
+		stream.mu.RLock()
+		if leverage, ok := stream.leverage[wp.Symbol]; ok {
+			pos.Leverage = leverage
+		}
+		stream.mu.RUnlock()
+		positions[index] = pos
+	}
+
+	t.cachedPositions = positions
+	if needRefresh {
+		t.positionsCacheTime = time.Time{}
+	} else {
+		t.positionsCacheTime = time.Now()
+	}
+}
+
+// applyConfigUpdate 记录杠杆变更
+func (t *FuturesTrader) applyConfigUpdate(stream *binanceUserStream, update futures.WsAccountConfigUpdate) {
+	if update.Symbol == "" || update.Leverage <= 0 {
+		return
+	}
+	stream.mu.Lock()
+	stream.leverage[update.Symbol] = int(update.Leverage)
+	stream.mu.Unlock()
+}
+
+// applyOrderUpdate 维护订单状态，订单完全成交时推送事件
+func (t *FuturesTrader) applyOrderUpdate(stream *binanceUserStream, update futures.WsOrderTradeUpdate) {
+	stream.mu.Lock()
+	order, exists := stream.orders[update.ID]
+	if !exists {
+		order = &streamOrder{
+			Symbol:       update.Symbol,
+			PositionSide: strings.ToLower(string(update.PositionSide)),
+			OrigType:     update.OriginalType,
+		}
+		stream.orders[update.ID] = order
+	}
+	order.Status = update.Status
+	if update.ExecutionType == futures.OrderExecutionTypeTrade {
+		realizedPnL, _ := strconv.ParseFloat(update.RealizedPnL, 64)
+		commission, _ := strconv.ParseFloat(update.Commission, 64)
+		order.RealizedPnL += realizedPnL
+		order.Commission += commission
+	}
+
+	final := update.Status == futures.OrderStatusTypeFilled ||
+		update.Status == futures.OrderStatusTypeCanceled ||
+		update.Status == futures.OrderStatusTypeExpired ||
+		update.Status == futures.OrderStatusTypeRejected
+	if final {
+		delete(stream.orders, update.ID)
+	}
+	stream.mu.Unlock()
+
+	if update.Status != futures.OrderStatusTypeFilled {
+		return
+	}
+
+	// 成交后持仓/余额已变化
+	t.invalidateCache()
+
+	price, _ := strconv.ParseFloat(update.AveragePrice, 64)
+	quantity, _ := strconv.ParseFloat(update.AccumulatedFilledQty, 64)
+	side := order.PositionSide
+	closing := update.IsReduceOnly || update.IsClosingPosition ||
+		(side == "long" && update.Side == futures.SideTypeSell) ||
+		(side == "short" && update.Side == futures.SideTypeBuy)
+
+	event := TradeEvent{
+		Type:        classifyBinanceFill(update),
+		Symbol:      update.Symbol,
+		Side:        side,
+		OrderID:     update.ID,
+		Price:       price,
+		Quantity:    quantity,
+		RealizedPnL: order.RealizedPnL,
+		Commission:  order.Commission,
+		Closing:     closing,
+		Time:        time.UnixMilli(update.TradeTime),
+	}
+	stream.handler(event)
+}
+
+// classifyBinanceFill 根据原始订单类型区分止损/止盈/强平
+func classifyBinanceFill(update futures.WsOrderTradeUpdate) string {
+	// 强平单clientOrderId以 autoclose- 开头，ADL为 adl_autoclose
+	if strings.HasPrefix(update.ClientOrderID, "autoclose-") || strings.HasPrefix(update.ClientOrderID, "adl_autoclose") {
+		return TradeEventLiquidation
+	}
+	switch update.OriginalType {
+	case futures.OrderTypeStopMarket, futures.OrderTypeStop:
+		return TradeEventStopLoss
+	case futures.OrderTypeTakeProfitMarket, futures.OrderTypeTakeProfit:
+		return TradeEventTakeProfit
+	}
+	return TradeEventFill
+}
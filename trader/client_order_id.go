@@ -0,0 +1,98 @@
+package trader
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"nofx-lite/ratelimit"
+	"strings"
+	"time"
+
+	"github.com/adshao/go-binance/v2/common"
+)
+
+// maxOrderAttempts 结果不确定时的最大下单次数（含首次）
+const maxOrderAttempts = 3
+
+// NewClientOrderID 生成确定性的客户端订单ID（32位十六进制）
+// 同一交易员、同一决策周期、同一币种、同一动作得到相同ID，重试不会产生第二笔订单
+func NewClientOrderID(traderID string, cycle int, symbol, action string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%s|%s", traderID, cycle, symbol, action)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// ensureClientOrderID 调用方未提供ID时随机生成（手动/紧急平仓等无周期上下文的下单）
+func ensureClientOrderID(clientOrderID string) string {
+	if clientOrderID != "" {
+		return clientOrderID
+	}
+	randomBytes := make([]byte, 16)
+	rand.Read(randomBytes)
+	return hex.EncodeToString(randomBytes)
+}
+
+// isAmbiguousOrderError 下单请求是否可能已被交易所处理（超时、连接中断、5xx、币安-1007等）
+func isAmbiguousOrderError(err error) bool {
+	if err == nil || errors.Is(err, ratelimit.ErrCircuitOpen) {
+		return false
+	}
+	if ratelimit.IsNetworkError(err) {
+		return true
+	}
+	var httpErr *ratelimit.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= http.StatusInternalServerError
+	}
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) {
+		// -1001 内部连接断开 / -1006 未知响应 / -1007 后端超时（执行状态未知）
+		return apiErr.Code == -1001 || apiErr.Code == -1006 || apiErr.Code == -1007
+	}
+	return strings.Contains(err.Error(), "status unknown")
+}
+
+// placeOrderIdempotent 幂等下单
+// place 使用同一 clientOrderID 下单；query 按该ID查询订单（未找到返回 nil, nil）
+// 结果不确定时先查询：已存在则直接返回，确认不存在才重试；查询失败则放弃，宁可漏单不重复下单
+func placeOrderIdempotent(clientOrderID string, place func() (*OrderResult, error), query func() (*OrderResult, error)) (*OrderResult, error) {
+	var lastErr error
+	for attempt := 1; attempt <= maxOrderAttempts; attempt++ {
+		result, err := place()
+		if err == nil {
+			return result, nil
+		}
+		if !isAmbiguousOrderError(err) {
+			return nil, err
+		}
+		lastErr = err
+		log.Printf("  ⚠️ 下单结果不确定 (clientOrderId=%s): %v，查询订单状态...", clientOrderID, err)
+
+		// 等待交易所处理完成后再查询
+		time.Sleep(ratelimit.Backoff(attempt))
+		existing, queryErr := query()
+		if queryErr != nil {
+			return nil, fmt.Errorf("下单结果未知且查询失败，未重试以避免重复下单 (clientOrderId=%s, 查询错误: %v): %w", clientOrderID, queryErr, err)
+		}
+		if existing != nil {
+			log.Printf("  ✓ 订单已在交易所创建 (clientOrderId=%s, 订单ID=%d, 状态=%s)", clientOrderID, existing.OrderID, existing.Status)
+			return existing, nil
+		}
+		log.Printf("  ↻ 确认订单未创建，使用相同clientOrderId重试 (%d/%d)", attempt, maxOrderAttempts)
+	}
+	return nil, fmt.Errorf("下单失败（已尝试%d次）: %w", maxOrderAttempts, lastErr)
+}
+
+// decisionOrderID 决策对应的客户端订单ID
+// 加入启动时间，避免重启后周期计数从头开始与历史订单ID重复
+// 人工决策使用独立的序号空间，不会与同周期AI决策（相同币种、动作）的订单ID重复
+func (at *AutoTrader) decisionOrderID(symbol, action string) string {
+	runID := fmt.Sprintf("%s@%d", at.id, at.startTime.Unix())
+	if at.activeManualSeq > 0 {
+		return NewClientOrderID(runID+"|manual", at.activeManualSeq, symbol, action)
+	}
+	return NewClientOrderID(runID, at.callCount, symbol, action)
+}
@@ -0,0 +1,317 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx-lite/decision"
+	"nofx-lite/logger"
+	"nofx-lite/market"
+	"strings"
+	"time"
+)
+
+// 拆单执行算法
+const (
+	ExecAlgoMarket  = "market"  // 单笔市价单
+	ExecAlgoIceberg = "iceberg" // 冰山：按对手盘深度拆成小单，等待盘口补充后再下
+	ExecAlgoTWAP    = "twap"    // 时间加权：在一段时间内等额分批下单
+)
+
+const (
+	// execDepthLevels 判断拆单时参考的对手盘档位数
+	execDepthLevels = 5
+	// execMarketDepthRatio 名义价值不超过对手盘前5档深度的此比例时直接一笔市价单
+	execMarketDepthRatio = 0.25
+	// icebergChildDepthRatio 冰山子单占对手盘前5档深度的比例
+	icebergChildDepthRatio = 0.2
+	// icebergRefillWait 冰山子单之间等待盘口补充的时间
+	icebergRefillWait = 3 * time.Second
+	// twapMaxDuration TWAP最长执行时间（不超过扫描间隔的1/3）
+	twapMaxDuration = 2 * time.Minute
+	// maxChildOrders 最多拆成的子单数
+	maxChildOrders = 10
+	// minChildNotionalUSD 子单最小名义价值（留出交易所最小下单额的余量）
+	minChildNotionalUSD = 20.0
+	// maxExecutionHistory 保留的执行记录数
+	maxExecutionHistory = 20
+)
+
+// ExecutionProgress 开仓执行进度（拆单时汇总所有子单）
+type ExecutionProgress struct {
+	ID              string    `json:"id"`
+	Symbol          string    `json:"symbol"`
+	Side            string    `json:"side"` // long / short
+	Algo            string    `json:"algo"`
+	TargetQuantity  float64   `json:"target_quantity"`
+	FilledQuantity  float64   `json:"filled_quantity"`
+	AvgPrice        float64   `json:"avg_price"`        // 子单成交均价加权（交易所未返回均价时为0）
+	ChildOrders     int       `json:"child_orders"`     // 计划子单数
+	CompletedOrders int       `json:"completed_orders"` // 已成交子单数
+	OrderIDs        []int64   `json:"order_ids"`
+	Status          string    `json:"status"` // running / completed / cancelled / failed
+	Error           string    `json:"error,omitempty"`
+	StartedAt       time.Time `json:"started_at"`
+	FinishedAt      time.Time `json:"finished_at,omitempty"`
+}
+
+// executionPlan 拆单计划
+type executionPlan struct {
+	algo     string
+	children int
+	interval time.Duration
+}
+
+// planExecution 根据订单名义价值与对手盘深度选择执行算法
+func (at *AutoTrader) planExecution(notional float64, depth *market.DepthData, isBuy bool) executionPlan {
+	single := executionPlan{algo: ExecAlgoMarket, children: 1}
+
+	bookNotional := topBookNotional(depth, isBuy, execDepthLevels)
+	if bookNotional <= 0 || notional <= bookNotional*execMarketDepthRatio {
+		return single
+	}
+
+	// 子单数受最小名义价值限制
+	maxByNotional := int(notional / minChildNotionalUSD)
+	if maxByNotional > maxChildOrders {
+		maxByNotional = maxChildOrders
+	}
+	if maxByNotional <= 1 {
+		return single
+	}
+
+	ratio := notional / bookNotional
+	if ratio <= 1 {
+		children := int(math.Ceil(ratio / icebergChildDepthRatio))
+		if children > maxByNotional {
+			children = maxByNotional
+		}
+		return executionPlan{algo: ExecAlgoIceberg, children: children, interval: icebergRefillWait}
+	}
+
+	// 超过可见深度：按时间分批，让盘口有时间补充
+	children := int(math.Ceil(ratio / execMarketDepthRatio))
+	if children > maxByNotional {
+		children = maxByNotional
+	}
+	duration := twapMaxDuration
+	if at.config.ScanInterval > 0 && at.config.ScanInterval/3 < duration {
+		duration = at.config.ScanInterval / 3
+	}
+	return executionPlan{algo: ExecAlgoTWAP, children: children, interval: duration / time.Duration(children)}
+}
+
+// topBookNotional 对手盘前N档的名义价值（买单看卖盘，卖单看买盘）
+func topBookNotional(depth *market.DepthData, isBuy bool, levels int) float64 {
+	if depth == nil {
+		return 0
+	}
+	book := depth.Bids
+	if isBuy {
+		book = depth.Asks
+	}
+	total := 0.0
+	for i, level := range book {
+		if i >= levels {
+			break
+		}
+		total += level.Price * level.Quantity
+	}
+	return total
+}
+
+// executeOpenOrder 按执行计划开仓（可能拆成多笔子单）
+// existingQty 为加仓前的持仓数量（新开仓为0）：新开仓的首笔子单清理旧委托并设置杠杆，
+// 其余子单与加仓走追加下单，不撤销已有止损；拆单时每笔子单成交后按累计持仓刷新止损，避免已成交部分无保护
+// 返回汇总的执行进度；部分成交后失败或被 Stop() 取消时同时返回进度与错误，调用方按已成交数量继续设置止损止盈
+func (at *AutoTrader) executeOpenOrder(d *decision.Decision, side string, quantity, price float64, depth *market.DepthData, existingQty float64) (*ExecutionProgress, error) {
+	isBuy := side == "long"
+	if fresh, err := at.marketSource.GetDepth(d.Symbol, execDepthLevels*4); err == nil && fresh != nil {
+		depth = fresh
+	}
+	plan := at.planExecution(quantity*price, depth, isBuy)
+
+	progress := &ExecutionProgress{
+		ID:             fmt.Sprintf("%s-%d-%s", d.Symbol, at.callCount, side),
+		Symbol:         d.Symbol,
+		Side:           side,
+		Algo:           plan.algo,
+		TargetQuantity: quantity,
+		ChildOrders:    plan.children,
+		Status:         "running",
+		StartedAt:      time.Now(),
+	}
+	at.trackExecution(progress)
+
+	if plan.children > 1 {
+		log.Printf("  🧊 %s 订单较大（%.2f USDT），使用%s执行: %d 笔子单，间隔 %v",
+			d.Symbol, quantity*price, plan.algo, plan.children, plan.interval)
+	}
+
+	var notionalFilled float64
+	var pricedQuantity float64
+	var execErr error
+	for i := 0; i < plan.children; i++ {
+		remaining := quantity - progress.FilledQuantity
+		if remaining <= 0 {
+			break
+		}
+
+		// 子单间等待（Stop() 时立即取消剩余子单）
+		if i > 0 {
+			select {
+			case <-at.stopMonitorCh:
+				execErr = fmt.Errorf("交易员已停止，取消剩余 %d 笔子单", plan.children-i)
+			case <-time.After(plan.interval):
+			}
+			if execErr != nil {
+				at.setExecutionStatus(progress, "cancelled")
+				break
+			}
+		}
+
+		childQty := at.childQuantity(plan, d.Symbol, isBuy, quantity, remaining, price, i)
+
+		// 子单使用独立的确定性ID（首笔与不拆单时一致）
+		action := d.Action
+		if i > 0 {
+			action = fmt.Sprintf("%s#%d", d.Action, i)
+		}
+		clientOrderID := at.decisionOrderID(d.Symbol, action)
+
+		var order *OrderResult
+		var err error
+		switch {
+		case i == 0 && existingQty == 0 && isBuy:
+			order, err = at.trader.OpenLong(d.Symbol, childQty, d.Leverage, clientOrderID)
+		case i == 0 && existingQty == 0:
+			order, err = at.trader.OpenShort(d.Symbol, childQty, d.Leverage, clientOrderID)
+		case isBuy:
+			order, err = at.trader.IncreaseLong(d.Symbol, childQty, clientOrderID)
+		default:
+			order, err = at.trader.IncreaseShort(d.Symbol, childQty, clientOrderID)
+		}
+		if err == nil && order.ExecutedQty <= 0 {
+			err = fmt.Errorf("子单未成交（订单状态 %s）", order.Status)
+		}
+		if err != nil {
+			execErr = err
+			at.setExecutionStatus(progress, "failed")
+			break
+		}
+
+		filled := order.ExecutedQty
+		at.executionsMutex.Lock()
+		progress.FilledQuantity += filled
+		progress.CompletedOrders++
+		progress.OrderIDs = append(progress.OrderIDs, order.OrderID)
+		if order.AvgPrice > 0 {
+			notionalFilled += order.AvgPrice * filled
+			pricedQuantity += filled
+			progress.AvgPrice = notionalFilled / pricedQuantity
+		}
+		at.executionsMutex.Unlock()
+
+		if plan.children > 1 {
+			log.Printf("  🧩 子单 %d/%d 成交: 数量 %.4f，累计 %.4f / %.4f",
+				i+1, plan.children, filled, progress.FilledQuantity, quantity)
+			// 后续子单还要等待：先按累计持仓刷新止损（全部完成后由调用方统一设置止损止盈）
+			if i < plan.children-1 && progress.FilledQuantity < quantity {
+				at.refreshInterimStopLoss(d, side, existingQty+progress.FilledQuantity)
+			}
+		}
+	}
+
+	at.executionsMutex.Lock()
+	if progress.Status == "running" {
+		progress.Status = "completed"
+	}
+	if execErr != nil {
+		progress.Error = execErr.Error()
+	}
+	progress.FinishedAt = time.Now()
+	at.executionsMutex.Unlock()
+
+	return progress, execErr
+}
+
+// setExecutionStatus 在锁内更新执行状态（GetExecutions 会并发读取）
+func (at *AutoTrader) setExecutionStatus(progress *ExecutionProgress, status string) {
+	at.executionsMutex.Lock()
+	progress.Status = status
+	at.executionsMutex.Unlock()
+}
+
+// refreshInterimStopLoss 拆单执行期间按已成交的持仓数量重设止损
+func (at *AutoTrader) refreshInterimStopLoss(d *decision.Decision, side string, quantity float64) {
+	if d.StopLoss <= 0 {
+		return
+	}
+	positionSide := strings.ToUpper(side)
+	if err := at.trader.CancelStopLossOrdersBySide(d.Symbol, positionSide); err != nil {
+		log.Printf("  ⚠ 取消旧止损单失败: %v", err)
+	}
+	if err := at.trader.SetStopLoss(d.Symbol, positionSide, quantity, d.StopLoss); err != nil {
+		log.Printf("  ⚠ 拆单期间设置止损失败: %v", err)
+		return
+	}
+	at.setPositionStopLoss(d.Symbol+"_"+side, d.StopLoss)
+}
+
+// childQuantity 计算第i笔子单数量（最后一笔或剩余过小时吃掉全部剩余）
+func (at *AutoTrader) childQuantity(plan executionPlan, symbol string, isBuy bool, total, remaining, price float64, i int) float64 {
+	if plan.children <= 1 || i == plan.children-1 {
+		return remaining
+	}
+
+	childQty := total / float64(plan.children)
+	if plan.algo == ExecAlgoIceberg {
+		// 冰山按最新盘口深度决定子单大小
+		if depth, err := at.marketSource.GetDepth(symbol, execDepthLevels*4); err == nil {
+			if book := topBookNotional(depth, isBuy, execDepthLevels); book > 0 && price > 0 {
+				childQty = math.Max(book*icebergChildDepthRatio, minChildNotionalUSD) / price
+			}
+		}
+	}
+
+	if childQty >= remaining || (remaining-childQty)*price < minChildNotionalUSD {
+		return remaining
+	}
+	return childQty
+}
+
+// trackExecution 记录执行进度（保留最近的记录）
+func (at *AutoTrader) trackExecution(progress *ExecutionProgress) {
+	at.executionsMutex.Lock()
+	defer at.executionsMutex.Unlock()
+	at.executions = append(at.executions, progress)
+	if len(at.executions) > maxExecutionHistory {
+		at.executions = at.executions[len(at.executions)-maxExecutionHistory:]
+	}
+}
+
+// GetExecutions 获取最近的开仓执行进度（最新的在前）
+func (at *AutoTrader) GetExecutions() []ExecutionProgress {
+	at.executionsMutex.RLock()
+	defer at.executionsMutex.RUnlock()
+
+	result := make([]ExecutionProgress, 0, len(at.executions))
+	for i := len(at.executions) - 1; i >= 0; i-- {
+		exec := *at.executions[i]
+		exec.OrderIDs = append([]int64(nil), exec.OrderIDs...)
+		result = append(result, exec)
+	}
+	return result
+}
+
+// recordExecution 将汇总的执行结果写入决策记录
+func (at *AutoTrader) recordExecution(progress *ExecutionProgress, estimate *market.SlippageEstimate, actionRecord *logger.DecisionAction) {
+	actionRecord.Quantity = progress.FilledQuantity
+	actionRecord.ExecAlgo = progress.Algo
+	actionRecord.ChildOrders = progress.CompletedOrders
+	actionRecord.OrderIDs = progress.OrderIDs
+	if len(progress.OrderIDs) > 0 {
+		actionRecord.OrderID = progress.OrderIDs[0]
+	}
+	at.recordFill(progress.AvgPrice, estimate, actionRecord)
+}
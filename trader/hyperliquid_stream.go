@@ -0,0 +1,125 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sonirico/go-hyperliquid"
+)
+
+// triggerOrderRetention 触发单记录保留时间
+const triggerOrderRetention = 7 * 24 * time.Hour
+
+// triggerOrder 已下的止损/止盈触发单
+type triggerOrder struct {
+	EventType string
+	CreatedAt time.Time
+}
+
+// recordTriggerOrder 记录触发单oid，用于成交推送时识别止损/止盈
+func (t *HyperliquidTrader) recordTriggerOrder(oid int64, eventType string) {
+	t.triggerMutex.Lock()
+	defer t.triggerMutex.Unlock()
+
+	if t.triggerOrders == nil {
+		t.triggerOrders = make(map[int64]triggerOrder)
+	}
+	for id, order := range t.triggerOrders {
+		if time.Since(order.CreatedAt) > triggerOrderRetention {
+			delete(t.triggerOrders, id)
+		}
+	}
+	t.triggerOrders[oid] = triggerOrder{EventType: eventType, CreatedAt: time.Now()}
+}
+
+// StartAccountStream 订阅用户成交推送（userFills）
+// 断线重连由SDK的WebsocketClient负责
+func (t *HyperliquidTrader) StartAccountStream(handler func(TradeEvent)) error {
+	t.streamMutex.Lock()
+	defer t.streamMutex.Unlock()
+
+	if t.ws != nil {
+		return nil
+	}
+
+	ws := hyperliquid.NewWebsocketClient(t.apiURL)
+	if err := ws.Connect(context.Background()); err != nil {
+		return fmt.Errorf("连接Hyperliquid WebSocket失败: %w", err)
+	}
+
+	_, err := ws.OrderFills(hyperliquid.OrderFillsSubscriptionParams{User: t.walletAddr}, func(fills hyperliquid.WsOrderFills, err error) {
+		if err != nil {
+			log.Printf("⚠️ Hyperliquid成交推送解析失败: %v", err)
+			return
+		}
+		// 首条消息为历史成交快照，忽略
+		if fills.IsSnapshot {
+			return
+		}
+		for _, fill := range fills.Fills {
+			handler(t.convertFill(fill))
+		}
+	})
+	if err != nil {
+		ws.Close()
+		return fmt.Errorf("订阅Hyperliquid成交推送失败: %w", err)
+	}
+
+	t.ws = ws
+	log.Printf("✓ Hyperliquid成交推送已订阅 (wallet=%s)", t.walletAddr)
+	return nil
+}
+
+// StopAccountStream 停止成交推送
+func (t *HyperliquidTrader) StopAccountStream() {
+	t.streamMutex.Lock()
+	defer t.streamMutex.Unlock()
+
+	if t.ws != nil {
+		t.ws.Close()
+		t.ws = nil
+		log.Printf("⏹ Hyperliquid成交推送已停止")
+	}
+}
+
+// convertFill 将Hyperliquid成交转换为交易事件
+// Dir 形如 "Open Long" / "Close Short" / "Liquidated Cross Long"
+func (t *HyperliquidTrader) convertFill(fill hyperliquid.WsOrderFill) TradeEvent {
+	price, _ := strconv.ParseFloat(fill.Px, 64)
+	quantity, _ := strconv.ParseFloat(fill.Sz, 64)
+	realizedPnL, _ := strconv.ParseFloat(fill.ClosedPnl, 64)
+	fee, _ := strconv.ParseFloat(fill.Fee, 64)
+
+	side := "long"
+	if strings.HasSuffix(fill.Dir, "Short") {
+		side = "short"
+	}
+
+	eventType := TradeEventFill
+	if fill.Liquidation != nil || strings.HasPrefix(fill.Dir, "Liquidated") {
+		eventType = TradeEventLiquidation
+	} else {
+		t.triggerMutex.Lock()
+		if order, ok := t.triggerOrders[fill.Oid]; ok {
+			eventType = order.EventType
+		}
+		t.triggerMutex.Unlock()
+	}
+
+	return TradeEvent{
+		Type:        eventType,
+		Symbol:      fill.Coin + "USDT",
+		Side:        side,
+		OrderID:     fill.Oid,
+		Price:       price,
+		Quantity:    quantity,
+		RealizedPnL: realizedPnL,
+		Commission:  fee,
+		Closing:     !strings.HasPrefix(fill.Dir, "Open"),
+		Time:        time.UnixMilli(fill.Time),
+	}
+}
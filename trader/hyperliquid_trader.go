@@ -1,13 +1,19 @@
 package trader
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"nofx-lite/ratelimit"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sonirico/go-hyperliquid"
@@ -18,8 +24,20 @@ type HyperliquidTrader struct {
 	exchange      *hyperliquid.Exchange
 	ctx           context.Context
 	walletAddr    string
-	meta          *hyperliquid.Meta // 缓存meta信息（包含精度等）
+	apiURL        string            // API地址（用于 /info 原始查询）
+	meta          *hyperliquid.Meta // 缓存meta信息（包含精度等，刷新时整体替换）
+	metaMutex     sync.RWMutex      // 保护 meta（决策周期刷新，后台监控下单时读取）
 	isCrossMargin bool              // 是否为全仓模式
+
+	// 限流（SDK不支持自定义HTTP客户端，SDK调用通过 ratelimit.Call 包装）
+	limiter    *ratelimit.Limiter
+	httpClient *http.Client // 原始 /info 查询
+
+	// 用户成交推送
+	ws            *hyperliquid.WebsocketClient
+	streamMutex   sync.Mutex
+	triggerOrders map[int64]triggerOrder // 止损/止盈触发单（按oid）
+	triggerMutex  sync.Mutex
 }
 
 // NewHyperliquidTrader 创建Hyperliquid交易器
@@ -79,8 +97,13 @@ func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool)
 
 	log.Printf("✓ Hyperliquid交易器初始化成功 (testnet=%v, wallet=%s)", testnet, walletAddr)
 
+	// 同一钱包的所有请求共享权重限流
+	limiter := ratelimit.For("hyperliquid", walletAddr)
+
 	// 获取meta信息（包含精度等配置）
-	meta, err := exchange.Info().Meta(ctx)
+	meta, err := ratelimit.Call(ctx, limiter, ratelimit.HyperliquidInfoWeight("meta"), func() (*hyperliquid.Meta, error) {
+		return exchange.Info().Meta(ctx)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("获取meta信息失败: %w", err)
 	}
@@ -122,17 +145,22 @@ func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool)
 		exchange:      exchange,
 		ctx:           ctx,
 		walletAddr:    walletAddr,
+		apiURL:        apiURL,
 		meta:          meta,
 		isCrossMargin: true, // 默认使用全仓模式
+		limiter:       limiter,
+		httpClient:    ratelimit.WrapClient(&http.Client{Timeout: 30 * time.Second}, limiter, ratelimit.HyperliquidProfile),
 	}, nil
 }
 
 // GetBalance 获取账户余额
-func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
+func (t *HyperliquidTrader) GetBalance() (*AccountBalance, error) {
 	log.Printf("🔄 正在调用Hyperliquid API获取账户余额...")
 
 	// ✅ Step 1: 查询 Spot 现货账户余额
-	spotState, err := t.exchange.Info().SpotUserState(t.ctx, t.walletAddr)
+	spotState, err := ratelimit.Call(t.ctx, t.limiter, ratelimit.HyperliquidInfoWeight("spotClearinghouseState"), func() (*hyperliquid.SpotUserState, error) {
+		return t.exchange.Info().SpotUserState(t.ctx, t.walletAddr)
+	})
 	var spotUSDCBalance float64 = 0.0
 	if err != nil {
 		log.Printf("⚠️ 查询 Spot 余额失败（可能无现货资产）: %v", err)
@@ -147,15 +175,15 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 	}
 
 	// ✅ Step 2: 查询 Perpetuals 合约账户状态
-	accountState, err := t.exchange.Info().UserState(t.ctx, t.walletAddr)
+	accountState, err := ratelimit.Call(t.ctx, t.limiter, ratelimit.HyperliquidInfoWeight("clearinghouseState"), func() (*hyperliquid.UserState, error) {
+		return t.exchange.Info().UserState(t.ctx, t.walletAddr)
+	})
 	if err != nil {
 		log.Printf("❌ Hyperliquid Perpetuals API调用失败: %v", err)
 		return nil, fmt.Errorf("获取账户信息失败: %w", err)
 	}
 
 	// 解析余额信息（MarginSummary字段都是string）
-	result := make(map[string]interface{})
-
 	// ✅ Step 3: 根据保证金模式动态选择正确的摘要（CrossMarginSummary 或 MarginSummary）
 	var accountValue, totalMarginUsed float64
 	var summaryType string
@@ -220,10 +248,12 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 	//      原因：Spot 和 Perpetuals 是独立帐户，需手动 ClassTransfer 才能转账
 	totalWalletBalance := walletBalanceWithoutUnrealized + spotUSDCBalance
 
-	result["totalWalletBalance"] = totalWalletBalance    // 总资产（Perp + Spot）
-	result["availableBalance"] = availableBalance        // 可用余额（仅 Perpetuals，不含 Spot）
-	result["totalUnrealizedProfit"] = totalUnrealizedPnl // 未实现盈亏（仅来自 Perpetuals）
-	result["spotBalance"] = spotUSDCBalance              // Spot 现货余额（单独返回）
+	result := &AccountBalance{
+		TotalWalletBalance:    totalWalletBalance, // 总资产（Perp + Spot）
+		AvailableBalance:      availableBalance,   // 可用余额（仅 Perpetuals，不含 Spot）
+		TotalUnrealizedProfit: totalUnrealizedPnl, // 未实现盈亏（仅来自 Perpetuals）
+		SpotBalance:           spotUSDCBalance,    // Spot 现货余额（单独返回）
+	}
 
 	log.Printf("✓ Hyperliquid 完整账户:")
 	log.Printf("  • Spot 现货余额: %.2f USDC （需手动转账到 Perpetuals 才能开仓）", spotUSDCBalance)
@@ -241,14 +271,16 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 }
 
 // GetPositions 获取所有持仓
-func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
+func (t *HyperliquidTrader) GetPositions() ([]Position, error) {
 	// 获取账户状态
-	accountState, err := t.exchange.Info().UserState(t.ctx, t.walletAddr)
+	accountState, err := ratelimit.Call(t.ctx, t.limiter, ratelimit.HyperliquidInfoWeight("clearinghouseState"), func() (*hyperliquid.UserState, error) {
+		return t.exchange.Info().UserState(t.ctx, t.walletAddr)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
-	var result []map[string]interface{}
+	var result []Position
 
 	// 遍历所有持仓
 	for _, assetPos := range accountState.AssetPositions {
@@ -261,19 +293,16 @@ func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 			continue // 跳过无持仓的
 		}
 
-		posMap := make(map[string]interface{})
-
 		// 标准化symbol格式（Hyperliquid使用如"BTC"，我们转换为"BTCUSDT"）
-		symbol := position.Coin + "USDT"
-		posMap["symbol"] = symbol
+		pos := Position{Symbol: position.Coin + "USDT"}
 
 		// 持仓数量和方向
 		if posAmt > 0 {
-			posMap["side"] = "long"
-			posMap["positionAmt"] = posAmt
+			pos.Side = "long"
+			pos.Quantity = posAmt
 		} else {
-			posMap["side"] = "short"
-			posMap["positionAmt"] = -posAmt // 转为正数
+			pos.Side = "short"
+			pos.Quantity = -posAmt // 转为正数
 		}
 
 		// 价格信息（EntryPx和LiquidationPx是指针类型）
@@ -294,13 +323,13 @@ func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 			markPrice = positionValue / absFloat(posAmt)
 		}
 
-		posMap["entryPrice"] = entryPrice
-		posMap["markPrice"] = markPrice
-		posMap["unRealizedProfit"] = unrealizedPnl
-		posMap["leverage"] = float64(position.Leverage.Value)
-		posMap["liquidationPrice"] = liquidationPx
+		pos.EntryPrice = entryPrice
+		pos.MarkPrice = markPrice
+		pos.UnrealizedPnL = unrealizedPnl
+		pos.Leverage = position.Leverage.Value
+		pos.LiquidationPrice = liquidationPx
 
-		result = append(result, posMap)
+		result = append(result, pos)
 	}
 
 	return result, nil
@@ -325,7 +354,9 @@ func (t *HyperliquidTrader) SetLeverage(symbol string, leverage int) error {
 
 	// 调用UpdateLeverage (leverage int, name string, isCross bool)
 	// 第三个参数: true=全仓模式, false=逐仓模式
-	_, err := t.exchange.UpdateLeverage(t.ctx, leverage, coin, t.isCrossMargin)
+	_, err := ratelimit.CallOnce(t.ctx, t.limiter, 1, func() (*hyperliquid.UserState, error) {
+		return t.exchange.UpdateLeverage(t.ctx, leverage, coin, t.isCrossMargin)
+	})
 	if err != nil {
 		return fmt.Errorf("设置杠杆失败: %w", err)
 	}
@@ -335,7 +366,7 @@ func (t *HyperliquidTrader) SetLeverage(symbol string, leverage int) error {
 }
 
 // OpenLong 开多仓
-func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage int, clientOrderID string) (*OrderResult, error) {
 	// 先取消该币种的所有委托单
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
@@ -346,6 +377,11 @@ func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage i
 		return nil, err
 	}
 
+	return t.IncreaseLong(symbol, quantity, clientOrderID)
+}
+
+// IncreaseLong 追加多仓（不撤销已有委托单、不重设杠杆）
+func (t *HyperliquidTrader) IncreaseLong(symbol string, quantity float64, clientOrderID string) (*OrderResult, error) {
 	// Hyperliquid symbol格式
 	coin := convertSymbolToHyperliquid(symbol)
 
@@ -377,23 +413,18 @@ func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage i
 		ReduceOnly: false,
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	result, err := t.placeOrder(symbol, order, clientOrderID)
 	if err != nil {
 		return nil, fmt.Errorf("开多仓失败: %w", err)
 	}
 
 	log.Printf("✓ 开多仓成功: %s 数量: %.4f", symbol, roundedQuantity)
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0 // Hyperliquid没有返回order ID
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
-
 	return result, nil
 }
 
 // OpenShort 开空仓
-func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage int, clientOrderID string) (*OrderResult, error) {
 	// 先取消该币种的所有委托单
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
@@ -404,6 +435,11 @@ func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage
 		return nil, err
 	}
 
+	return t.IncreaseShort(symbol, quantity, clientOrderID)
+}
+
+// IncreaseShort 追加空仓（不撤销已有委托单、不重设杠杆）
+func (t *HyperliquidTrader) IncreaseShort(symbol string, quantity float64, clientOrderID string) (*OrderResult, error) {
 	// Hyperliquid symbol格式
 	coin := convertSymbolToHyperliquid(symbol)
 
@@ -435,23 +471,18 @@ func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage
 		ReduceOnly: false,
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	result, err := t.placeOrder(symbol, order, clientOrderID)
 	if err != nil {
 		return nil, fmt.Errorf("开空仓失败: %w", err)
 	}
 
 	log.Printf("✓ 开空仓成功: %s 数量: %.4f", symbol, roundedQuantity)
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
-
 	return result, nil
 }
 
 // CloseLong 平多仓
-func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64, clientOrderID string) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -460,8 +491,8 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 		}
 
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
+			if pos.Symbol == symbol && pos.Side == "long" {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -502,7 +533,7 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 		ReduceOnly: true, // 只平仓，不开新仓
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	result, err := t.placeOrder(symbol, order, clientOrderID)
 	if err != nil {
 		return nil, fmt.Errorf("平多仓失败: %w", err)
 	}
@@ -514,16 +545,11 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
-
 	return result, nil
 }
 
 // CloseShort 平空仓
-func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64, clientOrderID string) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -532,8 +558,8 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 		}
 
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				quantity = pos["positionAmt"].(float64)
+			if pos.Symbol == symbol && pos.Side == "short" {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -574,7 +600,7 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 		ReduceOnly: true,
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	result, err := t.placeOrder(symbol, order, clientOrderID)
 	if err != nil {
 		return nil, fmt.Errorf("平空仓失败: %w", err)
 	}
@@ -586,30 +612,110 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
-
 	return result, nil
 }
 
+// placeOrder 幂等下单（cloid为32位十六进制，结果不确定时按cloid查询后再重试）
+func (t *HyperliquidTrader) placeOrder(symbol string, order hyperliquid.CreateOrderRequest, clientOrderID string) (*OrderResult, error) {
+	cloid := "0x" + ensureClientOrderID(clientOrderID)
+	order.ClientOrderID = &cloid
+
+	return placeOrderIdempotent(cloid, func() (*OrderResult, error) {
+		status, err := ratelimit.CallOnce(t.ctx, t.limiter, 1, func() (hyperliquid.OrderStatus, error) {
+			return t.exchange.Order(t.ctx, order, nil)
+		})
+		if err != nil {
+			return nil, err
+		}
+		result := &OrderResult{
+			Symbol: symbol,
+			Status: "FILLED",
+		}
+		if status.Filled != nil {
+			result.OrderID = int64(status.Filled.Oid)
+			result.AvgPrice, _ = strconv.ParseFloat(status.Filled.AvgPx, 64)
+			result.ExecutedQty, _ = strconv.ParseFloat(status.Filled.TotalSz, 64)
+		} else if status.Resting != nil {
+			result.OrderID = status.Resting.Oid
+			result.Status = "NEW"
+		}
+		return result, nil
+	}, func() (*OrderResult, error) {
+		queried, err := ratelimit.Call(t.ctx, t.limiter, ratelimit.HyperliquidInfoWeight("orderStatus"), func() (*hyperliquid.OrderQueryResult, error) {
+			return t.exchange.Info().QueryOrderByCloid(t.ctx, t.walletAddr, cloid)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if queried.Status != hyperliquid.OrderQueryStatusSuccess {
+			// unknownOid 订单不存在
+			return nil, nil
+		}
+		// 已成交数量 = 原始数量 - 剩余数量
+		origSz, _ := strconv.ParseFloat(queried.Order.Order.OrigSz, 64)
+		remainingSz, _ := strconv.ParseFloat(queried.Order.Order.Sz, 64)
+		return &OrderResult{
+			OrderID:     queried.Order.Order.Oid,
+			Symbol:      symbol,
+			Status:      string(queried.Order.Status),
+			ExecutedQty: origSz - remainingSz,
+		}, nil
+	})
+}
+
 // CancelStopOrders 取消该币种的止盈/止
 
 // CancelStopLossOrdersBySide 仅取消指定方向的止损单（防止双向持仓误删）
 func (t *HyperliquidTrader) CancelStopLossOrdersBySide(symbol string, positionSide string) error {
-	// Hyperliquid SDK 的 OpenOrder 结构不暴露 trigger 字段，也无法区分方向
-	// 降级为取消该币种所有挂单，并给出警告
-	log.Printf("  ⚠️ Hyperliquid 无法区分止损/止盈单及方向，将取消 %s 所有挂单", symbol)
-	return t.CancelStopOrders(symbol)
+	return t.cancelTriggerOrders(symbol, positionSide, OpenOrderStopLoss)
 }
 
 // CancelTakeProfitOrdersBySide 仅取消指定方向的止盈单（防止双向持仓误删）
 func (t *HyperliquidTrader) CancelTakeProfitOrdersBySide(symbol string, positionSide string) error {
-	// Hyperliquid SDK 的 OpenOrder 结构不暴露 trigger 字段，也无法区分方向
-	// 降级为取消该币种所有挂单，并给出警告
-	log.Printf("  ⚠️ Hyperliquid 无法区分止损/止盈单及方向，将取消 %s 所有挂单", symbol)
-	return t.CancelStopOrders(symbol)
+	return t.cancelTriggerOrders(symbol, positionSide, OpenOrderTakeProfit)
+}
+
+// cancelTriggerOrders 按触发单类型与方向取消挂单（frontendOpenOrders 带触发类型，positionSide 为空时不限方向）
+func (t *HyperliquidTrader) cancelTriggerOrders(symbol string, positionSide string, orderTypes ...string) error {
+	coin := convertSymbolToHyperliquid(symbol)
+
+	openOrders, err := t.GetOpenOrders()
+	if err != nil {
+		return err
+	}
+
+	canceledCount := 0
+	var cancelErrors []error
+	for _, order := range openOrders {
+		matched := false
+		for _, orderType := range orderTypes {
+			matched = matched || order.Type == orderType
+		}
+		if order.Symbol != coin+"USDT" || !matched {
+			continue
+		}
+		if positionSide != "" && !strings.EqualFold(order.PositionSide, positionSide) {
+			continue
+		}
+		oid := order.OrderID
+		_, err := ratelimit.CallOnce(t.ctx, t.limiter, 1, func() (*hyperliquid.APIResponse[hyperliquid.CancelOrderResponse], error) {
+			return t.exchange.Cancel(t.ctx, coin, oid)
+		})
+		if err != nil {
+			cancelErrors = append(cancelErrors, fmt.Errorf("oid=%d: %w", oid, err))
+			log.Printf("  ⚠ 取消订单失败 (oid=%d): %v", oid, err)
+			continue
+		}
+		canceledCount++
+	}
+
+	if canceledCount > 0 {
+		log.Printf("  ✓ 已取消 %s %s %v 共 %d 个", symbol, positionSide, orderTypes, canceledCount)
+	}
+	if len(cancelErrors) > 0 && canceledCount == 0 {
+		return fmt.Errorf("取消挂单失败: %v", cancelErrors)
+	}
+	return nil
 }
 
 // CancelAllOrders 取消该币种的所有挂单
@@ -617,7 +723,9 @@ func (t *HyperliquidTrader) CancelAllOrders(symbol string) error {
 	coin := convertSymbolToHyperliquid(symbol)
 
 	// 获取所有挂单
-	openOrders, err := t.exchange.Info().OpenOrders(t.ctx, t.walletAddr)
+	openOrders, err := ratelimit.Call(t.ctx, t.limiter, ratelimit.HyperliquidInfoWeight("openOrders"), func() ([]hyperliquid.OpenOrder, error) {
+		return t.exchange.Info().OpenOrders(t.ctx, t.walletAddr)
+	})
 	if err != nil {
 		return fmt.Errorf("获取挂单失败: %w", err)
 	}
@@ -625,7 +733,9 @@ func (t *HyperliquidTrader) CancelAllOrders(symbol string) error {
 	// 取消该币种的所有挂单
 	for _, order := range openOrders {
 		if order.Coin == coin {
-			_, err := t.exchange.Cancel(t.ctx, coin, order.Oid)
+			_, err := ratelimit.CallOnce(t.ctx, t.limiter, 1, func() (*hyperliquid.APIResponse[hyperliquid.CancelOrderResponse], error) {
+				return t.exchange.Cancel(t.ctx, coin, order.Oid)
+			})
 			if err != nil {
 				log.Printf("  ⚠ 取消订单失败 (oid=%d): %v", order.Oid, err)
 			}
@@ -638,20 +748,17 @@ func (t *HyperliquidTrader) CancelAllOrders(symbol string) error {
 
 // CancelStopOrders 取消该币种的止盈/止损单（用于调整止盈止损位置）
 func (t *HyperliquidTrader) CancelStopOrders(symbol string) error {
-	log.Printf("⚠ Hyperliquid 无法区分方向，将取消 %s 全部止盈止损单", symbol)
-	return t.CancelAllOrders(symbol)
+	return t.cancelTriggerOrders(symbol, "", OpenOrderStopLoss, OpenOrderTakeProfit)
 }
 
 // CancelStopLossOrders 仅取消止损单（取消所有方向的止损单）
 func (t *HyperliquidTrader) CancelStopLossOrders(symbol string) error {
-	log.Printf("⚠ Hyperliquid 无法区分方向，将取消 %s 全部止损单", symbol)
-	return t.CancelAllOrders(symbol)
+	return t.cancelTriggerOrders(symbol, "", OpenOrderStopLoss)
 }
 
 // CancelTakeProfitOrders 仅取消止盈单（取消所有方向的止盈单）
 func (t *HyperliquidTrader) CancelTakeProfitOrders(symbol string) error {
-	log.Printf("⚠ Hyperliquid 无法区分方向，将取消 %s 全部止盈单", symbol)
-	return t.CancelAllOrders(symbol)
+	return t.cancelTriggerOrders(symbol, "", OpenOrderTakeProfit)
 }
 
 // GetMarketPrice 获取市场价格
@@ -659,7 +766,9 @@ func (t *HyperliquidTrader) GetMarketPrice(symbol string) (float64, error) {
 	coin := convertSymbolToHyperliquid(symbol)
 
 	// 获取所有市场价格
-	allMids, err := t.exchange.Info().AllMids(t.ctx)
+	allMids, err := ratelimit.Call(t.ctx, t.limiter, ratelimit.HyperliquidInfoWeight("allMids"), func() (map[string]string, error) {
+		return t.exchange.Info().AllMids(t.ctx)
+	})
 	if err != nil {
 		return 0, fmt.Errorf("获取价格失败: %w", err)
 	}
@@ -704,10 +813,15 @@ func (t *HyperliquidTrader) SetStopLoss(symbol string, positionSide string, quan
 		ReduceOnly: true,
 	}
 
-	_, err := t.exchange.Order(t.ctx, order, nil)
+	status, err := ratelimit.CallOnce(t.ctx, t.limiter, 1, func() (hyperliquid.OrderStatus, error) {
+		return t.exchange.Order(t.ctx, order, nil)
+	})
 	if err != nil {
 		return fmt.Errorf("设置止损失败: %w", err)
 	}
+	if status.Resting != nil {
+		t.recordTriggerOrder(status.Resting.Oid, TradeEventStopLoss)
+	}
 
 	log.Printf("  止损价设置: %.4f", roundedStopPrice)
 	return nil
@@ -741,15 +855,26 @@ func (t *HyperliquidTrader) SetTakeProfit(symbol string, positionSide string, qu
 		ReduceOnly: true,
 	}
 
-	_, err := t.exchange.Order(t.ctx, order, nil)
+	status, err := ratelimit.CallOnce(t.ctx, t.limiter, 1, func() (hyperliquid.OrderStatus, error) {
+		return t.exchange.Order(t.ctx, order, nil)
+	})
 	if err != nil {
 		return fmt.Errorf("设置止盈失败: %w", err)
 	}
+	if status.Resting != nil {
+		t.recordTriggerOrder(status.Resting.Oid, TradeEventTakeProfit)
+	}
 
 	log.Printf("  止盈价设置: %.4f", roundedTakeProfitPrice)
 	return nil
 }
 
+// SetPartialTakeProfit 设置部分止盈单
+// Hyperliquid 的止盈触发单本身就是按数量的 reduce-only 单，可直接挂多档
+func (t *HyperliquidTrader) SetPartialTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	return t.SetTakeProfit(symbol, positionSide, quantity, takeProfitPrice)
+}
+
 // FormatQuantity 格式化数量到正确的精度
 func (t *HyperliquidTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
 	coin := convertSymbolToHyperliquid(symbol)
@@ -762,13 +887,14 @@ func (t *HyperliquidTrader) FormatQuantity(symbol string, quantity float64) (str
 
 // getSzDecimals 获取币种的数量精度
 func (t *HyperliquidTrader) getSzDecimals(coin string) int {
-	if t.meta == nil {
+	meta := t.getMeta()
+	if meta == nil {
 		log.Printf("⚠️  meta信息为空，使用默认精度4")
 		return 4 // 默认精度
 	}
 
 	// 在meta.Universe中查找对应的币种
-	for _, asset := range t.meta.Universe {
+	for _, asset := range meta.Universe {
 		if asset.Name == coin {
 			return asset.SzDecimals
 		}
@@ -778,6 +904,35 @@ func (t *HyperliquidTrader) getSzDecimals(coin string) int {
 	return 4 // 默认精度
 }
 
+// getMeta 读取缓存的meta信息（刷新时整体替换，读取后不会被修改）
+func (t *HyperliquidTrader) getMeta() *hyperliquid.Meta {
+	t.metaMutex.RLock()
+	defer t.metaMutex.RUnlock()
+	return t.meta
+}
+
+// GetTradableSymbols 获取可交易的永续合约（排除已下架币种，同时刷新meta缓存）
+func (t *HyperliquidTrader) GetTradableSymbols() (map[string]bool, error) {
+	meta, err := ratelimit.Call(t.ctx, t.limiter, ratelimit.HyperliquidInfoWeight("meta"), func() (*hyperliquid.Meta, error) {
+		return t.exchange.Info().Meta(t.ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取meta信息失败: %w", err)
+	}
+	t.metaMutex.Lock()
+	t.meta = meta
+	t.metaMutex.Unlock()
+
+	symbols := make(map[string]bool)
+	for _, asset := range meta.Universe {
+		if asset.IsDelisted {
+			continue
+		}
+		symbols[asset.Name+"USDT"] = true
+	}
+	return symbols, nil
+}
+
 // roundToSzDecimals 将数量四舍五入到正确的精度
 func (t *HyperliquidTrader) roundToSzDecimals(coin string, quantity float64) float64 {
 	szDecimals := t.getSzDecimals(coin)
@@ -847,3 +1002,155 @@ func absFloat(x float64) float64 {
 	}
 	return x
 }
+
+// postInfo 直接调用 /info 接口（SDK未覆盖或解析不完整的查询）
+func (t *HyperliquidTrader) postInfo(payload map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodPost, t.apiURL+"/info", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(data))
+	}
+	return json.Unmarshal(data, out)
+}
+
+// GetFundingFees 获取资金费净额（userFunding，usdc为正表示收取）
+func (t *HyperliquidTrader) GetFundingFees(symbol string, since time.Time) (float64, error) {
+	var records []struct {
+		Time  int64 `json:"time"`
+		Delta struct {
+			Type string `json:"type"`
+			Coin string `json:"coin"`
+			Usdc string `json:"usdc"`
+		} `json:"delta"`
+	}
+	err := t.postInfo(map[string]interface{}{
+		"type":      "userFunding",
+		"user":      t.walletAddr,
+		"startTime": since.UnixMilli(),
+	}, &records)
+	if err != nil {
+		return 0, fmt.Errorf("获取资金费记录失败: %w", err)
+	}
+
+	coin := convertSymbolToHyperliquid(symbol)
+	total := 0.0
+	for _, r := range records {
+		if r.Delta.Type != "funding" || r.Delta.Coin != coin {
+			continue
+		}
+		usdc, _ := strconv.ParseFloat(r.Delta.Usdc, 64)
+		total += usdc
+	}
+	return total, nil
+}
+
+// GetOpenOrders 获取所有币种的未成交挂单（frontendOpenOrders 带触发信息，可区分止损/止盈）
+func (t *HyperliquidTrader) GetOpenOrders() ([]OpenOrder, error) {
+	openOrders, err := ratelimit.Call(t.ctx, t.limiter, ratelimit.HyperliquidInfoWeight("frontendOpenOrders"), func() ([]hyperliquid.FrontendOpenOrder, error) {
+		return t.exchange.Info().FrontendOpenOrders(t.ctx, t.walletAddr)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	result := make([]OpenOrder, 0, len(openOrders))
+	for _, order := range openOrders {
+		orderType := OpenOrderLimit
+		if order.IsTrigger {
+			switch {
+			case strings.HasPrefix(order.OrderType, "Stop"):
+				orderType = OpenOrderStopLoss
+			case strings.HasPrefix(order.OrderType, "Take Profit"):
+				orderType = OpenOrderTakeProfit
+			default:
+				orderType = OpenOrderOther
+			}
+		}
+		// 卖出减多，买入减空
+		positionSide := "short"
+		if order.Side == hyperliquid.OrderSideAsk {
+			positionSide = "long"
+		}
+		result = append(result, OpenOrder{
+			OrderID:      order.Oid,
+			Symbol:       order.Coin + "USDT",
+			Type:         orderType,
+			PositionSide: positionSide,
+			Price:        order.LimitPx,
+			StopPrice:    order.TriggerPx,
+			Quantity:     order.Sz,
+			ReduceOnly:   order.ReduceOnly,
+		})
+	}
+	return result, nil
+}
+
+// GetMarginTiers 按meta中的保证金表计算维持保证金阶梯
+// Hyperliquid 维持保证金率为该档最大杠杆对应初始保证金率的一半，速算数保证各档之间连续
+// marginTableId < 50 的币种没有分档，按币种最大杠杆处理
+func (t *HyperliquidTrader) GetMarginTiers(symbol string) ([]MarginTier, error) {
+	meta := t.getMeta()
+	if meta == nil {
+		return nil, fmt.Errorf("meta信息为空")
+	}
+	coin := convertSymbolToHyperliquid(symbol)
+
+	var asset *hyperliquid.AssetInfo
+	for i := range meta.Universe {
+		if meta.Universe[i].Name == coin {
+			asset = &meta.Universe[i]
+			break
+		}
+	}
+	if asset == nil {
+		return nil, fmt.Errorf("未找到 %s 的meta信息", coin)
+	}
+
+	var tiers []MarginTier
+	for _, table := range meta.MarginTables {
+		if table.ID != asset.MarginTableId {
+			continue
+		}
+		for _, tier := range table.MarginTiers {
+			if tier.MaxLeverage <= 0 {
+				continue
+			}
+			floor, _ := strconv.ParseFloat(tier.LowerBound, 64)
+			tiers = append(tiers, MarginTier{
+				NotionalFloor:   floor,
+				MaintMarginRate: 1 / (2 * float64(tier.MaxLeverage)),
+				MaxLeverage:     tier.MaxLeverage,
+			})
+		}
+	}
+	if len(tiers) == 0 && asset.MaxLeverage > 0 {
+		tiers = append(tiers, MarginTier{
+			MaintMarginRate: 1 / (2 * float64(asset.MaxLeverage)),
+			MaxLeverage:     asset.MaxLeverage,
+		})
+	}
+
+	for i := 1; i < len(tiers); i++ {
+		tiers[i-1].NotionalCap = tiers[i].NotionalFloor
+		tiers[i].MaintAmount = tiers[i-1].MaintAmount + tiers[i].NotionalFloor*(tiers[i].MaintMarginRate-tiers[i-1].MaintMarginRate)
+	}
+	return tiers, nil
+}
@@ -1,25 +1,85 @@
 package trader
 
+import "time"
+
+// Position 持仓信息（各交易所统一格式）
+type Position struct {
+	Symbol           string  `json:"symbol"`            // 交易对（如 BTCUSDT）
+	Side             string  `json:"side"`              // "long" 或 "short"
+	Quantity         float64 `json:"quantity"`          // 持仓数量（始终为正数）
+	EntryPrice       float64 `json:"entry_price"`       // 开仓均价
+	MarkPrice        float64 `json:"mark_price"`        // 标记价格
+	UnrealizedPnL    float64 `json:"unrealized_pnl"`    // 未实现盈亏
+	Leverage         int     `json:"leverage"`          // 杠杆倍数
+	LiquidationPrice float64 `json:"liquidation_price"` // 强平价格（0表示未知）
+}
+
+// EffectiveLeverage 杠杆倍数（交易所未返回时按10倍估算）
+func (p Position) EffectiveLeverage() int {
+	if p.Leverage <= 0 {
+		return 10
+	}
+	return p.Leverage
+}
+
+// MarginUsed 持仓占用保证金（名义价值 / 杠杆）
+func (p Position) MarginUsed() float64 {
+	return p.Quantity * p.MarkPrice / float64(p.EffectiveLeverage())
+}
+
+// AccountBalance 账户余额
+type AccountBalance struct {
+	TotalWalletBalance    float64 `json:"total_wallet_balance"`    // 钱包余额（不含未实现盈亏）
+	AvailableBalance      float64 `json:"available_balance"`       // 可用余额
+	TotalUnrealizedProfit float64 `json:"total_unrealized_profit"` // 未实现盈亏
+	SpotBalance           float64 `json:"spot_balance,omitempty"`  // 现货余额（仅Hyperliquid，不计入可用余额）
+}
+
+// TotalEquity 账户净值 = 钱包余额 + 未实现盈亏
+func (b *AccountBalance) TotalEquity() float64 {
+	return b.TotalWalletBalance + b.TotalUnrealizedProfit
+}
+
+// OrderResult 下单结果
+type OrderResult struct {
+	OrderID int64  `json:"order_id"` // 交易所订单ID（不返回订单ID的交易所为0）
+	Symbol  string `json:"symbol"`
+	Status  string `json:"status"`
+	// AvgPrice 成交均价（交易所未返回时为0）
+	AvgPrice float64 `json:"avg_price,omitempty"`
+	// ExecutedQty 实际成交数量（IOC/部分成交时可能小于下单数量）
+	ExecutedQty float64 `json:"executed_qty"`
+}
+
 // Trader 交易器统一接口
 // 支持多个交易平台（币安、Hyperliquid等）
 type Trader interface {
 	// GetBalance 获取账户余额
-	GetBalance() (map[string]interface{}, error)
+	GetBalance() (*AccountBalance, error)
 
 	// GetPositions 获取所有持仓
-	GetPositions() ([]map[string]interface{}, error)
+	GetPositions() ([]Position, error)
+
+	// 下单方法的 clientOrderID 由 NewClientOrderID 生成（为空时随机生成）
+	// 结果不确定的失败会先按该ID查询订单，确认未下单后才重试，避免重复开仓
 
 	// OpenLong 开多仓
-	OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error)
+	OpenLong(symbol string, quantity float64, leverage int, clientOrderID string) (*OrderResult, error)
 
 	// OpenShort 开空仓
-	OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error)
+	OpenShort(symbol string, quantity float64, leverage int, clientOrderID string) (*OrderResult, error)
+
+	// IncreaseLong 追加多仓（不撤销已有止损止盈、不重设杠杆；用于拆单的后续子单与加仓）
+	IncreaseLong(symbol string, quantity float64, clientOrderID string) (*OrderResult, error)
+
+	// IncreaseShort 追加空仓（不撤销已有止损止盈、不重设杠杆；用于拆单的后续子单与加仓）
+	IncreaseShort(symbol string, quantity float64, clientOrderID string) (*OrderResult, error)
 
 	// CloseLong 平多仓（quantity=0表示全部平仓）
-	CloseLong(symbol string, quantity float64) (map[string]interface{}, error)
+	CloseLong(symbol string, quantity float64, clientOrderID string) (*OrderResult, error)
 
 	// CloseShort 平空仓（quantity=0表示全部平仓）
-	CloseShort(symbol string, quantity float64) (map[string]interface{}, error)
+	CloseShort(symbol string, quantity float64, clientOrderID string) (*OrderResult, error)
 
 	// SetLeverage 设置杠杆
 	SetLeverage(symbol string, leverage int) error
@@ -36,6 +96,9 @@ type Trader interface {
 	// SetTakeProfit 设置止盈单
 	SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error
 
+	// SetPartialTakeProfit 设置部分止盈单（reduce-only，只平指定数量，可同时挂多档组成止盈阶梯）
+	SetPartialTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error
+
 	// CancelStopLossOrders 仅取消止损单（修复 BUG：调整止损时不删除止盈）
 	CancelStopLossOrders(symbol string) error
 
@@ -57,3 +120,120 @@ type Trader interface {
 	// FormatQuantity 格式化数量到正确的精度
 	FormatQuantity(symbol string, quantity float64) (string, error)
 }
+
+// SymbolUniverseProvider 可交易币种查询（可选接口）
+// 用于过滤已下架/结算中的合约，避免下单失败
+type SymbolUniverseProvider interface {
+	// GetTradableSymbols 返回当前可交易的永续合约（key为USDT交易对，如 BTCUSDT）
+	GetTradableSymbols() (map[string]bool, error)
+}
+
+// MarginTier 维持保证金阶梯的一档（按持仓名义价值分档）
+type MarginTier struct {
+	NotionalFloor   float64 // 该档名义价值下限（USDT）
+	NotionalCap     float64 // 该档名义价值上限（0表示无上限）
+	MaintMarginRate float64 // 维持保证金率
+	MaintAmount     float64 // 速算数（维持保证金 = 名义价值 × 维持保证金率 − 速算数）
+	MaxLeverage     int     // 该档最大杠杆
+}
+
+// MarginTierProvider 维持保证金阶梯查询（可选接口）
+// 用于估算开仓后的强平价格，不支持时按默认维持保证金率估算
+type MarginTierProvider interface {
+	// GetMarginTiers 返回symbol的维持保证金阶梯（按名义价值从低到高）
+	GetMarginTiers(symbol string) ([]MarginTier, error)
+}
+
+// FundingFeeProvider 资金费流水查询（可选接口）
+// 用于统计持仓期间实际支付/收取的资金费
+type FundingFeeProvider interface {
+	// GetFundingFees 返回symbol自since以来的资金费净额（正数=收取，负数=支付）
+	// 流水不区分持仓方向，双向持仓同时有多空时为两个方向的合计
+	GetFundingFees(symbol string, since time.Time) (float64, error)
+}
+
+// 挂单类型
+const (
+	OpenOrderStopLoss   = "stop_loss"   // 止损（条件单）
+	OpenOrderTakeProfit = "take_profit" // 止盈（条件单）
+	OpenOrderLimit      = "limit"       // 普通限价单
+	OpenOrderOther      = "other"
+)
+
+// OpenOrder 未成交挂单（各交易所统一格式）
+type OpenOrder struct {
+	OrderID      int64   `json:"order_id"`
+	Symbol       string  `json:"symbol"`
+	Type         string  `json:"type"`          // stop_loss / take_profit / limit / other
+	PositionSide string  `json:"position_side"` // 作用的持仓方向 long/short
+	Price        float64 `json:"price"`         // 限价（市价条件单为0）
+	StopPrice    float64 `json:"stop_price"`    // 触发价（非条件单为0）
+	Quantity     float64 `json:"quantity"`      // 数量（平掉整个持仓的条件单为0）
+	ReduceOnly   bool    `json:"reduce_only"`
+}
+
+// OpenOrderProvider 挂单查询（可选接口）
+// 用于启动对账：检查持仓是否挂有止损/止盈
+type OpenOrderProvider interface {
+	// GetOpenOrders 返回所有币种的未成交挂单
+	GetOpenOrders() ([]OpenOrder, error)
+}
+
+// 交易事件类型
+const (
+	TradeEventFill            = "fill"             // 普通订单成交（开仓/平仓）
+	TradeEventStopLoss        = "stop_loss"        // 止损单触发成交
+	TradeEventTakeProfit      = "take_profit"      // 止盈单触发成交
+	TradeEventLiquidation     = "liquidation"      // 强平/自动减仓
+	TradeEventTriggerRejected = "trigger_rejected" // 条件单触发后被拒绝（止损可能失效）
+)
+
+// TradeEvent 交易所实时推送的成交/触发事件
+type TradeEvent struct {
+	Type        string    `json:"type"`
+	Symbol      string    `json:"symbol"`
+	Side        string    `json:"side"` // 持仓方向 long/short
+	OrderID     int64     `json:"order_id"`
+	Price       float64   `json:"price"`    // 成交均价
+	Quantity    float64   `json:"quantity"` // 累计成交数量
+	RealizedPnL float64   `json:"realized_pnl"`
+	Commission  float64   `json:"commission"`
+	Closing     bool      `json:"closing"` // 是否为减仓/平仓成交
+	Reason      string    `json:"reason,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// AccountStreamProvider 实时账户推送（可选接口）
+// 连接期间余额/持仓由推送更新缓存，成交与止盈止损触发以事件形式回调
+type AccountStreamProvider interface {
+	// StartAccountStream 启动推送（断线自动重连），handler 在推送goroutine中调用，不应阻塞
+	StartAccountStream(handler func(TradeEvent)) error
+	// StopAccountStream 停止推送
+	StopAccountStream()
+}
+
+// 编译期检查各交易所实现是否满足接口
+var (
+	_ Trader = (*FuturesTrader)(nil)
+	_ Trader = (*HyperliquidTrader)(nil)
+	_ Trader = (*AsterTrader)(nil)
+
+	_ FundingFeeProvider = (*FuturesTrader)(nil)
+	_ FundingFeeProvider = (*HyperliquidTrader)(nil)
+	_ FundingFeeProvider = (*AsterTrader)(nil)
+
+	_ SymbolUniverseProvider = (*FuturesTrader)(nil)
+	_ SymbolUniverseProvider = (*HyperliquidTrader)(nil)
+	_ SymbolUniverseProvider = (*AsterTrader)(nil)
+
+	_ OpenOrderProvider = (*FuturesTrader)(nil)
+	_ OpenOrderProvider = (*HyperliquidTrader)(nil)
+	_ OpenOrderProvider = (*AsterTrader)(nil)
+
+	_ MarginTierProvider = (*FuturesTrader)(nil)
+	_ MarginTierProvider = (*HyperliquidTrader)(nil)
+	_ MarginTierProvider = (*AsterTrader)(nil)
+
+	_ AccountStreamProvider = (*FuturesTrader)(nil)
+	_ AccountStreamProvider = (*HyperliquidTrader)(nil)
+)
@@ -0,0 +1,150 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx-lite/decision"
+	"time"
+)
+
+const (
+	// defaultMaintMarginRate 无法获取维持保证金阶梯时使用的维持保证金率（偏保守）
+	defaultMaintMarginRate = 0.01
+	// marginTierCacheTTL 维持保证金阶梯缓存时间
+	marginTierCacheTTL = 1 * time.Hour
+	// liquidationAlertPct 标记价格距强平价格低于该比例（%）时告警
+	liquidationAlertPct = 5.0
+	// liquidationAlertInterval 同一持仓重复告警的最小间隔
+	liquidationAlertInterval = 15 * time.Minute
+)
+
+// marginTierCacheEntry 缓存的维持保证金阶梯
+type marginTierCacheEntry struct {
+	tiers     []MarginTier
+	fetchedAt time.Time
+}
+
+// liquidationEstimator 按维持保证金阶梯与仓位模式估算强平价格（实现 decision.LiquidationEstimator）
+type liquidationEstimator struct {
+	at        *AutoTrader
+	available float64 // 全仓模式下承担亏损的可用余额
+}
+
+// EstimateLiquidationPrice 估算开仓后的强平价格
+// 逐仓：保证金 = 名义价值/杠杆；全仓：按当前可用余额承担亏损（不计其他持仓的浮动盈亏）
+func (e *liquidationEstimator) EstimateLiquidationPrice(symbol string, isLong bool, entryPrice, notional float64, leverage int) (float64, error) {
+	if entryPrice <= 0 || notional <= 0 || leverage <= 0 {
+		return 0, fmt.Errorf("无效的开仓参数")
+	}
+	margin := notional / float64(leverage)
+	if e.at.config.IsCrossMargin {
+		margin = math.Max(margin, e.available)
+	}
+	tier := selectMarginTier(e.at.marginTiersFor(symbol), notional)
+	return estimateLiquidationPrice(isLong, entryPrice, notional, margin, tier), nil
+}
+
+// estimateLiquidationPrice 强平价格：保证金 + 浮动盈亏 = 维持保证金
+// 多头 LP = (N − WB − cum) / (Q × (1 − MMR))，空头 LP = (N + WB + cum) / (Q × (1 + MMR))
+func estimateLiquidationPrice(isLong bool, entryPrice, notional, margin float64, tier MarginTier) float64 {
+	quantity := notional / entryPrice
+	if isLong {
+		price := (notional - margin - tier.MaintAmount) / (quantity * (1 - tier.MaintMarginRate))
+		return math.Max(price, 0)
+	}
+	return (notional + margin + tier.MaintAmount) / (quantity * (1 + tier.MaintMarginRate))
+}
+
+// selectMarginTier 按名义价值选择所在的档位（没有阶梯时使用默认维持保证金率）
+func selectMarginTier(tiers []MarginTier, notional float64) MarginTier {
+	for _, tier := range tiers {
+		if notional >= tier.NotionalFloor && (tier.NotionalCap <= 0 || notional < tier.NotionalCap) {
+			return tier
+		}
+	}
+	if len(tiers) > 0 {
+		return tiers[len(tiers)-1]
+	}
+	return MarginTier{MaintMarginRate: defaultMaintMarginRate}
+}
+
+// marginTiersFor 获取维持保证金阶梯（缓存1小时，交易所不支持或查询失败时返回nil）
+func (at *AutoTrader) marginTiersFor(symbol string) []MarginTier {
+	provider, ok := at.trader.(MarginTierProvider)
+	if !ok {
+		return nil
+	}
+
+	at.marginTiersMutex.Lock()
+	defer at.marginTiersMutex.Unlock()
+
+	if entry, ok := at.marginTiers[symbol]; ok && time.Since(entry.fetchedAt) < marginTierCacheTTL {
+		return entry.tiers
+	}
+
+	tiers, err := provider.GetMarginTiers(symbol)
+	if err != nil {
+		log.Printf("⚠️ 获取 %s 维持保证金阶梯失败，按默认维持保证金率 %.1f%% 估算强平价格: %v", symbol, defaultMaintMarginRate*100, err)
+		// 失败时也缓存，避免每次校验都重试
+	}
+	at.marginTiers[symbol] = marginTierCacheEntry{tiers: tiers, fetchedAt: time.Now()}
+	return tiers
+}
+
+// checkEntryLiquidation 执行前按最终仓位重新校验开仓/加仓止损与强平价格的距离（开仓必要时降低 d.Leverage）
+func (at *AutoTrader) checkEntryLiquidation(d *decision.Decision, ctx *decision.Context) error {
+	return decision.CheckLiquidationDistance(d, ctx)
+}
+
+// checkLiquidationDistance 后台监控：持仓标记价格接近强平价格、或止损位于强平价格之外时告警
+func (at *AutoTrader) checkLiquidationDistance() {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("❌ 强平距离监控: 获取持仓失败: %v", err)
+		return
+	}
+
+	open := make(map[string]bool)
+	for _, pos := range positions {
+		if pos.Quantity == 0 || pos.LiquidationPrice <= 0 || pos.MarkPrice <= 0 {
+			continue
+		}
+		posKey := pos.Symbol + "_" + pos.Side
+		open[posKey] = true
+
+		distancePct := (pos.MarkPrice - pos.LiquidationPrice) / pos.MarkPrice * 100
+		if pos.Side == "short" {
+			distancePct = -distancePct
+		}
+
+		var alert string
+		if distancePct < liquidationAlertPct {
+			alert = fmt.Sprintf("标记价格 %.4f 距强平价格 %.4f 仅 %.2f%%", pos.MarkPrice, pos.LiquidationPrice, distancePct)
+		}
+		if stopLoss, ok := at.positionStopLossPrice(posKey); ok {
+			if (pos.Side == "long" && stopLoss <= pos.LiquidationPrice) || (pos.Side == "short" && stopLoss >= pos.LiquidationPrice) {
+				if alert != "" {
+					alert += "；"
+				}
+				alert += fmt.Sprintf("止损 %.4f 位于强平价格 %.4f 之外，止损不会先于强平触发", stopLoss, pos.LiquidationPrice)
+			}
+		}
+		if alert == "" {
+			delete(at.liquidationAlertTime, posKey)
+			continue
+		}
+
+		if last, ok := at.liquidationAlertTime[posKey]; ok && time.Since(last) < liquidationAlertInterval {
+			continue
+		}
+		at.liquidationAlertTime[posKey] = time.Now()
+		log.Printf("🚨 [%s] 强平预警 %s %s: %s", at.name, pos.Symbol, pos.Side, alert)
+	}
+
+	for key := range at.liquidationAlertTime {
+		if !open[key] {
+			delete(at.liquidationAlertTime, key)
+		}
+	}
+}
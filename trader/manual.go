@@ -0,0 +1,163 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"nofx-lite/decision"
+	"nofx-lite/logger"
+	"time"
+)
+
+// manualDecisionTimeout 等待主循环接收人工决策的最长时间（主循环正在执行决策周期时需要排队）
+const manualDecisionTimeout = 2 * time.Minute
+
+// maxPendingManualActions 等待告知AI的人工操作最多保留条数
+const maxPendingManualActions = 20
+
+var (
+	ErrTraderNotRunning      = errors.New("交易员未运行")
+	ErrTraderBusy            = errors.New("交易员正在执行决策周期，请稍后重试")
+	ErrInvalidManualDecision = errors.New("人工决策校验失败")
+)
+
+// manualDecisionRequest 交给主循环执行的人工决策
+type manualDecisionRequest struct {
+	decision decision.Decision
+	note     string
+	result   chan manualDecisionResult
+}
+
+type manualDecisionResult struct {
+	action logger.DecisionAction
+	err    error
+}
+
+// ExecuteManualDecision 人工下达决策，由主循环串行执行（与AI决策相同的校验和执行路径）
+// 主循环在超时内未接收时返回 ErrTraderBusy，此时决策不会被执行
+func (at *AutoTrader) ExecuteManualDecision(d decision.Decision, note string) (*logger.DecisionAction, error) {
+	if !at.isRunning.Load() {
+		return nil, ErrTraderNotRunning
+	}
+
+	req := &manualDecisionRequest{
+		decision: d,
+		note:     note,
+		result:   make(chan manualDecisionResult, 1),
+	}
+
+	timer := time.NewTimer(manualDecisionTimeout)
+	defer timer.Stop()
+	select {
+	case at.manualDecisionCh <- req:
+	case <-timer.C:
+		return nil, ErrTraderBusy
+	case <-at.stopMonitorCh:
+		return nil, ErrTraderNotRunning
+	}
+
+	// 已被主循环接收，等待执行完成
+	res := <-req.result
+	return &res.action, res.err
+}
+
+// handleManualDecision 在主循环中校验并执行人工决策，单独写入决策记录（initiator=manual）
+func (at *AutoTrader) handleManualDecision(req *manualDecisionRequest) {
+	d := req.decision
+	log.Printf("👤 [%s] 人工决策: %s %s（%s）", at.name, d.Symbol, d.Action, req.note)
+
+	actionRecord := logger.DecisionAction{
+		Action:    d.Action,
+		Symbol:    d.Symbol,
+		Leverage:  d.Leverage,
+		Timestamp: time.Now(),
+	}
+	record := &logger.DecisionRecord{
+		Initiator:    logger.InitiatorManual,
+		ExecutionLog: []string{fmt.Sprintf("Manual decision: %s %s", d.Symbol, d.Action)},
+		Success:      true,
+	}
+	if req.note != "" {
+		record.ExecutionLog = append(record.ExecutionLog, "Note: "+req.note)
+	}
+
+	err := at.executeManualDecision(&d, &actionRecord, record)
+	if err != nil {
+		log.Printf("❌ 人工决策执行失败 (%s %s): %v", d.Symbol, d.Action, err)
+		actionRecord.Error = err.Error()
+		record.Success = false
+		record.ErrorMessage = err.Error()
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✗ %s %s failed: %v", d.Symbol, d.Action, err))
+	} else {
+		actionRecord.Success = true
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s success", d.Symbol, d.Action))
+	}
+	record.Decisions = []logger.DecisionAction{actionRecord}
+
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存人工决策记录失败: %v", err)
+	}
+
+	// 下个周期告知AI（校验失败未执行的不需要告知）
+	if !errors.Is(err, ErrInvalidManualDecision) {
+		at.pendingManualActions = append(at.pendingManualActions, decision.ManualAction{
+			Time:    actionRecord.Timestamp,
+			Symbol:  d.Symbol,
+			Action:  d.Action,
+			Note:    req.note,
+			Success: err == nil,
+			Error:   actionRecord.Error,
+		})
+		if n := len(at.pendingManualActions); n > maxPendingManualActions {
+			at.pendingManualActions = at.pendingManualActions[n-maxPendingManualActions:]
+		}
+	}
+
+	req.result <- manualDecisionResult{action: actionRecord, err: err}
+}
+
+// executeManualDecision 构建交易上下文、校验并执行
+func (at *AutoTrader) executeManualDecision(d *decision.Decision, actionRecord *logger.DecisionAction, record *logger.DecisionRecord) error {
+	ctx, err := at.buildTradingContext()
+	if err != nil {
+		return fmt.Errorf("构建交易上下文失败: %w", err)
+	}
+	record.AccountState = logger.AccountSnapshot{
+		TotalBalance:          ctx.Account.TotalEquity,
+		AvailableBalance:      ctx.Account.AvailableBalance,
+		TotalUnrealizedProfit: ctx.Account.TotalPnL,
+		PositionCount:         ctx.Account.PositionCount,
+		MarginUsedPct:         ctx.Account.MarginUsedPct,
+		DayStartEquity:        at.dayStartEquity,
+		EquityPeak:            at.equityPeak,
+	}
+
+	if err := decision.ValidateManualDecision(d, ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidManualDecision, err)
+	}
+	actionRecord.Leverage = d.Leverage
+
+	// 开仓/加仓与AI决策走相同的仓位计算，并按最终仓位重新校验
+	if d.Action == "open_long" || d.Action == "open_short" || decision.IsAddAction(d.Action) {
+		recentPerf, _ := at.decisionLogger.AnalyzePerformance(30)
+		actionRecord.Sizing = at.sizeDecision(d, ctx, at.loadSizingInputs(ctx, recentPerf), record)
+		if err, ok := decision.ValidateSizedDecisions([]decision.Decision{*d}, ctx)[0]; ok {
+			actionRecord.Sizing.Note = fmt.Sprintf("rejected after sizing: %v", err)
+			return fmt.Errorf("%w: 按最终仓位 %.2f USD 校验失败: %v", ErrInvalidManualDecision, d.PositionSizeUSD, err)
+		}
+	}
+
+	// 每个人工决策使用独立的订单ID序号（不复用AI周期的 callCount）
+	at.manualOrderSeq++
+	at.activeManualSeq = at.manualOrderSeq
+	defer func() { at.activeManualSeq = 0 }()
+
+	return at.executeDecisionWithRecord(d, actionRecord)
+}
+
+// takeManualActions 取出待告知AI的人工操作
+func (at *AutoTrader) takeManualActions() []decision.ManualAction {
+	actions := at.pendingManualActions
+	at.pendingManualActions = nil
+	return actions
+}
@@ -0,0 +1,156 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx-lite/decision"
+	"nofx-lite/logger"
+	"nofx-lite/market"
+	"strings"
+	"time"
+)
+
+// positionAddCount 持仓已加仓次数（不含首次开仓）
+func (at *AutoTrader) positionAddCount(posKey string) int {
+	count := 0
+	for _, leg := range at.positionBuildUp[posKey] {
+		if decision.IsAddAction(leg.Action) {
+			count++
+		}
+	}
+	return count
+}
+
+// recordPositionLeg 记录一笔建仓（开仓重置历史，加仓追加），并写入决策记录
+func (at *AutoTrader) recordPositionLeg(posKey, action string, quantity, fillPrice, fallbackPrice float64, actionRecord *logger.DecisionAction) {
+	price := fillPrice
+	if price <= 0 {
+		price = fallbackPrice
+	}
+	leg := logger.PositionLeg{
+		Action:    action,
+		Quantity:  quantity,
+		Price:     price,
+		Timestamp: time.Now(),
+	}
+
+	if decision.IsAddAction(action) {
+		at.positionBuildUp[posKey] = append(at.positionBuildUp[posKey], leg)
+	} else {
+		at.positionBuildUp[posKey] = []logger.PositionLeg{leg}
+	}
+	actionRecord.BuildUp = append([]logger.PositionLeg(nil), at.positionBuildUp[posKey]...)
+}
+
+// executeAddWithRecord 执行加仓（add_long/add_short）并记录详细信息
+// 只向盈利持仓加仓，加仓后按新的总数量重新设置止损止盈
+func (at *AutoTrader) executeAddWithRecord(d *decision.Decision, actionRecord *logger.DecisionAction) error {
+	side := "long"
+	if d.Action == "add_short" {
+		side = "short"
+	}
+	positionSide := strings.ToUpper(side)
+	log.Printf("  ➕ 加仓%s: %s", positionSide, d.Symbol)
+
+	// 找到要加仓的持仓（以交易所实时数据为准）
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return fmt.Errorf("获取持仓失败: %w", err)
+	}
+	var pos *Position
+	for i := range positions {
+		if positions[i].Symbol == d.Symbol && positions[i].Side == side && positions[i].Quantity != 0 {
+			pos = &positions[i]
+			break
+		}
+	}
+	if pos == nil {
+		return fmt.Errorf("❌ %s 没有%s持仓，无法加仓", d.Symbol, positionSide)
+	}
+	if pos.UnrealizedPnL <= 0 {
+		return fmt.Errorf("❌ %s %s 持仓未盈利（%.2f USDT），拒绝加仓", d.Symbol, positionSide, pos.UnrealizedPnL)
+	}
+
+	posKey := d.Symbol + "_" + side
+	if len(at.positionBuildUp[posKey]) == 0 {
+		// 重启前已有的持仓：以当前持仓作为首笔
+		firstSeen := time.Now()
+		if ms, ok := at.positionFirstSeenTime[posKey]; ok {
+			firstSeen = time.UnixMilli(ms)
+		}
+		at.positionBuildUp[posKey] = []logger.PositionLeg{{
+			Action:    "existing",
+			Quantity:  pos.Quantity,
+			Price:     pos.EntryPrice,
+			Timestamp: firstSeen,
+		}}
+	}
+	if addCount := at.positionAddCount(posKey); addCount >= decision.MaxPositionAdds {
+		return fmt.Errorf("❌ %s %s 已加仓 %d 次，达到上限 %d", d.Symbol, positionSide, addCount, decision.MaxPositionAdds)
+	}
+
+	// 加仓沿用持仓的杠杆
+	d.Leverage = pos.EffectiveLeverage()
+
+	marketData, err := market.GetFrom(at.marketSource, d.Symbol)
+	if err != nil {
+		return err
+	}
+
+	// 按订单簿深度估算滑点（超限时缩减仓位或拒绝）
+	slippage, err := at.checkSlippage(d, side == "long", marketData.DepthData, actionRecord)
+	if err != nil {
+		return err
+	}
+
+	quantity := d.PositionSizeUSD / marketData.CurrentPrice
+	actionRecord.Quantity = quantity
+	actionRecord.Price = marketData.CurrentPrice
+	actionRecord.Leverage = d.Leverage
+
+	// ⚠️ 保证金验证
+	balance, err := at.trader.GetBalance()
+	if err != nil {
+		return fmt.Errorf("获取账户余额失败: %w", err)
+	}
+	requiredMargin := d.PositionSizeUSD / float64(d.Leverage)
+	estimatedFee := d.PositionSizeUSD * 0.0004
+	if requiredMargin+estimatedFee > balance.AvailableBalance {
+		return fmt.Errorf("❌ 保证金不足: 需要 %.2f USDT（保证金 %.2f + 手续费 %.2f），可用 %.2f USDT",
+			requiredMargin+estimatedFee, requiredMargin, estimatedFee, balance.AvailableBalance)
+	}
+
+	// 追加下单（不撤销原持仓的止损止盈，加仓失败时原保护仍然有效）
+	execution, err := at.executeOpenOrder(d, side, quantity, marketData.CurrentPrice, marketData.DepthData, pos.Quantity)
+	if execution == nil || execution.FilledQuantity <= 0 {
+		return err
+	}
+	if err != nil {
+		log.Printf("  ⚠️ 加仓未全部完成（已成交 %.4f / %.4f）: %v", execution.FilledQuantity, quantity, err)
+	}
+	at.recordExecution(execution, slippage, actionRecord)
+
+	// 重算均价、总数量与整仓风险
+	fillPrice := execution.AvgPrice
+	if fillPrice <= 0 {
+		fillPrice = marketData.CurrentPrice
+	}
+	totalQuantity := pos.Quantity + execution.FilledQuantity
+	avgEntry := decision.AverageEntryPrice(pos.Quantity, pos.EntryPrice, execution.FilledQuantity, fillPrice)
+	positionRisk := totalQuantity * math.Abs(avgEntry-d.StopLoss)
+	if (side == "long" && d.StopLoss >= avgEntry) || (side == "short" && d.StopLoss <= avgEntry) {
+		positionRisk = 0 // 止损已锁定利润
+	}
+	actionRecord.AvgEntryPrice = avgEntry
+	actionRecord.TotalQuantity = totalQuantity
+	actionRecord.PositionRisk = positionRisk
+	at.recordPositionLeg(posKey, d.Action, execution.FilledQuantity, execution.AvgPrice, marketData.CurrentPrice, actionRecord)
+
+	log.Printf("  ✓ 加仓成功: 数量 %.4f @ %.4f，持仓 %.4f -> %.4f，均价 %.4f -> %.4f，整仓风险 %.2f USDT（第%d次加仓）",
+		execution.FilledQuantity, fillPrice, pos.Quantity, totalQuantity, pos.EntryPrice, avgEntry, positionRisk, at.positionAddCount(posKey))
+
+	// 按新的总数量重新设置止损止盈（含止盈阶梯）
+	at.placeProtectiveOrders(d, positionSide, totalQuantity, actionRecord)
+	return nil
+}
@@ -0,0 +1,362 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"nofx-lite/logger"
+	"strings"
+	"time"
+)
+
+// 启动对账时无止损持仓的处理方式
+const (
+	ReconcileProtect = "protect" // 按默认止损距离补挂止损（可选同时挂止盈）
+	ReconcileClose   = "close"   // 直接市价平仓
+	ReconcileIgnore  = "ignore"  // 不处理，只在报告中标记
+)
+
+// defaultReconcileStopLossPct protect 未配置止损距离时的默认值（价格%）
+const defaultReconcileStopLossPct = 3.0
+
+// reasonReconcileUnprotected 启动对账平掉无止损持仓的原因代码
+const reasonReconcileUnprotected = "reconcile_unprotected"
+
+// ReconcilePolicy 启动对账策略：重启或在交易所界面手动下单后，如何处理没有止损的持仓
+type ReconcilePolicy struct {
+	Action        string  `json:"action"`          // protect / close / ignore（默认ignore）
+	StopLossPct   float64 `json:"stop_loss_pct"`   // protect 时止损距离（价格%，0=默认3）
+	TakeProfitPct float64 `json:"take_profit_pct"` // protect 时同时补挂的止盈距离（价格%，0=不挂）
+}
+
+// ParseReconcilePolicy 解析trader配置中的JSON（空字符串返回nil，即默认策略）
+func ParseReconcilePolicy(raw string) (*ReconcilePolicy, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var policy ReconcilePolicy
+	if err := json.Unmarshal([]byte(raw), &policy); err != nil {
+		return nil, fmt.Errorf("解析启动对账策略失败: %w", err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Validate 校验处理方式与止损止盈距离
+func (p *ReconcilePolicy) Validate() error {
+	switch p.Action {
+	case "", ReconcileProtect, ReconcileClose, ReconcileIgnore:
+	default:
+		return fmt.Errorf("reconcile_policy.action 必须是 protect / close / ignore，当前: %s", p.Action)
+	}
+	if p.StopLossPct < 0 || p.StopLossPct > 50 {
+		return fmt.Errorf("reconcile_policy.stop_loss_pct 必须在 0-50 之间")
+	}
+	if p.TakeProfitPct < 0 || p.TakeProfitPct > 100 {
+		return fmt.Errorf("reconcile_policy.take_profit_pct 必须在 0-100 之间")
+	}
+	return nil
+}
+
+// IsDefault 是否为默认策略（只标记不处理）
+func (p *ReconcilePolicy) IsDefault() bool {
+	return p == nil || ((p.Action == "" || p.Action == ReconcileIgnore) && p.StopLossPct == 0 && p.TakeProfitPct == 0)
+}
+
+// action 实际使用的处理方式
+func (p *ReconcilePolicy) action() string {
+	if p == nil || p.Action == "" {
+		return ReconcileIgnore
+	}
+	return p.Action
+}
+
+func (p *ReconcilePolicy) stopLossPct() float64 {
+	if p == nil || p.StopLossPct <= 0 {
+		return defaultReconcileStopLossPct
+	}
+	return p.StopLossPct
+}
+
+// reconcilePositions 启动对账：核对持仓与挂单，接管外部持仓，按策略处理没有止损的持仓
+// 在 Run() 启动后台监控之前调用，结果写入决策日志
+func (at *AutoTrader) reconcilePositions() {
+	policy := at.reconcilePolicy.action()
+	report := &logger.ReconciliationReport{Policy: policy}
+	record := &logger.DecisionRecord{
+		Initiator:      logger.InitiatorSystem,
+		ExecutionLog:   []string{"Startup reconciliation"},
+		Success:        true,
+		Reconciliation: report,
+	}
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠️ [%s] 启动对账失败，无法获取持仓: %v", at.name, err)
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("获取持仓失败: %v", err)
+		at.logReconciliation(record)
+		return
+	}
+
+	// 查询挂单（不支持时只接管持仓，不判断是否有止损）
+	var orders []OpenOrder
+	ordersKnown := false
+	if provider, ok := at.trader.(OpenOrderProvider); ok {
+		if orders, err = provider.GetOpenOrders(); err != nil {
+			log.Printf("⚠️ [%s] 启动对账：获取挂单失败: %v", at.name, err)
+			report.Error = fmt.Sprintf("获取挂单失败: %v", err)
+		} else {
+			ordersKnown = true
+		}
+	} else {
+		report.Error = "交易所不支持挂单查询，未检查止损"
+	}
+	report.OpenOrders = len(orders)
+
+	log.Printf("🔎 [%s] 启动对账: %d 个持仓 | %d 个挂单 | 无止损持仓处理: %s", at.name, len(positions), len(orders), policy)
+
+	hasPosition := make(map[string]bool)
+	for _, pos := range positions {
+		if pos.Quantity == 0 {
+			continue
+		}
+		posKey := pos.Symbol + "_" + pos.Side
+		hasPosition[posKey] = true
+
+		entry := logger.ReconciledPosition{
+			Symbol:     pos.Symbol,
+			Side:       pos.Side,
+			Quantity:   math.Abs(pos.Quantity),
+			EntryPrice: pos.EntryPrice,
+			MarkPrice:  pos.MarkPrice,
+			Action:     "adopted",
+		}
+		record.Positions = append(record.Positions, logger.PositionSnapshot{
+			Symbol:           pos.Symbol,
+			Side:             pos.Side,
+			PositionAmt:      pos.Quantity,
+			EntryPrice:       pos.EntryPrice,
+			MarkPrice:        pos.MarkPrice,
+			UnrealizedProfit: pos.UnrealizedPnL,
+			Leverage:         float64(pos.Leverage),
+			LiquidationPrice: pos.LiquidationPrice,
+		})
+
+		// 接管：重启前没有记录的持仓从现在开始计时
+		at.positionStateMutex.Lock()
+		if _, ok := at.positionFirstSeenTime[posKey]; ok {
+			entry.Known = true
+		} else {
+			at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+		}
+		at.positionStateMutex.Unlock()
+
+		for _, order := range orders {
+			if order.Symbol != pos.Symbol || order.PositionSide != pos.Side {
+				continue
+			}
+			switch order.Type {
+			case OpenOrderStopLoss:
+				entry.StopLoss = closestStop(pos.Side, entry.StopLoss, order.StopPrice)
+			case OpenOrderTakeProfit:
+				entry.TakeProfit = order.StopPrice
+			}
+		}
+		entry.Protected = entry.StopLoss > 0
+		if entry.Protected {
+			at.setPositionStopLoss(posKey, entry.StopLoss)
+		}
+
+		if !entry.Known {
+			log.Printf("  🆕 外部持仓 %s %s: 数量 %.4f @ %.4f，已接管", pos.Symbol, pos.Side, entry.Quantity, pos.EntryPrice)
+		}
+
+		if !ordersKnown || entry.Protected {
+			report.Positions = append(report.Positions, entry)
+			continue
+		}
+
+		log.Printf("  ⚠️ %s %s 没有止损单（标记价 %.4f，未实现盈亏 %+.2f USDT）", pos.Symbol, pos.Side, pos.MarkPrice, pos.UnrealizedPnL)
+		switch policy {
+		case ReconcileProtect:
+			at.protectUnprotectedPosition(pos, &entry, record)
+		case ReconcileClose:
+			at.closeUnprotectedPosition(pos, &entry, record)
+		default:
+			entry.Action = "flagged"
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⚠ %s %s has no stop loss (flagged)", pos.Symbol, pos.Side))
+		}
+		report.Positions = append(report.Positions, entry)
+	}
+
+	// 没有对应持仓的止损/止盈单（持仓已平，条件单残留）
+	for _, order := range orders {
+		if order.Type != OpenOrderStopLoss && order.Type != OpenOrderTakeProfit {
+			continue
+		}
+		if hasPosition[order.Symbol+"_"+order.PositionSide] {
+			continue
+		}
+		desc := fmt.Sprintf("%s %s %s @ %.4f (order %d)", order.Symbol, order.PositionSide, order.Type, order.StopPrice, order.OrderID)
+		report.OrphanOrders = append(report.OrphanOrders, desc)
+		log.Printf("  ⚠️ 残留条件单（无对应持仓）: %s", desc)
+	}
+
+	for _, entry := range report.Positions {
+		if entry.Error != "" {
+			record.Success = false
+			record.ErrorMessage = entry.Error
+		}
+	}
+	at.logReconciliation(record)
+}
+
+// protectUnprotectedPosition 按默认距离补挂止损（以及可选止盈）
+func (at *AutoTrader) protectUnprotectedPosition(pos Position, entry *logger.ReconciledPosition, record *logger.DecisionRecord) {
+	positionSide := strings.ToUpper(pos.Side)
+	quantity := math.Abs(pos.Quantity)
+	stopPct := at.reconcilePolicy.stopLossPct() / 100
+
+	// 以开仓价和标记价中较不利的一侧为基准，避免止损价已被穿越
+	var stopLoss float64
+	if pos.Side == "long" {
+		stopLoss = math.Min(pos.EntryPrice, pos.MarkPrice) * (1 - stopPct)
+	} else {
+		stopLoss = math.Max(pos.EntryPrice, pos.MarkPrice) * (1 + stopPct)
+	}
+
+	actionRecord := logger.DecisionAction{
+		Action:    "update_stop_loss",
+		Symbol:    pos.Symbol,
+		Quantity:  quantity,
+		Price:     stopLoss,
+		Timestamp: time.Now(),
+		Reason:    reasonReconcileUnprotected,
+	}
+	if err := at.trader.SetStopLoss(pos.Symbol, positionSide, quantity, stopLoss); err != nil {
+		log.Printf("  ❌ 补挂止损失败 %s %s: %v", pos.Symbol, pos.Side, err)
+		entry.Action = "flagged"
+		entry.Error = fmt.Sprintf("补挂止损失败: %v", err)
+		actionRecord.Error = err.Error()
+		record.Decisions = append(record.Decisions, actionRecord)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✗ %s %s set stop loss failed: %v", pos.Symbol, pos.Side, err))
+		return
+	}
+	actionRecord.Success = true
+	record.Decisions = append(record.Decisions, actionRecord)
+	at.setPositionStopLoss(pos.Symbol+"_"+pos.Side, stopLoss)
+	entry.Action = "protected"
+	entry.StopLoss = stopLoss
+	entry.Protected = true
+	log.Printf("  🛡 已补挂止损 %s %s @ %.4f（距离 %.1f%%）", pos.Symbol, pos.Side, stopLoss, stopPct*100)
+	record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s stop loss set @ %.4f", pos.Symbol, pos.Side, stopLoss))
+
+	if at.reconcilePolicy == nil || at.reconcilePolicy.TakeProfitPct <= 0 || entry.TakeProfit > 0 {
+		return
+	}
+	tpPct := at.reconcilePolicy.TakeProfitPct / 100
+	var takeProfit float64
+	if pos.Side == "long" {
+		takeProfit = math.Max(pos.EntryPrice, pos.MarkPrice) * (1 + tpPct)
+	} else {
+		takeProfit = math.Min(pos.EntryPrice, pos.MarkPrice) * (1 - tpPct)
+	}
+	tpRecord := logger.DecisionAction{
+		Action:    "update_take_profit",
+		Symbol:    pos.Symbol,
+		Quantity:  quantity,
+		Price:     takeProfit,
+		Timestamp: time.Now(),
+		Reason:    reasonReconcileUnprotected,
+	}
+	if err := at.trader.SetTakeProfit(pos.Symbol, positionSide, quantity, takeProfit); err != nil {
+		log.Printf("  ⚠️ 补挂止盈失败 %s %s: %v", pos.Symbol, pos.Side, err)
+		tpRecord.Error = err.Error()
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✗ %s %s set take profit failed: %v", pos.Symbol, pos.Side, err))
+	} else {
+		tpRecord.Success = true
+		entry.TakeProfit = takeProfit
+		log.Printf("  🎯 已补挂止盈 %s %s @ %.4f", pos.Symbol, pos.Side, takeProfit)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s take profit set @ %.4f", pos.Symbol, pos.Side, takeProfit))
+	}
+	record.Decisions = append(record.Decisions, tpRecord)
+}
+
+// closeUnprotectedPosition 市价平掉没有止损的持仓
+func (at *AutoTrader) closeUnprotectedPosition(pos Position, entry *logger.ReconciledPosition, record *logger.DecisionRecord) {
+	actionRecord := logger.DecisionAction{
+		Action:    "close_" + pos.Side,
+		Symbol:    pos.Symbol,
+		Quantity:  math.Abs(pos.Quantity),
+		Leverage:  pos.EffectiveLeverage(),
+		Price:     pos.MarkPrice,
+		Timestamp: time.Now(),
+		Reason:    reasonReconcileUnprotected,
+	}
+
+	var order *OrderResult
+	var err error
+	if pos.Side == "long" {
+		order, err = at.trader.CloseLong(pos.Symbol, 0, "") // 0 = 全部平仓
+	} else {
+		order, err = at.trader.CloseShort(pos.Symbol, 0, "")
+	}
+	if err != nil {
+		log.Printf("  ❌ 平仓失败 %s %s: %v", pos.Symbol, pos.Side, err)
+		entry.Action = "flagged"
+		entry.Error = fmt.Sprintf("平仓失败: %v", err)
+		actionRecord.Error = err.Error()
+		record.Decisions = append(record.Decisions, actionRecord)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✗ %s %s close failed: %v", pos.Symbol, pos.Side, err))
+		return
+	}
+
+	actionRecord.OrderID = order.OrderID
+	actionRecord.Success = true
+	at.recordFill(order.AvgPrice, nil, &actionRecord)
+	record.Decisions = append(record.Decisions, actionRecord)
+	entry.Action = "closed"
+
+	posKey := pos.Symbol + "_" + pos.Side
+	at.ClearPeakPnLCache(pos.Symbol, pos.Side)
+	at.positionStateMutex.Lock()
+	delete(at.positionFirstSeenTime, posKey)
+	delete(at.positionStopLoss, posKey)
+	at.positionStateMutex.Unlock()
+	log.Printf("  ✓ 已平掉无止损持仓 %s %s", pos.Symbol, pos.Side)
+	record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s closed (no stop loss)", pos.Symbol, pos.Side))
+}
+
+// closestStop 多个止损单时取离当前价最近的一个（多头取最高，空头取最低）
+func closestStop(side string, current, price float64) float64 {
+	if price <= 0 {
+		return current
+	}
+	if current <= 0 {
+		return price
+	}
+	if side == "long" {
+		return math.Max(current, price)
+	}
+	return math.Min(current, price)
+}
+
+// logReconciliation 把对账报告写入决策日志
+func (at *AutoTrader) logReconciliation(record *logger.DecisionRecord) {
+	flagged := 0
+	for _, entry := range record.Reconciliation.Positions {
+		if entry.Action == "flagged" {
+			flagged++
+		}
+	}
+	log.Printf("✓ [%s] 启动对账完成: %d 个持仓 | %d 个待人工处理 | %d 个残留条件单",
+		at.name, len(record.Reconciliation.Positions), flagged, len(record.Reconciliation.OrphanOrders))
+
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存对账报告失败: %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package trader
+
+import (
+	"encoding/json"
+	"log"
+	"nofx-lite/config"
+	"nofx-lite/logger"
+	"time"
+)
+
+// runtimeState 需要跨重启保留的运行时状态（JSON保存在 trader_runtime_state 表）
+type runtimeState struct {
+	CallCount             int                `json:"call_count"`
+	DailyPnL              float64            `json:"daily_pnl"`
+	LastResetTime         time.Time          `json:"last_reset_time"`
+	DayStartEquity        float64            `json:"day_start_equity"`
+	EquityPeak            float64            `json:"equity_peak"`
+	StopUntil             time.Time          `json:"stop_until"`
+	PeakPnLCache          map[string]float64 `json:"peak_pnl_cache"`
+	DrawdownBreachCount   map[string]int     `json:"drawdown_breach_count"`
+	PositionFirstSeenTime map[string]int64   `json:"position_first_seen_time"`
+	// PositionBuildUp 持仓建仓历史（重启后继续按加仓次数限制 MaxPositionAdds）
+	PositionBuildUp map[string][]logger.PositionLeg `json:"position_build_up,omitempty"`
+	// TakeProfitLadders 多级止盈状态（重启后继续识别已成交档位并按需把止损移到保本）
+	TakeProfitLadders map[string]*takeProfitLadder `json:"take_profit_ladders,omitempty"`
+}
+
+// restoreRuntimeState 从数据库恢复上次保存的运行时状态（在 NewAutoTrader 中调用，此时还没有goroutine）
+func (at *AutoTrader) restoreRuntimeState() {
+	defer at.fillRuntimeState()
+
+	if at.database == nil {
+		return
+	}
+
+	type RuntimeStateReader interface {
+		GetTraderRuntimeState(traderID string) (*config.TraderRuntimeState, error)
+	}
+
+	db, ok := at.database.(RuntimeStateReader)
+	if !ok {
+		return
+	}
+
+	saved, err := db.GetTraderRuntimeState(at.id)
+	if err != nil {
+		// 首次启动没有记录
+		return
+	}
+
+	var state runtimeState
+	if err := json.Unmarshal([]byte(saved.State), &state); err != nil {
+		log.Printf("⚠️ [%s] 解析运行时状态失败，使用初始状态: %v", at.name, err)
+		return
+	}
+
+	at.callCount = state.CallCount
+	if !state.LastResetTime.IsZero() {
+		at.dailyPnL = state.DailyPnL
+		at.lastResetTime = state.LastResetTime
+	}
+	if state.DayStartEquity > 0 {
+		at.dayStartEquity = state.DayStartEquity
+	}
+	if state.EquityPeak > 0 {
+		at.equityPeak = state.EquityPeak
+	}
+	if time.Now().Before(state.StopUntil) {
+		at.stopUntil = state.StopUntil
+		log.Printf("⏸ [%s] 恢复风控暂停，剩余 %.0f 分钟", at.name, time.Until(state.StopUntil).Minutes())
+	}
+	for k, v := range state.PeakPnLCache {
+		at.peakPnLCache[k] = v
+	}
+	for k, v := range state.DrawdownBreachCount {
+		at.drawdownBreachCount[k] = v
+	}
+	for k, v := range state.PositionFirstSeenTime {
+		at.positionFirstSeenTime[k] = v
+	}
+	for k, legs := range state.PositionBuildUp {
+		at.positionBuildUp[k] = legs
+	}
+	for k, ladder := range state.TakeProfitLadders {
+		at.takeProfitLadders[k] = ladder
+	}
+
+	log.Printf("✓ [%s] 已恢复运行时状态（保存于 %s）: 周期 #%d | 日初净值 %.2f | 净值峰值 %.2f | 跟踪持仓 %d 个",
+		at.name, saved.UpdatedAt.Format("2006-01-02 15:04:05"), at.callCount,
+		at.dayStartEquity, at.equityPeak, len(at.positionFirstSeenTime))
+}
+
+// fillRuntimeState 用当前内存状态初始化快照（之后主循环和后台监控只更新各自负责的字段）
+func (at *AutoTrader) fillRuntimeState() {
+	at.runtimeStateMutex.Lock()
+	defer at.runtimeStateMutex.Unlock()
+
+	at.copyCycleState()
+	at.copyPositionPlanState()
+	at.copyMonitorState()
+}
+
+// checkpointCycleState 决策周期结束后保存状态（主循环调用）
+func (at *AutoTrader) checkpointCycleState() {
+	at.runtimeStateMutex.Lock()
+	defer at.runtimeStateMutex.Unlock()
+
+	at.copyCycleState()
+	at.copyPositionPlanState()
+	at.saveRuntimeState()
+}
+
+// checkpointStopState Stop() 时保存状态
+// 主循环可能仍在执行周期，不复制主循环独占的持仓计划（保留上次周期结束时的快照）
+func (at *AutoTrader) checkpointStopState() {
+	at.runtimeStateMutex.Lock()
+	defer at.runtimeStateMutex.Unlock()
+
+	at.copyCycleState()
+	at.saveRuntimeState()
+}
+
+// checkpointMonitorState 后台监控每次检查后保存状态（监控goroutine调用）
+func (at *AutoTrader) checkpointMonitorState() {
+	at.runtimeStateMutex.Lock()
+	defer at.runtimeStateMutex.Unlock()
+
+	at.copyMonitorState()
+	at.saveRuntimeState()
+}
+
+// copyCycleState 复制主循环维护的字段（调用方持有 runtimeStateMutex）
+func (at *AutoTrader) copyCycleState() {
+	at.runtimeState.CallCount = at.callCount
+	at.runtimeState.DailyPnL = at.dailyPnL
+	at.runtimeState.LastResetTime = at.lastResetTime
+	at.runtimeState.DayStartEquity = at.dayStartEquity
+	at.runtimeState.EquityPeak = at.equityPeak
+	at.runtimeState.StopUntil = at.stopUntil
+	at.runtimeState.PeakPnLCache = at.GetPeakPnLCache()
+
+	at.positionStateMutex.RLock()
+	firstSeen := make(map[string]int64, len(at.positionFirstSeenTime))
+	for k, v := range at.positionFirstSeenTime {
+		firstSeen[k] = v
+	}
+	at.positionStateMutex.RUnlock()
+	at.runtimeState.PositionFirstSeenTime = firstSeen
+}
+
+// copyPositionPlanState 复制只由主循环读写的持仓计划（调用方持有 runtimeStateMutex，且在主循环或启动时调用）
+func (at *AutoTrader) copyPositionPlanState() {
+	buildUp := make(map[string][]logger.PositionLeg, len(at.positionBuildUp))
+	for k, legs := range at.positionBuildUp {
+		buildUp[k] = append([]logger.PositionLeg(nil), legs...)
+	}
+	at.runtimeState.PositionBuildUp = buildUp
+
+	// 阶梯在主循环中原地修改，保存深拷贝（后台监控保存时会序列化快照）
+	ladders := make(map[string]*takeProfitLadder, len(at.takeProfitLadders))
+	for k, ladder := range at.takeProfitLadders {
+		copied := *ladder
+		copied.Rungs = append([]takeProfitRung(nil), ladder.Rungs...)
+		ladders[k] = &copied
+	}
+	at.runtimeState.TakeProfitLadders = ladders
+}
+
+// copyMonitorState 复制后台监控维护的字段（调用方持有 runtimeStateMutex）
+func (at *AutoTrader) copyMonitorState() {
+	breaches := make(map[string]int)
+	for k, v := range at.drawdownBreachCount {
+		if v > 0 {
+			breaches[k] = v
+		}
+	}
+	at.runtimeState.DrawdownBreachCount = breaches
+	at.runtimeState.PeakPnLCache = at.GetPeakPnLCache()
+}
+
+// saveRuntimeState 写入数据库（与上次保存的内容相同时跳过，调用方持有 runtimeStateMutex）
+func (at *AutoTrader) saveRuntimeState() {
+	if at.database == nil {
+		return
+	}
+
+	type RuntimeStateWriter interface {
+		SaveTraderRuntimeState(traderID, userID, state string) error
+	}
+
+	db, ok := at.database.(RuntimeStateWriter)
+	if !ok {
+		return
+	}
+
+	data, err := json.Marshal(at.runtimeState)
+	if err != nil {
+		log.Printf("⚠️ [%s] 序列化运行时状态失败: %v", at.name, err)
+		return
+	}
+	if string(data) == at.savedRuntimeState {
+		return
+	}
+
+	if err := db.SaveTraderRuntimeState(at.id, at.userID, string(data)); err != nil {
+		log.Printf("⚠️ [%s] 保存运行时状态失败: %v", at.name, err)
+		return
+	}
+	at.savedRuntimeState = string(data)
+}
@@ -0,0 +1,280 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx-lite/decision"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultEventBufferMinutes 未配置时日历事件前后禁止开仓的分钟数
+const defaultEventBufferMinutes = 30
+
+// ScheduleWindow 按星期和时刻定义的时段（cron风格的星期字段）
+type ScheduleWindow struct {
+	Name  string `json:"name,omitempty"`
+	Days  string `json:"days"`  // 星期："*"、"1-5"、"0,6"（0或7=周日）
+	Start string `json:"start"` // "HH:MM"
+	End   string `json:"end"`   // "HH:MM"，不晚于 start 表示跨午夜（算在 start 所在的那天）
+
+	days       [7]bool
+	start, end int // 当天的分钟数
+}
+
+// CalendarEvent 本地日历文件中的事件（如 FOMC、CPI 公布）
+type CalendarEvent struct {
+	Name  string    `json:"name"`
+	Start time.Time `json:"start"`         // RFC3339
+	End   time.Time `json:"end,omitempty"` // 为空时按时间点处理
+}
+
+// TradingSchedule 交易时段配置：只在 windows 内允许开仓，blackouts 与日历事件期间禁止开仓
+// 禁止开仓时仍允许平仓、部分平仓与调整止损止盈
+type TradingSchedule struct {
+	Timezone           string           `json:"timezone"`             // IANA时区（如 Asia/Shanghai），默认UTC
+	Windows            []ScheduleWindow `json:"windows"`              // 允许开仓的时段（为空表示全天）
+	Blackouts          []ScheduleWindow `json:"blackouts"`            // 禁止开仓的时段（如周末、低流动性时段）
+	CalendarFile       string           `json:"calendar_file"`        // 本地事件日历（JSON数组，修改后自动重新加载）
+	EventBufferMinutes int              `json:"event_buffer_minutes"` // 事件前后禁止开仓的分钟数（0=默认30）
+
+	location      *time.Location
+	calendarMu    sync.Mutex
+	calendar      []CalendarEvent
+	calendarMtime time.Time
+}
+
+// ParseTradingSchedule 解析trader配置中的JSON（空字符串返回nil）
+func ParseTradingSchedule(raw string) (*TradingSchedule, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var schedule TradingSchedule
+	if err := json.Unmarshal([]byte(raw), &schedule); err != nil {
+		return nil, fmt.Errorf("解析交易时段配置失败: %w", err)
+	}
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// Validate 校验时区、星期与时刻格式（同时完成解析）
+// 不读取日历文件：文件暂时缺失或格式错误时仍保留时段限制，由 calendarEvents 之后重试加载
+func (s *TradingSchedule) Validate() error {
+	s.location = time.UTC
+	if s.Timezone != "" {
+		loc, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			return fmt.Errorf("无效的时区 %s: %w", s.Timezone, err)
+		}
+		s.location = loc
+	}
+	for i := range s.Windows {
+		if err := s.Windows[i].parse(); err != nil {
+			return fmt.Errorf("交易时段 #%d: %w", i+1, err)
+		}
+	}
+	for i := range s.Blackouts {
+		if err := s.Blackouts[i].parse(); err != nil {
+			return fmt.Errorf("禁止开仓时段 #%d: %w", i+1, err)
+		}
+	}
+	if s.EventBufferMinutes < 0 || s.EventBufferMinutes > 24*60 {
+		return fmt.Errorf("event_buffer_minutes 必须在 0-1440 之间")
+	}
+	return nil
+}
+
+// ValidateCalendarFile 检查日历文件能否加载（保存配置时调用）
+func (s *TradingSchedule) ValidateCalendarFile() error {
+	if s.CalendarFile == "" {
+		return nil
+	}
+	_, err := loadCalendarFile(s.CalendarFile)
+	return err
+}
+
+// Enabled 是否配置了任何限制
+func (s *TradingSchedule) Enabled() bool {
+	return s != nil && (len(s.Windows) > 0 || len(s.Blackouts) > 0 || s.CalendarFile != "")
+}
+
+// EntryBlockReason 返回当前禁止开仓的原因（允许开仓时返回空字符串）
+func (s *TradingSchedule) EntryBlockReason(now time.Time) string {
+	if !s.Enabled() {
+		return ""
+	}
+	local := now.In(s.location)
+
+	for _, w := range s.Blackouts {
+		if w.contains(local) {
+			return fmt.Sprintf("禁止开仓时段 %s", w.describe())
+		}
+	}
+
+	if len(s.Windows) > 0 {
+		inWindow := false
+		for _, w := range s.Windows {
+			if w.contains(local) {
+				inWindow = true
+				break
+			}
+		}
+		if !inWindow {
+			return fmt.Sprintf("不在交易时段内（%s %s）", local.Format("Mon 15:04"), s.location)
+		}
+	}
+
+	buffer := time.Duration(s.EventBufferMinutes) * time.Minute
+	if s.EventBufferMinutes == 0 {
+		buffer = defaultEventBufferMinutes * time.Minute
+	}
+	for _, event := range s.calendarEvents() {
+		end := event.End
+		if end.IsZero() || end.Before(event.Start) {
+			end = event.Start
+		}
+		if !now.Before(event.Start.Add(-buffer)) && !now.After(end.Add(buffer)) {
+			return fmt.Sprintf("日历事件 %s（%s，前后%v）", event.Name, event.Start.In(s.location).Format("01-02 15:04"), buffer)
+		}
+	}
+	return ""
+}
+
+// calendarEvents 返回日历事件（文件修改后重新加载，加载失败时沿用上次的结果）
+func (s *TradingSchedule) calendarEvents() []CalendarEvent {
+	if s.CalendarFile == "" {
+		return nil
+	}
+	s.calendarMu.Lock()
+	defer s.calendarMu.Unlock()
+
+	info, err := os.Stat(s.CalendarFile)
+	if err != nil {
+		log.Printf("⚠️ 读取事件日历失败: %v", err)
+		return s.calendar
+	}
+	if info.ModTime().Equal(s.calendarMtime) {
+		return s.calendar
+	}
+
+	events, err := loadCalendarFile(s.CalendarFile)
+	if err != nil {
+		log.Printf("⚠️ %v", err)
+		return s.calendar
+	}
+	s.calendar = events
+	s.calendarMtime = info.ModTime()
+	log.Printf("📅 已加载事件日历: %s（%d 个事件）", s.CalendarFile, len(events))
+	return s.calendar
+}
+
+// loadCalendarFile 读取本地日历文件
+func loadCalendarFile(path string) ([]CalendarEvent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取事件日历 %s 失败: %w", path, err)
+	}
+	var events []CalendarEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, fmt.Errorf("解析事件日历 %s 失败: %w", path, err)
+	}
+	for i, event := range events {
+		if event.Start.IsZero() {
+			return nil, fmt.Errorf("事件日历 %s 第%d个事件缺少 start", path, i+1)
+		}
+	}
+	return events, nil
+}
+
+// parse 解析星期与时刻
+func (w *ScheduleWindow) parse() error {
+	days, err := parseCronDays(w.Days)
+	if err != nil {
+		return err
+	}
+	w.days = days
+	if w.start, err = parseClock(w.Start); err != nil {
+		return err
+	}
+	if w.end, err = parseClock(w.End); err != nil {
+		return err
+	}
+	return nil
+}
+
+// contains 时刻是否落在时段内（跨午夜的时段算在开始那天）
+func (w *ScheduleWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	day := int(t.Weekday())
+	if w.start < w.end {
+		return w.days[day] && minute >= w.start && minute < w.end
+	}
+	prev := (day + 6) % 7
+	return (w.days[day] && minute >= w.start) || (w.days[prev] && minute < w.end)
+}
+
+func (w *ScheduleWindow) describe() string {
+	desc := fmt.Sprintf("[%s] %s-%s", w.Days, w.Start, w.End)
+	if w.Name != "" {
+		desc = w.Name + " " + desc
+	}
+	return desc
+}
+
+// parseCronDays 解析cron风格的星期字段："*"、"1-5"、"0,6"、"1-3,5"
+func parseCronDays(field string) ([7]bool, error) {
+	var days [7]bool
+	field = strings.TrimSpace(field)
+	if field == "" || field == "*" {
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	}
+
+	for _, part := range strings.Split(field, ",") {
+		lo, hi := part, part
+		if idx := strings.Index(part, "-"); idx >= 0 {
+			lo, hi = part[:idx], part[idx+1:]
+		}
+		from, err1 := strconv.Atoi(strings.TrimSpace(lo))
+		to, err2 := strconv.Atoi(strings.TrimSpace(hi))
+		if err1 != nil || err2 != nil || from < 0 || to > 7 || from > to {
+			return days, fmt.Errorf("无效的星期字段: %q（0-7，0和7均为周日）", field)
+		}
+		for d := from; d <= to; d++ {
+			days[d%7] = true
+		}
+	}
+	return days, nil
+}
+
+// parseClock 解析 "HH:MM" 为当天的分钟数（允许 24:00）
+func parseClock(value string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("无效的时刻: %q（格式 HH:MM）", value)
+	}
+	hour, err1 := strconv.Atoi(parts[0])
+	minute, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || hour < 0 || minute < 0 || minute > 59 || hour*60+minute > 24*60 {
+		return 0, fmt.Errorf("无效的时刻: %q（格式 HH:MM）", value)
+	}
+	return hour*60 + minute, nil
+}
+
+// isEntryAction 是否为开仓/加仓动作（禁止开仓时被跳过）
+func isEntryAction(action string) bool {
+	return action == "open_long" || action == "open_short" || decision.IsAddAction(action)
+}
+
+// entryBlockReason 当前是否禁止开仓（未配置交易时段时返回空字符串）
+func (at *AutoTrader) entryBlockReason() string {
+	return at.tradingSchedule.EntryBlockReason(time.Now())
+}
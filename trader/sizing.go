@@ -1,6 +1,223 @@
 package trader
 
-import "math"
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "nofx-lite/logger"
+    "strings"
+)
+
+// 仓位计算模型
+const (
+    SizingModelAI         = "ai"         // AI建议仓位，按胜率/盈亏因子/信心度微调（默认）
+    SizingModelFixedRisk  = "fixed_risk" // 固定风险：按止损距离，止损亏损 = 净值 × risk_pct
+    SizingModelVolatility = "volatility" // 波动率目标：按ATR，atr_multiple 倍ATR的波动 = 净值 × risk_pct
+    SizingModelKelly      = "kelly"      // 分数凯利：按历史胜率与盈亏比决定每笔风险，再按止损距离计算仓位
+)
+
+// 仓位模型参数默认值
+const (
+    defaultSizingRiskPct     = 1.0
+    defaultSizingATRMultiple = 2.0
+    defaultKellyFraction     = 0.25
+    defaultKellyMaxRiskPct   = 2.0
+    defaultKellyMinTrades    = 20
+)
+
+// PositionSizing 交易员的仓位计算模型配置
+type PositionSizing struct {
+    Model         string  `json:"model"`          // ai / fixed_risk / volatility / kelly
+    RiskPct       float64 `json:"risk_pct"`       // fixed_risk / volatility：每笔风险（净值%，默认1）
+    ATRMultiple   float64 `json:"atr_multiple"`   // volatility：波动单位（ATR倍数，默认2）
+    KellyFraction float64 `json:"kelly_fraction"` // kelly：凯利比例折扣（默认0.25）
+    MaxRiskPct    float64 `json:"max_risk_pct"`   // kelly：每笔风险上限（净值%，默认2）
+    MinTrades     int     `json:"min_trades"`     // kelly：最少历史交易数，不足时退回AI仓位（默认20）
+}
+
+// ParsePositionSizing 解析trader配置中的JSON（空字符串返回nil，即AI建议仓位）
+func ParsePositionSizing(raw string) (*PositionSizing, error) {
+    if strings.TrimSpace(raw) == "" {
+        return nil, nil
+    }
+    var sizing PositionSizing
+    if err := json.Unmarshal([]byte(raw), &sizing); err != nil {
+        return nil, fmt.Errorf("解析仓位计算模型失败: %w", err)
+    }
+    if err := sizing.Validate(); err != nil {
+        return nil, err
+    }
+    return &sizing, nil
+}
+
+// Validate 校验模型名称与参数范围
+func (s *PositionSizing) Validate() error {
+    switch s.Model {
+    case "", SizingModelAI, SizingModelFixedRisk, SizingModelVolatility, SizingModelKelly:
+    default:
+        return fmt.Errorf("position_sizing.model 必须是 ai / fixed_risk / volatility / kelly，当前: %s", s.Model)
+    }
+    if s.RiskPct < 0 || s.RiskPct > 10 {
+        return fmt.Errorf("position_sizing.risk_pct 必须在 0-10 之间")
+    }
+    if s.ATRMultiple < 0 || s.ATRMultiple > 10 {
+        return fmt.Errorf("position_sizing.atr_multiple 必须在 0-10 之间")
+    }
+    if s.KellyFraction < 0 || s.KellyFraction > 1 {
+        return fmt.Errorf("position_sizing.kelly_fraction 必须在 0-1 之间")
+    }
+    if s.MaxRiskPct < 0 || s.MaxRiskPct > 10 {
+        return fmt.Errorf("position_sizing.max_risk_pct 必须在 0-10 之间")
+    }
+    if s.MinTrades < 0 {
+        return fmt.Errorf("position_sizing.min_trades 不能为负数")
+    }
+    return nil
+}
+
+// Enabled 是否使用AI建议仓位以外的模型
+func (s *PositionSizing) Enabled() bool {
+    return s != nil && s.Model != "" && s.Model != SizingModelAI
+}
+
+// model 实际使用的模型
+func (s *PositionSizing) model() string {
+    if !s.Enabled() {
+        return SizingModelAI
+    }
+    return s.Model
+}
+
+func (s *PositionSizing) riskPct() float64 {
+    if s.RiskPct <= 0 {
+        return defaultSizingRiskPct
+    }
+    return s.RiskPct
+}
+
+func (s *PositionSizing) atrMultiple() float64 {
+    if s.ATRMultiple <= 0 {
+        return defaultSizingATRMultiple
+    }
+    return s.ATRMultiple
+}
+
+func (s *PositionSizing) kellyFraction() float64 {
+    if s.KellyFraction <= 0 {
+        return defaultKellyFraction
+    }
+    return s.KellyFraction
+}
+
+func (s *PositionSizing) maxRiskPct() float64 {
+    if s.MaxRiskPct <= 0 {
+        return defaultKellyMaxRiskPct
+    }
+    return s.MaxRiskPct
+}
+
+func (s *PositionSizing) minTrades() int {
+    if s.MinTrades <= 0 {
+        return defaultKellyMinTrades
+    }
+    return s.MinTrades
+}
+
+// describe 日志用的模型描述
+func (s *PositionSizing) describe() string {
+    switch s.model() {
+    case SizingModelFixedRisk:
+        return fmt.Sprintf("fixed_risk（每笔风险 %.2f%%）", s.riskPct())
+    case SizingModelVolatility:
+        return fmt.Sprintf("volatility（%.1f×ATR 波动 = 净值 %.2f%%）", s.atrMultiple(), s.riskPct())
+    case SizingModelKelly:
+        return fmt.Sprintf("kelly（%.2f 倍凯利，每笔风险上限 %.2f%%，至少 %d 笔历史交易）", s.kellyFraction(), s.maxRiskPct(), s.minTrades())
+    }
+    return SizingModelAI
+}
+
+// ComputeModelSize 按配置的模型计算开仓名义价值（USD），计算输入写入 rec
+// 返回 ok=false 表示输入不足（缺少止损、ATR或历史交易），调用方退回AI建议仓位
+// kelly 在历史期望为负时返回 0（不开仓）
+// 加仓时（rec.ExistingUSD > 0）按整仓计算：返回整仓目标扣除现有持仓后的加仓部分
+func ComputeModelSize(s *PositionSizing, rec *logger.SizingRecord, perf *logger.PerformanceAnalysis) (float64, bool) {
+    if rec.Equity <= 0 || rec.Price <= 0 {
+        rec.Note = "缺少净值或价格"
+        return 0, false
+    }
+    if rec.StopLoss > 0 {
+        rec.StopDistPct = math.Abs(rec.Price-rec.StopLoss) / rec.Price * 100
+    }
+
+    switch s.model() {
+    case SizingModelFixedRisk:
+        if rec.StopDistPct <= 0 {
+            rec.Note = "缺少止损价"
+            return 0, false
+        }
+        rec.RiskPct = s.riskPct()
+        return riskBudgetSize(rec), true
+
+    case SizingModelVolatility:
+        if rec.ATR <= 0 {
+            rec.Note = "缺少ATR数据"
+            return 0, false
+        }
+        rec.RiskPct = s.riskPct()
+        volDistPct := s.atrMultiple() * rec.ATR / rec.Price * 100
+        return math.Max(rec.Equity*rec.RiskPct/volDistPct-rec.ExistingUSD, 0), true
+
+    case SizingModelKelly:
+        if perf == nil || perf.TotalTrades < s.minTrades() {
+            rec.Note = fmt.Sprintf("历史交易不足 %d 笔", s.minTrades())
+            return 0, false
+        }
+        if rec.StopDistPct <= 0 {
+            rec.Note = "缺少止损价"
+            return 0, false
+        }
+        rec.WinRate = perf.WinRate
+        rec.ProfitFactor = perf.ProfitFactor
+        if perf.AvgLoss == 0 || perf.AvgWin <= 0 {
+            rec.Note = "缺少平均盈利/亏损"
+            return 0, false
+        }
+        // f* = W - (1-W)/R，R = 平均盈利/平均亏损
+        rec.PayoffRatio = perf.AvgWin / math.Abs(perf.AvgLoss)
+        w := perf.WinRate / 100
+        kelly := w - (1-w)/rec.PayoffRatio
+        rec.KellyPct = kelly * 100
+        if kelly <= 0 {
+            rec.Note = "凯利比例为负（历史期望为负），不开仓"
+            return 0, true
+        }
+        rec.RiskPct = math.Min(rec.KellyPct*s.kellyFraction(), s.maxRiskPct())
+        return riskBudgetSize(rec), true
+    }
+    return 0, false
+}
+
+// riskBudgetSize 止损亏损 = 净值 × RiskPct 的仓位（加仓时先扣除现有持仓按新止损的风险）
+func riskBudgetSize(rec *logger.SizingRecord) float64 {
+    budget := rec.Equity*rec.RiskPct/100 - rec.ExistingRiskUSD
+    if budget <= 0 {
+        rec.Note = "现有持仓已用完风险预算"
+        return 0
+    }
+    return budget / (rec.StopDistPct / 100)
+}
+
+// ApplySizeProtections 对模型计算的仓位应用与AI仓位相同的保护：近期亏损冷却、滑点折扣、保证金上限
+func ApplySizeProtections(size float64, leverage int, available float64, feeRate float64, recentLossCooldown bool, slippagePct float64) float64 {
+    if size <= 0 {
+        return 0
+    }
+    if recentLossCooldown {
+        size *= 0.60
+    }
+    size *= slippageMultiplier(slippagePct)
+    return clampToMargin(size, leverage, available, feeRate, slippagePct)
+}
 
 // ComputeAdjustedSize computes adjusted position size in USD based on
 // recent win rate, profit factor, confidence, recent loss cooldown and margin safety.
@@ -12,7 +229,8 @@ import "math"
 // - profitFactor: recent profit factor (>=0)
 // - confidence: decision confidence (0-100)
 // - recentLossCooldown: whether to apply cooldown due to recent large loss
-func ComputeAdjustedSize(base float64, leverage int, available float64, feeRate float64, winRate float64, profitFactor float64, confidence float64, recentLossCooldown bool) float64 {
+// - slippagePct: estimated slippage from order book depth in percent (e.g., 0.3), 0 if unknown
+func ComputeAdjustedSize(base float64, leverage int, available float64, feeRate float64, winRate float64, profitFactor float64, confidence float64, recentLossCooldown bool, slippagePct float64) float64 {
     if base <= 0 {
         return base
     }
@@ -41,6 +259,9 @@ func ComputeAdjustedSize(base float64, leverage int, available float64, feeRate
         multiplier *= 0.60
     }
 
+    // Thin order book: expected slippage eats into the edge
+    multiplier *= slippageMultiplier(slippagePct)
+
     newSize := base * multiplier
 
     // Smooth relative-change clamp (easing): limit increases/decreases per decision
@@ -55,9 +276,23 @@ func ComputeAdjustedSize(base float64, leverage int, available float64, feeRate
         }
     }
 
-    // Margin safety clamp: required margin + fee must fit available
+    return clampToMargin(newSize, leverage, available, feeRate, slippagePct)
+}
+
+// slippageMultiplier thin order book: expected slippage eats into the edge
+func slippageMultiplier(slippagePct float64) float64 {
+    if slippagePct >= 0.5 {
+        return 0.70
+    } else if slippagePct >= 0.2 {
+        return 0.85
+    }
+    return 1.0
+}
+
+// clampToMargin margin safety clamp: required margin + fee + slippage cost must fit available
+func clampToMargin(newSize float64, leverage int, available float64, feeRate float64, slippagePct float64) float64 {
     if leverage > 0 && available > 0 {
-        denom := (1.0/float64(leverage) + feeRate)
+        denom := (1.0/float64(leverage) + feeRate + math.Max(slippagePct, 0)/100)
         limit := available / denom
         if limit > 0 {
             // Keep a cushion to reduce near-limit errors
@@ -0,0 +1,95 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx-lite/decision"
+	"nofx-lite/logger"
+	"nofx-lite/market"
+)
+
+const (
+	// defaultMaxSlippagePct 未配置时开仓允许的最大预估滑点（%）
+	defaultMaxSlippagePct = 0.5
+	// slippageDepthLimit 下单前拉取的订单簿档位数（比AI分析用的10档更深）
+	slippageDepthLimit = 50
+	// minSlippageDownsizeRatio 缩减后低于原仓位的此比例则直接拒绝（流动性太差）
+	minSlippageDownsizeRatio = 0.3
+	// minOrderNotionalUSD 缩减后的最小名义价值（各交易所最小下单额约5-10 USDT）
+	minOrderNotionalUSD = 10.0
+)
+
+// maxSlippagePct 交易员配置的最大预估滑点（%）
+func (at *AutoTrader) maxSlippagePct() float64 {
+	if at.config.MaxSlippagePct > 0 {
+		return at.config.MaxSlippagePct
+	}
+	return defaultMaxSlippagePct
+}
+
+// checkSlippage 开仓前按订单簿深度估算滑点：超出上限时缩减仓位，缩减后过小则拒绝
+// 会修改 d.PositionSizeUSD，并在 actionRecord 中记录预估成交价与滑点
+// 无法获取深度时不阻止开仓（返回 nil, nil）
+func (at *AutoTrader) checkSlippage(d *decision.Decision, isBuy bool, fallback *market.DepthData, actionRecord *logger.DecisionAction) (*market.SlippageEstimate, error) {
+	depth, err := at.marketSource.GetDepth(d.Symbol, slippageDepthLimit)
+	if err != nil || depth == nil {
+		depth = fallback
+	}
+	if depth == nil {
+		log.Printf("  ⚠️ %s 无订单簿深度数据，跳过滑点检查", d.Symbol)
+		return nil, nil
+	}
+
+	estimate, err := market.EstimateSlippage(depth, isBuy, d.PositionSizeUSD)
+	if err != nil {
+		log.Printf("  ⚠️ %s 滑点估算失败，跳过检查: %v", d.Symbol, err)
+		return nil, nil
+	}
+
+	maxPct := at.maxSlippagePct()
+	if estimate.SlippagePct > maxPct || !estimate.SufficientDepth {
+		maxNotional := market.MaxNotionalWithinSlippage(depth, isBuy, maxPct)
+		if maxNotional < d.PositionSizeUSD*minSlippageDownsizeRatio || maxNotional < minOrderNotionalUSD {
+			return nil, fmt.Errorf("❌ %s 预估滑点 %.3f%% 超过上限 %.2f%%（仓位 %.2f USDT，可见深度内仅能成交 %.2f USDT），拒绝开仓",
+				d.Symbol, estimate.SlippagePct, maxPct, d.PositionSizeUSD, maxNotional)
+		}
+
+		log.Printf("  ✂️ %s 预估滑点 %.3f%% 超过上限 %.2f%%，仓位缩减: %.2f -> %.2f USDT",
+			d.Symbol, estimate.SlippagePct, maxPct, d.PositionSizeUSD, maxNotional)
+		d.PositionSizeUSD = maxNotional
+		if estimate, err = market.EstimateSlippage(depth, isBuy, maxNotional); err != nil {
+			return nil, nil
+		}
+	}
+
+	log.Printf("  📐 %s 预估成交均价 %.6f，滑点 %.3f%%（吃 %d 档）", d.Symbol, estimate.AvgPrice, estimate.SlippagePct, estimate.LevelsUsed)
+	actionRecord.EstimatedPrice = estimate.AvgPrice
+	actionRecord.EstimatedSlippagePct = estimate.SlippagePct
+	return estimate, nil
+}
+
+// recordFill 记录实际成交均价与滑点（交易所未返回成交均价时跳过）
+func (at *AutoTrader) recordFill(avgPrice float64, estimate *market.SlippageEstimate, actionRecord *logger.DecisionAction) {
+	if avgPrice <= 0 {
+		return
+	}
+	actionRecord.FillPrice = avgPrice
+	if estimate == nil {
+		return
+	}
+	actionRecord.ActualSlippagePct = market.SlippagePct(avgPrice, estimate.ReferencePrice, estimate.IsBuy)
+	log.Printf("  📐 实际成交均价 %.6f（预估 %.6f），滑点 %.3f%%（预估 %.3f%%）",
+		avgPrice, estimate.AvgPrice, actionRecord.ActualSlippagePct, estimate.SlippagePct)
+}
+
+// estimateSlippagePct 用决策周期内的深度数据估算滑点（用于仓位调整，失败返回0）
+func estimateSlippagePct(data *market.Data, isBuy bool, notional float64) float64 {
+	if data == nil || data.DepthData == nil || notional <= 0 {
+		return 0
+	}
+	estimate, err := market.EstimateSlippage(data.DepthData, isBuy, notional)
+	if err != nil {
+		return 0
+	}
+	return estimate.SlippagePct
+}
@@ -0,0 +1,158 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx-lite/config"
+	"nofx-lite/decision"
+	"strings"
+	"sync"
+	"time"
+)
+
+// symbolUniverseTTL 交易所可交易币种列表的刷新间隔
+const symbolUniverseTTL = 30 * time.Minute
+
+// SymbolUniverse 交易员可交易币种范围：交易所上架状态 + 用户黑白名单
+type SymbolUniverse struct {
+	provider SymbolUniverseProvider // 为nil时不校验上架状态（交易所不支持查询）
+
+	mu        sync.RWMutex
+	tradable  map[string]bool // 交易所当前可交易的永续合约
+	updatedAt time.Time
+	blacklist map[string]bool
+	whitelist map[string]bool // 非空时只允许名单内币种
+}
+
+// NewSymbolUniverse 创建可交易币种范围
+func NewSymbolUniverse(provider SymbolUniverseProvider) *SymbolUniverse {
+	return &SymbolUniverse{provider: provider}
+}
+
+// SetFilters 设置黑白名单
+func (u *SymbolUniverse) SetFilters(blacklist, whitelist []string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.blacklist = symbolSet(blacklist)
+	u.whitelist = symbolSet(whitelist)
+}
+
+// Refresh 刷新交易所可交易币种（未过期时跳过；失败时保留旧数据）
+func (u *SymbolUniverse) Refresh() error {
+	if u.provider == nil {
+		return nil
+	}
+
+	u.mu.RLock()
+	fresh := u.tradable != nil && time.Since(u.updatedAt) < symbolUniverseTTL
+	u.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	symbols, err := u.provider.GetTradableSymbols()
+	if err != nil {
+		return fmt.Errorf("获取可交易币种失败: %w", err)
+	}
+	if len(symbols) == 0 {
+		return fmt.Errorf("交易所返回的可交易币种为空")
+	}
+
+	u.mu.Lock()
+	removed := 0
+	for symbol := range u.tradable {
+		if !symbols[symbol] {
+			removed++
+			log.Printf("⚠️  %s 已不可交易（下架或结算中）", symbol)
+		}
+	}
+	u.tradable = symbols
+	u.updatedAt = time.Now()
+	u.mu.Unlock()
+
+	log.Printf("✓ 可交易币种已刷新: %d个（新增不可交易%d个）", len(symbols), removed)
+	return nil
+}
+
+// CheckSymbol 校验币种是否在可交易范围内
+func (u *SymbolUniverse) CheckSymbol(symbol string) error {
+	symbol = normalizeSymbol(symbol)
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	if u.blacklist[symbol] {
+		return fmt.Errorf("%s 在黑名单中", symbol)
+	}
+	if len(u.whitelist) > 0 && !u.whitelist[symbol] {
+		return fmt.Errorf("%s 不在白名单中", symbol)
+	}
+	if u.tradable != nil && !u.tradable[symbol] {
+		return fmt.Errorf("%s 在交易所不可交易（未上架、已下架或结算中）", symbol)
+	}
+	return nil
+}
+
+// FilterCandidates 过滤候选币种，返回保留的币种
+func (u *SymbolUniverse) FilterCandidates(candidates []decision.CandidateCoin) []decision.CandidateCoin {
+	filtered := make([]decision.CandidateCoin, 0, len(candidates))
+	var skipped []string
+	for _, coin := range candidates {
+		if err := u.CheckSymbol(coin.Symbol); err != nil {
+			skipped = append(skipped, coin.Symbol)
+			continue
+		}
+		filtered = append(filtered, coin)
+	}
+	if len(skipped) > 0 {
+		log.Printf("🚫 候选币种过滤: 移除%d个 %v", len(skipped), skipped)
+	}
+	return filtered
+}
+
+// refreshSymbolUniverse 刷新可交易币种与用户黑白名单（不影响主流程）
+func (at *AutoTrader) refreshSymbolUniverse() {
+	if err := at.symbolUniverse.Refresh(); err != nil {
+		log.Printf("⚠️ [%s] %v", at.name, err)
+	}
+
+	if at.database == nil {
+		return
+	}
+
+	type SymbolFilterReader interface {
+		GetUserSymbolFilter(userID string) (*config.UserSymbolFilter, error)
+	}
+
+	db, ok := at.database.(SymbolFilterReader)
+	if !ok {
+		return
+	}
+	filter, err := db.GetUserSymbolFilter(at.userID)
+	if err != nil {
+		// 未配置黑白名单
+		at.symbolUniverse.SetFilters(nil, nil)
+		return
+	}
+	at.symbolUniverse.SetFilters(ParseSymbolList(filter.Blacklist), ParseSymbolList(filter.Whitelist))
+}
+
+// ParseSymbolList 解析逗号分隔的币种列表（自动补全USDT后缀）
+func ParseSymbolList(raw string) []string {
+	var symbols []string
+	for _, symbol := range strings.Split(raw, ",") {
+		if strings.TrimSpace(symbol) == "" {
+			continue
+		}
+		symbols = append(symbols, normalizeSymbol(symbol))
+	}
+	return symbols
+}
+
+func symbolSet(symbols []string) map[string]bool {
+	set := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		set[normalizeSymbol(symbol)] = true
+	}
+	return set
+}
@@ -0,0 +1,333 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx-lite/decision"
+	"nofx-lite/logger"
+	"strings"
+)
+
+// ladderFillTolerance 持仓减少量达到某档数量的此比例即视为该档已成交（容忍数量精度误差）
+const ladderFillTolerance = 0.5
+
+// takeProfitRung 止盈阶梯中的一档（数量按持仓换算后的实际挂单数量）
+type takeProfitRung struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+	Filled   bool    `json:"filled"`
+}
+
+// takeProfitLadder 持仓的多级止盈状态
+type takeProfitLadder struct {
+	PositionSide  string           `json:"position_side"` // LONG / SHORT
+	Rungs         []takeProfitRung `json:"rungs"`
+	StopLoss      float64          `json:"stop_loss"` // 当前止损价（部分平仓后按剩余数量重挂止损）
+	Breakeven     bool             `json:"breakeven"` // TP1成交后把止损移到开仓均价
+	BreakevenDone bool             `json:"breakeven_done"`
+	Quantity      float64          `json:"quantity"` // 上次同步时的持仓数量
+}
+
+// buildLadderRungs 按比例把持仓数量分配到各档（最后一档吃掉剩余，低于最小下单额的档并入下一档）
+func buildLadderRungs(levels []decision.TakeProfitLevel, quantity float64) []takeProfitRung {
+	rungs := make([]takeProfitRung, 0, len(levels))
+	allocated := 0.0
+	carry := 0.0
+	for i, level := range levels {
+		qty := quantity*level.Percentage/100 + carry
+		if i == len(levels)-1 {
+			qty = quantity - allocated
+		} else if qty*level.Price < minOrderNotionalUSD {
+			carry = qty
+			continue
+		}
+		carry = 0
+		allocated += qty
+		rungs = append(rungs, takeProfitRung{Price: level.Price, Quantity: qty})
+	}
+	return rungs
+}
+
+// pendingLevels 未成交的档位（比例按未成交总量换算）
+func (l *takeProfitLadder) pendingLevels() []decision.TakeProfitLevel {
+	pending := 0.0
+	for _, rung := range l.Rungs {
+		if !rung.Filled {
+			pending += rung.Quantity
+		}
+	}
+	var levels []decision.TakeProfitLevel
+	if pending <= 0 {
+		return levels
+	}
+	for _, rung := range l.Rungs {
+		if !rung.Filled {
+			levels = append(levels, decision.TakeProfitLevel{Price: rung.Price, Percentage: rung.Quantity / pending * 100})
+		}
+	}
+	return levels
+}
+
+// rebuild 保留已成交的档位，按新的持仓数量重新分配未成交部分
+func (l *takeProfitLadder) rebuild(levels []decision.TakeProfitLevel, quantity float64) {
+	var rungs []takeProfitRung
+	for _, rung := range l.Rungs {
+		if rung.Filled {
+			rungs = append(rungs, rung)
+		}
+	}
+	l.Rungs = append(rungs, buildLadderRungs(levels, quantity)...)
+	l.Quantity = quantity
+}
+
+// record 把实际挂出的止盈阶梯写入决策记录
+func (l *takeProfitLadder) record(actionRecord *logger.DecisionAction) {
+	if actionRecord == nil {
+		return
+	}
+	actionRecord.TakeProfitLadder = actionRecord.TakeProfitLadder[:0]
+	for _, rung := range l.Rungs {
+		actionRecord.TakeProfitLadder = append(actionRecord.TakeProfitLadder, logger.TakeProfitTarget{
+			Price:    rung.Price,
+			Quantity: rung.Quantity,
+			Filled:   rung.Filled,
+		})
+	}
+	actionRecord.MoveStopToBreakeven = l.Breakeven
+}
+
+// describe 日志用的阶梯描述
+func (l *takeProfitLadder) describe() string {
+	parts := make([]string, 0, len(l.Rungs))
+	for i, rung := range l.Rungs {
+		status := ""
+		if rung.Filled {
+			status = "✓"
+		}
+		parts = append(parts, fmt.Sprintf("TP%d %.4f×%.4f%s", i+1, rung.Price, rung.Quantity, status))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// placeProtectiveOrders 为整个持仓设置止损止盈（决策带止盈阶梯时分档挂 reduce-only 止盈单）
+func (at *AutoTrader) placeProtectiveOrders(d *decision.Decision, positionSide string, quantity float64, actionRecord *logger.DecisionAction) {
+	posKey := d.Symbol + "_" + strings.ToLower(positionSide)
+	// 加仓前的止损止盈与拆单期间的临时止损仍在：先撤销该方向的旧单再按整仓重挂
+	if err := at.trader.CancelStopLossOrdersBySide(d.Symbol, positionSide); err != nil {
+		log.Printf("  ⚠ 取消旧止损单失败: %v", err)
+	}
+	if err := at.trader.CancelTakeProfitOrdersBySide(d.Symbol, positionSide); err != nil {
+		log.Printf("  ⚠ 取消旧止盈单失败: %v", err)
+	}
+	if err := at.trader.SetStopLoss(d.Symbol, positionSide, quantity, d.StopLoss); err != nil {
+		log.Printf("  ⚠ 设置止损失败: %v", err)
+	} else {
+		at.setPositionStopLoss(posKey, d.StopLoss)
+	}
+
+	if len(d.TakeProfitLadder) == 0 {
+		delete(at.takeProfitLadders, posKey)
+		if err := at.trader.SetTakeProfit(d.Symbol, positionSide, quantity, d.TakeProfit); err != nil {
+			log.Printf("  ⚠ 设置止盈失败: %v", err)
+		}
+		return
+	}
+
+	ladder := &takeProfitLadder{
+		PositionSide: positionSide,
+		StopLoss:     d.StopLoss,
+		Breakeven:    d.MoveStopToBreakeven,
+	}
+	ladder.rebuild(d.TakeProfitLadder, quantity)
+	at.takeProfitLadders[posKey] = ladder
+	at.placeLadderOrders(d.Symbol, ladder)
+	ladder.record(actionRecord)
+}
+
+// placeLadderOrders 挂出所有未成交档位（最后一档用 SetTakeProfit 平掉剩余，其余为部分止盈）
+func (at *AutoTrader) placeLadderOrders(symbol string, l *takeProfitLadder) {
+	var pending []int
+	for i, rung := range l.Rungs {
+		if !rung.Filled {
+			pending = append(pending, i)
+		}
+	}
+
+	for n, i := range pending {
+		rung := l.Rungs[i]
+		var err error
+		if n == len(pending)-1 {
+			err = at.trader.SetTakeProfit(symbol, l.PositionSide, rung.Quantity, rung.Price)
+		} else {
+			err = at.trader.SetPartialTakeProfit(symbol, l.PositionSide, rung.Quantity, rung.Price)
+		}
+		if err != nil {
+			log.Printf("  ⚠ 设置第%d档止盈失败: %v", i+1, err)
+		}
+	}
+
+	breakeven := ""
+	if l.Breakeven && !l.BreakevenDone {
+		breakeven = "（TP1成交后止损移至保本）"
+	}
+	log.Printf("  🪜 %s %s 止盈阶梯: %s%s", symbol, l.PositionSide, l.describe(), breakeven)
+}
+
+// syncTakeProfitLadder 按交易所持仓数量推进止盈阶梯：识别已成交的档位，TP1成交后按需把止损移到保本
+// pos 为 nil 或数量为0时清理阶梯
+func (at *AutoTrader) syncTakeProfitLadder(posKey, symbol string, pos *Position) {
+	l := at.takeProfitLadders[posKey]
+	if l == nil {
+		return
+	}
+	if pos == nil || pos.Quantity == 0 {
+		delete(at.takeProfitLadders, posKey)
+		return
+	}
+
+	quantity := math.Abs(pos.Quantity)
+	drop := l.Quantity - quantity
+	for i := range l.Rungs {
+		if l.Rungs[i].Filled {
+			continue
+		}
+		if drop < l.Rungs[i].Quantity*ladderFillTolerance {
+			break
+		}
+		l.Rungs[i].Filled = true
+		drop -= l.Rungs[i].Quantity
+		log.Printf("  🎯 %s %s 第%d档止盈已成交 @ %.4f，剩余持仓 %.4f", symbol, l.PositionSide, i+1, l.Rungs[i].Price, quantity)
+	}
+	l.Quantity = quantity
+
+	if l.Breakeven && !l.BreakevenDone && len(l.Rungs) > 0 && l.Rungs[0].Filled {
+		at.moveStopToBreakeven(symbol, l, pos)
+	}
+}
+
+// moveStopToBreakeven TP1成交后把止损移到开仓均价（失败时下次同步重试）
+func (at *AutoTrader) moveStopToBreakeven(symbol string, l *takeProfitLadder, pos *Position) {
+	entry := pos.EntryPrice
+	if entry <= 0 {
+		return
+	}
+	// 止损已在保本或更优的位置
+	if (l.PositionSide == "LONG" && l.StopLoss >= entry) || (l.PositionSide == "SHORT" && l.StopLoss > 0 && l.StopLoss <= entry) {
+		l.BreakevenDone = true
+		return
+	}
+
+	if err := at.trader.CancelStopLossOrdersBySide(symbol, l.PositionSide); err != nil {
+		log.Printf("  ⚠ 取消旧止损单失败: %v", err)
+	}
+	if err := at.trader.SetStopLoss(symbol, l.PositionSide, math.Abs(pos.Quantity), entry); err != nil {
+		log.Printf("  ⚠ 止损移至保本失败（下次同步重试）: %v", err)
+		return
+	}
+
+	log.Printf("  🛡️ %s %s TP1已成交，止损 %.4f → 开仓均价 %.4f", symbol, l.PositionSide, l.StopLoss, entry)
+	at.setPositionStopLoss(symbol+"_"+strings.ToLower(l.PositionSide), entry)
+	l.StopLoss = entry
+	l.BreakevenDone = true
+}
+
+// advanceTakeProfitLadder 处理止盈触发推送：持仓仍在时推进阶梯并返回 true，已全部平仓时清理阶梯并返回 false
+func (at *AutoTrader) advanceTakeProfitLadder(posKey, symbol, side string) bool {
+	if at.takeProfitLadders[posKey] == nil {
+		return false
+	}
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		// 无法确认剩余持仓：保留阶梯，下个周期再同步
+		log.Printf("⚠️ [%s] 获取持仓失败，止盈阶梯稍后同步: %v", at.name, err)
+		return true
+	}
+	for i := range positions {
+		if positions[i].Symbol == symbol && positions[i].Side == side && positions[i].Quantity != 0 {
+			at.syncTakeProfitLadder(posKey, symbol, &positions[i])
+			return true
+		}
+	}
+
+	delete(at.takeProfitLadders, posKey)
+	return false
+}
+
+// rescaleTakeProfitLadder 部分平仓后按剩余数量等比缩小未成交档位，并重挂止损与止盈阶梯
+// （平仓时交易所会撤销该币种的挂单）
+func (at *AutoTrader) rescaleTakeProfitLadder(symbol, positionSide string, remaining float64, actionRecord *logger.DecisionAction) {
+	posKey := symbol + "_" + strings.ToLower(positionSide)
+	l := at.takeProfitLadders[posKey]
+	if l == nil {
+		return
+	}
+	if remaining <= 0 {
+		delete(at.takeProfitLadders, posKey)
+		return
+	}
+
+	l.rebuild(l.pendingLevels(), remaining)
+
+	if err := at.trader.CancelStopLossOrdersBySide(symbol, positionSide); err != nil {
+		log.Printf("  ⚠ 取消旧止损单失败: %v", err)
+	}
+	if err := at.trader.CancelTakeProfitOrdersBySide(symbol, positionSide); err != nil {
+		log.Printf("  ⚠ 取消旧止盈单失败: %v", err)
+	}
+	if l.StopLoss > 0 {
+		if err := at.trader.SetStopLoss(symbol, positionSide, remaining, l.StopLoss); err != nil {
+			log.Printf("  ⚠ 重设止损失败: %v", err)
+		}
+	}
+	at.placeLadderOrders(symbol, l)
+	l.record(actionRecord)
+}
+
+// updateTakeProfitLadder 调整止盈（update_take_profit）时维护阶梯，返回是否已按阶梯处理
+// 决策给出新阶梯时替换未成交档位；只给 new_take_profit 时移动最远一档，越过新止盈价的中间档并入最后一档
+// 调用前需已撤销该方向的旧止盈单
+func (at *AutoTrader) updateTakeProfitLadder(d *decision.Decision, pos *Position, actionRecord *logger.DecisionAction) bool {
+	positionSide := strings.ToUpper(pos.Side)
+	posKey := d.Symbol + "_" + pos.Side
+	l := at.takeProfitLadders[posKey]
+	if l == nil && len(d.TakeProfitLadder) == 0 {
+		return false
+	}
+	if l == nil {
+		l = &takeProfitLadder{PositionSide: positionSide, Quantity: math.Abs(pos.Quantity)}
+		at.takeProfitLadders[posKey] = l
+	}
+	at.syncTakeProfitLadder(posKey, d.Symbol, pos)
+
+	levels := d.TakeProfitLadder
+	if len(levels) > 0 {
+		if d.MoveStopToBreakeven {
+			l.Breakeven = true
+		}
+	} else {
+		pending := l.pendingLevels()
+		kept := 0.0
+		for _, level := range pending {
+			beyond := (positionSide == "LONG" && level.Price >= d.NewTakeProfit) ||
+				(positionSide == "SHORT" && level.Price <= d.NewTakeProfit)
+			if beyond {
+				break
+			}
+			levels = append(levels, level)
+			kept += level.Percentage
+		}
+		// 原最后一档（平剩余）移到新止盈价
+		if len(levels) > 0 && len(levels) == len(pending) {
+			kept -= levels[len(levels)-1].Percentage
+			levels = levels[:len(levels)-1]
+		}
+		levels = append(levels, decision.TakeProfitLevel{Price: d.NewTakeProfit, Percentage: 100 - kept})
+	}
+
+	l.rebuild(levels, math.Abs(pos.Quantity))
+	at.placeLadderOrders(d.Symbol, l)
+	l.record(actionRecord)
+	return true
+}
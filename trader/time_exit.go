@@ -0,0 +1,373 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"nofx-lite/logger"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultStaleMinProgressPct 未配置时"有进展"的最低有利价格变动（%）
+	defaultStaleMinProgressPct = 0.5
+	// defaultStaleStopPct 未配置时收紧后止损距当前价的距离（%）
+	defaultStaleStopPct = 1.0
+	// defaultFundingExitRatePct 未配置时触发结算前平仓的不利资金费率（%）
+	defaultFundingExitRatePct = 0.05
+)
+
+// 系统发起动作的触发原因
+const (
+	TimeExitReasonMaxHolding     = "max_holding_period"
+	TimeExitReasonStalePosition  = "stale_position"
+	TimeExitReasonAdverseFunding = "adverse_funding"
+)
+
+// TimeExitRules 按持仓时间的退出规则（0表示不启用该规则）
+type TimeExitRules struct {
+	MaxHoldingHours     float64 `json:"max_holding_hours"`      // 持仓超过X小时平仓
+	StaleHours          float64 `json:"stale_hours"`            // 持仓Y小时仍无进展时收紧止损
+	StaleMinProgressPct float64 `json:"stale_min_progress_pct"` // "有进展"的最低有利价格变动（%，0=默认0.5）
+	StaleStopPct        float64 `json:"stale_stop_pct"`         // 收紧后止损距当前价（%，0=默认1.0）
+	FundingExitMinutes  int     `json:"funding_exit_minutes"`   // 资金费结算前N分钟内检查，不利时平仓
+	FundingExitRatePct  float64 `json:"funding_exit_rate_pct"`  // 不利资金费率阈值（%，0=默认0.05）
+}
+
+// ParseTimeExitRules 解析trader配置中的JSON（空字符串返回nil）
+func ParseTimeExitRules(raw string) (*TimeExitRules, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var rules TimeExitRules
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		return nil, fmt.Errorf("解析持仓时间退出规则失败: %w", err)
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}
+
+// Validate 校验参数范围
+func (r *TimeExitRules) Validate() error {
+	if r.MaxHoldingHours < 0 || r.StaleHours < 0 {
+		return fmt.Errorf("持仓时长不能为负数")
+	}
+	if r.MaxHoldingHours > 0 && r.StaleHours >= r.MaxHoldingHours {
+		return fmt.Errorf("stale_hours (%.1f) 必须小于 max_holding_hours (%.1f)", r.StaleHours, r.MaxHoldingHours)
+	}
+	if r.StaleMinProgressPct < 0 || r.StaleMinProgressPct > 20 {
+		return fmt.Errorf("stale_min_progress_pct 必须在 0-20 之间")
+	}
+	if r.StaleStopPct < 0 || r.StaleStopPct > 20 {
+		return fmt.Errorf("stale_stop_pct 必须在 0-20 之间")
+	}
+	if r.FundingExitMinutes < 0 || r.FundingExitMinutes > 240 {
+		return fmt.Errorf("funding_exit_minutes 必须在 0-240 之间")
+	}
+	if r.FundingExitRatePct < 0 || r.FundingExitRatePct > 5 {
+		return fmt.Errorf("funding_exit_rate_pct 必须在 0-5 之间")
+	}
+	return nil
+}
+
+// Enabled 是否启用了任一规则
+func (r *TimeExitRules) Enabled() bool {
+	return r != nil && (r.MaxHoldingHours > 0 || r.StaleHours > 0 || r.FundingExitMinutes > 0)
+}
+
+func (r *TimeExitRules) staleMinProgressPct() float64 {
+	if r.StaleMinProgressPct > 0 {
+		return r.StaleMinProgressPct
+	}
+	return defaultStaleMinProgressPct
+}
+
+func (r *TimeExitRules) staleStopPct() float64 {
+	if r.StaleStopPct > 0 {
+		return r.StaleStopPct
+	}
+	return defaultStaleStopPct
+}
+
+func (r *TimeExitRules) fundingExitRatePct() float64 {
+	if r.FundingExitRatePct > 0 {
+		return r.FundingExitRatePct
+	}
+	return defaultFundingExitRatePct
+}
+
+// positionOpenTime 持仓首次出现时间（后台监控读取，需加锁）
+func (at *AutoTrader) positionOpenTime(posKey string) (time.Time, bool) {
+	at.positionStateMutex.RLock()
+	defer at.positionStateMutex.RUnlock()
+	ms, ok := at.positionFirstSeenTime[posKey]
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+// setPositionStopLoss 记录持仓当前止损价（后台监控收紧止损时用来避免放宽止损）
+func (at *AutoTrader) setPositionStopLoss(posKey string, price float64) {
+	at.positionStateMutex.Lock()
+	defer at.positionStateMutex.Unlock()
+	at.positionStopLoss[posKey] = price
+}
+
+// positionStopLossPrice 持仓当前止损价（未知时返回false）
+func (at *AutoTrader) positionStopLossPrice(posKey string) (float64, bool) {
+	at.positionStateMutex.RLock()
+	defer at.positionStateMutex.RUnlock()
+	price, ok := at.positionStopLoss[posKey]
+	return price, ok && price > 0
+}
+
+// checkTimeExits 后台监控：按持仓时间执行退出规则（超时平仓、无进展收紧止损、结算前规避不利资金费）
+func (at *AutoTrader) checkTimeExits() {
+	rules := at.timeExitRules
+	if !rules.Enabled() {
+		return
+	}
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("❌ 持仓时间监控: 获取持仓失败: %v", err)
+		return
+	}
+
+	open := make(map[string]bool)
+	for _, pos := range positions {
+		if pos.Quantity == 0 || pos.EntryPrice <= 0 {
+			continue
+		}
+		posKey := pos.Symbol + "_" + pos.Side
+		open[posKey] = true
+
+		openedAt, ok := at.positionOpenTime(posKey)
+		if !ok {
+			continue // 决策周期尚未记录该持仓
+		}
+		held := time.Since(openedAt)
+
+		// 1. 超过最长持仓时间：平仓
+		if rules.MaxHoldingHours > 0 && held.Hours() >= rules.MaxHoldingHours {
+			reason := fmt.Sprintf("持仓 %.1f 小时，超过上限 %.1f 小时", held.Hours(), rules.MaxHoldingHours)
+			at.systemClosePosition(pos, TimeExitReasonMaxHolding, reason)
+			continue
+		}
+
+		// 2. 资金费结算前费率不利：平仓
+		if rules.FundingExitMinutes > 0 && at.checkAdverseFunding(pos, rules) {
+			continue
+		}
+
+		// 3. 长时间无进展：收紧止损（每个持仓只收紧一次）
+		if rules.StaleHours > 0 && held.Hours() >= rules.StaleHours && !at.isTimeExitTightened(posKey) {
+			at.tightenStaleStop(pos, held, rules)
+		}
+	}
+
+	at.positionStateMutex.Lock()
+	for key := range at.timeExitTightened {
+		if !open[key] {
+			delete(at.timeExitTightened, key)
+		}
+	}
+	at.positionStateMutex.Unlock()
+}
+
+// isTimeExitTightened 持仓是否已收紧（或已提交收紧）止损
+func (at *AutoTrader) isTimeExitTightened(posKey string) bool {
+	at.positionStateMutex.RLock()
+	defer at.positionStateMutex.RUnlock()
+	return at.timeExitTightened[posKey]
+}
+
+// setTimeExitTightened 标记持仓的收紧状态（执行失败时清除，后台监控下次重试）
+func (at *AutoTrader) setTimeExitTightened(posKey string, tightened bool) {
+	at.positionStateMutex.Lock()
+	defer at.positionStateMutex.Unlock()
+	if tightened {
+		at.timeExitTightened[posKey] = true
+	} else {
+		delete(at.timeExitTightened, posKey)
+	}
+}
+
+// checkAdverseFunding 结算前N分钟内资金费率不利（多仓付费率>阈值 / 空仓付费率<-阈值）时平仓，返回是否已平仓
+func (at *AutoTrader) checkAdverseFunding(pos Position, rules *TimeExitRules) bool {
+	funding, err := at.marketSource.GetFundingData(pos.Symbol)
+	if err != nil || funding == nil || funding.NextFundingTime.IsZero() {
+		return false
+	}
+	until := time.Until(funding.NextFundingTime)
+	if until <= 0 || until > time.Duration(rules.FundingExitMinutes)*time.Minute {
+		return false
+	}
+
+	ratePct := funding.PredictedRate * 100
+	paying := ratePct
+	if pos.Side == "short" {
+		paying = -ratePct
+	}
+	if paying < rules.fundingExitRatePct() {
+		return false
+	}
+
+	reason := fmt.Sprintf("%.0f 分钟后结算资金费，预测费率 %.4f%% 对%s不利（阈值 %.4f%%，预计支付 %.2f USDT）",
+		until.Minutes(), ratePct, pos.Side, rules.fundingExitRatePct(), pos.Quantity*pos.MarkPrice*paying/100)
+	at.systemClosePosition(pos, TimeExitReasonAdverseFunding, reason)
+	return true
+}
+
+// staleStopRequest 后台监控发起的无进展收紧止损（交给主循环执行，保持止盈阶梯记录的止损价一致）
+type staleStopRequest struct {
+	pos     Position
+	newStop float64
+	reason  string
+}
+
+// tightenStaleStop 持仓长时间无进展时把止损收紧到距当前价 stale_stop_pct（只收紧不放宽）
+// 后台监控只计算新止损，实际撤单/挂单由主循环在 applyStaleStop 中执行
+func (at *AutoTrader) tightenStaleStop(pos Position, held time.Duration, rules *TimeExitRules) {
+	posKey := pos.Symbol + "_" + pos.Side
+	progressPct := (pos.MarkPrice - pos.EntryPrice) / pos.EntryPrice * 100
+	if pos.Side == "short" {
+		progressPct = -progressPct
+	}
+	if progressPct >= rules.staleMinProgressPct() {
+		return
+	}
+
+	newStop := pos.MarkPrice * (1 - rules.staleStopPct()/100)
+	if pos.Side == "short" {
+		newStop = pos.MarkPrice * (1 + rules.staleStopPct()/100)
+	}
+	if !at.isTighterStop(posKey, pos.Side, newStop) {
+		// 现有止损已经更紧
+		at.setTimeExitTightened(posKey, true)
+		return
+	}
+
+	reason := fmt.Sprintf("持仓 %.1f 小时无进展（有利变动 %.2f%% < %.2f%%），止损收紧至距当前价 %.2f%%",
+		held.Hours(), progressPct, rules.staleMinProgressPct(), rules.staleStopPct())
+	log.Printf("⏳ [%s] %s %s %s", at.name, pos.Symbol, pos.Side, reason)
+
+	// 排队期间不重复提交
+	at.setTimeExitTightened(posKey, true)
+	select {
+	case at.staleStopCh <- staleStopRequest{pos: pos, newStop: newStop, reason: reason}:
+	default:
+		at.setTimeExitTightened(posKey, false)
+		log.Printf("⚠️ [%s] 收紧止损队列已满，下次检查重试: %s %s", at.name, pos.Symbol, pos.Side)
+	}
+}
+
+// isTighterStop 新止损是否比当前记录的止损更紧（没有记录时视为更紧）
+func (at *AutoTrader) isTighterStop(posKey, side string, newStop float64) bool {
+	current, ok := at.positionStopLossPrice(posKey)
+	if !ok {
+		return true
+	}
+	if side == "long" {
+		return newStop > current
+	}
+	return newStop < current
+}
+
+// applyStaleStop 在主循环中收紧止损，并同步止盈阶梯记录的止损价（部分平仓重挂时不会恢复旧止损）
+func (at *AutoTrader) applyStaleStop(req staleStopRequest) {
+	pos := req.pos
+	posKey := pos.Symbol + "_" + pos.Side
+	positionSide := strings.ToUpper(pos.Side)
+
+	// 排队期间AI可能已把止损调整得更紧
+	if !at.isTighterStop(posKey, pos.Side, req.newStop) {
+		return
+	}
+
+	actionRecord := logger.DecisionAction{
+		Action:    "update_stop_loss",
+		Symbol:    pos.Symbol,
+		Quantity:  pos.Quantity,
+		Leverage:  pos.EffectiveLeverage(),
+		Price:     pos.MarkPrice,
+		Timestamp: time.Now(),
+		Reason:    TimeExitReasonStalePosition,
+	}
+
+	if err := at.trader.CancelStopLossOrdersBySide(pos.Symbol, positionSide); err != nil {
+		log.Printf("  ⚠ 取消旧止损单失败: %v", err)
+	}
+	err := at.trader.SetStopLoss(pos.Symbol, positionSide, math.Abs(pos.Quantity), req.newStop)
+	if err == nil {
+		at.setPositionStopLoss(posKey, req.newStop)
+		if l := at.takeProfitLadders[posKey]; l != nil {
+			l.StopLoss = req.newStop
+		}
+		log.Printf("  ✓ 止损已收紧: %.4f (当前价格: %.4f)", req.newStop, pos.MarkPrice)
+	} else {
+		// 允许后台监控下次重试
+		at.setTimeExitTightened(posKey, false)
+	}
+	at.logSystemAction(actionRecord, req.reason, err)
+}
+
+// systemClosePosition 后台监控发起的全部平仓
+func (at *AutoTrader) systemClosePosition(pos Position, reasonCode, reason string) {
+	log.Printf("⏰ [%s] %s %s 系统平仓: %s", at.name, pos.Symbol, pos.Side, reason)
+
+	actionRecord := logger.DecisionAction{
+		Action:    "close_" + pos.Side,
+		Symbol:    pos.Symbol,
+		Quantity:  pos.Quantity,
+		Leverage:  pos.EffectiveLeverage(),
+		Price:     pos.MarkPrice,
+		Timestamp: time.Now(),
+		Reason:    reasonCode,
+	}
+
+	var order *OrderResult
+	var err error
+	if pos.Side == "long" {
+		order, err = at.trader.CloseLong(pos.Symbol, 0, "") // 0 = 全部平仓
+	} else {
+		order, err = at.trader.CloseShort(pos.Symbol, 0, "")
+	}
+	if err == nil {
+		actionRecord.OrderID = order.OrderID
+		at.recordFill(order.AvgPrice, nil, &actionRecord)
+		at.ClearPeakPnLCache(pos.Symbol, pos.Side)
+		log.Printf("  ✓ 系统平仓成功: %s %s", pos.Symbol, pos.Side)
+		// 释放的保证金交给AI重新评估
+		at.TriggerCycle(fmt.Sprintf("系统平仓 %s %s", pos.Symbol, pos.Side))
+	}
+	at.logSystemAction(actionRecord, reason, err)
+}
+
+// logSystemAction 把后台监控发起的动作单独写入决策记录（initiator=system）
+func (at *AutoTrader) logSystemAction(actionRecord logger.DecisionAction, reason string, err error) {
+	record := &logger.DecisionRecord{
+		Initiator:    logger.InitiatorSystem,
+		ExecutionLog: []string{reason},
+		Success:      err == nil,
+	}
+	if err != nil {
+		log.Printf("❌ 系统动作执行失败 (%s %s): %v", actionRecord.Symbol, actionRecord.Action, err)
+		actionRecord.Error = err.Error()
+		record.ErrorMessage = err.Error()
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✗ %s %s failed: %v", actionRecord.Symbol, actionRecord.Action, err))
+	} else {
+		actionRecord.Success = true
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s success", actionRecord.Symbol, actionRecord.Action))
+	}
+	record.Decisions = []logger.DecisionAction{actionRecord}
+
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存系统动作记录失败: %v", err)
+	}
+}
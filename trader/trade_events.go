@@ -0,0 +1,94 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+)
+
+// startAccountStream 启动交易所实时推送，返回停止函数（不支持时返回nil）
+func (at *AutoTrader) startAccountStream() func() {
+	provider, ok := at.trader.(AccountStreamProvider)
+	if !ok {
+		return nil
+	}
+
+	err := provider.StartAccountStream(func(event TradeEvent) {
+		// 推送goroutine中不做处理，交给主循环串行执行
+		select {
+		case at.tradeEventCh <- event:
+		default:
+			log.Printf("⚠️ [%s] 交易事件队列已满，丢弃事件: %s %s", at.name, event.Type, event.Symbol)
+		}
+	})
+	if err != nil {
+		log.Printf("⚠️ [%s] 启动实时账户推送失败，继续使用REST轮询: %v", at.name, err)
+		return nil
+	}
+
+	log.Printf("📶 [%s] 实时账户推送已启动", at.name)
+	return provider.StopAccountStream
+}
+
+// handleTradeEvent 处理交易所推送事件（在主循环中执行）
+func (at *AutoTrader) handleTradeEvent(event TradeEvent) {
+	posKey := event.Symbol + "_" + event.Side
+
+	switch event.Type {
+	case TradeEventStopLoss, TradeEventTakeProfit, TradeEventLiquidation:
+		log.Printf("%s [%s] %s %s %s 成交: 数量 %.4f @ %.4f，已实现盈亏 %+.2f USDT",
+			tradeEventIcon(event.Type), at.name, tradeEventLabel(event.Type),
+			event.Symbol, event.Side, event.Quantity, event.Price, event.RealizedPnL)
+
+		// 止盈阶梯的中间档成交：持仓仍在，只推进阶梯（必要时止损移到保本）
+		if event.Type == TradeEventTakeProfit && at.advanceTakeProfitLadder(posKey, event.Symbol, event.Side) {
+			return
+		}
+
+		// 持仓已被交易所侧平掉，清理本地跟踪状态
+		at.ClearPeakPnLCache(event.Symbol, event.Side)
+		at.positionStateMutex.Lock()
+		delete(at.positionFirstSeenTime, posKey)
+		delete(at.positionStopLoss, posKey)
+		at.positionStateMutex.Unlock()
+		delete(at.positionBuildUp, posKey)
+		delete(at.takeProfitLadders, posKey)
+
+		// 立即让AI重新评估（释放出的保证金、剩余持仓）
+		at.TriggerCycle(fmt.Sprintf("%s %s %s", tradeEventLabel(event.Type), event.Symbol, event.Side))
+
+	case TradeEventTriggerRejected:
+		log.Printf("🚨 [%s] %s 条件单 %d 触发失败: %s（止损可能未生效）", at.name, event.Symbol, event.OrderID, event.Reason)
+		at.TriggerCycle(fmt.Sprintf("条件单触发失败 %s", event.Symbol))
+
+	default:
+		action := "开仓"
+		if event.Closing {
+			action = "平仓"
+		}
+		log.Printf("📬 [%s] %s成交: %s %s 数量 %.4f @ %.4f", at.name, action, event.Symbol, event.Side, event.Quantity, event.Price)
+	}
+}
+
+func tradeEventLabel(eventType string) string {
+	switch eventType {
+	case TradeEventStopLoss:
+		return "止损触发"
+	case TradeEventTakeProfit:
+		return "止盈触发"
+	case TradeEventLiquidation:
+		return "强平"
+	}
+	return "成交"
+}
+
+func tradeEventIcon(eventType string) string {
+	switch eventType {
+	case TradeEventStopLoss:
+		return "🛑"
+	case TradeEventTakeProfit:
+		return "🎯"
+	case TradeEventLiquidation:
+		return "💥"
+	}
+	return "📬"
+}
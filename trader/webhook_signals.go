@@ -0,0 +1,127 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx-lite/config"
+	"nofx-lite/decision"
+	"nofx-lite/pool"
+	"sort"
+	"time"
+)
+
+// webhookSourceName 外部Webhook信号在候选币种中的来源名称
+const webhookSourceName = "webhook"
+
+// minTriggerInterval 外部信号触发决策周期的最小间隔（防止告警风暴）
+const minTriggerInterval = time.Minute
+
+// TriggerCycle 请求立即执行一次决策周期（非阻塞，已有待执行的请求时忽略）
+func (at *AutoTrader) TriggerCycle(reason string) bool {
+	if !at.isRunning.Load() {
+		return false
+	}
+	select {
+	case at.cycleTriggerCh <- struct{}{}:
+		log.Printf("⚡ [%s] 收到立即执行请求: %s", at.name, reason)
+		return true
+	default:
+		return false
+	}
+}
+
+// getWebhookSignals 从数据库读取未过期的外部信号（同一币种只保留最新一条）
+func (at *AutoTrader) getWebhookSignals() []*config.WebhookSignal {
+	if at.database == nil {
+		return nil
+	}
+
+	type WebhookSignalReader interface {
+		GetActiveWebhookSignals(traderID string) ([]*config.WebhookSignal, error)
+	}
+
+	db, ok := at.database.(WebhookSignalReader)
+	if !ok {
+		return nil
+	}
+
+	signals, err := db.GetActiveWebhookSignals(at.id)
+	if err != nil {
+		log.Printf("⚠️ [%s] 读取外部信号失败: %v", at.name, err)
+		return nil
+	}
+
+	// 已按接收时间倒序，同一币种取第一条
+	seen := make(map[string]bool)
+	var latest []*config.WebhookSignal
+	for _, signal := range signals {
+		symbol := normalizeSymbol(signal.Symbol)
+		if seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		latest = append(latest, signal)
+	}
+	return latest
+}
+
+// mergeWebhookSignals 将外部信号注入候选币种（作为webhook来源），带信号的币种排在最前
+func (at *AutoTrader) mergeWebhookSignals(candidates []decision.CandidateCoin) []decision.CandidateCoin {
+	signals := at.getWebhookSignals()
+	if len(signals) == 0 {
+		return candidates
+	}
+
+	index := make(map[string]int, len(candidates))
+	for i, coin := range candidates {
+		index[coin.Symbol] = i
+	}
+
+	for _, signal := range signals {
+		symbol := normalizeSymbol(signal.Symbol)
+		source := pool.SourceScore{
+			Name:   webhookSourceName,
+			Score:  signal.Strength,
+			Reason: formatWebhookReason(signal),
+		}
+		if i, ok := index[symbol]; ok {
+			candidates[i].Sources = append(candidates[i].Sources, source)
+			continue
+		}
+		index[symbol] = len(candidates)
+		candidates = append(candidates, decision.CandidateCoin{
+			Symbol:  symbol,
+			Sources: []pool.SourceScore{source},
+		})
+	}
+
+	// 带外部信号的币种优先（保证不被候选数量上限截断），其余保持原顺序
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return hasSource(candidates[i], webhookSourceName) && !hasSource(candidates[j], webhookSourceName)
+	})
+
+	log.Printf("📡 [%s] 注入 %d 条外部信号", at.name, len(signals))
+	return candidates
+}
+
+// formatWebhookReason 外部信号说明（传入AI prompt）
+func formatWebhookReason(signal *config.WebhookSignal) string {
+	direction := "做多"
+	if signal.Direction == "short" {
+		direction = "做空"
+	}
+	reason := fmt.Sprintf("外部信号%s 强度%.2f 剩余%.0f分钟", direction, signal.Strength, time.Until(signal.ExpiresAt).Minutes())
+	if signal.Message != "" {
+		reason += " - " + signal.Message
+	}
+	return reason
+}
+
+func hasSource(coin decision.CandidateCoin, name string) bool {
+	for _, src := range coin.Sources {
+		if src.Name == name {
+			return true
+		}
+	}
+	return false
+}